@@ -10,10 +10,16 @@ const (
 	DefaultMaxConns          = int32(20)        // Максимальное количество соединений в пуле
 	DefaultMinConns          = int32(5)         // Минимальное количество соединений в пуле
 
+	DefaultListenerMinReconnect = 1 * time.Second // Начальная пауза перед переподключением LISTEN
+	DefaultListenerMaxReconnect = 1 * time.Minute // Максимальная пауза перед переподключением LISTEN
+
 	DefaultPort = 5432
 	DefaultHost = "localhost"
 	DefaultUser = "postgres"
 	DevSchema   = "mexc_bot_db"
+
+	// DefaultMasterKeysEnv — переменная окружения с мастер-ключами шифрования ключей MEXC API
+	DefaultMasterKeysEnv = "METACORE_MASTER_KEYS"
 )
 
 // DBConfig описывает конфигурацию подключения к базе данных.
@@ -24,6 +30,8 @@ type DBConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// SkipSchemaCheck отключает проверку схемы БД на соответствие доменным моделям при подключении
+	SkipSchemaCheck bool
 }
 
 // PoolConfig описывает конфигурацию пула соединений.
@@ -36,10 +44,21 @@ type PoolConfig struct {
 	ConnectTimeout    time.Duration
 }
 
+// SecretsConfig описывает источник мастер-ключей для шифрования ключей MEXC API.
+// Ключи задаются строками "версия:base64" (32 байта), текущим считается ключ с наибольшей версией.
+// Если не задан ни KeyFile, ни KeyEnv, ключи хранятся в БД открыто.
+type SecretsConfig struct {
+	// KeyFile — путь к файлу с ключами, по одному на строку
+	KeyFile string
+	// KeyEnv — имя переменной окружения с ключами через запятую, например DefaultMasterKeysEnv
+	KeyEnv string
+}
+
 // Config объединяет всю конфигурацию БД.
 type Config struct {
-	DB   DBConfig
-	Pool PoolConfig
+	DB      DBConfig
+	Pool    PoolConfig
+	Secrets SecretsConfig
 }
 
 func DefaultConfig() Config {
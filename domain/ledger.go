@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LedgerAccount — счет двойной записи. Активы пользователя учитываются на счете USER,
+// остальные счета — контрагенты его операций.
+type LedgerAccount string
+
+const (
+	LedgerAccountUser     LedgerAccount = "USER"     // активы пользователя на бирже
+	LedgerAccountExchange LedgerAccount = "EXCHANGE" // встречная сторона сделок
+	LedgerAccountFees     LedgerAccount = "FEES"     // комиссии биржи
+	LedgerAccountExternal LedgerAccount = "EXTERNAL" // внешние кошельки: вводы и выводы
+)
+
+// LedgerEntryKind — тип операции, породившей проводку.
+type LedgerEntryKind string
+
+const (
+	LedgerEntryTrade      LedgerEntryKind = "TRADE"
+	LedgerEntryCommission LedgerEntryKind = "COMMISSION"
+	LedgerEntryDeposit    LedgerEntryKind = "DEPOSIT"
+	LedgerEntryWithdrawal LedgerEntryKind = "WITHDRAWAL"
+)
+
+var (
+	// ErrUnbalancedEntries возвращается, если сумма проводок операции по активу не равна нулю.
+	ErrUnbalancedEntries = errors.New("ledger entries are not balanced")
+	// ErrUnknownSymbol возвращается, если из символа нельзя выделить базовый и котируемый актив.
+	ErrUnknownSymbol = errors.New("unknown symbol")
+)
+
+// LedgerEntry — одна проводка. Amount положителен для прихода на счет и отрицателен для расхода.
+// Проводки одной операции (TransactionID) по каждому активу в сумме дают ноль.
+// Поля соответствуют таблице ledger_entries в БД.
+type LedgerEntry struct {
+	ID            uint64          `db:"id"`
+	UserID        uint64          `db:"user_id"`
+	TransactionID string          `db:"transaction_id"` // например trade:<mexc_trade_id>
+	Account       LedgerAccount   `db:"account"`
+	Asset         string          `db:"asset"`
+	Amount        decimal.Decimal `db:"amount"`
+	Kind          LedgerEntryKind `db:"kind"`
+	RefID         string          `db:"ref_id"` // mexc_trade_id, id ввода или вывода
+	EntryTime     time.Time       `db:"entry_time"`
+	CreatedAt     time.Time       `db:"created_at"`
+	// Balance — баланс счета по активу после проводки, заполняется при чтении истории
+	Balance decimal.Decimal `db:"-"`
+}
+
+// LedgerBalance — баланс счета USER по активу по данным ledger.
+type LedgerBalance struct {
+	Asset   string
+	Balance decimal.Decimal
+}
+
+// quoteAssets — котируемые активы спота MEXC. USD проверяется после FDUSD, TUSD и BUSD,
+// которые на него оканчиваются.
+var quoteAssets = []string{"USDT", "USDC", "USDE", "FDUSD", "TUSD", "BUSD", "DAI", "BTC", "ETH", "EUR", "BRL", "TRY", "USD", "MX"}
+
+// SplitSymbol выделяет базовый и котируемый актив из символа спота, например BTCUSDT -> BTC, USDT.
+func SplitSymbol(symbol string) (base, quote string, err error) {
+	for _, q := range quoteAssets {
+		if strings.HasSuffix(symbol, q) && len(symbol) > len(q) {
+			return strings.TrimSuffix(symbol, q), q, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrUnknownSymbol, symbol)
+}
+
+// TradeLedgerEntries строит проводки сделки: базовый и котируемый актив против EXCHANGE
+// и комиссию против FEES. Нулевая комиссия не проводится.
+func TradeLedgerEntries(trade *Trade) ([]*LedgerEntry, error) {
+	base, quote, err := SplitSymbol(trade.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	baseAmount, quoteAmount := trade.Quantity, trade.QuoteQuantity.Neg()
+	if !trade.IsBuyer {
+		baseAmount, quoteAmount = baseAmount.Neg(), trade.QuoteQuantity
+	}
+
+	tx := ledgerTx{
+		userID:        trade.UserID,
+		transactionID: "trade:" + trade.MexcTradeID,
+		refID:         trade.MexcTradeID,
+		entryTime:     trade.TradeTime,
+	}
+	tx.transfer(LedgerAccountExchange, base, baseAmount, LedgerEntryTrade)
+	tx.transfer(LedgerAccountExchange, quote, quoteAmount, LedgerEntryTrade)
+	if !trade.Commission.IsZero() {
+		tx.transfer(LedgerAccountFees, trade.CommissionAsset, trade.Commission.Neg(), LedgerEntryCommission)
+	}
+
+	return tx.entries, nil
+}
+
+// DepositLedgerEntries строит проводки ввода amount актива с внешнего кошелька.
+func DepositLedgerEntries(userID uint64, asset string, amount decimal.Decimal, refID string, at time.Time) []*LedgerEntry {
+	tx := ledgerTx{userID: userID, transactionID: "deposit:" + refID, refID: refID, entryTime: at}
+	tx.transfer(LedgerAccountExternal, asset, amount, LedgerEntryDeposit)
+	return tx.entries
+}
+
+// WithdrawalLedgerEntries строит проводки вывода amount актива на внешний кошелек и комиссии fee.
+func WithdrawalLedgerEntries(userID uint64, asset string, amount, fee decimal.Decimal, refID string, at time.Time) []*LedgerEntry {
+	tx := ledgerTx{userID: userID, transactionID: "withdrawal:" + refID, refID: refID, entryTime: at}
+	tx.transfer(LedgerAccountExternal, asset, amount.Neg(), LedgerEntryWithdrawal)
+	if !fee.IsZero() {
+		tx.transfer(LedgerAccountFees, asset, fee.Neg(), LedgerEntryCommission)
+	}
+	return tx.entries
+}
+
+// ValidateLedgerEntries проверяет, что проводки каждой операции по каждому активу сбалансированы.
+func ValidateLedgerEntries(entries []*LedgerEntry) error {
+	type key struct{ transactionID, asset string }
+	sums := make(map[key]decimal.Decimal)
+	var order []key
+	for _, e := range entries {
+		k := key{e.TransactionID, e.Asset}
+		if _, ok := sums[k]; !ok {
+			order = append(order, k)
+		}
+		sums[k] = sums[k].Add(e.Amount)
+	}
+	for _, k := range order {
+		if !sums[k].IsZero() {
+			return fmt.Errorf("%w: transaction %s, asset %s, sum %s", ErrUnbalancedEntries, k.transactionID, k.asset, sums[k])
+		}
+	}
+	return nil
+}
+
+// ledgerTx собирает проводки одной операции.
+type ledgerTx struct {
+	userID        uint64
+	transactionID string
+	refID         string
+	entryTime     time.Time
+	entries       []*LedgerEntry
+}
+
+// transfer проводит amount на счет USER и -amount на счет counter.
+func (tx *ledgerTx) transfer(counter LedgerAccount, asset string, amount decimal.Decimal, kind LedgerEntryKind) {
+	for _, side := range []struct {
+		account LedgerAccount
+		amount  decimal.Decimal
+	}{
+		{LedgerAccountUser, amount},
+		{counter, amount.Neg()},
+	} {
+		tx.entries = append(tx.entries, &LedgerEntry{
+			UserID:        tx.userID,
+			TransactionID: tx.transactionID,
+			Account:       side.account,
+			Asset:         asset,
+			Amount:        side.amount,
+			Kind:          kind,
+			RefID:         tx.refID,
+			EntryTime:     tx.entryTime,
+		})
+	}
+}
+
+// ReconciliationItem — сравнение баланса ledger с балансом биржи (free + locked) по активу.
+type ReconciliationItem struct {
+	Asset      string
+	Ledger     decimal.Decimal
+	Exchange   decimal.Decimal
+	Difference decimal.Decimal // Ledger - Exchange
+}
+
+// ReconciliationReport — результат сверки ledger с user_balances.
+type ReconciliationReport struct {
+	UserID uint64
+	Items  []ReconciliationItem // по всем активам, отсортированы по активу
+}
+
+// Mismatches возвращает активы с ненулевой разницей.
+func (r *ReconciliationReport) Mismatches() []ReconciliationItem {
+	var result []ReconciliationItem
+	for _, item := range r.Items {
+		if !item.Difference.IsZero() {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// OK возвращает true, если ledger совпадает с балансами биржи по всем активам.
+func (r *ReconciliationReport) OK() bool {
+	return len(r.Mismatches()) == 0
+}
+
+// Reconcile сравнивает балансы ledger с последними балансами биржи.
+// Актив, отсутствующий с одной из сторон, считается нулевым.
+func Reconcile(userID uint64, ledger []*LedgerBalance, balances []*UserBalance) *ReconciliationReport {
+	items := make(map[string]*ReconciliationItem)
+	item := func(asset string) *ReconciliationItem {
+		if items[asset] == nil {
+			items[asset] = &ReconciliationItem{Asset: asset}
+		}
+		return items[asset]
+	}
+
+	for _, l := range ledger {
+		item(l.Asset).Ledger = l.Balance
+	}
+	for _, b := range balances {
+		item(b.Asset).Exchange = b.Free.Add(b.Locked)
+	}
+
+	report := &ReconciliationReport{UserID: userID}
+	for _, it := range items {
+		it.Difference = it.Ledger.Sub(it.Exchange)
+		report.Items = append(report.Items, *it)
+	}
+	sort.Slice(report.Items, func(i, j int) bool { return report.Items[i].Asset < report.Items[j].Asset })
+
+	return report
+}
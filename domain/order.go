@@ -9,28 +9,47 @@ import (
 // Order представляет собой ордер в системе.
 // Поля должны соответствовать таблице orders в БД.
 type Order struct {
-	ID                  uint64          `db:"id"`
-	InternalID          int64           `db:"internal_id"` // BIGINT UNIQUE
-	UserID              uint64          `db:"user_id"`
-	MexcOrderID         string          `db:"mexc_order_id"`
-	Symbol              string          `db:"symbol"`
-	Side                string          `db:"side"` // BUY, SELL
-	Type                string          `db:"type"` // LIMIT, MARKET, etc.
-	Status              string          `db:"status"`
-	Price               decimal.Decimal `db:"price"` // DECIMAL(30, 15)
-	Quantity            decimal.Decimal `db:"quantity"`
-	QuoteOrderQty       decimal.Decimal `db:"quote_order_qty"` // Может быть NULL
-	ExecutedQuantity    decimal.Decimal `db:"executed_quantity"`
-	CummulativeQuoteQty decimal.Decimal `db:"cummulative_quote_qty"`
-	ClientOrderID       string          `db:"client_order_id"` // Может быть NULL
-	TransactTime        time.Time       `db:"transact_time"`   // Unix timestamp в миллисекундах из API
-	CreatedAt           time.Time       `db:"created_at"`      // Можно добавить, если нужно в коде
-	UpdatedAt           time.Time       `db:"updated_at"`      // Можно добавить, если нужно в коде
+	ID                  uint64              `db:"id,auto"`
+	InternalID          int64               `db:"internal_id"` // BIGINT UNIQUE
+	UserID              uint64              `db:"user_id"`
+	MexcOrderID         string              `db:"mexc_order_id"`
+	Symbol              string              `db:"symbol"`
+	Side                OrderSide           `db:"side"` // BUY, SELL
+	Type                OrderType           `db:"type"` // LIMIT, MARKET, etc.
+	Status              OrderStatus         `db:"status"`
+	Price               decimal.Decimal     `db:"price"` // DECIMAL(30, 15)
+	Quantity            decimal.Decimal     `db:"quantity"`
+	QuoteOrderQty       decimal.Decimal     `db:"quote_order_qty"` // Может быть NULL
+	ExecutedQuantity    decimal.Decimal     `db:"executed_quantity"`
+	CummulativeQuoteQty decimal.Decimal     `db:"cummulative_quote_qty"`
+	ClientOrderID       string              `db:"client_order_id"`        // Может быть NULL
+	TransactTime        time.Time           `db:"transact_time"`          // Unix timestamp в миллисекундах из API
+	TimeInForce         TimeInForce         `db:"time_in_force,nullzero"` // GTC, IOC, FOK; может быть NULL
+	StopPrice           decimal.NullDecimal `db:"stop_price"`             // Для стоп-ордеров, может быть NULL
+	IcebergQty          decimal.NullDecimal `db:"iceberg_qty"`            // Может быть NULL
+	OrderListID         string              `db:"order_list_id,nullzero"` // mexc_order_list_id списка (OCO), может быть NULL
+	CreatedAt           time.Time           `db:"created_at,auto"`        // Можно добавить, если нужно в коде
+	UpdatedAt           time.Time           `db:"updated_at,now"`         // Можно добавить, если нужно в коде
+}
+
+// IsOrderAdvance сообщает, продвигает ли снимок next ордер current вперед:
+// смена статуса разрешена таблицей переходов, исполненные объемы не уменьшаются
+// и хотя бы одно из изменяемых полей отличается.
+func IsOrderAdvance(current, next *Order) bool {
+	if next.ExecutedQuantity.LessThan(current.ExecutedQuantity) ||
+		next.CummulativeQuoteQty.LessThan(current.CummulativeQuoteQty) {
+		return false
+	}
+	if next.Status != current.Status {
+		return current.Status.CanTransitionTo(next.Status)
+	}
+	return !next.ExecutedQuantity.Equal(current.ExecutedQuantity) ||
+		!next.CummulativeQuoteQty.Equal(current.CummulativeQuoteQty)
 }
 
 // OrderUpdate представляет запись истории изменения статуса ордера.
 type OrderUpdate struct {
-	ID                  uint64          `db:"id"`
+	ID                  uint64          `db:"id,auto"`
 	UserID              uint64          `db:"user_id"`
 	OrderID             string          `db:"order_id"`
 	Status              string          `db:"status"`
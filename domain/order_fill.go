@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// fillPricePrecision — точность средней цены, совпадает с DECIMAL(30, 15) в БД
+const fillPricePrecision = 15
+
+// OrderFill — сводка исполнения ордера по его сделкам.
+type OrderFill struct {
+	OrderID       string
+	TradeCount    int
+	Quantity      decimal.Decimal // сумма trades.quantity
+	QuoteQuantity decimal.Decimal // сумма trades.quote_quantity
+	// AvgPrice — средневзвешенная цена QuoteQuantity / Quantity, ноль если сделок нет
+	AvgPrice decimal.Decimal
+	// Commissions — сумма комиссий по активам
+	Commissions map[string]decimal.Decimal
+}
+
+// SummarizeFills считает сводку исполнения ордера orderID.
+// Сделки других ордеров пропускаются.
+func SummarizeFills(orderID string, trades []*Trade) *OrderFill {
+	fill := &OrderFill{
+		OrderID:     orderID,
+		Commissions: make(map[string]decimal.Decimal),
+	}
+
+	for _, t := range trades {
+		if t.OrderID != orderID {
+			continue
+		}
+		fill.TradeCount++
+		fill.Quantity = fill.Quantity.Add(t.Quantity)
+		fill.QuoteQuantity = fill.QuoteQuantity.Add(t.QuoteQuantity)
+		fill.Commissions[t.CommissionAsset] = fill.Commissions[t.CommissionAsset].Add(t.Commission)
+	}
+
+	if !fill.Quantity.IsZero() {
+		fill.AvgPrice = fill.QuoteQuantity.DivRound(fill.Quantity, fillPricePrecision)
+	}
+
+	return fill
+}
+
+// FillMismatch описывает ордер, у которого исполненные объемы не совпадают с суммой его сделок.
+type FillMismatch struct {
+	OrderID             string
+	UserID              uint64
+	Symbol              string
+	Status              OrderStatus
+	ExecutedQuantity    decimal.Decimal // orders.executed_quantity
+	CummulativeQuoteQty decimal.Decimal // orders.cummulative_quote_qty
+	TradesQuantity      decimal.Decimal // сумма trades.quantity
+	TradesQuoteQuantity decimal.Decimal // сумма trades.quote_quantity
+	TradeCount          int
+}
+
+// CheckFill сравнивает исполненные объемы ордера со сводкой его сделок.
+// Возвращает nil, если объемы совпадают.
+func (o *Order) CheckFill(fill *OrderFill) *FillMismatch {
+	if o.ExecutedQuantity.Equal(fill.Quantity) && o.CummulativeQuoteQty.Equal(fill.QuoteQuantity) {
+		return nil
+	}
+
+	return &FillMismatch{
+		OrderID:             o.MexcOrderID,
+		UserID:              o.UserID,
+		Symbol:              o.Symbol,
+		Status:              o.Status,
+		ExecutedQuantity:    o.ExecutedQuantity,
+		CummulativeQuoteQty: o.CummulativeQuoteQty,
+		TradesQuantity:      fill.Quantity,
+		TradesQuoteQuantity: fill.QuoteQuantity,
+		TradeCount:          fill.TradeCount,
+	}
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// ContingencyType — тип связи ордеров в списке
+type ContingencyType string
+
+const ContingencyTypeOCO ContingencyType = "OCO"
+
+// ListStatusType — статус списка ордеров (listStatusType в API)
+type ListStatusType string
+
+const (
+	ListStatusTypeResponse    ListStatusType = "RESPONSE"
+	ListStatusTypeExecStarted ListStatusType = "EXEC_STARTED"
+	ListStatusTypeAllDone     ListStatusType = "ALL_DONE"
+)
+
+// ListOrderStatus — статус исполнения списка ордеров (listOrderStatus в API)
+type ListOrderStatus string
+
+const (
+	ListOrderStatusExecuting ListOrderStatus = "EXECUTING"
+	ListOrderStatusAllDone   ListOrderStatus = "ALL_DONE"
+	ListOrderStatusReject    ListOrderStatus = "REJECT"
+)
+
+// OrderList представляет список связанных ордеров (OCO).
+// Ордера списка ссылаются на него через Order.OrderListID.
+type OrderList struct {
+	ID                uint64          `db:"id,auto"`
+	UserID            uint64          `db:"user_id"`
+	MexcOrderListID   string          `db:"mexc_order_list_id"`
+	ListClientOrderID string          `db:"list_client_order_id,nullzero"` // Может быть NULL
+	Symbol            string          `db:"symbol"`
+	ContingencyType   ContingencyType `db:"contingency_type"`
+	ListStatusType    ListStatusType  `db:"list_status_type"`
+	ListOrderStatus   ListOrderStatus `db:"list_order_status"`
+	TransactionTime   time.Time       `db:"transaction_time"`
+	CreatedAt         time.Time       `db:"created_at,auto"`
+	UpdatedAt         time.Time       `db:"updated_at,now"`
+}
@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OrderStatus — статус ордера MEXC
+type OrderStatus string
+
+const (
+	OrderStatusNew               OrderStatus = "NEW"
+	OrderStatusPartiallyFilled   OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusFilled            OrderStatus = "FILLED"
+	OrderStatusCanceled          OrderStatus = "CANCELED"
+	OrderStatusPartiallyCanceled OrderStatus = "PARTIALLY_CANCELED"
+	OrderStatusRejected          OrderStatus = "REJECTED"
+	OrderStatusExpired           OrderStatus = "EXPIRED"
+)
+
+// OrderStatuses перечисляет все известные статусы
+var OrderStatuses = []OrderStatus{
+	OrderStatusNew,
+	OrderStatusPartiallyFilled,
+	OrderStatusFilled,
+	OrderStatusCanceled,
+	OrderStatusPartiallyCanceled,
+	OrderStatusRejected,
+	OrderStatusExpired,
+}
+
+// orderTransitions — разрешенные переходы статусов. Финальные статусы переходов не имеют.
+// PARTIALLY_FILLED -> PARTIALLY_FILLED разрешен: ордер получает новые исполнения.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew: {
+		OrderStatusPartiallyFilled,
+		OrderStatusFilled,
+		OrderStatusCanceled,
+		OrderStatusPartiallyCanceled,
+		OrderStatusRejected,
+		OrderStatusExpired,
+	},
+	OrderStatusPartiallyFilled: {
+		OrderStatusPartiallyFilled,
+		OrderStatusFilled,
+		OrderStatusCanceled,
+		OrderStatusPartiallyCanceled,
+		OrderStatusExpired,
+	},
+}
+
+// Valid сообщает, является ли статус известным
+func (s OrderStatus) Valid() bool {
+	for _, status := range OrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal сообщает, является ли статус финальным
+func (s OrderStatus) IsTerminal() bool {
+	return s.Valid() && len(orderTransitions[s]) == 0
+}
+
+// CanTransitionTo сообщает, разрешен ли переход из s в next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusesBefore возвращает статусы, из которых разрешен переход в next
+func OrderStatusesBefore(next OrderStatus) []OrderStatus {
+	var from []OrderStatus
+	for _, status := range OrderStatuses {
+		if status.CanTransitionTo(next) {
+			from = append(from, status)
+		}
+	}
+	return from
+}
+
+// OrderTransitions возвращает копию таблицы переходов
+func OrderTransitions() map[OrderStatus][]OrderStatus {
+	transitions := make(map[OrderStatus][]OrderStatus, len(orderTransitions))
+	for from, to := range orderTransitions {
+		transitions[from] = append([]OrderStatus(nil), to...)
+	}
+	return transitions
+}
+
+// OrderSide — направление ордера
+type OrderSide string
+
+const (
+	OrderSideBuy  OrderSide = "BUY"
+	OrderSideSell OrderSide = "SELL"
+)
+
+// Valid сообщает, является ли направление известным
+func (s OrderSide) Valid() bool {
+	return s == OrderSideBuy || s == OrderSideSell
+}
+
+// OrderType — тип ордера MEXC
+type OrderType string
+
+const (
+	OrderTypeLimit             OrderType = "LIMIT"
+	OrderTypeMarket            OrderType = "MARKET"
+	OrderTypeLimitMaker        OrderType = "LIMIT_MAKER"
+	OrderTypeImmediateOrCancel OrderType = "IMMEDIATE_OR_CANCEL"
+	OrderTypeFillOrKill        OrderType = "FILL_OR_KILL"
+	OrderTypeStopLimit         OrderType = "STOP_LIMIT"
+)
+
+// Valid сообщает, является ли тип известным
+func (t OrderType) Valid() bool {
+	switch t {
+	case OrderTypeLimit, OrderTypeMarket, OrderTypeLimitMaker, OrderTypeImmediateOrCancel, OrderTypeFillOrKill,
+		OrderTypeStopLimit:
+		return true
+	}
+	return false
+}
+
+// TimeInForce — срок действия ордера
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC"
+	TimeInForceIOC TimeInForce = "IOC"
+	TimeInForceFOK TimeInForce = "FOK"
+)
+
+// Valid сообщает, является ли значение известным
+func (t TimeInForce) Valid() bool {
+	return t == TimeInForceGTC || t == TimeInForceIOC || t == TimeInForceFOK
+}
+
+// ErrInvalidTransition возвращается при недопустимой смене статуса ордера
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// TransitionError описывает отклоненный переход статуса.
+// errors.Is(err, ErrInvalidTransition) возвращает true.
+type TransitionError struct {
+	OrderID string
+	From    OrderStatus
+	To      OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: order %s %s -> %s", ErrInvalidTransition, e.OrderID, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
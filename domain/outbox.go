@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// OutboxStatus — состояние сообщения в outbox.
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "PENDING" // ждет отправки или повторной попытки
+	OutboxSent    OutboxStatus = "SENT"
+	OutboxDead    OutboxStatus = "DEAD" // отправка не удалась, сообщение больше не повторяется
+)
+
+// OutboxTopicOrderFilled — уведомление об исполнении ордера.
+const OutboxTopicOrderFilled = "order.filled"
+
+// OutboxMessage — уведомление бота, поставленное в очередь в одной транзакции
+// с изменением данных. Поля соответствуют таблице outbox в БД.
+type OutboxMessage struct {
+	ID            uint64       `db:"id"`
+	UserID        uint64       `db:"user_id"`
+	Topic         string       `db:"topic"`
+	DedupKey      string       `db:"dedup_key"` // повторная постановка с тем же ключом игнорируется; пусто — без дедупликации
+	Payload       []byte       `db:"payload"`   // JSON
+	Status        OutboxStatus `db:"status"`
+	Attempts      int32        `db:"attempts"` // число захватов на отправку
+	LastError     string       `db:"last_error"`
+	NextAttemptAt time.Time    `db:"next_attempt_at"`
+	LockedUntil   *time.Time   `db:"locked_until"` // срок захвата диспетчером
+	CreatedAt     time.Time    `db:"created_at"`
+	SentAt        *time.Time   `db:"sent_at"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Price — наблюдение цены символа: сколько котируемого актива стоит единица базового.
+// Поля соответствуют таблице prices в БД.
+type Price struct {
+	ID         uint64          `db:"id"`
+	Symbol     string          `db:"symbol"` // например BTCUSDT
+	Price      decimal.Decimal `db:"price"`
+	Source     string          `db:"source"` // откуда получена цена, например mexc/ticker
+	ObservedAt time.Time       `db:"observed_at"`
+	CreatedAt  time.Time       `db:"created_at"`
+}
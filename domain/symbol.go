@@ -0,0 +1,191 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SymbolStatus — состояние торговли символом.
+type SymbolStatus string
+
+const (
+	SymbolStatusEnabled SymbolStatus = "ENABLED"
+	SymbolStatusPaused  SymbolStatus = "PAUSED"
+	SymbolStatusOffline SymbolStatus = "OFFLINE"
+)
+
+// ParseSymbolStatus приводит статус из exchangeInfo MEXC ("1", "2", "3" или "ENABLED")
+// к SymbolStatus. Неизвестные значения возвращаются в верхнем регистре.
+func ParseSymbolStatus(s string) SymbolStatus {
+	switch strings.ToUpper(s) {
+	case "1", "ENABLED", "TRADING":
+		return SymbolStatusEnabled
+	case "2", "PAUSED", "HALT", "BREAK":
+		return SymbolStatusPaused
+	case "3", "OFFLINE":
+		return SymbolStatusOffline
+	default:
+		return SymbolStatus(strings.ToUpper(s))
+	}
+}
+
+// Symbol — торговая пара и ее правила из exchangeInfo.
+// Нулевые MaxQty и MaxNotional означают отсутствие ограничения.
+// Поля соответствуют таблице symbols в БД.
+type Symbol struct {
+	ID          uint64          `db:"id"`
+	Symbol      string          `db:"symbol"`
+	BaseAsset   string          `db:"base_asset"`
+	QuoteAsset  string          `db:"quote_asset"`
+	Status      SymbolStatus    `db:"status"`
+	TickSize    decimal.Decimal `db:"tick_size"` // шаг цены
+	StepSize    decimal.Decimal `db:"step_size"` // шаг количества
+	MinQty      decimal.Decimal `db:"min_qty"`
+	MaxQty      decimal.Decimal `db:"max_qty"`
+	MinNotional decimal.Decimal `db:"min_notional"` // минимальная сумма ордера в котируемом активе
+	MaxNotional decimal.Decimal `db:"max_notional"`
+	OrderTypes  string          `db:"order_types"` // JSON array
+	Permissions string          `db:"permissions"` // JSON array
+	UpdatedAt   time.Time       `db:"updated_at"`
+}
+
+// AllowsOrderType сообщает, разрешен ли тип ордера. Пустой список разрешает все типы.
+func (s *Symbol) AllowsOrderType(t OrderType) bool {
+	types := decodeList(s.OrderTypes)
+	if len(types) == 0 {
+		return true
+	}
+	for _, allowed := range types {
+		if OrderType(allowed) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrOrderRejected возвращается, если ордер нарушает правила символа.
+var ErrOrderRejected = errors.New("order violates symbol rules")
+
+// OrderRuleError перечисляет нарушенные правила символа.
+// errors.Is(err, ErrOrderRejected) возвращает true.
+type OrderRuleError struct {
+	Symbol     string
+	Violations []string
+}
+
+func (e *OrderRuleError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrOrderRejected, e.Symbol, strings.Join(e.Violations, "; "))
+}
+
+func (e *OrderRuleError) Is(target error) bool {
+	return target == ErrOrderRejected
+}
+
+// PrepareOrder округляет цену и количество ордера по шагам символа и проверяет правила.
+// Количество округляется вниз; цена покупки — вниз, продажи — вверх, чтобы округление
+// не ухудшало цену для пользователя.
+func (s *Symbol) PrepareOrder(o *Order) error {
+	if s.StepSize.IsPositive() {
+		o.Quantity = roundDown(o.Quantity, s.StepSize)
+	}
+	if s.TickSize.IsPositive() && o.Price.IsPositive() {
+		if o.Side == OrderSideSell {
+			o.Price = roundUp(o.Price, s.TickSize)
+		} else {
+			o.Price = roundDown(o.Price, s.TickSize)
+		}
+	}
+	return s.ValidateOrder(o)
+}
+
+// ValidateOrder проверяет ордер по правилам символа без изменения ордера.
+func (s *Symbol) ValidateOrder(o *Order) error {
+	var violations []string
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+
+	if o.Symbol != s.Symbol {
+		violate("order symbol %s does not match", o.Symbol)
+	}
+	if s.Status != SymbolStatusEnabled {
+		violate("symbol is %s", s.Status)
+	}
+	if !s.AllowsOrderType(o.Type) {
+		violate("order type %s is not allowed", o.Type)
+	}
+
+	needsPrice := o.Type != OrderTypeMarket
+	switch {
+	case needsPrice && !o.Price.IsPositive():
+		violate("price must be positive")
+	case o.Price.IsPositive() && s.TickSize.IsPositive() && !isMultiple(o.Price, s.TickSize):
+		violate("price %s is not a multiple of tick size %s", o.Price, s.TickSize)
+	}
+
+	switch {
+	case o.Quantity.IsPositive():
+		if s.StepSize.IsPositive() && !isMultiple(o.Quantity, s.StepSize) {
+			violate("quantity %s is not a multiple of step size %s", o.Quantity, s.StepSize)
+		}
+		if o.Quantity.LessThan(s.MinQty) {
+			violate("quantity %s is below min %s", o.Quantity, s.MinQty)
+		}
+		if s.MaxQty.IsPositive() && o.Quantity.GreaterThan(s.MaxQty) {
+			violate("quantity %s is above max %s", o.Quantity, s.MaxQty)
+		}
+	case o.Type != OrderTypeMarket || !o.QuoteOrderQty.IsPositive():
+		violate("quantity must be positive")
+	}
+
+	if notional, ok := orderNotional(o); ok {
+		if notional.LessThan(s.MinNotional) {
+			violate("notional %s is below min %s", notional, s.MinNotional)
+		}
+		if s.MaxNotional.IsPositive() && notional.GreaterThan(s.MaxNotional) {
+			violate("notional %s is above max %s", notional, s.MaxNotional)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &OrderRuleError{Symbol: s.Symbol, Violations: violations}
+	}
+	return nil
+}
+
+// orderNotional возвращает сумму ордера в котируемом активе, если ее можно определить.
+func orderNotional(o *Order) (decimal.Decimal, bool) {
+	if o.Price.IsPositive() && o.Quantity.IsPositive() {
+		return o.Price.Mul(o.Quantity), true
+	}
+	if o.QuoteOrderQty.IsPositive() {
+		return o.QuoteOrderQty, true
+	}
+	return decimal.Zero, false
+}
+
+func roundDown(v, step decimal.Decimal) decimal.Decimal {
+	return v.Div(step).Floor().Mul(step)
+}
+
+func roundUp(v, step decimal.Decimal) decimal.Decimal {
+	return v.Div(step).Ceil().Mul(step)
+}
+
+func isMultiple(v, step decimal.Decimal) bool {
+	return v.Mod(step).IsZero()
+}
+
+// decodeList разбирает JSON-массив строк; некорректный JSON считается пустым списком.
+func decodeList(s string) []string {
+	var list []string
+	if s == "" || json.Unmarshal([]byte(s), &list) != nil {
+		return nil
+	}
+	return list
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// SyncStream — поток данных биржи, который синхронизируется инкрементально.
+type SyncStream string
+
+const (
+	SyncStreamTrades      SyncStream = "TRADES"      // /api/v3/myTrades, по символу
+	SyncStreamOrders      SyncStream = "ORDERS"      // /api/v3/allOrders, по символу
+	SyncStreamDeposits    SyncStream = "DEPOSITS"    // /api/v3/capital/deposit/hisrec
+	SyncStreamWithdrawals SyncStream = "WITHDRAWALS" // /api/v3/capital/withdraw/history
+)
+
+// SyncStatus — состояние последней синхронизации потока.
+type SyncStatus string
+
+const (
+	SyncStatusOK      SyncStatus = "OK"
+	SyncStatusRunning SyncStatus = "RUNNING"
+	SyncStatusFailed  SyncStatus = "FAILED"
+)
+
+// SyncCursor — позиция синхронизации потока пользователя по символу.
+// Version увеличивается при каждом сохранении и защищает от одновременных синхронизаций.
+// Поля соответствуют таблице sync_cursors в БД.
+type SyncCursor struct {
+	ID        uint64     `db:"id"`
+	UserID    uint64     `db:"user_id"`
+	Stream    SyncStream `db:"stream"`
+	Symbol    string     `db:"symbol"`    // пусто для потоков без символа
+	FromID    string     `db:"from_id"`   // id последней обработанной записи (fromId)
+	LastTime  *time.Time `db:"last_time"` // время последней обработанной записи
+	Status    SyncStatus `db:"status"`
+	LastError string     `db:"last_error"` // текст ошибки для FAILED
+	Version   uint64     `db:"version"`
+	UpdatedAt time.Time  `db:"updated_at"`
+}
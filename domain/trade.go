@@ -9,7 +9,7 @@ import (
 // Trade представляет выполненную сделку в системе MEXC.
 // Поля соответствуют таблице trades в БД и API MEXC.
 type Trade struct {
-	ID              uint64          `db:"id"`
+	ID              uint64          `db:"id,auto"`
 	UserID          uint64          `db:"user_id"`
 	MexcTradeID     string          `db:"mexc_trade_id"`
 	OrderID         string          `db:"order_id"`
@@ -22,5 +22,5 @@ type Trade struct {
 	TradeTime       time.Time       `db:"trade_time"`
 	IsBuyer         bool            `db:"is_buyer"`
 	IsMaker         bool            `db:"is_maker"`
-	CreatedAt       time.Time       `db:"created_at"`
+	CreatedAt       time.Time       `db:"created_at,auto"`
 }
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TransferStatus — состояние ввода или вывода, приведенное из кодов MEXC.
+type TransferStatus string
+
+const (
+	TransferStatusPending  TransferStatus = "PENDING"  // в обработке или ожидает подтверждений
+	TransferStatusSuccess  TransferStatus = "SUCCESS"  // средства зачислены или отправлены
+	TransferStatusFailed   TransferStatus = "FAILED"   // отклонен биржей или сетью
+	TransferStatusCanceled TransferStatus = "CANCELED" // вывод отменен пользователем
+)
+
+// ParseDepositStatus приводит status из /api/v3/capital/deposit/hisrec к TransferStatus.
+// 5 — SUCCESS, 7 — REJECTED, остальные коды — промежуточные состояния.
+func ParseDepositStatus(code int) TransferStatus {
+	switch code {
+	case 5:
+		return TransferStatusSuccess
+	case 7:
+		return TransferStatusFailed
+	default:
+		return TransferStatusPending
+	}
+}
+
+// ParseWithdrawalStatus приводит status из /api/v3/capital/withdraw/history к TransferStatus.
+// 7 — SUCCESS, 8 — FAILED, 9 — CANCEL, остальные коды — промежуточные состояния.
+func ParseWithdrawalStatus(code int) TransferStatus {
+	switch code {
+	case 7:
+		return TransferStatusSuccess
+	case 8:
+		return TransferStatusFailed
+	case 9:
+		return TransferStatusCanceled
+	default:
+		return TransferStatusPending
+	}
+}
+
+// Deposit — ввод актива на биржу.
+// Amount — зачисленная сумма, Fee — справочно удержанная сетью или биржей.
+// Поля соответствуют таблице deposits в БД.
+type Deposit struct {
+	ID            uint64          `db:"id"`
+	UserID        uint64          `db:"user_id"`
+	MexcDepositID string          `db:"mexc_deposit_id"`
+	Asset         string          `db:"asset"` // coin
+	Network       string          `db:"network"`
+	Amount        decimal.Decimal `db:"amount"`
+	Fee           decimal.Decimal `db:"fee"`
+	Address       string          `db:"address"`
+	Memo          string          `db:"memo"`
+	TxID          string          `db:"tx_id"`
+	Status        TransferStatus  `db:"status"`
+	InsertTime    time.Time       `db:"insert_time"` // время поступления на бирже
+	CreatedAt     time.Time       `db:"created_at"`
+	UpdatedAt     time.Time       `db:"updated_at"`
+}
+
+// LedgerEntries строит проводки зачисления ввода.
+func (d *Deposit) LedgerEntries() []*LedgerEntry {
+	return DepositLedgerEntries(d.UserID, d.Asset, d.Amount, d.MexcDepositID, d.InsertTime)
+}
+
+// Withdrawal — вывод актива с биржи.
+// Amount — отправленная сумма без комиссии, Fee — комиссия вывода в том же активе.
+// Поля соответствуют таблице withdrawals в БД.
+type Withdrawal struct {
+	ID               uint64          `db:"id"`
+	UserID           uint64          `db:"user_id"`
+	MexcWithdrawalID string          `db:"mexc_withdrawal_id"`
+	Asset            string          `db:"asset"` // coin
+	Network          string          `db:"network"`
+	Amount           decimal.Decimal `db:"amount"`
+	Fee              decimal.Decimal `db:"fee"` // transactionFee
+	Address          string          `db:"address"`
+	Memo             string          `db:"memo"`
+	TxID             string          `db:"tx_id"`
+	Status           TransferStatus  `db:"status"`
+	ApplyTime        time.Time       `db:"apply_time"` // время заявки на вывод
+	CreatedAt        time.Time       `db:"created_at"`
+	UpdatedAt        time.Time       `db:"updated_at"`
+}
+
+// LedgerEntries строит проводки списания вывода и его комиссии.
+func (w *Withdrawal) LedgerEntries() []*LedgerEntry {
+	return WithdrawalLedgerEntries(w.UserID, w.Asset, w.Amount, w.Fee, w.MexcWithdrawalID, w.ApplyTime)
+}
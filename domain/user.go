@@ -7,7 +7,7 @@ import (
 // User представляет пользователя в системе MEXC.
 // Поля соответствуют таблице users в БД и API MEXC.
 type User struct {
-	ID              uint64     `db:"id"`
+	ID              uint64     `db:"id,auto"`
 	TelegramID      int64      `db:"telegram_id"`
 	MexcUID         string     `db:"mexc_uid"`
 	Username        string     `db:"username"`
@@ -22,6 +22,6 @@ type User struct {
 	Permissions     string     `db:"permissions"` // JSON array
 	LastAccountSync *time.Time `db:"last_account_sync"`
 	IsActive        bool       `db:"is_active"`
-	CreatedAt       time.Time  `db:"created_at"`
-	UpdatedAt       time.Time  `db:"updated_at"`
+	CreatedAt       time.Time  `db:"created_at,auto"`
+	UpdatedAt       time.Time  `db:"updated_at,now"`
 }
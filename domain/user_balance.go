@@ -9,10 +9,21 @@ import (
 // UserBalance представляет баланс пользователя по конкретному активу.
 // Поля соответствуют таблице user_balances в БД.
 type UserBalance struct {
-	ID        uint64          `db:"id"`
+	ID        uint64          `db:"id,auto"`
 	UserID    uint64          `db:"user_id"`
 	Asset     string          `db:"asset"`
 	Free      decimal.Decimal `db:"free"`   // Доступный баланс
 	Locked    decimal.Decimal `db:"locked"` // Заблокированный баланс
 	UpdatedAt time.Time       `db:"updated_at"`
 }
+
+// BalanceSnapshot — запись истории баланса. Снимок добавляется при каждом изменении
+// free или locked и никогда не изменяется. Поля соответствуют таблице balance_snapshots в БД.
+type BalanceSnapshot struct {
+	ID         uint64          `db:"id,auto"`
+	UserID     uint64          `db:"user_id"`
+	Asset      string          `db:"asset"`
+	Free       decimal.Decimal `db:"free"`
+	Locked     decimal.Decimal `db:"locked"`
+	RecordedAt time.Time       `db:"recorded_at"` // updated_at баланса на момент изменения
+}
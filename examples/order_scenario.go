@@ -10,15 +10,15 @@ import (
 
 	"github.com/samar/sup_bot/metacore/domain"
 	"github.com/samar/sup_bot/metacore/postgres"
+	"github.com/samar/sup_bot/metacore/storage"
 )
 
 // RunOrderScenario демонстрирует базовый сценарий работы с библиотекой:
 // 1) Создание пользователя
 // 2) Создание ордера
 // 3) Получение ордера
-// 4) Обновление статуса ордера (FILLED)
-// 5) Создание сделки по ордеру
-// 6) Обновление балансов пользователя
+// 4-6) Фиксация исполнения в одной транзакции: статус FILLED, сделка,
+// история изменений ордера и балансы пользователя
 // 7) Удаление ордера
 func RunOrderScenario(ctx context.Context, db *postgres.DB) error {
 	log.Println("\n🚦 Запуск order-сценария...")
@@ -74,13 +74,8 @@ func RunOrderScenario(ctx context.Context, db *postgres.DB) error {
 	}
 	log.Printf("🔎 Ордер найден: %s %s %s qty=%s", gotOrder.Symbol, gotOrder.Side, gotOrder.Status, gotOrder.Quantity.String())
 
-	// Шаг 4. Обновление статуса ордера -> FILLED
-	if err := db.UpdateOrderStatus(ctx, mexcOrderID, "FILLED"); err != nil {
-		return fmt.Errorf("update order status: %w", err)
-	}
-	log.Println("🔄 Статус ордера обновлен на FILLED")
-
-	// Шаг 5. Создание сделки по ордеру
+	// Шаги 4-6. Фиксация исполнения ордера в одной транзакции:
+	// статус ордера, сделка, история изменений и балансы либо сохраняются вместе, либо не сохраняются вовсе
 	trade := &domain.Trade{
 		UserID:          user.ID,
 		MexcTradeID:     fmt.Sprintf("trade_%d", time.Now().UnixNano()),
@@ -95,20 +90,37 @@ func RunOrderScenario(ctx context.Context, db *postgres.DB) error {
 		IsBuyer:         true,
 		IsMaker:         false,
 	}
-	if err := db.CreateTrade(ctx, trade); err != nil {
-		return fmt.Errorf("create trade: %w", err)
-	}
-	log.Printf("🤝 Сделка создана: mexc_trade_id=%s qty=%s", trade.MexcTradeID, trade.Quantity.String())
-
-	// Шаг 6. Обновление балансов пользователя (пример: списали USDT, зачислили BTC)
 	balances := []*domain.UserBalance{
 		{UserID: user.ID, Asset: "USDT", Free: decimal.NewFromFloat(900.00), Locked: decimal.Zero},
 		{UserID: user.ID, Asset: "BTC", Free: decimal.NewFromFloat(0.002), Locked: decimal.Zero},
 	}
-	if err := db.UpdateUserBalances(ctx, user.ID, balances); err != nil {
-		return fmt.Errorf("update user balances: %w", err)
+	err = db.WithTx(ctx, nil, func(tx storage.FullStorage) error {
+		if err := tx.UpdateOrderStatus(ctx, mexcOrderID, "FILLED"); err != nil {
+			return fmt.Errorf("update order status: %w", err)
+		}
+		if err := tx.CreateTrade(ctx, trade); err != nil {
+			return fmt.Errorf("create trade: %w", err)
+		}
+		update := &domain.OrderUpdate{
+			UserID:              user.ID,
+			OrderID:             mexcOrderID,
+			Status:              "FILLED",
+			ExecutedQuantity:    trade.Quantity,
+			CummulativeQuoteQty: trade.QuoteQuantity,
+			UpdateTime:          trade.TradeTime,
+		}
+		if err := tx.AppendOrderUpdate(ctx, update); err != nil {
+			return fmt.Errorf("append order update: %w", err)
+		}
+		if err := tx.UpdateUserBalances(ctx, user.ID, balances); err != nil {
+			return fmt.Errorf("update user balances: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("record fill: %w", err)
 	}
-	log.Println("💰 Балансы пользователя обновлены")
+	log.Printf("🤝 Исполнение зафиксировано: статус FILLED, сделка %s qty=%s, балансы обновлены", trade.MexcTradeID, trade.Quantity.String())
 
 	// Шаг 7. Удаление ордера (как завершенного)
 	if err := db.DeleteOrderByID(ctx, mexcOrderID); err != nil {
@@ -0,0 +1,195 @@
+// Package exchangeinfo импортирует правила торговых пар из ответа MEXC /api/v3/exchangeInfo
+// в SymbolStorage и проверяет ордера по этим правилам перед отправкой на биржу.
+//
+// MEXC отдает правила полями символа (quotePrecision, baseAssetPrecision, baseSizePrecision,
+// quoteAmountPrecision, maxQuoteAmount); фильтры в формате Binance (PRICE_FILTER, LOT_SIZE,
+// MIN_NOTIONAL, NOTIONAL), если они есть, имеют приоритет.
+package exchangeinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// ErrInvalidExchangeInfo возвращается для ответа, который не удалось разобрать.
+var ErrInvalidExchangeInfo = errors.New("invalid exchangeInfo")
+
+type response struct {
+	Symbols []symbolInfo `json:"symbols"`
+}
+
+type symbolInfo struct {
+	Symbol               string     `json:"symbol"`
+	Status               flexString `json:"status"`
+	BaseAsset            string     `json:"baseAsset"`
+	QuoteAsset           string     `json:"quoteAsset"`
+	BaseAssetPrecision   *int32     `json:"baseAssetPrecision"`
+	QuotePrecision       *int32     `json:"quotePrecision"`
+	BaseSizePrecision    string     `json:"baseSizePrecision"`    // минимальное количество
+	QuoteAmountPrecision string     `json:"quoteAmountPrecision"` // минимальная сумма ордера
+	MaxQuoteAmount       string     `json:"maxQuoteAmount"`
+	OrderTypes           []string   `json:"orderTypes"`
+	Permissions          []string   `json:"permissions"`
+	Filters              []filter   `json:"filters"`
+}
+
+type filter struct {
+	FilterType  string `json:"filterType"`
+	TickSize    string `json:"tickSize"`
+	StepSize    string `json:"stepSize"`
+	MinQty      string `json:"minQty"`
+	MaxQty      string `json:"maxQty"`
+	MinNotional string `json:"minNotional"`
+	MaxNotional string `json:"maxNotional"`
+}
+
+// flexString принимает строку или число: MEXC отдает status как "1" или "ENABLED".
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*f = flexString(s)
+		return nil
+	}
+	*f = flexString(strings.TrimSpace(string(data)))
+	return nil
+}
+
+// Parse разбирает ответ exchangeInfo в символы в порядке ответа.
+func Parse(r io.Reader) ([]*domain.Symbol, error) {
+	var resp response
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExchangeInfo, err)
+	}
+
+	symbols := make([]*domain.Symbol, 0, len(resp.Symbols))
+	for _, info := range resp.Symbols {
+		sym, err := info.toDomain()
+		if err != nil {
+			return nil, fmt.Errorf("%w: symbol %s: %v", ErrInvalidExchangeInfo, info.Symbol, err)
+		}
+		symbols = append(symbols, sym)
+	}
+
+	return symbols, nil
+}
+
+// Import разбирает ответ exchangeInfo и сохраняет символы. Возвращает число символов.
+func Import(ctx context.Context, st storage.SymbolStorage, r io.Reader) (int, error) {
+	symbols, err := Parse(r)
+	if err != nil {
+		return 0, err
+	}
+	if err := st.UpsertSymbols(ctx, symbols); err != nil {
+		return 0, fmt.Errorf("failed to save symbols: %w", err)
+	}
+	return len(symbols), nil
+}
+
+func (info symbolInfo) toDomain() (*domain.Symbol, error) {
+	if info.Symbol == "" || info.BaseAsset == "" || info.QuoteAsset == "" {
+		return nil, errors.New("symbol, baseAsset and quoteAsset are required")
+	}
+
+	sym := &domain.Symbol{
+		Symbol:     info.Symbol,
+		BaseAsset:  info.BaseAsset,
+		QuoteAsset: info.QuoteAsset,
+		Status:     domain.ParseSymbolStatus(string(info.Status)),
+	}
+	if info.QuotePrecision != nil {
+		sym.TickSize = decimal.New(1, -*info.QuotePrecision)
+	}
+	if info.BaseAssetPrecision != nil {
+		sym.StepSize = decimal.New(1, -*info.BaseAssetPrecision)
+	}
+
+	// Поля MEXC задают значения по умолчанию, фильтры их переопределяют
+	fields := []decimalField{
+		{&sym.MinQty, info.BaseSizePrecision},
+		{&sym.MinNotional, info.QuoteAmountPrecision},
+		{&sym.MaxNotional, info.MaxQuoteAmount},
+	}
+	for _, f := range info.Filters {
+		switch f.FilterType {
+		case "PRICE_FILTER":
+			fields = append(fields, decimalField{&sym.TickSize, f.TickSize})
+		case "LOT_SIZE":
+			fields = append(fields,
+				decimalField{&sym.StepSize, f.StepSize},
+				decimalField{&sym.MinQty, f.MinQty},
+				decimalField{&sym.MaxQty, f.MaxQty})
+		case "MIN_NOTIONAL", "NOTIONAL":
+			fields = append(fields,
+				decimalField{&sym.MinNotional, f.MinNotional},
+				decimalField{&sym.MaxNotional, f.MaxNotional})
+		}
+	}
+
+	var err error
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if *f.target, err = decimal.NewFromString(f.value); err != nil {
+			return nil, err
+		}
+	}
+
+	if sym.OrderTypes, err = encodeList(info.OrderTypes); err != nil {
+		return nil, err
+	}
+	if sym.Permissions, err = encodeList(info.Permissions); err != nil {
+		return nil, err
+	}
+
+	return sym, nil
+}
+
+// decimalField — строковое значение из ответа и поле символа для него
+type decimalField struct {
+	target *decimal.Decimal
+	value  string
+}
+
+func encodeList(list []string) (string, error) {
+	if len(list) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(list)
+	return string(data), err
+}
+
+// Validator готовит ордера к отправке по правилам символов из хранилища.
+type Validator struct {
+	symbols storage.SymbolStorage
+}
+
+// NewValidator создает Validator поверх SymbolStorage.
+func NewValidator(symbols storage.SymbolStorage) *Validator {
+	return &Validator{symbols: symbols}
+}
+
+// Prepare округляет цену и количество ордера и проверяет его по правилам символа
+// (см. domain.Symbol.PrepareOrder). Нарушения возвращаются как *domain.OrderRuleError.
+func (v *Validator) Prepare(ctx context.Context, order *domain.Order) error {
+	sym, err := v.symbols.GetSymbol(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+	return sym.PrepareOrder(order)
+}
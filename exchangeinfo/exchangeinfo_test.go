@@ -0,0 +1,205 @@
+package exchangeinfo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage/memstore"
+)
+
+// Фрагмент ответа MEXC /api/v3/exchangeInfo
+const mexcExchangeInfo = `{
+  "timezone": "CST",
+  "serverTime": 1710000000000,
+  "symbols": [
+    {
+      "symbol": "BTCUSDT",
+      "status": "1",
+      "baseAsset": "BTC",
+      "baseAssetPrecision": 6,
+      "quoteAsset": "USDT",
+      "quotePrecision": 2,
+      "quoteAssetPrecision": 2,
+      "orderTypes": ["LIMIT", "MARKET", "LIMIT_MAKER"],
+      "isSpotTradingAllowed": true,
+      "permissions": ["SPOT"],
+      "filters": [],
+      "baseSizePrecision": "0.0001",
+      "quoteAmountPrecision": "5.000000000000000000000000000000",
+      "maxQuoteAmount": "2000000.000000000000000000000000000000"
+    },
+    {
+      "symbol": "XYZUSDT",
+      "status": "2",
+      "baseAsset": "XYZ",
+      "quoteAsset": "USDT",
+      "baseAssetPrecision": 0,
+      "quotePrecision": 4,
+      "permissions": ["SPOT"],
+      "filters": [
+        {"filterType": "PRICE_FILTER", "tickSize": "0.0005"},
+        {"filterType": "LOT_SIZE", "stepSize": "10", "minQty": "100", "maxQty": "1000000"},
+        {"filterType": "NOTIONAL", "minNotional": "1"}
+      ]
+    }
+  ]
+}`
+
+func requireDecimal(t *testing.T, expected string, actual decimal.Decimal) {
+	t.Helper()
+	require.True(t, decimal.RequireFromString(expected).Equal(actual), "expected %s, got %s", expected, actual)
+}
+
+func TestParse(t *testing.T) {
+	t.Run("mexc fields", func(t *testing.T) {
+		symbols, err := Parse(strings.NewReader(mexcExchangeInfo))
+		require.NoError(t, err)
+		require.Len(t, symbols, 2)
+
+		btc := symbols[0]
+		assert.Equal(t, "BTCUSDT", btc.Symbol)
+		assert.Equal(t, "BTC", btc.BaseAsset)
+		assert.Equal(t, "USDT", btc.QuoteAsset)
+		assert.Equal(t, domain.SymbolStatusEnabled, btc.Status)
+		requireDecimal(t, "0.01", btc.TickSize)
+		requireDecimal(t, "0.000001", btc.StepSize)
+		requireDecimal(t, "0.0001", btc.MinQty)
+		requireDecimal(t, "0", btc.MaxQty)
+		requireDecimal(t, "5", btc.MinNotional)
+		requireDecimal(t, "2000000", btc.MaxNotional)
+		assert.JSONEq(t, `["LIMIT","MARKET","LIMIT_MAKER"]`, btc.OrderTypes)
+		assert.JSONEq(t, `["SPOT"]`, btc.Permissions)
+	})
+
+	t.Run("filters override mexc fields", func(t *testing.T) {
+		symbols, err := Parse(strings.NewReader(mexcExchangeInfo))
+		require.NoError(t, err)
+
+		xyz := symbols[1]
+		assert.Equal(t, domain.SymbolStatusPaused, xyz.Status)
+		requireDecimal(t, "0.0005", xyz.TickSize)
+		requireDecimal(t, "10", xyz.StepSize)
+		requireDecimal(t, "100", xyz.MinQty)
+		requireDecimal(t, "1000000", xyz.MaxQty)
+		requireDecimal(t, "1", xyz.MinNotional)
+		assert.Empty(t, xyz.OrderTypes)
+	})
+
+	t.Run("numeric status", func(t *testing.T) {
+		symbols, err := Parse(strings.NewReader(`{"symbols":[{"symbol":"ETHUSDT","status":3,"baseAsset":"ETH","quoteAsset":"USDT"}]}`))
+		require.NoError(t, err)
+		assert.Equal(t, domain.SymbolStatusOffline, symbols[0].Status)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"not json":      `{"symbols":`,
+			"missing asset": `{"symbols":[{"symbol":"ETHUSDT","status":"1","baseAsset":"ETH"}]}`,
+			"bad decimal":   `{"symbols":[{"symbol":"ETHUSDT","baseAsset":"ETH","quoteAsset":"USDT","baseSizePrecision":"x"}]}`,
+		}
+		for name, text := range cases {
+			_, err := Parse(strings.NewReader(text))
+			assert.ErrorIs(t, err, ErrInvalidExchangeInfo, name)
+		}
+	})
+}
+
+func TestImportAndValidate(t *testing.T) {
+	ctx := context.Background()
+	st := memstore.New()
+
+	n, err := Import(ctx, st, strings.NewReader(mexcExchangeInfo))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v := NewValidator(st)
+
+	limit := func(side domain.OrderSide, price, quantity string) *domain.Order {
+		return &domain.Order{
+			Symbol:   "BTCUSDT",
+			Side:     side,
+			Type:     domain.OrderTypeLimit,
+			Price:    decimal.RequireFromString(price),
+			Quantity: decimal.RequireFromString(quantity),
+		}
+	}
+
+	t.Run("rounds price and quantity", func(t *testing.T) {
+		buy := limit(domain.OrderSideBuy, "50000.129", "0.0012349")
+		require.NoError(t, v.Prepare(ctx, buy))
+		requireDecimal(t, "50000.12", buy.Price)
+		requireDecimal(t, "0.001234", buy.Quantity)
+
+		sell := limit(domain.OrderSideSell, "50000.121", "0.0012349")
+		require.NoError(t, v.Prepare(ctx, sell))
+		requireDecimal(t, "50000.13", sell.Price)
+	})
+
+	t.Run("market order by quote amount", func(t *testing.T) {
+		order := &domain.Order{
+			Symbol:        "BTCUSDT",
+			Side:          domain.OrderSideBuy,
+			Type:          domain.OrderTypeMarket,
+			QuoteOrderQty: decimal.NewFromInt(10),
+		}
+		require.NoError(t, v.Prepare(ctx, order))
+
+		order.QuoteOrderQty = decimal.NewFromInt(1)
+		assert.ErrorIs(t, v.Prepare(ctx, order), domain.ErrOrderRejected)
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		cases := []struct {
+			name      string
+			order     *domain.Order
+			violation string
+		}{
+			{"below min quantity", limit(domain.OrderSideBuy, "50000", "0.00005"), "quantity 0.00005 is below min 0.0001"},
+			{"below min notional", limit(domain.OrderSideBuy, "10", "0.0001"), "notional 0.001 is below min 5"},
+			{"above max notional", limit(domain.OrderSideBuy, "50000", "100"), "notional 5000000 is above max 2000000"},
+			{"zero price", limit(domain.OrderSideBuy, "0", "1"), "price must be positive"},
+			{"zero quantity", limit(domain.OrderSideBuy, "50000", "0"), "quantity must be positive"},
+			{"order type", &domain.Order{Symbol: "BTCUSDT", Type: domain.OrderTypeStopLimit, Price: decimal.NewFromInt(50000), Quantity: decimal.NewFromInt(1)}, "order type STOP_LIMIT is not allowed"},
+		}
+		for _, tc := range cases {
+			err := v.Prepare(ctx, tc.order)
+			require.ErrorIs(t, err, domain.ErrOrderRejected, tc.name)
+
+			var ruleErr *domain.OrderRuleError
+			require.ErrorAs(t, err, &ruleErr)
+			assert.Equal(t, "BTCUSDT", ruleErr.Symbol)
+			assert.Contains(t, ruleErr.Violations, tc.violation, tc.name)
+		}
+	})
+
+	t.Run("validation without rounding", func(t *testing.T) {
+		sym, err := st.GetSymbol(ctx, "BTCUSDT")
+		require.NoError(t, err)
+
+		err = sym.ValidateOrder(limit(domain.OrderSideBuy, "50000.129", "0.001"))
+		var ruleErr *domain.OrderRuleError
+		require.ErrorAs(t, err, &ruleErr)
+		assert.Equal(t, []string{"price 50000.129 is not a multiple of tick size 0.01"}, ruleErr.Violations)
+	})
+
+	t.Run("paused symbol", func(t *testing.T) {
+		order := &domain.Order{Symbol: "XYZUSDT", Side: domain.OrderSideBuy, Type: domain.OrderTypeLimit,
+			Price: decimal.RequireFromString("0.01"), Quantity: decimal.NewFromInt(1000)}
+		err := v.Prepare(ctx, order)
+		var ruleErr *domain.OrderRuleError
+		require.ErrorAs(t, err, &ruleErr)
+		assert.Equal(t, []string{"symbol is PAUSED"}, ruleErr.Violations)
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		err := v.Prepare(ctx, &domain.Order{Symbol: "NOPEUSDT"})
+		assert.ErrorIs(t, err, postgreserr.ErrSymbolNotFound)
+	})
+}
@@ -51,3 +51,26 @@ type TradeStorage = storage.TradeStorage
 
 // BalanceStorage интерфейс для работы с балансами
 type BalanceStorage = storage.BalanceStorage
+
+// OrderStatus статус ордера
+type OrderStatus = domain.OrderStatus
+
+// OrderSide направление ордера
+type OrderSide = domain.OrderSide
+
+// OrderType тип ордера
+type OrderType = domain.OrderType
+
+// Статусы ордера
+const (
+	OrderStatusNew               = domain.OrderStatusNew
+	OrderStatusPartiallyFilled   = domain.OrderStatusPartiallyFilled
+	OrderStatusFilled            = domain.OrderStatusFilled
+	OrderStatusCanceled          = domain.OrderStatusCanceled
+	OrderStatusPartiallyCanceled = domain.OrderStatusPartiallyCanceled
+	OrderStatusRejected          = domain.OrderStatusRejected
+	OrderStatusExpired           = domain.OrderStatusExpired
+)
+
+// ErrInvalidTransition возвращается при недопустимой смене статуса ордера
+var ErrInvalidTransition = domain.ErrInvalidTransition
@@ -0,0 +1,203 @@
+// Package outbox доставляет уведомления бота из таблицы outbox.
+// Сообщения ставятся в очередь через storage.OutboxStorage в одной транзакции с изменением
+// данных (postgres.DB.WithTx): откат не отправит уведомление, а сбой после коммита его не потеряет.
+// Dispatcher захватывает готовые сообщения, передает их Sender и повторяет неудачные
+// отправки с экспоненциальной задержкой; сообщения, которые так и не удалось отправить,
+// переводятся в DEAD.
+//
+// Доставка — at-least-once: если процесс упадет между Send и MarkMessageSent, сообщение
+// будет отправлено повторно после истечения захвата. Sender может отсеивать повторы по OutboxMessage.ID.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+const (
+	DefaultBatchSize    = 100              // Сообщений за один захват
+	DefaultPollInterval = 1 * time.Second  // Пауза, когда очередь пуста
+	DefaultLease        = 30 * time.Second // Время на отправку захваченного сообщения
+	DefaultMaxAttempts  = 10               // Попыток до перевода в DEAD
+	DefaultMinBackoff   = 1 * time.Second  // Задержка после первой неудачи
+	DefaultMaxBackoff   = 1 * time.Hour    // Максимальная задержка между попытками
+)
+
+// Sender отправляет сообщение получателю, например в Telegram.
+type Sender interface {
+	Send(ctx context.Context, msg *domain.OutboxMessage) error
+}
+
+// SenderFunc позволяет использовать функцию как Sender.
+type SenderFunc func(ctx context.Context, msg *domain.OutboxMessage) error
+
+// Send вызывает f.
+func (f SenderFunc) Send(ctx context.Context, msg *domain.OutboxMessage) error {
+	return f(ctx, msg)
+}
+
+// permanentError — ошибка отправки, которую бессмысленно повторять.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку Sender как постоянную (например, пользователь заблокировал бота):
+// сообщение сразу переводится в DEAD без повторов.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent сообщает, помечена ли ошибка через Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Config задает параметры диспетчера. Нулевые поля заменяются значениями Default*.
+type Config struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease — срок захвата сообщения; Send ограничен этим сроком, чтобы сообщение
+	// не захватил другой диспетчер, пока отправка еще идет
+	Lease       time.Duration
+	MaxAttempts int32
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.Lease <= 0 {
+		c.Lease = DefaultLease
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = DefaultMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = max(DefaultMaxBackoff, c.MinBackoff)
+	}
+	return c
+}
+
+// Dispatcher отправляет сообщения outbox через Sender. Несколько диспетчеров
+// могут работать с одной БД одновременно: каждое сообщение захватывает только один из них.
+type Dispatcher struct {
+	store  storage.OutboxStorage
+	sender Sender
+	cfg    Config
+}
+
+// NewDispatcher создает диспетчер.
+func NewDispatcher(store storage.OutboxStorage, sender Sender, cfg Config) *Dispatcher {
+	return &Dispatcher{store: store, sender: sender, cfg: cfg.withDefaults()}
+}
+
+// Run отправляет сообщения до отмены ctx и возвращает ctx.Err().
+// Ошибки хранилища логируются, отправка продолжается после PollInterval.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for {
+		n, err := d.DispatchOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("outbox: %v", err)
+		} else if n == d.cfg.BatchSize {
+			// Пачка заполнена — в очереди, вероятно, есть еще сообщения
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.cfg.PollInterval):
+		}
+	}
+}
+
+// DispatchOnce захватывает одну пачку сообщений и обрабатывает их по очереди.
+// Возвращает число обработанных сообщений. При ошибке хранилища оставшиеся сообщения
+// пачки вернутся в очередь после истечения захвата.
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	messages, err := d.store.ClaimMessages(ctx, d.cfg.BatchSize, d.cfg.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	for i, msg := range messages {
+		if err := d.deliver(ctx, msg); err != nil {
+			return i, err
+		}
+	}
+
+	return len(messages), nil
+}
+
+// deliver отправляет одно захваченное сообщение и сохраняет результат.
+func (d *Dispatcher) deliver(ctx context.Context, msg *domain.OutboxMessage) error {
+	if msg.Attempts > d.cfg.MaxAttempts {
+		// Предыдущие захваты не завершились ни успехом, ни ошибкой:
+		// отправка этого сообщения, вероятно, роняет процесс
+		return d.deadLetter(ctx, msg, fmt.Sprintf("no result after %d attempts", msg.Attempts-1))
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.cfg.Lease)
+	err := d.sender.Send(sendCtx, msg)
+	cancel()
+
+	switch {
+	case err == nil:
+		if err := d.store.MarkMessageSent(ctx, msg.ID); err != nil {
+			return fmt.Errorf("failed to mark outbox message %d sent: %w", msg.ID, err)
+		}
+		return nil
+	case ctx.Err() != nil:
+		// Диспетчер остановлен; сообщение вернется в очередь после истечения захвата
+		return ctx.Err()
+	case IsPermanent(err) || msg.Attempts >= d.cfg.MaxAttempts:
+		return d.deadLetter(ctx, msg, err.Error())
+	default:
+		if err := d.store.RetryMessage(ctx, msg.ID, err.Error(), d.Backoff(msg.Attempts)); err != nil {
+			return fmt.Errorf("failed to retry outbox message %d: %w", msg.ID, err)
+		}
+		return nil
+	}
+}
+
+func (d *Dispatcher) deadLetter(ctx context.Context, msg *domain.OutboxMessage, reason string) error {
+	if err := d.store.DeadLetterMessage(ctx, msg.ID, reason); err != nil {
+		return fmt.Errorf("failed to dead-letter outbox message %d: %w", msg.ID, err)
+	}
+	log.Printf("outbox: message %d (%s) moved to dead letters: %s", msg.ID, msg.Topic, reason)
+	return nil
+}
+
+// Backoff возвращает задержку после неудачной попытки attempt (с 1):
+// MinBackoff, удваивающийся с каждой попыткой, но не больше MaxBackoff.
+func (d *Dispatcher) Backoff(attempt int32) time.Duration {
+	delay := d.cfg.MinBackoff
+	for i := int32(1); i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
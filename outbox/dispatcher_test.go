@@ -0,0 +1,244 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage/memstore"
+)
+
+// recorder запоминает отправленные сообщения и возвращает ошибки из errs по очереди.
+type recorder struct {
+	mu   sync.Mutex
+	sent []uint64
+	errs []error
+}
+
+func (r *recorder) Send(_ context.Context, msg *domain.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg.ID)
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+func newTestStore(t *testing.T, messages int) (*memstore.Store, []*domain.OutboxMessage) {
+	t.Helper()
+	ctx := context.Background()
+	st := memstore.New()
+
+	user := &domain.User{MexcUID: "uid", Username: "trader", IsActive: true}
+	require.NoError(t, st.CreateUser(ctx, user))
+
+	var result []*domain.OutboxMessage
+	for i := 0; i < messages; i++ {
+		msg := &domain.OutboxMessage{UserID: user.ID, Topic: "note"}
+		_, err := st.EnqueueMessage(ctx, msg)
+		require.NoError(t, err)
+		result = append(result, msg)
+	}
+	return st, result
+}
+
+func getMessage(t *testing.T, st *memstore.Store, id uint64) *domain.OutboxMessage {
+	t.Helper()
+	msg, err := st.GetOutboxMessage(context.Background(), id)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestDispatcher_DispatchOnce(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sends in order", func(t *testing.T) {
+		st, messages := newTestStore(t, 3)
+		sender := &recorder{}
+		d := NewDispatcher(st, sender, Config{BatchSize: 2})
+
+		n, err := d.DispatchOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		n, err = d.DispatchOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		assert.Equal(t, []uint64{messages[0].ID, messages[1].ID, messages[2].ID}, sender.sent)
+		for _, m := range messages {
+			assert.Equal(t, domain.OutboxSent, getMessage(t, st, m.ID).Status)
+		}
+
+		n, err = d.DispatchOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("transient error is retried with backoff", func(t *testing.T) {
+		st, messages := newTestStore(t, 1)
+		sender := &recorder{errs: []error{errors.New("telegram: 502")}}
+		d := NewDispatcher(st, sender, Config{MinBackoff: time.Hour})
+
+		_, err := d.DispatchOnce(ctx)
+		require.NoError(t, err)
+
+		got := getMessage(t, st, messages[0].ID)
+		assert.Equal(t, domain.OutboxPending, got.Status)
+		assert.Equal(t, "telegram: 502", got.LastError)
+		assert.Equal(t, int32(1), got.Attempts)
+		assert.True(t, got.NextAttemptAt.After(time.Now().Add(50*time.Minute)))
+
+		// До истечения задержки сообщение не отправляется
+		n, err := d.DispatchOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("retry succeeds", func(t *testing.T) {
+		st, messages := newTestStore(t, 1)
+		sender := &recorder{errs: []error{errors.New("timeout")}}
+		d := NewDispatcher(st, sender, Config{MinBackoff: time.Nanosecond})
+
+		_, err := d.DispatchOnce(ctx)
+		require.NoError(t, err)
+		_, err = d.DispatchOnce(ctx)
+		require.NoError(t, err)
+
+		got := getMessage(t, st, messages[0].ID)
+		assert.Equal(t, domain.OutboxSent, got.Status)
+		assert.Equal(t, int32(2), got.Attempts)
+		assert.Equal(t, 2, sender.count())
+	})
+
+	t.Run("dead letter after max attempts", func(t *testing.T) {
+		st, messages := newTestStore(t, 1)
+		fail := errors.New("timeout")
+		sender := &recorder{errs: []error{fail, fail, fail}}
+		d := NewDispatcher(st, sender, Config{MaxAttempts: 2, MinBackoff: time.Nanosecond})
+
+		for i := 0; i < 3; i++ {
+			_, err := d.DispatchOnce(ctx)
+			require.NoError(t, err)
+		}
+
+		got := getMessage(t, st, messages[0].ID)
+		assert.Equal(t, domain.OutboxDead, got.Status)
+		assert.Equal(t, "timeout", got.LastError)
+		assert.Equal(t, 2, sender.count())
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		st, messages := newTestStore(t, 1)
+		sender := &recorder{errs: []error{Permanent(errors.New("bot was blocked by the user"))}}
+		d := NewDispatcher(st, sender, Config{})
+
+		_, err := d.DispatchOnce(ctx)
+		require.NoError(t, err)
+
+		got := getMessage(t, st, messages[0].ID)
+		assert.Equal(t, domain.OutboxDead, got.Status)
+		assert.Equal(t, "bot was blocked by the user", got.LastError)
+		assert.Equal(t, int32(1), got.Attempts)
+	})
+
+	t.Run("poisoned message is not sent", func(t *testing.T) {
+		st, messages := newTestStore(t, 1)
+
+		// Процесс падал во время отправки: захваты истекали без результата
+		for i := 0; i < 2; i++ {
+			_, err := st.ClaimMessages(ctx, 10, 0)
+			require.NoError(t, err)
+		}
+
+		sender := &recorder{}
+		d := NewDispatcher(st, sender, Config{MaxAttempts: 2})
+		_, err := d.DispatchOnce(ctx)
+		require.NoError(t, err)
+
+		got := getMessage(t, st, messages[0].ID)
+		assert.Equal(t, domain.OutboxDead, got.Status)
+		assert.Equal(t, "no result after 2 attempts", got.LastError)
+		assert.Zero(t, sender.count())
+	})
+
+	t.Run("send is bounded by lease", func(t *testing.T) {
+		st, messages := newTestStore(t, 1)
+		sender := SenderFunc(func(ctx context.Context, _ *domain.OutboxMessage) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		d := NewDispatcher(st, sender, Config{Lease: 10 * time.Millisecond, MinBackoff: time.Hour})
+
+		_, err := d.DispatchOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, context.DeadlineExceeded.Error(), getMessage(t, st, messages[0].ID).LastError)
+	})
+
+	t.Run("stopped dispatcher leaves message claimed", func(t *testing.T) {
+		st, messages := newTestStore(t, 1)
+		runCtx, cancel := context.WithCancel(ctx)
+		sender := SenderFunc(func(context.Context, *domain.OutboxMessage) error {
+			cancel()
+			return errors.New("interrupted")
+		})
+		d := NewDispatcher(st, sender, Config{})
+
+		_, err := d.DispatchOnce(runCtx)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		got := getMessage(t, st, messages[0].ID)
+		assert.Equal(t, domain.OutboxPending, got.Status)
+		assert.Empty(t, got.LastError)
+		assert.NotNil(t, got.LockedUntil)
+	})
+}
+
+func TestDispatcher_Run(t *testing.T) {
+	st, messages := newTestStore(t, 5)
+	sender := &recorder{}
+	d := NewDispatcher(st, sender, Config{BatchSize: 2, PollInterval: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return sender.count() == len(messages) }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, Config{MinBackoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	assert.Equal(t, time.Second, d.Backoff(1))
+	assert.Equal(t, 2*time.Second, d.Backoff(2))
+	assert.Equal(t, 8*time.Second, d.Backoff(4))
+	assert.Equal(t, 10*time.Second, d.Backoff(5))
+	assert.Equal(t, 10*time.Second, d.Backoff(1000))
+}
+
+func TestPermanent(t *testing.T) {
+	err := errors.New("blocked")
+
+	assert.Nil(t, Permanent(nil))
+	assert.True(t, IsPermanent(Permanent(err)))
+	assert.True(t, IsPermanent(errors.Join(errors.New("send"), Permanent(err))))
+	assert.ErrorIs(t, Permanent(err), err)
+	assert.False(t, IsPermanent(err))
+}
@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+)
+
+// OrderFilled — payload уведомления domain.OutboxTopicOrderFilled.
+type OrderFilled struct {
+	OrderID       string           `json:"order_id"` // mexc_order_id
+	Symbol        string           `json:"symbol"`
+	Side          domain.OrderSide `json:"side"`
+	Quantity      decimal.Decimal  `json:"quantity"`       // исполненное количество
+	QuoteQuantity decimal.Decimal  `json:"quote_quantity"` // исполненная сумма в котируемом активе
+}
+
+// OrderFilledMessage строит уведомление об исполнении ордера. DedupKey по mexc_order_id
+// оставляет одно уведомление на ордер, даже если статус FILLED записывается повторно.
+func OrderFilledMessage(order *domain.Order) (*domain.OutboxMessage, error) {
+	payload, err := json.Marshal(OrderFilled{
+		OrderID:       order.MexcOrderID,
+		Symbol:        order.Symbol,
+		Side:          order.Side,
+		Quantity:      order.ExecutedQuantity,
+		QuoteQuantity: order.CummulativeQuoteQty,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order filled payload: %w", err)
+	}
+
+	return &domain.OutboxMessage{
+		UserID:   order.UserID,
+		Topic:    domain.OutboxTopicOrderFilled,
+		DedupKey: domain.OutboxTopicOrderFilled + ":" + order.MexcOrderID,
+		Payload:  payload,
+	}, nil
+}
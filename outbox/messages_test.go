@@ -0,0 +1,30 @@
+package outbox
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+)
+
+func TestOrderFilledMessage(t *testing.T) {
+	order := &domain.Order{
+		UserID:              7,
+		MexcOrderID:         "C02__1",
+		Symbol:              "BTCUSDT",
+		Side:                domain.OrderSideBuy,
+		Status:              domain.OrderStatusFilled,
+		ExecutedQuantity:    decimal.RequireFromString("0.01"),
+		CummulativeQuoteQty: decimal.RequireFromString("500.5"),
+	}
+
+	msg, err := OrderFilledMessage(order)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), msg.UserID)
+	assert.Equal(t, domain.OutboxTopicOrderFilled, msg.Topic)
+	assert.Equal(t, "order.filled:C02__1", msg.DedupKey)
+	assert.JSONEq(t, `{"order_id":"C02__1","symbol":"BTCUSDT","side":"BUY","quantity":"0.01","quote_quantity":"500.5"}`, string(msg.Payload))
+}
@@ -0,0 +1,288 @@
+// Package pnl считает реализованный и нереализованный PnL по сделкам пользователя.
+// Позиции строятся по символам из domain.Trade в хронологическом порядке; покупки
+// открывают лоты, продажи закрывают их по выбранному методу (FIFO, LIFO или средняя цена).
+// Все суммы считаются в котируемом активе символа через shopspring/decimal.
+//
+// Комиссии учитываются так:
+//   - в котируемом активе: увеличивают стоимость покупки и уменьшают выручку продажи;
+//   - в базовом активе: уменьшают полученное количество при покупке и увеличивают
+//     списанное количество при продаже;
+//   - в третьем активе (например, MX): переводятся в котируемый актив через Options.FeePrice
+//     и учитываются как комиссия в котируемом активе; без курса они не входят в PnL
+//     и накапливаются в Position.UnconvertedFees.
+package pnl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// divPrecision — точность деления при распределении стоимости лотов
+const divPrecision = 15
+
+// Method — метод сопоставления продаж с лотами покупок.
+type Method string
+
+const (
+	FIFO        Method = "FIFO" // первыми закрываются самые старые лоты
+	LIFO        Method = "LIFO" // первыми закрываются самые новые лоты
+	AverageCost Method = "AVG"  // все покупки сливаются в один лот по средней цене
+)
+
+// ErrUnknownMethod возвращается для метода, отличного от FIFO, LIFO и AverageCost.
+var ErrUnknownMethod = errors.New("unknown lot matching method")
+
+// FeePriceFunc возвращает цену актива комиссии в котируемом активе на момент сделки.
+// ok=false означает, что курса нет.
+type FeePriceFunc func(asset, quote string, at time.Time) (price decimal.Decimal, ok bool)
+
+// Options — параметры расчета.
+type Options struct {
+	// Method — метод сопоставления лотов, по умолчанию FIFO
+	Method Method
+	// FeePrice переводит комиссии в третьем активе в котируемый актив; может быть nil
+	FeePrice FeePriceFunc
+}
+
+// Lot — открытая часть покупки.
+type Lot struct {
+	TradeID  string // mexc_trade_id покупки; пусто для лота средней цены
+	OpenedAt time.Time
+	Quantity decimal.Decimal
+	Cost     decimal.Decimal // стоимость Quantity в котируемом активе с учетом комиссий
+}
+
+// Price возвращает стоимость единицы лота.
+func (l Lot) Price() decimal.Decimal {
+	if l.Quantity.IsZero() {
+		return decimal.Zero
+	}
+	return l.Cost.DivRound(l.Quantity, divPrecision)
+}
+
+// Position — позиция по символу.
+type Position struct {
+	Symbol string
+	Base   string
+	Quote  string
+
+	// Quantity — открытое количество базового актива
+	Quantity decimal.Decimal
+	// CostBasis — стоимость открытого количества в котируемом активе
+	CostBasis decimal.Decimal
+	// Realized — реализованный PnL в котируемом активе за всю историю
+	Realized decimal.Decimal
+	// Fees — комиссии, учтенные в PnL, в котируемом активе
+	Fees decimal.Decimal
+	// UnconvertedFees — комиссии в третьих активах без курса, не вошедшие в PnL
+	UnconvertedFees map[string]decimal.Decimal
+	// Unmatched — проданное количество без покупок в истории (например, после ввода актива).
+	// Выручка за него не входит в Realized.
+	Unmatched decimal.Decimal
+
+	// Lots — открытые лоты в порядке открытия
+	Lots []Lot
+}
+
+// AvgCost возвращает среднюю стоимость единицы открытой позиции.
+func (p *Position) AvgCost() decimal.Decimal {
+	if p.Quantity.IsZero() {
+		return decimal.Zero
+	}
+	return p.CostBasis.DivRound(p.Quantity, divPrecision)
+}
+
+// Realization — закрытие позиции одной продажей.
+type Realization struct {
+	Symbol   string
+	Quote    string
+	TradeID  string
+	Time     time.Time
+	Quantity decimal.Decimal // сопоставленное с лотами количество
+	Proceeds decimal.Decimal // выручка за Quantity за вычетом комиссий
+	Cost     decimal.Decimal // стоимость закрытых лотов
+	PnL      decimal.Decimal // Proceeds - Cost
+}
+
+// Report — результат расчета по всем символам.
+type Report struct {
+	Method       Method
+	Positions    map[string]*Position // по символу
+	Realizations []Realization        // в хронологическом порядке
+}
+
+// Build строит позиции по сделкам. Порядок входных сделок не важен:
+// они сортируются по trade_time и id.
+func Build(trades []*domain.Trade, opts Options) (*Report, error) {
+	if opts.Method == "" {
+		opts.Method = FIFO
+	}
+	switch opts.Method {
+	case FIFO, LIFO, AverageCost:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMethod, opts.Method)
+	}
+
+	sorted := make([]*domain.Trade, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].TradeTime.Equal(sorted[j].TradeTime) {
+			return sorted[i].TradeTime.Before(sorted[j].TradeTime)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	report := &Report{Method: opts.Method, Positions: make(map[string]*Position)}
+	for _, trade := range sorted {
+		position, err := report.position(trade.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("trade %s: %w", trade.MexcTradeID, err)
+		}
+
+		fee, quantityFee := position.fee(trade, opts.FeePrice)
+		if trade.IsBuyer {
+			position.buy(trade, trade.Quantity.Sub(quantityFee), trade.QuoteQuantity.Add(fee), opts.Method)
+			continue
+		}
+
+		if r, ok := position.sell(trade, trade.Quantity.Add(quantityFee), trade.QuoteQuantity.Sub(fee), opts.Method); ok {
+			report.Realizations = append(report.Realizations, r)
+		}
+	}
+
+	return report, nil
+}
+
+// FromStorage загружает все сделки пользователя и строит позиции.
+func FromStorage(ctx context.Context, trades storage.TradeStorage, userID uint64, opts Options) (*Report, error) {
+	list, err := trades.GetUserTrades(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trades: %w", err)
+	}
+	return Build(list, opts)
+}
+
+func (r *Report) position(symbol string) (*Position, error) {
+	if p, ok := r.Positions[symbol]; ok {
+		return p, nil
+	}
+	base, quote, err := domain.SplitSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	p := &Position{Symbol: symbol, Base: base, Quote: quote}
+	r.Positions[symbol] = p
+	return p, nil
+}
+
+// fee возвращает комиссию сделки в котируемом активе и в базовом активе.
+func (p *Position) fee(trade *domain.Trade, feePrice FeePriceFunc) (quote, base decimal.Decimal) {
+	if trade.Commission.IsZero() {
+		return decimal.Zero, decimal.Zero
+	}
+
+	switch trade.CommissionAsset {
+	case p.Quote:
+		quote = trade.Commission
+	case p.Base:
+		return decimal.Zero, trade.Commission
+	default:
+		price, ok := decimal.Zero, false
+		if feePrice != nil {
+			price, ok = feePrice(trade.CommissionAsset, p.Quote, trade.TradeTime)
+		}
+		if !ok {
+			if p.UnconvertedFees == nil {
+				p.UnconvertedFees = make(map[string]decimal.Decimal)
+			}
+			p.UnconvertedFees[trade.CommissionAsset] = p.UnconvertedFees[trade.CommissionAsset].Add(trade.Commission)
+			return decimal.Zero, decimal.Zero
+		}
+		quote = trade.Commission.Mul(price)
+	}
+
+	p.Fees = p.Fees.Add(quote)
+	return quote, decimal.Zero
+}
+
+func (p *Position) buy(trade *domain.Trade, quantity, cost decimal.Decimal, method Method) {
+	p.Quantity = p.Quantity.Add(quantity)
+	p.CostBasis = p.CostBasis.Add(cost)
+
+	if method == AverageCost {
+		if len(p.Lots) == 0 {
+			p.Lots = []Lot{{OpenedAt: trade.TradeTime}}
+		}
+		p.Lots[0].Quantity = p.Lots[0].Quantity.Add(quantity)
+		p.Lots[0].Cost = p.Lots[0].Cost.Add(cost)
+		return
+	}
+
+	p.Lots = append(p.Lots, Lot{TradeID: trade.MexcTradeID, OpenedAt: trade.TradeTime, Quantity: quantity, Cost: cost})
+}
+
+// sell закрывает quantity по лотам. Количество сверх открытой позиции попадает в Unmatched,
+// выручка распределяется пропорционально количеству.
+func (p *Position) sell(trade *domain.Trade, quantity, proceeds decimal.Decimal, method Method) (Realization, bool) {
+	matched, cost := decimal.Zero, decimal.Zero
+	remaining := quantity
+
+	for remaining.IsPositive() && len(p.Lots) > 0 {
+		i := 0
+		if method == LIFO {
+			i = len(p.Lots) - 1
+		}
+		lot := &p.Lots[i]
+
+		take := decimal.Min(remaining, lot.Quantity)
+		lotCost := lot.Cost
+		if take.LessThan(lot.Quantity) {
+			lotCost = lot.Cost.Mul(take).DivRound(lot.Quantity, divPrecision)
+		}
+
+		lot.Quantity = lot.Quantity.Sub(take)
+		lot.Cost = lot.Cost.Sub(lotCost)
+		if lot.Quantity.IsZero() {
+			p.Lots = append(p.Lots[:i], p.Lots[i+1:]...)
+		}
+
+		matched = matched.Add(take)
+		cost = cost.Add(lotCost)
+		remaining = remaining.Sub(take)
+	}
+
+	p.Quantity = p.Quantity.Sub(matched)
+	p.CostBasis = p.CostBasis.Sub(cost)
+	if remaining.IsPositive() {
+		p.Unmatched = p.Unmatched.Add(remaining)
+	}
+	if matched.IsZero() {
+		return Realization{}, false
+	}
+
+	if matched.LessThan(quantity) {
+		proceeds = proceeds.Mul(matched).DivRound(quantity, divPrecision)
+	}
+
+	r := Realization{
+		Symbol:   p.Symbol,
+		Quote:    p.Quote,
+		TradeID:  trade.MexcTradeID,
+		Time:     trade.TradeTime,
+		Quantity: matched,
+		Proceeds: proceeds,
+		Cost:     cost,
+		PnL:      proceeds.Sub(cost),
+	}
+	p.Realized = p.Realized.Add(r.PnL)
+
+	return r, true
+}
@@ -0,0 +1,248 @@
+package pnl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage/memstore"
+)
+
+var baseTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func requireDecimal(t *testing.T, expected string, actual decimal.Decimal) {
+	t.Helper()
+	require.True(t, dec(expected).Equal(actual), "expected %s, got %s", expected, actual)
+}
+
+// trade создает сделку BTCUSDT без комиссии
+func trade(id string, offset time.Duration, isBuyer bool, quantity, quote string) *domain.Trade {
+	return &domain.Trade{
+		MexcTradeID:     id,
+		Symbol:          "BTCUSDT",
+		Quantity:        dec(quantity),
+		QuoteQuantity:   dec(quote),
+		Commission:      decimal.Zero,
+		CommissionAsset: "USDT",
+		TradeTime:       baseTime.Add(offset),
+		IsBuyer:         isBuyer,
+	}
+}
+
+func withFee(t *domain.Trade, commission, asset string) *domain.Trade {
+	t.Commission = dec(commission)
+	t.CommissionAsset = asset
+	return t
+}
+
+func TestBuild_Methods(t *testing.T) {
+	trades := []*domain.Trade{
+		trade("b1", 0, true, "1", "100"),
+		trade("b2", time.Hour, true, "1", "200"),
+		trade("s1", 2*time.Hour, false, "1", "300"),
+	}
+
+	cases := []struct {
+		method   Method
+		realized string
+		cost     string
+		lots     int
+	}{
+		{FIFO, "200", "200", 1},
+		{LIFO, "100", "100", 1},
+		{AverageCost, "150", "150", 1},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.method), func(t *testing.T) {
+			report, err := Build(trades, Options{Method: tc.method})
+			require.NoError(t, err)
+
+			position := report.Positions["BTCUSDT"]
+			require.NotNil(t, position)
+			assert.Equal(t, "BTC", position.Base)
+			assert.Equal(t, "USDT", position.Quote)
+			requireDecimal(t, tc.realized, position.Realized)
+			requireDecimal(t, "1", position.Quantity)
+			requireDecimal(t, tc.cost, position.CostBasis)
+			requireDecimal(t, tc.cost, position.AvgCost())
+			assert.Len(t, position.Lots, tc.lots)
+
+			require.Len(t, report.Realizations, 1)
+			assert.Equal(t, "s1", report.Realizations[0].TradeID)
+			requireDecimal(t, "300", report.Realizations[0].Proceeds)
+		})
+	}
+
+	t.Run("default method is FIFO", func(t *testing.T) {
+		report, err := Build(trades, Options{})
+		require.NoError(t, err)
+		assert.Equal(t, FIFO, report.Method)
+		assert.Equal(t, "b2", report.Positions["BTCUSDT"].Lots[0].TradeID)
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		_, err := Build(trades, Options{Method: "HIFO"})
+		assert.ErrorIs(t, err, ErrUnknownMethod)
+	})
+}
+
+func TestBuild_Commissions(t *testing.T) {
+	t.Run("quote asset", func(t *testing.T) {
+		report, err := Build([]*domain.Trade{
+			withFee(trade("b1", 0, true, "1", "100"), "1", "USDT"),
+			withFee(trade("s1", time.Hour, false, "1", "110"), "1.1", "USDT"),
+		}, Options{})
+		require.NoError(t, err)
+
+		position := report.Positions["BTCUSDT"]
+		requireDecimal(t, "7.9", position.Realized)
+		requireDecimal(t, "2.1", position.Fees)
+		requireDecimal(t, "108.9", report.Realizations[0].Proceeds)
+		requireDecimal(t, "101", report.Realizations[0].Cost)
+	})
+
+	t.Run("base asset", func(t *testing.T) {
+		report, err := Build([]*domain.Trade{
+			withFee(trade("b1", 0, true, "1", "100"), "0.001", "BTC"),
+			trade("s1", time.Hour, false, "0.999", "120"),
+		}, Options{})
+		require.NoError(t, err)
+
+		position := report.Positions["BTCUSDT"]
+		requireDecimal(t, "20", position.Realized)
+		requireDecimal(t, "0", position.Quantity)
+		assert.Empty(t, position.Lots)
+		requireDecimal(t, "0", position.Unmatched)
+	})
+
+	t.Run("base asset on sell", func(t *testing.T) {
+		report, err := Build([]*domain.Trade{
+			trade("b1", 0, true, "2", "200"),
+			withFee(trade("s1", time.Hour, false, "1", "150"), "0.5", "BTC"),
+		}, Options{})
+		require.NoError(t, err)
+
+		position := report.Positions["BTCUSDT"]
+		requireDecimal(t, "0.5", position.Quantity)
+		// 1.5 BTC списано за 150 USDT при стоимости 150
+		requireDecimal(t, "0", position.Realized)
+	})
+
+	t.Run("third asset with price", func(t *testing.T) {
+		feePrice := func(asset, quote string, at time.Time) (decimal.Decimal, bool) {
+			assert.Equal(t, "MX", asset)
+			assert.Equal(t, "USDT", quote)
+			return dec("0.5"), true
+		}
+		report, err := Build([]*domain.Trade{
+			withFee(trade("b1", 0, true, "1", "100"), "2", "MX"),
+			withFee(trade("s1", time.Hour, false, "1", "110"), "2", "MX"),
+		}, Options{FeePrice: feePrice})
+		require.NoError(t, err)
+
+		position := report.Positions["BTCUSDT"]
+		requireDecimal(t, "8", position.Realized)
+		requireDecimal(t, "2", position.Fees)
+		assert.Empty(t, position.UnconvertedFees)
+	})
+
+	t.Run("third asset without price", func(t *testing.T) {
+		report, err := Build([]*domain.Trade{
+			withFee(trade("b1", 0, true, "1", "100"), "2", "MX"),
+			withFee(trade("s1", time.Hour, false, "1", "110"), "3", "MX"),
+		}, Options{})
+		require.NoError(t, err)
+
+		position := report.Positions["BTCUSDT"]
+		requireDecimal(t, "10", position.Realized)
+		requireDecimal(t, "0", position.Fees)
+		requireDecimal(t, "5", position.UnconvertedFees["MX"])
+	})
+}
+
+func TestBuild_Lots(t *testing.T) {
+	t.Run("partial lots keep the total cost exact", func(t *testing.T) {
+		report, err := Build([]*domain.Trade{
+			trade("b1", 0, true, "3", "100"),
+			trade("s1", time.Hour, false, "1", "40"),
+			trade("s2", 2*time.Hour, false, "1", "40"),
+			trade("s3", 3*time.Hour, false, "1", "40"),
+		}, Options{})
+		require.NoError(t, err)
+
+		position := report.Positions["BTCUSDT"]
+		requireDecimal(t, "20", position.Realized)
+		requireDecimal(t, "0", position.CostBasis)
+		assert.Empty(t, position.Lots)
+
+		cost := decimal.Zero
+		for _, r := range report.Realizations {
+			cost = cost.Add(r.Cost)
+		}
+		requireDecimal(t, "100", cost)
+	})
+
+	t.Run("sell beyond position is unmatched", func(t *testing.T) {
+		report, err := Build([]*domain.Trade{
+			trade("b1", 0, true, "1", "100"),
+			trade("s1", time.Hour, false, "2", "400"),
+			trade("s2", 2*time.Hour, false, "1", "200"),
+		}, Options{})
+		require.NoError(t, err)
+
+		position := report.Positions["BTCUSDT"]
+		requireDecimal(t, "100", position.Realized)
+		requireDecimal(t, "2", position.Unmatched)
+		require.Len(t, report.Realizations, 1)
+		requireDecimal(t, "1", report.Realizations[0].Quantity)
+		requireDecimal(t, "200", report.Realizations[0].Proceeds)
+	})
+
+	t.Run("input order does not matter", func(t *testing.T) {
+		newestFirst := []*domain.Trade{
+			trade("s1", 2*time.Hour, false, "1", "300"),
+			trade("b2", time.Hour, true, "1", "200"),
+			trade("b1", 0, true, "1", "100"),
+		}
+		report, err := Build(newestFirst, Options{Method: FIFO})
+		require.NoError(t, err)
+		requireDecimal(t, "200", report.Positions["BTCUSDT"].Realized)
+		assert.Equal(t, "s1", newestFirst[0].MexcTradeID, "input is not reordered")
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		bad := trade("b1", 0, true, "1", "100")
+		bad.Symbol = "XYZ"
+		_, err := Build([]*domain.Trade{bad}, Options{})
+		assert.ErrorIs(t, err, domain.ErrUnknownSymbol)
+	})
+}
+
+func TestFromStorage(t *testing.T) {
+	ctx := context.Background()
+	st := memstore.New()
+
+	user := &domain.User{MexcUID: "uid"}
+	require.NoError(t, st.CreateUser(ctx, user))
+	for _, tr := range []*domain.Trade{
+		trade("b1", 0, true, "1", "100"),
+		trade("s1", time.Hour, false, "0.5", "60"),
+	} {
+		tr.UserID = user.ID
+		require.NoError(t, st.CreateTrade(ctx, tr))
+	}
+
+	report, err := FromStorage(ctx, st, user.ID, Options{})
+	require.NoError(t, err)
+	requireDecimal(t, "10", report.Positions["BTCUSDT"].Realized)
+	requireDecimal(t, "0.5", report.Positions["BTCUSDT"].Quantity)
+}
@@ -0,0 +1,143 @@
+package pnl
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Period — длина периода для группировки реализованного PnL.
+type Period string
+
+const (
+	Day   Period = "day"
+	Week  Period = "week" // недели начинаются с понедельника
+	Month Period = "month"
+)
+
+// start возвращает начало периода, в который попадает t, в часовом поясе loc.
+func (p Period) start(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	switch p {
+	case Week:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return day
+	}
+}
+
+// next возвращает начало следующего периода.
+func (p Period) next(start time.Time) time.Time {
+	switch p {
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// PeriodPnL — реализованный PnL за период [Start, End) в одном котируемом активе.
+type PeriodPnL struct {
+	Start    time.Time
+	End      time.Time
+	Quote    string
+	Realized decimal.Decimal
+	Trades   int // число продаж, закрывших лоты
+}
+
+// RealizedByPeriod группирует реализованный PnL по периодам и котируемым активам.
+// Периоды без продаж не возвращаются. loc задает границы суток, nil — UTC.
+func (r *Report) RealizedByPeriod(period Period, loc *time.Location) []PeriodPnL {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	type key struct {
+		start int64
+		quote string
+	}
+	groups := make(map[key]*PeriodPnL)
+	var result []*PeriodPnL
+	for _, realization := range r.Realizations {
+		start := period.start(realization.Time, loc)
+		k := key{start.Unix(), realization.Quote}
+		group, ok := groups[k]
+		if !ok {
+			group = &PeriodPnL{Start: start, End: period.next(start), Quote: realization.Quote}
+			groups[k] = group
+			result = append(result, group)
+		}
+		group.Realized = group.Realized.Add(realization.PnL)
+		group.Trades++
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].Start.Equal(result[j].Start) {
+			return result[i].Start.Before(result[j].Start)
+		}
+		return result[i].Quote < result[j].Quote
+	})
+
+	periods := make([]PeriodPnL, len(result))
+	for i, group := range result {
+		periods[i] = *group
+	}
+	return periods
+}
+
+// UnrealizedPnL — оценка открытой позиции по текущей цене.
+type UnrealizedPnL struct {
+	Symbol      string
+	Quote       string
+	Quantity    decimal.Decimal
+	CostBasis   decimal.Decimal
+	Price       decimal.Decimal
+	MarketValue decimal.Decimal // Quantity * Price
+	PnL         decimal.Decimal // MarketValue - CostBasis
+}
+
+// Unrealized оценивает открытые позиции по ценам prices (символ -> цена в котируемом активе).
+// Возвращает оценки, отсортированные по символу, и символы открытых позиций без цены.
+func (r *Report) Unrealized(prices map[string]decimal.Decimal) (result []UnrealizedPnL, missing []string) {
+	for _, symbol := range r.symbols() {
+		position := r.Positions[symbol]
+		if position.Quantity.IsZero() {
+			continue
+		}
+
+		price, ok := prices[symbol]
+		if !ok {
+			missing = append(missing, symbol)
+			continue
+		}
+
+		value := position.Quantity.Mul(price)
+		result = append(result, UnrealizedPnL{
+			Symbol:      symbol,
+			Quote:       position.Quote,
+			Quantity:    position.Quantity,
+			CostBasis:   position.CostBasis,
+			Price:       price,
+			MarketValue: value,
+			PnL:         value.Sub(position.CostBasis),
+		})
+	}
+
+	return result, missing
+}
+
+// symbols возвращает символы позиций по алфавиту.
+func (r *Report) symbols() []string {
+	symbols := make([]string, 0, len(r.Positions))
+	for symbol := range r.Positions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
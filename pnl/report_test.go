@@ -0,0 +1,99 @@
+package pnl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+)
+
+func TestReport_RealizedByPeriod(t *testing.T) {
+	// baseTime — пятница 1 марта 2024, 12:00 UTC
+	eth := trade("e1", 0, true, "1", "0.05")
+	eth.Symbol = "ETHBTC"
+	ethSell := trade("e2", 2*time.Hour, false, "1", "0.06")
+	ethSell.Symbol = "ETHBTC"
+
+	report, err := Build([]*domain.Trade{
+		trade("b1", 0, true, "4", "400"),
+		trade("s1", time.Hour, false, "1", "110"),       // пятница
+		trade("s2", 13*time.Hour, false, "1", "120"),    // суббота 01:00 UTC
+		trade("s3", 4*24*time.Hour, false, "1", "90"),   // вторник 5 марта
+		trade("s4", 31*24*time.Hour, false, "1", "130"), // 1 апреля
+		eth, ethSell,
+	}, Options{})
+	require.NoError(t, err)
+
+	t.Run("day", func(t *testing.T) {
+		periods := report.RealizedByPeriod(Day, nil)
+		require.Len(t, periods, 5)
+		assert.Equal(t, baseTime.Truncate(24*time.Hour), periods[0].Start)
+		assert.Equal(t, "BTC", periods[0].Quote)
+		requireDecimal(t, "0.01", periods[0].Realized)
+		assert.Equal(t, "USDT", periods[1].Quote)
+		requireDecimal(t, "10", periods[1].Realized)
+		assert.Equal(t, periods[1].Start.AddDate(0, 0, 1), periods[1].End)
+		requireDecimal(t, "20", periods[2].Realized)
+	})
+
+	t.Run("day in local time zone", func(t *testing.T) {
+		// В UTC-5 продажа в субботу 01:00 UTC приходится на пятницу
+		loc := time.FixedZone("UTC-5", -5*60*60)
+		periods := report.RealizedByPeriod(Day, loc)
+		require.Len(t, periods, 4)
+		assert.Equal(t, "USDT", periods[1].Quote)
+		requireDecimal(t, "30", periods[1].Realized)
+		assert.Equal(t, 2, periods[1].Trades)
+	})
+
+	t.Run("week starts on monday", func(t *testing.T) {
+		periods := report.RealizedByPeriod(Week, nil)
+		require.Len(t, periods, 4)
+		assert.Equal(t, time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), periods[1].Start)
+		requireDecimal(t, "30", periods[1].Realized)
+		assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), periods[2].Start)
+		requireDecimal(t, "-10", periods[2].Realized)
+	})
+
+	t.Run("month", func(t *testing.T) {
+		periods := report.RealizedByPeriod(Month, nil)
+		require.Len(t, periods, 3)
+		assert.Equal(t, "BTC", periods[0].Quote)
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), periods[1].Start)
+		assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), periods[1].End)
+		requireDecimal(t, "20", periods[1].Realized)
+		assert.Equal(t, 3, periods[1].Trades)
+		requireDecimal(t, "30", periods[2].Realized)
+	})
+}
+
+func TestReport_Unrealized(t *testing.T) {
+	eth := trade("e1", 0, true, "2", "6000")
+	eth.Symbol = "ETHUSDT"
+	closed := trade("x1", 0, true, "10", "5")
+	closed.Symbol = "XRPUSDT"
+	closedSell := trade("x2", time.Hour, false, "10", "6")
+	closedSell.Symbol = "XRPUSDT"
+
+	report, err := Build([]*domain.Trade{
+		trade("b1", 0, true, "1", "100"),
+		trade("b2", time.Hour, true, "1", "200"),
+		eth, closed, closedSell,
+	}, Options{})
+	require.NoError(t, err)
+
+	result, missing := report.Unrealized(map[string]decimal.Decimal{
+		"BTCUSDT": dec("180"),
+		"XRPUSDT": dec("1"),
+	})
+	require.Len(t, result, 1, "closed positions are not valued")
+	assert.Equal(t, "BTCUSDT", result[0].Symbol)
+	requireDecimal(t, "360", result[0].MarketValue)
+	requireDecimal(t, "300", result[0].CostBasis)
+	requireDecimal(t, "60", result[0].PnL)
+	assert.Equal(t, []string{"ETHUSDT"}, missing)
+}
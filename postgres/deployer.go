@@ -5,15 +5,15 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"github.com/samar/sup_bot/metacore/configs"
+	"github.com/samar/sup_bot/metacore/postgres/migrations"
 
 	_ "github.com/lib/pq"
 )
 
-// Deployer отвечает за развертывание и инициализацию БД
+// Deployer отвечает за развертывание и миграции схемы БД
 type Deployer struct {
 	config configs.Config
 }
@@ -25,8 +25,58 @@ func NewDeployer(config configs.Config) *Deployer {
 	}
 }
 
-// DeploySchema развертывает схему БД
+// DeploySchema применяет все непримененные миграции
 func (d *Deployer) DeploySchema(ctx context.Context) error {
+	return d.withMigrator(ctx, func(m *migrations.Migrator) error {
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+
+		log.Printf("Schema deployed successfully! Applied migrations: %d", applied)
+		return nil
+	})
+}
+
+// MigrateDown откатывает n последних миграций
+func (d *Deployer) MigrateDown(ctx context.Context, n int) error {
+	return d.withMigrator(ctx, func(m *migrations.Migrator) error {
+		reverted, err := m.Down(ctx, n)
+		if err != nil {
+			return fmt.Errorf("failed to revert migrations: %w", err)
+		}
+
+		log.Printf("Reverted migrations: %d", reverted)
+		return nil
+	})
+}
+
+// MigrateRedo откатывает и заново применяет последнюю миграцию
+func (d *Deployer) MigrateRedo(ctx context.Context) error {
+	return d.withMigrator(ctx, func(m *migrations.Migrator) error {
+		if err := m.Redo(ctx); err != nil {
+			return fmt.Errorf("failed to redo migration: %w", err)
+		}
+		return nil
+	})
+}
+
+// MigrationStatus возвращает состояние миграций
+func (d *Deployer) MigrationStatus(ctx context.Context) ([]migrations.Status, error) {
+	var statuses []migrations.Status
+	err := d.withMigrator(ctx, func(m *migrations.Migrator) error {
+		var err error
+		statuses, err = m.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get migration status: %w", err)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withMigrator подключается к БД и выполняет fn с Migrator
+func (d *Deployer) withMigrator(ctx context.Context, fn func(m *migrations.Migrator) error) error {
 	// Формируем строку подключения
 	connString := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
@@ -47,35 +97,12 @@ func (d *Deployer) DeploySchema(ctx context.Context) error {
 
 	log.Println("Connected to the database successfully.")
 
-	// Читаем и выполняем схему
-	if err := d.executeSchema(ctx, db); err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
-	}
-
-	log.Println("Schema deployed successfully!")
-	return nil
-}
-
-// executeSchema читает и выполняет SQL схему
-func (d *Deployer) executeSchema(ctx context.Context, db *sql.DB) error {
-	// Путь к файлу схемы
-	schemaPath := "./postgres/schema.sql"
-
-	// Читаем файл схемы
-	sqlBytes, err := os.ReadFile(schemaPath)
-	if err != nil {
-		return fmt.Errorf("failed to read schema file %s: %w", schemaPath, err)
-	}
-
-	sql := string(sqlBytes)
-
-	// Выполняем схему
-	_, err = db.ExecContext(ctx, sql)
+	m, err := migrations.New(db)
 	if err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+		return err
 	}
 
-	return nil
+	return fn(m)
 }
 
 // DeploySchemaWithTimeout развертывает схему с таймаутом
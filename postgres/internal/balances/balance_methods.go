@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowmap"
 	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
 	"github.com/samar/sup_bot/metacore/storage"
 )
@@ -25,9 +26,7 @@ func NewBalanceStorage(db storage.DBInterface) *BalanceStorage {
 // UpdateBalance обновляет баланс пользователя атомарно.
 // Возвращает applied=true если баланс был обновлен (значения изменились).
 func (s *BalanceStorage) UpdateBalance(ctx context.Context, balance *domain.UserBalance) (applied bool, err error) {
-	query := `
-		INSERT INTO user_balances (user_id, asset, free, locked, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+	query := rowmap.InsertUserBalance + `
 		ON CONFLICT (user_id, asset) 
 		DO UPDATE SET 
 			free = EXCLUDED.free,
@@ -35,53 +34,38 @@ func (s *BalanceStorage) UpdateBalance(ctx context.Context, balance *domain.User
 			updated_at = EXCLUDED.updated_at
 		WHERE user_balances.free != EXCLUDED.free 
 		   OR user_balances.locked != EXCLUDED.locked
-		RETURNING id`
+		RETURNING ` + rowmap.UserBalanceReturning
 
 	balance.UpdatedAt = time.Now()
 
-	var id uint64
-	err = s.db.QueryRowContext(ctx, query,
-		balance.UserID,
-		balance.Asset,
-		balance.Free,
-		balance.Locked,
-		balance.UpdatedAt,
-	).Scan(&id)
+	err = s.db.QueryRowContext(ctx, query, rowmap.UserBalanceArgs(balance)...).
+		Scan(rowmap.UserBalanceReturningDest(balance)...)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// Значения не изменились
 			return false, nil
 		}
-		return false, fmt.Errorf("failed to update balance: %w", err)
+		return false, fmt.Errorf("failed to update balance: %w", postgreserr.Classify(err))
 	}
 
-	balance.ID = id
 	return true, nil
 }
 
 // GetBalance получает баланс пользователя по активу.
 func (s *BalanceStorage) GetBalance(ctx context.Context, userID uint64, asset string) (*domain.UserBalance, error) {
 	query := `
-		SELECT id, user_id, asset, free, locked, updated_at
+		SELECT ` + rowmap.UserBalanceColumns + `
 		FROM user_balances
 		WHERE user_id = $1 AND asset = $2`
 
-	balance := &domain.UserBalance{}
-	err := s.db.QueryRowContext(ctx, query, userID, asset).Scan(
-		&balance.ID,
-		&balance.UserID,
-		&balance.Asset,
-		&balance.Free,
-		&balance.Locked,
-		&balance.UpdatedAt,
-	)
+	balance, err := rowmap.ScanUserBalance(s.db.QueryRowContext(ctx, query, userID, asset))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, postgreserr.ErrBalanceNotFound
 		}
-		return nil, fmt.Errorf("failed to get balance: %w", err)
+		return nil, fmt.Errorf("failed to get balance: %w", postgreserr.Classify(err))
 	}
 
 	return balance, nil
@@ -90,36 +74,28 @@ func (s *BalanceStorage) GetBalance(ctx context.Context, userID uint64, asset st
 // GetUserBalances получает все балансы пользователя.
 func (s *BalanceStorage) GetUserBalances(ctx context.Context, userID uint64) ([]*domain.UserBalance, error) {
 	query := `
-		SELECT id, user_id, asset, free, locked, updated_at
+		SELECT ` + rowmap.UserBalanceColumns + `
 		FROM user_balances
 		WHERE user_id = $1
 		ORDER BY asset`
 
 	rows, err := s.db.QueryContext(ctx, query, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query user balances: %w", err)
+		return nil, fmt.Errorf("failed to query user balances: %w", postgreserr.Classify(err))
 	}
 	defer rows.Close()
 
 	var balances []*domain.UserBalance
 	for rows.Next() {
-		balance := &domain.UserBalance{}
-		err := rows.Scan(
-			&balance.ID,
-			&balance.UserID,
-			&balance.Asset,
-			&balance.Free,
-			&balance.Locked,
-			&balance.UpdatedAt,
-		)
+		balance, err := rowmap.ScanUserBalance(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan balance: %w", err)
+			return nil, fmt.Errorf("failed to scan balance: %w", postgreserr.Classify(err))
 		}
 		balances = append(balances, balance)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating balance rows: %w", err)
+		return nil, fmt.Errorf("error iterating balance rows: %w", postgreserr.Classify(err))
 	}
 
 	return balances, nil
@@ -134,7 +110,7 @@ func (s *BalanceStorage) UpdateUserBalances(ctx context.Context, userID uint64,
 	// Начинаем транзакцию
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
 	}
 	defer func() {
 		if err != nil {
@@ -143,19 +119,17 @@ func (s *BalanceStorage) UpdateUserBalances(ctx context.Context, userID uint64,
 	}()
 
 	// Подготавливаем запрос для обновления
-	updateQuery := `
-		INSERT INTO user_balances (user_id, asset, free, locked, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+	updateQuery := rowmap.InsertUserBalance + `
 		ON CONFLICT (user_id, asset) 
 		DO UPDATE SET 
 			free = EXCLUDED.free,
 			locked = EXCLUDED.locked,
 			updated_at = EXCLUDED.updated_at
-		RETURNING id`
+		RETURNING ` + rowmap.UserBalanceReturning
 
 	stmt, err := tx.PrepareContext(ctx, updateQuery)
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return fmt.Errorf("failed to prepare statement: %w", postgreserr.Classify(err))
 	}
 	defer stmt.Close()
 
@@ -166,21 +140,11 @@ func (s *BalanceStorage) UpdateUserBalances(ctx context.Context, userID uint64,
 		balance.UserID = userID
 		balance.UpdatedAt = updateTime
 
-		var id uint64
-		err = stmt.QueryRowContext(ctx,
-			balance.UserID,
-			balance.Asset,
-			balance.Free,
-			balance.Locked,
-			balance.UpdatedAt,
-		).Scan(&id)
+		err = stmt.QueryRowContext(ctx, rowmap.UserBalanceArgs(balance)...).
+			Scan(rowmap.UserBalanceReturningDest(balance)...)
 
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("failed to update balance for asset %s: %w", balance.Asset, err)
-		}
-
-		if err == nil {
-			balance.ID = id
+			return fmt.Errorf("failed to update balance for asset %s: %w", balance.Asset, postgreserr.Classify(err))
 		}
 	}
 
@@ -194,16 +158,74 @@ func (s *BalanceStorage) UpdateUserBalances(ctx context.Context, userID uint64,
 
 	_, err = tx.ExecContext(ctx, deleteQuery, userID, updateTime)
 	if err != nil {
-		return fmt.Errorf("failed to delete zero balances: %w", err)
+		return fmt.Errorf("failed to delete zero balances: %w", postgreserr.Classify(err))
 	}
 
 	// Коммитим транзакцию
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
 	}
 
 	return nil
 }
 
+// GetBalancesAt возвращает ненулевые балансы пользователя на момент t по истории balance_snapshots.
+// Для каждого актива берется последний снимок не позже t.
+func (s *BalanceStorage) GetBalancesAt(ctx context.Context, userID uint64, t time.Time) ([]*domain.BalanceSnapshot, error) {
+	query := `
+		SELECT ` + rowmap.BalanceSnapshotColumns + `
+		FROM (
+			SELECT DISTINCT ON (asset) ` + rowmap.BalanceSnapshotColumns + `
+			FROM balance_snapshots
+			WHERE user_id = $1 AND recorded_at <= $2
+			ORDER BY asset, recorded_at DESC, id DESC
+		) latest
+		WHERE free <> 0 OR locked <> 0
+		ORDER BY asset`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balances at %s: %w", t.Format(time.RFC3339), postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	return scanSnapshots(rows)
+}
+
+// GetBalanceHistory возвращает снимки баланса актива за период [from, to].
+// Сортировка: recorded_at ASC, id ASC.
+func (s *BalanceStorage) GetBalanceHistory(ctx context.Context, userID uint64, asset string, from, to time.Time) ([]*domain.BalanceSnapshot, error) {
+	query := `
+		SELECT ` + rowmap.BalanceSnapshotColumns + `
+		FROM balance_snapshots
+		WHERE user_id = $1 AND asset = $2 AND recorded_at >= $3 AND recorded_at <= $4
+		ORDER BY recorded_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, asset, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance history: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	return scanSnapshots(rows)
+}
+
+func scanSnapshots(rows *sql.Rows) ([]*domain.BalanceSnapshot, error) {
+	var snapshots []*domain.BalanceSnapshot
+	for rows.Next() {
+		snapshot, err := rowmap.ScanBalanceSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan balance snapshot: %w", postgreserr.Classify(err))
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance snapshot rows: %w", postgreserr.Classify(err))
+	}
+
+	return snapshots, nil
+}
+
 // Ensure BalanceStorage implements BalanceStorage interface
 var _ storage.BalanceStorage = (*BalanceStorage)(nil)
@@ -284,3 +284,87 @@ func TestBalanceStorage_UpdateUserBalances(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestBalanceStorage_GetBalancesAt(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{"id", "user_id", "asset", "free", "locked", "recorded_at"}
+
+	t.Run("latest snapshot per asset", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewBalanceStorage(storage.NewDBAdapter(db))
+		at := time.Now().Add(-24 * time.Hour)
+
+		mock.ExpectQuery("SELECT DISTINCT ON \\(asset\\)").
+			WithArgs(uint64(1), at).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(3, 1, "BTC", "0.5", "0", at).
+				AddRow(5, 1, "USDT", "100", "10", at))
+
+		snapshots, err := s.GetBalancesAt(ctx, 1, at)
+		assert.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+		assert.Equal(t, "BTC", snapshots[0].Asset)
+		assert.True(t, decimal.RequireFromString("10").Equal(snapshots[1].Locked))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewBalanceStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectQuery("FROM balance_snapshots").WillReturnError(errors.New("query error"))
+
+		snapshots, err := s.GetBalancesAt(ctx, 1, time.Now())
+		assert.Error(t, err)
+		assert.Nil(t, snapshots)
+		assert.Contains(t, err.Error(), "failed to query balances at")
+	})
+}
+
+func TestBalanceStorage_GetBalanceHistory(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("range of snapshots", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewBalanceStorage(storage.NewDBAdapter(db))
+		from := time.Now().Add(-time.Hour)
+		to := time.Now()
+
+		mock.ExpectQuery("FROM balance_snapshots").
+			WithArgs(uint64(1), "BTC", from, to).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "asset", "free", "locked", "recorded_at"}).
+				AddRow(1, 1, "BTC", "1", "0", from).
+				AddRow(2, 1, "BTC", "0", "0", to))
+
+		history, err := s.GetBalanceHistory(ctx, 1, "BTC", from, to)
+		assert.NoError(t, err)
+		assert.Len(t, history, 2)
+		assert.True(t, history[1].Free.IsZero())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("scan error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewBalanceStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectQuery("FROM balance_snapshots").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "asset", "free", "locked", "recorded_at"}).
+				AddRow("bad", 1, "BTC", "1", "0", time.Now()))
+
+		_, err = s.GetBalanceHistory(ctx, 1, "BTC", time.Now(), time.Now())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to scan balance snapshot")
+	})
+}
@@ -0,0 +1,233 @@
+// Package changefeed раздает подписчикам уведомления LISTEN/NOTIFY,
+// которые публикуют триггеры миграции 0013_change_notify.
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/lib/pq"
+
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// Channel — канал NOTIFY, в который пишут триггеры.
+const Channel = "metacore_changes"
+
+// DefaultBuffer — размер буфера канала подписчика по умолчанию.
+const DefaultBuffer = 256
+
+// ErrClosed возвращается при подписке на закрытую ленту.
+var ErrClosed = errors.New("change feed is closed")
+
+// Listener — соединение LISTEN. Реализуется *pq.Listener, который сам переподключается
+// и после переподключения отправляет в канал nil.
+type Listener interface {
+	Listen(channel string) error
+	NotificationChannel() <-chan *pq.Notification
+	Close() error
+}
+
+// Feed держит одно соединение LISTEN на все подписки. Соединение открывается
+// при первой подписке и закрывается вместе с лентой.
+type Feed struct {
+	connect func() Listener
+	buffer  int
+
+	mu       sync.Mutex
+	listener Listener
+	subs     map[*subscriber]struct{}
+	closed   bool
+	done     chan struct{}
+}
+
+// subscriber — канал подписки и ее фильтр.
+type subscriber struct {
+	filter storage.EventFilter
+	ch     chan storage.Event
+	// lost — событие отброшено из-за переполнения, следующим отправляется маркер
+	lost bool
+}
+
+// New создает ленту; connect открывает соединение LISTEN, buffer — размер буфера подписчика.
+func New(connect func() Listener, buffer int) *Feed {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Feed{
+		connect: connect,
+		buffer:  buffer,
+		subs:    make(map[*subscriber]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Subscribe подписывается на события, проходящие filter. Первым в канал приходит
+// маркер storage.EventResync: после него подписчик читает текущее состояние,
+// а дальнейшие изменения получает событиями.
+func (f *Feed) Subscribe(ctx context.Context, filter storage.EventFilter) (<-chan storage.Event, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil, ErrClosed
+	}
+	if f.listener == nil {
+		if err := f.listen(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := &subscriber{filter: filter, ch: make(chan storage.Event, f.buffer)}
+	sub.ch <- storage.ResyncEvent()
+	f.subs[sub] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.unsubscribe(sub)
+		case <-f.done:
+		}
+	}()
+
+	return sub.ch, nil
+}
+
+// listen открывает соединение и подписывается на Channel. Вызывается под f.mu.
+func (f *Feed) listen(ctx context.Context) error {
+	l := f.connect()
+
+	// Listen ждет установки соединения, поэтому ожидание ограничено ctx
+	errc := make(chan error, 1)
+	go func() { errc <- l.Listen(Channel) }()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			l.Close()
+			return fmt.Errorf("failed to listen %s: %w", Channel, err)
+		}
+	case <-ctx.Done():
+		l.Close()
+		return fmt.Errorf("failed to listen %s: %w", Channel, ctx.Err())
+	}
+
+	f.listener = l
+	go f.run(l.NotificationChannel())
+	return nil
+}
+
+// run разбирает уведомления и раздает их подписчикам до закрытия ленты.
+func (f *Feed) run(notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// Соединение восстановлено, уведомления за время разрыва потеряны
+				f.broadcast(storage.ResyncEvent())
+				continue
+			}
+			if n.Channel != Channel {
+				continue
+			}
+
+			event, err := Decode(n.Extra)
+			if err != nil {
+				log.Printf("changefeed: %v", err)
+				continue
+			}
+			f.broadcast(event)
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// Decode разбирает payload уведомления.
+func Decode(payload string) (storage.Event, error) {
+	var event storage.Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return storage.Event{}, fmt.Errorf("failed to decode change payload %q: %w", payload, err)
+	}
+	return event, nil
+}
+
+// broadcast отправляет событие подписчикам, не блокируясь на медленных.
+func (f *Feed) broadcast(event storage.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subs {
+		if sub.filter.Match(event) {
+			sub.send(event)
+		}
+	}
+}
+
+// send кладет событие в буфер. При переполнении событие отбрасывается,
+// а когда место появится, подписчик получит маркер пересинхронизации.
+func (s *subscriber) send(event storage.Event) {
+	if s.lost {
+		select {
+		case s.ch <- storage.ResyncEvent():
+			s.lost = false
+		default:
+			return
+		}
+		if event.IsResync() {
+			return
+		}
+	}
+
+	select {
+	case s.ch <- event:
+	default:
+		s.lost = true
+	}
+}
+
+func (f *Feed) unsubscribe(sub *subscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subs[sub]; ok {
+		delete(f.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Close закрывает соединение LISTEN и каналы всех подписок.
+func (f *Feed) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	close(f.done)
+
+	for sub := range f.subs {
+		delete(f.subs, sub)
+		close(sub.ch)
+	}
+
+	if f.listener != nil {
+		return f.listener.Close()
+	}
+	return nil
+}
+
+// Ensure Feed implements ChangeFeed interface
+var _ storage.ChangeFeed = (*Feed)(nil)
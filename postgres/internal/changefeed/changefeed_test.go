@@ -0,0 +1,221 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// fakeListener подменяет *pq.Listener: уведомления отправляются в notify вручную.
+type fakeListener struct {
+	notify    chan *pq.Notification
+	listenErr error
+	channels  []string
+	closed    bool
+}
+
+func newFakeListener() *fakeListener {
+	return &fakeListener{notify: make(chan *pq.Notification)}
+}
+
+func (l *fakeListener) Listen(channel string) error {
+	l.channels = append(l.channels, channel)
+	return l.listenErr
+}
+
+func (l *fakeListener) NotificationChannel() <-chan *pq.Notification { return l.notify }
+
+func (l *fakeListener) Close() error {
+	if !l.closed {
+		l.closed = true
+		close(l.notify)
+	}
+	return nil
+}
+
+func newTestFeed(t *testing.T, l *fakeListener, buffer int) *Feed {
+	t.Helper()
+	f := New(func() Listener { return l }, buffer)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func notification(payload string) *pq.Notification {
+	return &pq.Notification{Channel: Channel, Extra: payload}
+}
+
+func receive(t *testing.T, ch <-chan storage.Event) storage.Event {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return storage.Event{}
+	}
+}
+
+func TestFeed_Subscribe(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("resync first, then filtered events", func(t *testing.T) {
+		l := newFakeListener()
+		f := newTestFeed(t, l, 0)
+
+		ch, err := f.Subscribe(ctx, storage.EventFilter{Tables: []storage.EventTable{storage.EventOrders}, UserID: 7})
+		require.NoError(t, err)
+		assert.Equal(t, []string{Channel}, l.channels)
+		assert.True(t, receive(t, ch).IsResync())
+
+		l.notify <- notification(`{"table":"trades","op":"INSERT","id":1,"user_id":7,"key":"t1"}`)
+		l.notify <- notification(`{"table":"orders","op":"UPDATE","id":2,"user_id":8,"key":"o2","status":"FILLED"}`)
+		l.notify <- notification(`{"table":"orders","op":"UPDATE","id":3,"user_id":7,"key":"o3","symbol":"BTCUSDT","status":"FILLED"}`)
+
+		assert.Equal(t, storage.Event{
+			Table: storage.EventOrders, Op: storage.EventUpdate, ID: 3, UserID: 7,
+			Key: "o3", Symbol: "BTCUSDT", Status: "FILLED",
+		}, receive(t, ch))
+	})
+
+	t.Run("one listener for all subscribers", func(t *testing.T) {
+		connects := 0
+		l := newFakeListener()
+		f := New(func() Listener { connects++; return l }, 0)
+		defer f.Close()
+
+		a, err := f.Subscribe(ctx, storage.EventFilter{})
+		require.NoError(t, err)
+		b, err := f.Subscribe(ctx, storage.EventFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, connects)
+
+		receive(t, a)
+		receive(t, b)
+		l.notify <- notification(`{"table":"user_balances","op":"INSERT","id":1,"user_id":1,"key":"USDT"}`)
+		assert.Equal(t, "USDT", receive(t, a).Key)
+		assert.Equal(t, "USDT", receive(t, b).Key)
+	})
+
+	t.Run("reconnect sends resync", func(t *testing.T) {
+		l := newFakeListener()
+		f := newTestFeed(t, l, 0)
+
+		ch, err := f.Subscribe(ctx, storage.EventFilter{UserID: 1})
+		require.NoError(t, err)
+		receive(t, ch)
+
+		l.notify <- nil
+		assert.True(t, receive(t, ch).IsResync())
+	})
+
+	t.Run("invalid payload is skipped", func(t *testing.T) {
+		l := newFakeListener()
+		f := newTestFeed(t, l, 0)
+
+		ch, err := f.Subscribe(ctx, storage.EventFilter{})
+		require.NoError(t, err)
+		receive(t, ch)
+
+		l.notify <- notification(`not json`)
+		l.notify <- &pq.Notification{Channel: "other", Extra: `{"table":"orders","id":9}`}
+		l.notify <- notification(`{"table":"orders","op":"DELETE","id":5,"user_id":1}`)
+		assert.Equal(t, uint64(5), receive(t, ch).ID)
+	})
+
+	t.Run("overflow drops events and marks resync", func(t *testing.T) {
+		l := newFakeListener()
+		f := newTestFeed(t, l, 2)
+
+		ch, err := f.Subscribe(ctx, storage.EventFilter{})
+		require.NoError(t, err)
+
+		// Буфер: маркер подписки и событие 1; события 2 и 3 отбрасываются
+		for _, payload := range []string{
+			`{"table":"orders","op":"INSERT","id":1,"user_id":1}`,
+			`{"table":"orders","op":"INSERT","id":2,"user_id":1}`,
+			`{"table":"orders","op":"INSERT","id":3,"user_id":1}`,
+		} {
+			l.notify <- notification(payload)
+		}
+		// Следующее уведомление принимается только после рассылки предыдущих
+		l.notify <- &pq.Notification{Channel: "other"}
+		assert.Len(t, ch, 2)
+
+		assert.True(t, receive(t, ch).IsResync())
+		assert.Equal(t, uint64(1), receive(t, ch).ID)
+
+		l.notify <- notification(`{"table":"orders","op":"INSERT","id":4,"user_id":1}`)
+		assert.True(t, receive(t, ch).IsResync())
+		assert.Equal(t, uint64(4), receive(t, ch).ID)
+	})
+
+	t.Run("cancel closes channel", func(t *testing.T) {
+		l := newFakeListener()
+		f := newTestFeed(t, l, 0)
+
+		subCtx, cancel := context.WithCancel(ctx)
+		ch, err := f.Subscribe(subCtx, storage.EventFilter{})
+		require.NoError(t, err)
+		receive(t, ch)
+
+		cancel()
+		require.Eventually(t, func() bool {
+			_, ok := <-ch
+			return !ok
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("close closes channels and listener", func(t *testing.T) {
+		l := newFakeListener()
+		f := New(func() Listener { return l }, 0)
+
+		ch, err := f.Subscribe(ctx, storage.EventFilter{})
+		require.NoError(t, err)
+		receive(t, ch)
+
+		require.NoError(t, f.Close())
+		_, ok := <-ch
+		assert.False(t, ok)
+		assert.True(t, l.closed)
+
+		_, err = f.Subscribe(ctx, storage.EventFilter{})
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		f := newTestFeed(t, newFakeListener(), 0)
+
+		_, err := f.Subscribe(ctx, storage.EventFilter{Tables: []storage.EventTable{"prices"}})
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+	})
+
+	t.Run("listen error", func(t *testing.T) {
+		l := newFakeListener()
+		l.listenErr = errors.New("permission denied")
+		f := newTestFeed(t, l, 0)
+
+		_, err := f.Subscribe(ctx, storage.EventFilter{})
+		assert.ErrorContains(t, err, "failed to listen metacore_changes: permission denied")
+		assert.True(t, l.closed)
+	})
+}
+
+func TestDecode(t *testing.T) {
+	event, err := Decode(`{"table":"order_updates","op":"INSERT","id":10,"user_id":3,"key":"o1","status":"PARTIALLY_FILLED"}`)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Event{
+		Table: storage.EventOrderUpdates, Op: storage.EventInsert, ID: 10, UserID: 3,
+		Key: "o1", Status: "PARTIALLY_FILLED",
+	}, event)
+
+	_, err = Decode(`{"id":"x"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,24 @@
+// Package keyset строит условия keyset-пагинации по паре (время, id).
+package keyset
+
+import (
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/postgres/internal/query"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// Apply добавляет к запросу условие курсора, сортировку в порядке обхода
+// и LIMIT limit+1 для определения следующей страницы.
+// Без курсора и для обычного курсора записи идут от новых к старым, для Newer — наоборот.
+func Apply(q *query.Select, timeColumn string, cursor *storage.Cursor, limit int) *query.Select {
+	desc := true
+	if cursor != nil {
+		op := "<"
+		if cursor.Newer {
+			op, desc = ">", false
+		}
+		q.Where(fmt.Sprintf("(%s, id) %s (?, ?)", timeColumn, op), cursor.Time, cursor.ID)
+	}
+	return q.OrderBy(timeColumn, desc).OrderBy("id", desc).Limit(limit + 1)
+}
@@ -0,0 +1,48 @@
+package keyset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/samar/sup_bot/metacore/postgres/internal/query"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func TestApply(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor *storage.Cursor
+		query  string
+		args   []interface{}
+	}{
+		{
+			name:  "first page",
+			query: "SELECT id FROM trades WHERE user_id = $1 ORDER BY trade_time DESC, id DESC LIMIT $2",
+			args:  []interface{}{uint64(1), 11},
+		},
+		{
+			name:   "older",
+			cursor: &storage.Cursor{Time: ts, ID: 5},
+			query:  "SELECT id FROM trades WHERE user_id = $1 AND (trade_time, id) < ($2, $3) ORDER BY trade_time DESC, id DESC LIMIT $4",
+			args:   []interface{}{uint64(1), ts, uint64(5), 11},
+		},
+		{
+			name:   "newer",
+			cursor: &storage.Cursor{Time: ts, ID: 5, Newer: true},
+			query:  "SELECT id FROM trades WHERE user_id = $1 AND (trade_time, id) > ($2, $3) ORDER BY trade_time ASC, id ASC LIMIT $4",
+			args:   []interface{}{uint64(1), ts, uint64(5), 11},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := query.From("trades", "id").Where("user_id = ?", uint64(1))
+			sql, args := Apply(q, "trade_time", tc.cursor, 10).Build()
+			assert.Equal(t, tc.query, sql)
+			assert.Equal(t, tc.args, args)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// LedgerStorage реализует интерфейс LedgerStorage.
+type LedgerStorage struct {
+	db storage.DBInterface
+}
+
+// NewLedgerStorage создает новый экземпляр LedgerStorage.
+func NewLedgerStorage(db storage.DBInterface) *LedgerStorage {
+	return &LedgerStorage{db: db}
+}
+
+const insertEntryQuery = `
+	INSERT INTO ledger_entries (
+		user_id, transaction_id, account, asset, amount, kind, ref_id, entry_time
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at`
+
+// InsertEntries проверяет баланс проводок и записывает их в транзакции tx.
+// Используется хранилищами, которые проводят операции вместе со своими записями.
+func InsertEntries(ctx context.Context, tx storage.Tx, entries []*domain.LedgerEntry) error {
+	if err := domain.ValidateLedgerEntries(entries); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err := tx.QueryRowContext(ctx, insertEntryQuery,
+			e.UserID,
+			e.TransactionID,
+			e.Account,
+			e.Asset,
+			e.Amount,
+			e.Kind,
+			e.RefID,
+			e.EntryTime,
+		).Scan(&e.ID, &e.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create ledger entry %s: %w", e.TransactionID, postgreserr.Classify(err))
+		}
+	}
+
+	return nil
+}
+
+// CopyEntries проверяет баланс проводок и загружает их через COPY в транзакции tx.
+// В отличие от InsertEntries не заполняет ID и CreatedAt; используется при пакетной записи.
+func CopyEntries(ctx context.Context, tx storage.Tx, entries []*domain.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := domain.ValidateLedgerEntries(entries); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("ledger_entries",
+		"user_id", "transaction_id", "account", "asset", "amount", "kind", "ref_id", "entry_time"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare ledger copy: %w", postgreserr.Classify(err))
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		_, err = stmt.ExecContext(ctx, e.UserID, e.TransactionID, string(e.Account), e.Asset, e.Amount, string(e.Kind), e.RefID, e.EntryTime)
+		if err != nil {
+			return fmt.Errorf("failed to copy ledger entry %s: %w", e.TransactionID, postgreserr.Classify(err))
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to copy ledger entries: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+// PostEntries записывает проводки в одной транзакции.
+func (s *LedgerStorage) PostEntries(ctx context.Context, entries []*domain.LedgerEntry) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = InsertEntries(ctx, tx, entries); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+// GetLedgerEntries получает проводки счета USER по активу с нарастающим балансом.
+func (s *LedgerStorage) GetLedgerEntries(ctx context.Context, userID uint64, asset string) ([]*domain.LedgerEntry, error) {
+	query := `
+		SELECT id, user_id, transaction_id, account, asset, amount, kind, ref_id, entry_time, created_at,
+		       SUM(amount) OVER (ORDER BY entry_time, id) AS balance
+		FROM ledger_entries
+		WHERE user_id = $1 AND asset = $2 AND account = $3
+		ORDER BY entry_time, id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, asset, domain.LedgerAccountUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger entries: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var entries []*domain.LedgerEntry
+	for rows.Next() {
+		e := &domain.LedgerEntry{}
+		err := rows.Scan(
+			&e.ID,
+			&e.UserID,
+			&e.TransactionID,
+			&e.Account,
+			&e.Asset,
+			&e.Amount,
+			&e.Kind,
+			&e.RefID,
+			&e.EntryTime,
+			&e.CreatedAt,
+			&e.Balance,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", postgreserr.Classify(err))
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger entry rows: %w", postgreserr.Classify(err))
+	}
+
+	return entries, nil
+}
+
+// GetLedgerBalances получает баланс счета USER по каждому активу.
+func (s *LedgerStorage) GetLedgerBalances(ctx context.Context, userID uint64) ([]*domain.LedgerBalance, error) {
+	query := `
+		SELECT asset, SUM(amount)
+		FROM ledger_entries
+		WHERE user_id = $1 AND account = $2
+		GROUP BY asset
+		ORDER BY asset`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, domain.LedgerAccountUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger balances: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var balances []*domain.LedgerBalance
+	for rows.Next() {
+		b := &domain.LedgerBalance{}
+		if err := rows.Scan(&b.Asset, &b.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger balance: %w", postgreserr.Classify(err))
+		}
+		balances = append(balances, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger balance rows: %w", postgreserr.Classify(err))
+	}
+
+	return balances, nil
+}
+
+// Reconcile сравнивает балансы ledger с последними балансами user_balances.
+func (s *LedgerStorage) Reconcile(ctx context.Context, userID uint64) (*domain.ReconciliationReport, error) {
+	ledgerBalances, err := s.GetLedgerBalances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT asset, free, locked FROM user_balances WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balances: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	balances, err := scanBalances(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.Reconcile(userID, ledgerBalances, balances), nil
+}
+
+func scanBalances(rows *sql.Rows) ([]*domain.UserBalance, error) {
+	var balances []*domain.UserBalance
+	for rows.Next() {
+		b := &domain.UserBalance{}
+		if err := rows.Scan(&b.Asset, &b.Free, &b.Locked); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", postgreserr.Classify(err))
+		}
+		balances = append(balances, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance rows: %w", postgreserr.Classify(err))
+	}
+
+	return balances, nil
+}
+
+// Ensure LedgerStorage implements LedgerStorage interface
+var _ storage.LedgerStorage = (*LedgerStorage)(nil)
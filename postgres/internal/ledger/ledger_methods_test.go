@@ -0,0 +1,154 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func TestLedgerStorage_PostEntries(t *testing.T) {
+	ctx := context.Background()
+	entryTime := time.Now()
+
+	t.Run("entries are inserted in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewLedgerStorage(storage.NewDBAdapter(db))
+		entries := domain.DepositLedgerEntries(1, "USDT", decimal.NewFromInt(100), "d1", entryTime)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO ledger_entries").
+			WithArgs(uint64(1), "deposit:d1", domain.LedgerAccountUser, "USDT", decimal.NewFromInt(100), domain.LedgerEntryDeposit, "d1", entryTime).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, entryTime))
+		mock.ExpectQuery("INSERT INTO ledger_entries").
+			WithArgs(uint64(1), "deposit:d1", domain.LedgerAccountExternal, "USDT", decimal.NewFromInt(-100), domain.LedgerEntryDeposit, "d1", entryTime).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, entryTime))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.PostEntries(ctx, entries))
+		assert.Equal(t, uint64(1), entries[0].ID)
+		assert.Equal(t, uint64(2), entries[1].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insert error rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewLedgerStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO ledger_entries").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, entryTime))
+		mock.ExpectQuery("INSERT INTO ledger_entries").
+			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err = s.PostEntries(ctx, domain.DepositLedgerEntries(1, "USDT", decimal.NewFromInt(100), "d1", entryTime))
+		assert.ErrorContains(t, err, "failed to create ledger entry deposit:d1")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unbalanced entries are rejected", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewLedgerStorage(storage.NewDBAdapter(db))
+		entries := domain.DepositLedgerEntries(1, "USDT", decimal.NewFromInt(100), "d1", entryTime)[:1]
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.PostEntries(ctx, entries), domain.ErrUnbalancedEntries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCopyEntries(t *testing.T) {
+	ctx := context.Background()
+	entryTime := time.Now()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := storage.NewDBAdapter(db).BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	entries := domain.DepositLedgerEntries(1, "USDT", decimal.NewFromInt(100), "d1", entryTime)
+	copyStmt := mock.ExpectPrepare(`COPY "ledger_entries"`)
+	for _, e := range entries {
+		copyStmt.ExpectExec().
+			WithArgs(e.UserID, "deposit:d1", string(e.Account), "USDT", e.Amount, string(domain.LedgerEntryDeposit), "d1", entryTime).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	copyStmt.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, CopyEntries(ctx, tx, entries))
+	assert.ErrorIs(t, CopyEntries(ctx, tx, entries[:1]), domain.ErrUnbalancedEntries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerStorage_GetLedgerEntries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewLedgerStorage(storage.NewDBAdapter(db))
+	now := time.Now()
+
+	mock.ExpectQuery(`SUM\(amount\) OVER \(ORDER BY entry_time, id\)`).
+		WithArgs(uint64(1), "USDT", domain.LedgerAccountUser).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "user_id", "transaction_id", "account", "asset", "amount", "kind", "ref_id", "entry_time", "created_at", "balance",
+		}).
+			AddRow(1, 1, "deposit:d1", "USER", "USDT", "100", "DEPOSIT", "d1", now, now, "100").
+			AddRow(3, 1, "trade:t1", "USER", "USDT", "-40", "TRADE", "t1", now, now, "60"))
+
+	entries, err := s.GetLedgerEntries(context.Background(), 1, "USDT")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, domain.LedgerEntryTrade, entries[1].Kind)
+	assert.True(t, decimal.NewFromInt(60).Equal(entries[1].Balance))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerStorage_Reconcile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewLedgerStorage(storage.NewDBAdapter(db))
+
+	mock.ExpectQuery("FROM ledger_entries").
+		WithArgs(uint64(1), domain.LedgerAccountUser).
+		WillReturnRows(sqlmock.NewRows([]string{"asset", "sum"}).
+			AddRow("BTC", "0.01").
+			AddRow("USDT", "499.5"))
+	mock.ExpectQuery("FROM user_balances").
+		WithArgs(uint64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"asset", "free", "locked"}).
+			AddRow("USDT", "400", "99.5").
+			AddRow("ETH", "2", "0"))
+
+	report, err := s.Reconcile(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, report.Items, 3)
+	assert.Equal(t, []string{"BTC", "ETH"}, []string{report.Mismatches()[0].Asset, report.Mismatches()[1].Asset})
+	assert.True(t, report.Items[2].Difference.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,125 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowmap"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// OrderListStorage реализует интерфейс OrderListStorage.
+type OrderListStorage struct {
+	db storage.DBInterface
+}
+
+// NewOrderListStorage создает новый экземпляр OrderListStorage.
+func NewOrderListStorage(db storage.DBInterface) *OrderListStorage {
+	return &OrderListStorage{db: db}
+}
+
+// CreateOrderList сохраняет список ордеров и его ордера в одной транзакции.
+func (s *OrderListStorage) CreateOrderList(ctx context.Context, list *domain.OrderList, orders []*domain.Order) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := rowmap.InsertOrderList + ` RETURNING ` + rowmap.OrderListReturning
+
+	err = tx.QueryRowContext(ctx, query, rowmap.OrderListArgs(list)...).
+		Scan(rowmap.OrderListReturningDest(list)...)
+	if err != nil {
+		return fmt.Errorf("failed to create order list: %w", postgreserr.Classify(err))
+	}
+
+	for _, order := range orders {
+		order.UserID = list.UserID
+		order.OrderListID = list.MexcOrderListID
+
+		if _, err = tx.ExecContext(ctx, rowmap.InsertOrder, rowmap.OrderArgs(order)...); err != nil {
+			return fmt.Errorf("failed to create order %s: %w", order.MexcOrderID, postgreserr.Classify(err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+// GetOrderList получает список ордеров по mexc_order_list_id.
+func (s *OrderListStorage) GetOrderList(ctx context.Context, mexcOrderListID string) (*domain.OrderList, error) {
+	query := `SELECT ` + rowmap.OrderListColumns + ` FROM order_lists WHERE mexc_order_list_id = $1`
+
+	list, err := rowmap.ScanOrderList(s.db.QueryRowContext(ctx, query, mexcOrderListID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("order list with id %s not found: %w", mexcOrderListID, postgreserr.ErrOrderListNotFound)
+		}
+		return nil, fmt.Errorf("failed to get order list: %w", postgreserr.Classify(err))
+	}
+
+	return list, nil
+}
+
+// GetOrderListOrders получает ордера списка, отсортированные по id.
+func (s *OrderListStorage) GetOrderListOrders(ctx context.Context, mexcOrderListID string) ([]*domain.Order, error) {
+	query := `SELECT ` + rowmap.OrderColumns + ` FROM orders WHERE order_list_id = $1 ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, mexcOrderListID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order list orders: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var orders []*domain.Order
+	for rows.Next() {
+		o, err := rowmap.ScanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", postgreserr.Classify(err))
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("order list orders rows error: %w", postgreserr.Classify(err))
+	}
+	return orders, nil
+}
+
+// UpdateOrderListStatus обновляет статусы списка ордеров.
+func (s *OrderListStorage) UpdateOrderListStatus(ctx context.Context, mexcOrderListID string, statusType domain.ListStatusType, orderStatus domain.ListOrderStatus) error {
+	query := `
+		UPDATE order_lists
+		SET list_status_type = $1, list_order_status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE mexc_order_list_id = $3`
+
+	result, err := s.db.ExecContext(ctx, query, statusType, orderStatus, mexcOrderListID)
+	if err != nil {
+		return fmt.Errorf("failed to update order list status: %w", postgreserr.Classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", postgreserr.Classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("order list with id %s not found: %w", mexcOrderListID, postgreserr.ErrOrderListNotFound)
+	}
+
+	return nil
+}
+
+// Ensure OrderListStorage implements OrderListStorage interface
+var _ storage.OrderListStorage = (*OrderListStorage)(nil)
@@ -0,0 +1,117 @@
+package orders
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func newOrderListMock(t *testing.T) (*OrderListStorage, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewOrderListStorage(storage.NewDBAdapter(db)), mock
+}
+
+func TestOrderListStorage_CreateOrderList(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("list and orders in one transaction", func(t *testing.T) {
+		s, mock := newOrderListMock(t)
+		list := &domain.OrderList{UserID: 7, MexcOrderListID: "l1", Symbol: "BTCUSDT", ContingencyType: domain.ContingencyTypeOCO}
+		orders := []*domain.Order{{MexcOrderID: "o1"}, {MexcOrderID: "o2"}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO order_lists").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(3, now, now))
+		mock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO orders").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.CreateOrderList(ctx, list, orders))
+		assert.Equal(t, uint64(3), list.ID)
+		for _, o := range orders {
+			assert.Equal(t, uint64(7), o.UserID)
+			assert.Equal(t, "l1", o.OrderListID)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("order error rolls back", func(t *testing.T) {
+		s, mock := newOrderListMock(t)
+		list := &domain.OrderList{UserID: 7, MexcOrderListID: "l1"}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO order_lists").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(3, now, now))
+		mock.ExpectExec("INSERT INTO orders").
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "orders_mexc_order_id_key"})
+		mock.ExpectRollback()
+
+		err := s.CreateOrderList(ctx, list, []*domain.Order{{MexcOrderID: "o1"}})
+		assert.ErrorIs(t, err, postgreserr.ErrDuplicate)
+		assert.Contains(t, err.Error(), "failed to create order o1")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOrderListStorage_GetOrderList(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		s, mock := newOrderListMock(t)
+		now := time.Now()
+		mock.ExpectQuery("FROM order_lists WHERE mexc_order_list_id").WithArgs("l1").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "mexc_order_list_id", "list_client_order_id", "symbol",
+				"contingency_type", "list_status_type", "list_order_status", "transaction_time",
+				"created_at", "updated_at",
+			}).AddRow(3, 7, "l1", "", "BTCUSDT", "OCO", "EXEC_STARTED", "EXECUTING", now, now, now))
+
+		list, err := s.GetOrderList(ctx, "l1")
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), list.ID)
+		assert.Equal(t, domain.ContingencyTypeOCO, list.ContingencyType)
+		assert.Equal(t, domain.ListOrderStatusExecuting, list.ListOrderStatus)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, mock := newOrderListMock(t)
+		mock.ExpectQuery("FROM order_lists").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := s.GetOrderList(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrOrderListNotFound)
+	})
+}
+
+func TestOrderListStorage_UpdateOrderListStatus(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("updated", func(t *testing.T) {
+		s, mock := newOrderListMock(t)
+		mock.ExpectExec("UPDATE order_lists").WithArgs("ALL_DONE", "ALL_DONE", "l1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := s.UpdateOrderListStatus(ctx, "l1", domain.ListStatusTypeAllDone, domain.ListOrderStatusAllDone)
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, mock := newOrderListMock(t)
+		mock.ExpectExec("UPDATE order_lists").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := s.UpdateOrderListStatus(ctx, "missing", domain.ListStatusTypeAllDone, domain.ListOrderStatusAllDone)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderListNotFound)
+	})
+}
@@ -5,9 +5,17 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"iter"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 
 	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/keyset"
+	"github.com/samar/sup_bot/metacore/postgres/internal/query"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowiter"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowmap"
 	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
 	"github.com/samar/sup_bot/metacore/storage"
 )
@@ -24,52 +32,133 @@ func NewOrderStorage(db storage.DBInterface) *OrderStorage {
 
 // CreateOrder сохраняет новый ордер в хранилище.
 func (s *OrderStorage) CreateOrder(ctx context.Context, order *domain.Order) error {
-	query := `
-        INSERT INTO orders (
-            internal_id, user_id, mexc_order_id, symbol, side, type, status,
-            price, quantity, quote_order_qty, executed_quantity,
-            cummulative_quote_qty, client_order_id, transact_time
-        ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
-        )`
-
-	_, err := s.db.ExecContext(ctx, query,
-		order.InternalID,
-		order.UserID,
-		order.MexcOrderID,
-		order.Symbol,
-		order.Side,
-		order.Type,
-		order.Status,
-		order.Price,
-		order.Quantity,
-		order.QuoteOrderQty,
-		order.ExecutedQuantity,
-		order.CummulativeQuoteQty,
-		order.ClientOrderID,
-		order.TransactTime,
-	)
-
+	_, err := s.db.ExecContext(ctx, rowmap.InsertOrder, rowmap.OrderArgs(order)...)
 	if err != nil {
-		return fmt.Errorf("failed to create order: %w", err)
+		return fmt.Errorf("failed to create order: %w", postgreserr.Classify(err))
 	}
 
 	return nil
 }
 
+// upsertOrderQuery вставляет ордер или продвигает существующий вперед.
+// Условие WHERE повторяет domain.IsOrderAdvance: смена статуса разрешена таблицей переходов,
+// исполненные объемы не уменьшаются, и хотя бы одно поле меняется.
+var upsertOrderQuery = rowmap.InsertOrder + fmt.Sprintf(`
+        ON CONFLICT (mexc_order_id) DO UPDATE SET
+            status = EXCLUDED.status,
+            executed_quantity = EXCLUDED.executed_quantity,
+            cummulative_quote_qty = EXCLUDED.cummulative_quote_qty,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE EXCLUDED.executed_quantity >= orders.executed_quantity
+          AND EXCLUDED.cummulative_quote_qty >= orders.cummulative_quote_qty
+          AND (
+              (orders.status != EXCLUDED.status AND %s)
+              OR (orders.status = EXCLUDED.status AND (
+                  orders.executed_quantity != EXCLUDED.executed_quantity
+                  OR orders.cummulative_quote_qty != EXCLUDED.cummulative_quote_qty))
+          )
+        RETURNING %s, (xmax = 0) AS inserted`,
+	transitionSQL("orders.status", "EXCLUDED.status"), rowmap.OrderReturning)
+
+// transitionSQL повторяет domain.OrderStatus.CanTransitionTo для пары колонок
+func transitionSQL(from, to string) string {
+	transitions := domain.OrderTransitions()
+	conditions := make([]string, 0, len(transitions))
+	for _, status := range domain.OrderStatuses {
+		next := transitions[status]
+		if len(next) == 0 {
+			continue
+		}
+		quoted := make([]string, 0, len(next))
+		for _, n := range next {
+			quoted = append(quoted, "'"+string(n)+"'")
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s = '%s' AND %s IN (%s))", from, status, to, strings.Join(quoted, ", ")))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// scanUpsertResult читает RETURNING upsert-запроса и записывает ID и время в order.
+func scanUpsertResult(row storage.RowInterface, order *domain.Order) (storage.UpsertResult, error) {
+	var inserted bool
+	err := row.Scan(append(rowmap.OrderReturningDest(order), &inserted)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Ордер не изменился или снимок устарел
+			return storage.UpsertIgnored, nil
+		}
+		return storage.UpsertIgnored, err
+	}
+	if inserted {
+		return storage.UpsertCreated, nil
+	}
+	return storage.UpsertUpdated, nil
+}
+
+// UpsertOrder создает ордер или обновляет изменяемые поля существующего.
+// Снимок, который откатывает ордер назад, игнорируется.
+func (s *OrderStorage) UpsertOrder(ctx context.Context, order *domain.Order) (storage.UpsertResult, error) {
+	row := s.db.QueryRowContext(ctx, upsertOrderQuery, rowmap.OrderArgs(order)...)
+
+	result, err := scanUpsertResult(row, order)
+	if err != nil {
+		return storage.UpsertIgnored, fmt.Errorf("failed to upsert order: %w", postgreserr.Classify(err))
+	}
+
+	return result, nil
+}
+
+// UpsertOrders выполняет UpsertOrder для каждого ордера в одной транзакции.
+func (s *OrderStorage) UpsertOrders(ctx context.Context, orders []*domain.Order) (results []storage.UpsertResult, err error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, upsertOrderQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", postgreserr.Classify(err))
+	}
+	defer stmt.Close()
+
+	results = make([]storage.UpsertResult, 0, len(orders))
+	for _, order := range orders {
+		result, err := scanUpsertResult(stmt.QueryRowContext(ctx, rowmap.OrderArgs(order)...), order)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert order %s: %w", order.MexcOrderID, postgreserr.Classify(err))
+		}
+		results = append(results, result)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return results, nil
+}
+
 // DeleteOrderByID удаляет ордер из хранилища по его mexc_order_id.
 func (s *OrderStorage) DeleteOrderByID(ctx context.Context, mexcOrderID string) error {
 	query := `DELETE FROM orders WHERE mexc_order_id = $1`
 
 	result, err := s.db.ExecContext(ctx, query, mexcOrderID)
 	if err != nil {
-		return fmt.Errorf("failed to delete order: %w", err)
+		return fmt.Errorf("failed to delete order: %w", postgreserr.Classify(err))
 	}
 
 	// Проверка, была ли удалена хотя бы одна строка
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get rows affected: %w", postgreserr.Classify(err))
 	}
 
 	if rowsAffected == 0 {
@@ -81,66 +170,64 @@ func (s *OrderStorage) DeleteOrderByID(ctx context.Context, mexcOrderID string)
 }
 
 // UpdateOrderStatus обновляет статус ордера.
-func (s *OrderStorage) UpdateOrderStatus(ctx context.Context, mexcOrderID, status string) error {
-	query := `UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE mexc_order_id = $2`
+// Переход проверяется атомарно в UPDATE: текущий статус должен входить в список разрешенных.
+func (s *OrderStorage) UpdateOrderStatus(ctx context.Context, mexcOrderID string, status domain.OrderStatus) error {
+	if !status.Valid() {
+		return fmt.Errorf("unknown order status %q: %w", status, domain.ErrInvalidTransition)
+	}
+
+	allowedFrom := domain.OrderStatusesBefore(status)
+	from := make([]string, 0, len(allowedFrom))
+	for _, st := range allowedFrom {
+		from = append(from, string(st))
+	}
 
-	result, err := s.db.ExecContext(ctx, query, status, mexcOrderID)
+	query := `
+        UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP
+        WHERE mexc_order_id = $2 AND status = ANY($3)`
+
+	result, err := s.db.ExecContext(ctx, query, status, mexcOrderID, pq.Array(from))
 	if err != nil {
-		return fmt.Errorf("failed to update order status: %w", err)
+		return fmt.Errorf("failed to update order status: %w", postgreserr.Classify(err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get rows affected: %w", postgreserr.Classify(err))
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("order with id %s not found: %w", mexcOrderID, postgreserr.ErrOrderNotFound)
+	if rowsAffected > 0 {
+		return nil
 	}
 
-	return nil
+	// Ордер не обновлен: либо его нет, либо переход запрещен
+	var current domain.OrderStatus
+	err = s.db.QueryRowContext(ctx, `SELECT status FROM orders WHERE mexc_order_id = $1`, mexcOrderID).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("order with id %s not found: %w", mexcOrderID, postgreserr.ErrOrderNotFound)
+		}
+		return fmt.Errorf("failed to get order status: %w", postgreserr.Classify(err))
+	}
+
+	return &domain.TransitionError{OrderID: mexcOrderID, From: current, To: status}
 }
 
 // GetOrderByID получает ордер по его mexc_order_id.
 func (s *OrderStorage) GetOrderByID(ctx context.Context, mexcOrderID string) (*domain.Order, error) {
-	query := `
-        SELECT id, internal_id, user_id, mexc_order_id, symbol, side, type, status,
-               price, quantity, quote_order_qty, executed_quantity,
-               cummulative_quote_qty, client_order_id, transact_time, -- Возвращаем в миллисекундах
-               created_at,updated_at
-        FROM orders WHERE mexc_order_id = $1`
-
-	var order domain.Order
-
-	err := s.db.QueryRowContext(ctx, query, mexcOrderID).Scan(
-		&order.ID,
-		&order.InternalID,
-		&order.UserID,
-		&order.MexcOrderID,
-		&order.Symbol,
-		&order.Side,
-		&order.Type,
-		&order.Status,
-		&order.Price,
-		&order.Quantity,
-		&order.QuoteOrderQty,
-		&order.ExecutedQuantity,
-		&order.CummulativeQuoteQty,
-		&order.ClientOrderID,
-		&order.TransactTime,
-		&order.CreatedAt,
-		&order.UpdatedAt,
-	)
+	query := `SELECT ` + rowmap.OrderColumns + ` FROM orders WHERE mexc_order_id = $1`
+
+	order, err := rowmap.ScanOrder(s.db.QueryRowContext(ctx, query, mexcOrderID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// Можно вернуть кастомную ошибку
 			return nil, fmt.Errorf("order with id %s not found: %w", mexcOrderID, postgreserr.ErrOrderNotFound)
 		}
 
-		return nil, fmt.Errorf("failed to get order: %w", err)
+		return nil, fmt.Errorf("failed to get order: %w", postgreserr.Classify(err))
 	}
 
-	return &order, nil
+	return order, nil
 }
 
 // GetUserOrders получает ордера пользователя с фильтрами
@@ -150,102 +237,162 @@ func (s *OrderStorage) GetUserOrders(ctx context.Context, userID uint64, filters
 		filter = filters[0]
 	}
 
-	b := &strings.Builder{}
-	b.WriteString(`SELECT id, internal_id, user_id, mexc_order_id, symbol, side, type, status,
-price, quantity, quote_order_qty, executed_quantity, cummulative_quote_qty, client_order_id, transact_time, created_at, updated_at
-FROM orders WHERE user_id = $1`)
-	args := []interface{}{userID}
-	idx := 1
-
-	if filter.Symbol != "" {
-		idx++
-		b.WriteString(fmt.Sprintf(" AND symbol = $%d", idx))
-		args = append(args, filter.Symbol)
+	text, args, err := userOrdersListQuery(userID, filter)
+	if err != nil {
+		return nil, err
 	}
-	if filter.Status != "" {
-		idx++
-		b.WriteString(fmt.Sprintf(" AND status = $%d", idx))
-		args = append(args, filter.Status)
+
+	rows, err := s.db.QueryContext(ctx, text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", postgreserr.Classify(err))
 	}
-	if filter.StartTime != nil {
-		idx++
-		b.WriteString(fmt.Sprintf(" AND transact_time >= $%d", idx))
-		args = append(args, *filter.StartTime)
+	defer rows.Close()
+
+	return scanOrders(rows)
+}
+
+// IterOrders обходит ордера пользователя с фильтрами, не загружая их в память.
+// Порядок и фильтры те же, что у GetUserOrders.
+func (s *OrderStorage) IterOrders(ctx context.Context, userID uint64, filters ...storage.OrderFilter) iter.Seq2[*domain.Order, error] {
+	var filter storage.OrderFilter
+	if len(filters) > 0 {
+		filter = filters[0]
 	}
-	if filter.EndTime != nil {
-		idx++
-		b.WriteString(fmt.Sprintf(" AND transact_time <= $%d", idx))
-		args = append(args, *filter.EndTime)
+
+	text, args, err := userOrdersListQuery(userID, filter)
+	if err != nil {
+		return rowiter.Err[*domain.Order](err)
 	}
-	b.WriteString(" ORDER BY created_at DESC, id DESC")
-	if filter.Limit > 0 {
-		idx++
-		b.WriteString(fmt.Sprintf(" LIMIT $%d", idx))
-		args = append(args, filter.Limit)
+	return rowiter.Query(ctx, s.db, "orders", text, args, rowmap.ScanOrder)
+}
+
+// GetUserOrdersPage получает страницу ордеров пользователя по курсору (created_at, id).
+// Сортировка, Limit и Offset фильтра не используются.
+func (s *OrderStorage) GetUserOrdersPage(ctx context.Context, userID uint64, filter storage.OrderFilter, page storage.PageRequest) (*storage.Page[*domain.Order], error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
 	}
-	if filter.Offset > 0 {
-		idx++
-		b.WriteString(fmt.Sprintf(" OFFSET $%d", idx))
-		args = append(args, filter.Offset)
+	cursor, limit, err := page.Parse()
+	if err != nil {
+		return nil, err
 	}
 
-	rows, err := s.db.QueryContext(ctx, b.String(), args...)
+	text, args := keyset.Apply(userOrdersQuery(userID, filter), "created_at", cursor, limit).Build()
+	rows, err := s.db.QueryContext(ctx, text, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query orders: %w", err)
+		return nil, fmt.Errorf("failed to query orders: %w", postgreserr.Classify(err))
 	}
 	defer rows.Close()
 
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewPage(orders, cursor, limit, orderKey), nil
+}
+
+// orderSortColumns сопоставляет поля сортировки колонкам orders
+var orderSortColumns = map[storage.SortField]string{
+	"":                     "created_at",
+	storage.SortByTime:     "created_at",
+	storage.SortByPrice:    "price",
+	storage.SortByQuantity: "quantity",
+}
+
+// userOrdersListQuery строит запрос GetUserOrders: фильтр, сортировка, Limit и Offset.
+func userOrdersListQuery(userID uint64, filter storage.OrderFilter) (string, []interface{}, error) {
+	if err := filter.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	desc := !filter.SortAsc
+	text, args := userOrdersQuery(userID, filter).
+		OrderBy(orderSortColumns[filter.SortBy], desc).
+		OrderBy("id", desc).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Build()
+	return text, args, nil
+}
+
+// userOrdersQuery строит SELECT ордеров пользователя с условиями фильтра без сортировки и лимитов.
+func userOrdersQuery(userID uint64, filter storage.OrderFilter) *query.Select {
+	q := query.From("orders", rowmap.OrderColumns).Where("user_id = ?", userID)
+	query.In(q, "symbol", filter.AllSymbols())
+	query.In(q, "status", filter.AllStatuses())
+	if filter.Side != "" {
+		q.Where("side = ?", filter.Side)
+	}
+	if filter.Type != "" {
+		q.Where("type = ?", filter.Type)
+	}
+	q.Prefix("client_order_id", filter.ClientOrderIDPrefix)
+	if filter.MinPrice != nil {
+		q.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		q.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.MinQuantity != nil {
+		q.Where("quantity >= ?", *filter.MinQuantity)
+	}
+	if filter.MaxQuantity != nil {
+		q.Where("quantity <= ?", *filter.MaxQuantity)
+	}
+	if filter.StartTime != nil {
+		q.Where("transact_time >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		q.Where("transact_time <= ?", *filter.EndTime)
+	}
+	return q
+}
+
+func scanOrders(rows *sql.Rows) ([]*domain.Order, error) {
 	var orders []*domain.Order
 	for rows.Next() {
-		var o domain.Order
-		if err := rows.Scan(
-			&o.ID, &o.InternalID, &o.UserID, &o.MexcOrderID, &o.Symbol, &o.Side, &o.Type, &o.Status,
-			&o.Price, &o.Quantity, &o.QuoteOrderQty, &o.ExecutedQuantity, &o.CummulativeQuoteQty, &o.ClientOrderID,
-			&o.TransactTime, &o.CreatedAt, &o.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
+		o, err := rowmap.ScanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", postgreserr.Classify(err))
 		}
-		orders = append(orders, &o)
+		orders = append(orders, o)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("orders rows error: %w", err)
+		return nil, fmt.Errorf("orders rows error: %w", postgreserr.Classify(err))
 	}
 	return orders, nil
 }
 
+func orderKey(o *domain.Order) (time.Time, uint64) {
+	return o.CreatedAt, o.ID
+}
+
 // GetOpenOrders получает активные ордера пользователя
 func (s *OrderStorage) GetOpenOrders(ctx context.Context, userID uint64, symbol string) ([]*domain.Order, error) {
-	b := &strings.Builder{}
-	b.WriteString(`SELECT id, internal_id, user_id, mexc_order_id, symbol, side, type, status,
-price, quantity, quote_order_qty, executed_quantity, cummulative_quote_qty, client_order_id, transact_time, created_at, updated_at
-FROM orders WHERE user_id = $1 AND status IN ('NEW','PARTIALLY_FILLED')`)
-	args := []interface{}{userID}
+	q := query.From("orders", rowmap.OrderColumns).
+		Where("user_id = ?", userID).
+		Where("status IN ('NEW','PARTIALLY_FILLED')")
 	if symbol != "" {
-		b.WriteString(" AND symbol = $2")
-		args = append(args, symbol)
+		q.Where("symbol = ?", symbol)
 	}
-	b.WriteString(" ORDER BY created_at DESC, id DESC")
+	text, args := q.OrderBy("created_at", true).OrderBy("id", true).Build()
 
-	rows, err := s.db.QueryContext(ctx, b.String(), args...)
+	rows, err := s.db.QueryContext(ctx, text, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query open orders: %w", err)
+		return nil, fmt.Errorf("failed to query open orders: %w", postgreserr.Classify(err))
 	}
 	defer rows.Close()
 
 	var orders []*domain.Order
 	for rows.Next() {
-		var o domain.Order
-		if err := rows.Scan(
-			&o.ID, &o.InternalID, &o.UserID, &o.MexcOrderID, &o.Symbol, &o.Side, &o.Type, &o.Status,
-			&o.Price, &o.Quantity, &o.QuoteOrderQty, &o.ExecutedQuantity, &o.CummulativeQuoteQty, &o.ClientOrderID,
-			&o.TransactTime, &o.CreatedAt, &o.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan open order: %w", err)
+		o, err := rowmap.ScanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan open order: %w", postgreserr.Classify(err))
 		}
-		orders = append(orders, &o)
+		orders = append(orders, o)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("open orders rows error: %w", err)
+		return nil, fmt.Errorf("open orders rows error: %w", postgreserr.Classify(err))
 	}
 	return orders, nil
 }
@@ -261,38 +408,69 @@ func NewOrderUpdateStorage(db storage.DBInterface) *OrderUpdateStorageImpl {
 }
 
 func (s *OrderUpdateStorageImpl) AppendOrderUpdate(ctx context.Context, update *domain.OrderUpdate) error {
-	query := `INSERT INTO order_updates (user_id, order_id, status, executed_quantity, cummulative_quote_qty, update_time, raw_data)
-VALUES ($1,$2,$3,$4,$5,$6,$7)`
-	_, err := s.db.ExecContext(ctx, query,
-		update.UserID, update.OrderID, update.Status,
-		update.ExecutedQuantity, update.CummulativeQuoteQty,
-		update.UpdateTime, update.RawData,
-	)
+	_, err := s.db.ExecContext(ctx, rowmap.InsertOrderUpdate, rowmap.OrderUpdateArgs(update)...)
 	if err != nil {
-		return fmt.Errorf("failed to append order update: %w", err)
+		return fmt.Errorf("failed to append order update: %w", postgreserr.Classify(err))
 	}
 	return nil
 }
 
 func (s *OrderUpdateStorageImpl) GetOrderUpdates(ctx context.Context, userID uint64, orderID string) ([]*domain.OrderUpdate, error) {
-	query := `SELECT id, user_id, order_id, status, executed_quantity, cummulative_quote_qty, update_time, raw_data
-FROM order_updates WHERE user_id = $1 AND order_id = $2 ORDER BY update_time DESC, id DESC`
-	rows, err := s.db.QueryContext(ctx, query, userID, orderID)
+	rows, err := s.db.QueryContext(ctx, orderUpdatesQuery, userID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order updates: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	return scanOrderUpdates(rows)
+}
+
+// IterOrderUpdates обходит историю ордера в порядке GetOrderUpdates, не загружая ее в память.
+func (s *OrderUpdateStorageImpl) IterOrderUpdates(ctx context.Context, userID uint64, orderID string) iter.Seq2[*domain.OrderUpdate, error] {
+	return rowiter.Query(ctx, s.db, "order updates", orderUpdatesQuery, []interface{}{userID, orderID}, rowmap.ScanOrderUpdate)
+}
+
+// GetOrderUpdatesPage получает страницу истории ордера по курсору (update_time, id).
+func (s *OrderUpdateStorageImpl) GetOrderUpdatesPage(ctx context.Context, userID uint64, orderID string, page storage.PageRequest) (*storage.Page[*domain.OrderUpdate], error) {
+	cursor, limit, err := page.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	q := query.From("order_updates", rowmap.OrderUpdateColumns).
+		Where("user_id = ?", userID).
+		Where("order_id = ?", orderID)
+	text, args := keyset.Apply(q, "update_time", cursor, limit).Build()
+
+	rows, err := s.db.QueryContext(ctx, text, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query order updates: %w", err)
+		return nil, fmt.Errorf("failed to query order updates: %w", postgreserr.Classify(err))
 	}
 	defer rows.Close()
 
+	updates, err := scanOrderUpdates(rows)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewPage(updates, cursor, limit, func(u *domain.OrderUpdate) (time.Time, uint64) {
+		return u.UpdateTime, u.ID
+	}), nil
+}
+
+const orderUpdatesQuery = `SELECT ` + rowmap.OrderUpdateColumns + `
+FROM order_updates WHERE user_id = $1 AND order_id = $2 ORDER BY update_time DESC, id DESC`
+
+func scanOrderUpdates(rows *sql.Rows) ([]*domain.OrderUpdate, error) {
 	var updates []*domain.OrderUpdate
 	for rows.Next() {
-		u := &domain.OrderUpdate{}
-		if err := rows.Scan(&u.ID, &u.UserID, &u.OrderID, &u.Status, &u.ExecutedQuantity, &u.CummulativeQuoteQty, &u.UpdateTime, &u.RawData); err != nil {
-			return nil, fmt.Errorf("failed to scan order update: %w", err)
+		u, err := rowmap.ScanOrderUpdate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order update: %w", postgreserr.Classify(err))
 		}
 		updates = append(updates, u)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("order updates rows error: %w", err)
+		return nil, fmt.Errorf("order updates rows error: %w", postgreserr.Classify(err))
 	}
 	return updates, nil
 }
@@ -9,11 +9,15 @@ import (
 
 	"github.com/samar/sup_bot/metacore/domain"
 	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
 	"github.com/samar/sup_bot/metacore/storage/mocks"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -89,6 +93,19 @@ func (suite *OrderStorageTestSuite) TestCreateOrder() {
 		assert.Error(suite.T(), err)
 		assert.Contains(suite.T(), err.Error(), "failed to create order")
 	})
+
+	suite.Run("duplicate order", func() {
+		suite.mockDB.EXPECT().
+			ExecContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, &pq.Error{Code: "23505", Constraint: "orders_mexc_order_id_key", Table: "orders"})
+
+		err := suite.orderStorage.CreateOrder(suite.ctx, order)
+
+		assert.ErrorIs(suite.T(), err, postgreserr.ErrDuplicate)
+		var pgErr *postgreserr.Error
+		assert.ErrorAs(suite.T(), err, &pgErr)
+		assert.Equal(suite.T(), "orders_mexc_order_id_key", pgErr.Constraint)
+	})
 }
 
 // TestDeleteOrderByID тестирует удаление ордера
@@ -155,14 +172,14 @@ func (suite *OrderStorageTestSuite) TestDeleteOrderByID() {
 // TestUpdateOrderStatus тестирует обновление статуса ордера
 func (suite *OrderStorageTestSuite) TestUpdateOrderStatus() {
 	mexcOrderID := "mexc_order_123"
-	status := "FILLED"
+	status := domain.OrderStatusFilled
 
 	suite.Run("successful status update", func() {
 		mockResult := mocks.NewMockResult(suite.ctrl)
 		mockResult.EXPECT().RowsAffected().Return(int64(1), nil)
 
 		suite.mockDB.EXPECT().
-			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID).
+			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID, gomock.Any()).
 			Return(mockResult, nil)
 
 		err := suite.orderStorage.UpdateOrderStatus(suite.ctx, mexcOrderID, status)
@@ -175,9 +192,15 @@ func (suite *OrderStorageTestSuite) TestUpdateOrderStatus() {
 		mockResult.EXPECT().RowsAffected().Return(int64(0), nil)
 
 		suite.mockDB.EXPECT().
-			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID).
+			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID, gomock.Any()).
 			Return(mockResult, nil)
 
+		mockRow := mocks.NewMockRowInterface(suite.ctrl)
+		mockRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		suite.mockDB.EXPECT().
+			QueryRowContext(gomock.Any(), gomock.Any(), mexcOrderID).
+			Return(mockRow)
+
 		err := suite.orderStorage.UpdateOrderStatus(suite.ctx, mexcOrderID, status)
 
 		assert.Error(suite.T(), err)
@@ -189,7 +212,7 @@ func (suite *OrderStorageTestSuite) TestUpdateOrderStatus() {
 		expectedError := errors.New("database connection failed")
 
 		suite.mockDB.EXPECT().
-			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID).
+			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID, gomock.Any()).
 			Return(nil, expectedError)
 
 		err := suite.orderStorage.UpdateOrderStatus(suite.ctx, mexcOrderID, status)
@@ -204,7 +227,7 @@ func (suite *OrderStorageTestSuite) TestUpdateOrderStatus() {
 		mockResult.EXPECT().RowsAffected().Return(int64(0), expectedError)
 
 		suite.mockDB.EXPECT().
-			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID).
+			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID, gomock.Any()).
 			Return(mockResult, nil)
 
 		err := suite.orderStorage.UpdateOrderStatus(suite.ctx, mexcOrderID, status)
@@ -212,6 +235,38 @@ func (suite *OrderStorageTestSuite) TestUpdateOrderStatus() {
 		assert.Error(suite.T(), err)
 		assert.Contains(suite.T(), err.Error(), "failed to get rows affected")
 	})
+
+	suite.Run("invalid transition", func() {
+		mockResult := mocks.NewMockResult(suite.ctrl)
+		mockResult.EXPECT().RowsAffected().Return(int64(0), nil)
+
+		suite.mockDB.EXPECT().
+			ExecContext(gomock.Any(), gomock.Any(), status, mexcOrderID, gomock.Any()).
+			Return(mockResult, nil)
+
+		mockRow := mocks.NewMockRowInterface(suite.ctrl)
+		mockRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...interface{}) error {
+			*dest[0].(*domain.OrderStatus) = domain.OrderStatusCanceled
+			return nil
+		})
+		suite.mockDB.EXPECT().
+			QueryRowContext(gomock.Any(), gomock.Any(), mexcOrderID).
+			Return(mockRow)
+
+		err := suite.orderStorage.UpdateOrderStatus(suite.ctx, mexcOrderID, status)
+
+		assert.ErrorIs(suite.T(), err, domain.ErrInvalidTransition)
+		var transitionErr *domain.TransitionError
+		assert.ErrorAs(suite.T(), err, &transitionErr)
+		assert.Equal(suite.T(), domain.OrderStatusCanceled, transitionErr.From)
+		assert.Equal(suite.T(), status, transitionErr.To)
+	})
+
+	suite.Run("unknown status", func() {
+		err := suite.orderStorage.UpdateOrderStatus(suite.ctx, mexcOrderID, "DONE")
+
+		assert.ErrorIs(suite.T(), err, domain.ErrInvalidTransition)
+	})
 }
 
 // TestGetOrderByID тестирует получение ордера по ID
@@ -222,7 +277,7 @@ func (suite *OrderStorageTestSuite) TestGetOrderByID() {
 		// Создаем мок для RowInterface
 		mockRow := mocks.NewMockRowInterface(suite.ctrl)
 		mockRow.EXPECT().
-			Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 			DoAndReturn(func(dest ...interface{}) error {
 				// Устанавливаем значения в dest
 				if len(dest) >= 1 {
@@ -251,8 +306,8 @@ func (suite *OrderStorageTestSuite) TestGetOrderByID() {
 					}
 				}
 				if len(dest) >= 6 {
-					if side, ok := dest[5].(*string); ok {
-						*side = "BUY"
+					if side, ok := dest[5].(*domain.OrderSide); ok {
+						*side = domain.OrderSideBuy
 					}
 				}
 				// Устанавливаем остальные поля...
@@ -269,13 +324,13 @@ func (suite *OrderStorageTestSuite) TestGetOrderByID() {
 		assert.NotNil(suite.T(), order)
 		assert.Equal(suite.T(), mexcOrderID, order.MexcOrderID)
 		assert.Equal(suite.T(), "BTCUSDT", order.Symbol)
-		assert.Equal(suite.T(), "BUY", order.Side)
+		assert.Equal(suite.T(), domain.OrderSideBuy, order.Side)
 	})
 
 	suite.Run("order not found", func() {
 		mockRow := mocks.NewMockRowInterface(suite.ctrl)
 		mockRow.EXPECT().
-			Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(sql.ErrNoRows)
 
 		suite.mockDB.EXPECT().
@@ -293,7 +348,7 @@ func (suite *OrderStorageTestSuite) TestGetOrderByID() {
 		expectedError := errors.New("database connection failed")
 		mockRow := mocks.NewMockRowInterface(suite.ctrl)
 		mockRow.EXPECT().
-			Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 			Return(expectedError)
 
 		suite.mockDB.EXPECT().
@@ -308,6 +363,75 @@ func (suite *OrderStorageTestSuite) TestGetOrderByID() {
 	})
 }
 
+// TestUpsertOrder тестирует upsert ордера
+func (suite *OrderStorageTestSuite) TestUpsertOrder() {
+	order := &domain.Order{
+		InternalID:  123,
+		UserID:      1,
+		MexcOrderID: "mexc_order_123",
+		Status:      "PARTIALLY_FILLED",
+	}
+
+	expectUpsert := func(scan func(dest ...interface{}) error) {
+		mockRow := mocks.NewMockRowInterface(suite.ctrl)
+		mockRow.EXPECT().
+			Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(scan)
+
+		suite.mockDB.EXPECT().
+			QueryRowContext(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, query string, args ...interface{}) storage.RowInterface {
+				assert.Contains(suite.T(), query, "ON CONFLICT (mexc_order_id) DO UPDATE")
+				assert.Len(suite.T(), args, 18)
+				return mockRow
+			})
+	}
+
+	suite.Run("created", func() {
+		expectUpsert(func(dest ...interface{}) error {
+			*dest[0].(*uint64) = 7
+			*dest[3].(*bool) = true
+			return nil
+		})
+
+		result, err := suite.orderStorage.UpsertOrder(suite.ctx, order)
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), storage.UpsertCreated, result)
+		assert.Equal(suite.T(), uint64(7), order.ID)
+	})
+
+	suite.Run("updated", func() {
+		expectUpsert(func(dest ...interface{}) error {
+			*dest[3].(*bool) = false
+			return nil
+		})
+
+		result, err := suite.orderStorage.UpsertOrder(suite.ctx, order)
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), storage.UpsertUpdated, result)
+	})
+
+	suite.Run("ignored", func() {
+		expectUpsert(func(...interface{}) error { return sql.ErrNoRows })
+
+		result, err := suite.orderStorage.UpsertOrder(suite.ctx, order)
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), storage.UpsertIgnored, result)
+	})
+
+	suite.Run("database error", func() {
+		expectUpsert(func(...interface{}) error { return errors.New("database connection failed") })
+
+		_, err := suite.orderStorage.UpsertOrder(suite.ctx, order)
+
+		assert.Error(suite.T(), err)
+		assert.Contains(suite.T(), err.Error(), "failed to upsert order")
+	})
+}
+
 // TestNewOrderStorage тестирует создание нового экземпляра OrderStorage
 func (suite *OrderStorageTestSuite) TestNewOrderStorage() {
 	assert.NotNil(suite.T(), suite.orderStorage)
@@ -319,3 +443,182 @@ func (suite *OrderStorageTestSuite) TestClose() {
 	suite.mockDB.EXPECT().Close().Return(nil)
 	suite.orderStorage.Close()
 }
+
+func TestOrderStorage_UpsertOrders(t *testing.T) {
+	ctx := context.Background()
+	orders := []*domain.Order{
+		{InternalID: 1, UserID: 1, MexcOrderID: "o1", Status: "FILLED"},
+		{InternalID: 2, UserID: 1, MexcOrderID: "o2", Status: "NEW"},
+	}
+	returning := []string{"id", "created_at", "updated_at", "inserted"}
+
+	t.Run("mixed results in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewOrderStorage(storage.NewDBAdapter(db))
+		now := time.Now()
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("INSERT INTO orders")
+		prep.ExpectQuery().WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "o1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "FILLED",
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(returning).AddRow(1, now, now, false))
+		prep.ExpectQuery().WillReturnRows(sqlmock.NewRows(returning))
+		mock.ExpectCommit()
+
+		results, err := s.UpsertOrders(ctx, orders)
+		require.NoError(t, err)
+		assert.Equal(t, []storage.UpsertResult{storage.UpsertUpdated, storage.UpsertIgnored}, results)
+		assert.Equal(t, uint64(1), orders[0].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewOrderStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare("INSERT INTO orders")
+		prep.ExpectQuery().WillReturnError(&pq.Error{Code: "23503", Constraint: "orders_user_id_fkey"})
+		mock.ExpectRollback()
+
+		_, err = s.UpsertOrders(ctx, orders)
+		assert.ErrorIs(t, err, postgreserr.ErrForeignKeyViolation)
+		assert.Contains(t, err.Error(), "failed to upsert order o1")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOrderStorage_GetUserOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("extended filter and sort", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewOrderStorage(storage.NewDBAdapter(db))
+		minPrice, maxQty := decimal.NewFromInt(100), decimal.NewFromInt(2)
+
+		mock.ExpectQuery(`FROM orders WHERE user_id = \$1 AND symbol = ANY\(\$2\) AND status = \$3 AND side = \$4 AND type = \$5 `+
+			`AND client_order_id LIKE \$6 AND price >= \$7 AND quantity <= \$8 ORDER BY price ASC, id ASC LIMIT \$9`).
+			WithArgs(uint64(1), `{"BTCUSDT","ETHUSDT"}`, "NEW", domain.OrderSideBuy, domain.OrderTypeLimit, "bot\\_%", minPrice, maxQty, 20).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		orders, err := s.GetUserOrders(ctx, 1, storage.OrderFilter{
+			Symbols:             []string{"BTCUSDT", "ETHUSDT"},
+			Statuses:            []domain.OrderStatus{domain.OrderStatusNew},
+			Side:                domain.OrderSideBuy,
+			Type:                domain.OrderTypeLimit,
+			ClientOrderIDPrefix: "bot_",
+			MinPrice:            &minPrice,
+			MaxQuantity:         &maxQty,
+			SortBy:              storage.SortByPrice,
+			SortAsc:             true,
+			Limit:               20,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid sort field", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewOrderStorage(storage.NewDBAdapter(db))
+
+		_, err = s.GetUserOrders(ctx, 1, storage.OrderFilter{SortBy: "symbol; DROP TABLE orders"})
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOrderStorage_GetUserOrdersPage(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{
+		"id", "internal_id", "user_id", "mexc_order_id", "symbol", "side", "type", "status",
+		"price", "quantity", "quote_order_qty", "executed_quantity", "cummulative_quote_qty", "client_order_id", "transact_time",
+		"time_in_force", "stop_price", "iceberg_qty", "order_list_id", "created_at", "updated_at",
+	}
+	addOrder := func(rows *sqlmock.Rows, id int) *sqlmock.Rows {
+		return rows.AddRow(
+			id, id, 1, "o", "BTCUSDT", "BUY", "LIMIT", "NEW",
+			decimal.NewFromInt(50000), decimal.NewFromFloat(0.01), decimal.Zero, decimal.Zero, decimal.Zero, "c", createdAt,
+			"GTC", decimal.Zero, decimal.Zero, "", createdAt, createdAt,
+		)
+	}
+
+	t.Run("first page with filter", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewOrderStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectQuery(`FROM orders WHERE user_id = \$1 AND status = \$2 ORDER BY created_at DESC, id DESC LIMIT \$3`).
+			WithArgs(uint64(1), domain.OrderStatus("NEW"), 3).
+			WillReturnRows(addOrder(addOrder(addOrder(sqlmock.NewRows(columns), 3), 2), 1))
+
+		page, err := s.GetUserOrdersPage(ctx, 1, storage.OrderFilter{Status: "NEW", Limit: 10}, storage.PageRequest{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Items, 2)
+		assert.Equal(t, uint64(3), page.Items[0].ID)
+		assert.Equal(t, storage.Cursor{Time: createdAt, ID: 2}.Encode(), page.NextCursor)
+		assert.Empty(t, page.PrevCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewOrderStorage(storage.NewDBAdapter(db))
+		cursor := storage.Cursor{Time: createdAt, ID: 5}
+
+		mock.ExpectQuery(`AND \(created_at, id\) < \(\$2, \$3\)`).
+			WithArgs(uint64(1), createdAt, uint64(5), storage.DefaultPageLimit+1).
+			WillReturnError(errors.New("query error"))
+
+		_, err = s.GetUserOrdersPage(ctx, 1, storage.OrderFilter{}, storage.PageRequest{Cursor: cursor.Encode()})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to query orders")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOrderUpdateStorage_GetOrderUpdatesPage(t *testing.T) {
+	ctx := context.Background()
+	updateTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "order_id", "status", "executed_quantity", "cummulative_quote_qty", "update_time", "raw_data"}
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewOrderUpdateStorage(storage.NewDBAdapter(db))
+	cursor := storage.Cursor{Time: updateTime, ID: 1, Newer: true}
+
+	mock.ExpectQuery(`FROM order_updates WHERE user_id = \$1 AND order_id = \$2 AND \(update_time, id\) > \(\$3, \$4\) ORDER BY update_time ASC, id ASC LIMIT \$5`).
+		WithArgs(uint64(1), "o1", updateTime, uint64(1), 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(2, 1, "o1", "PARTIALLY_FILLED", decimal.NewFromFloat(0.005), decimal.NewFromInt(250), updateTime, nil).
+			AddRow(3, 1, "o1", "FILLED", decimal.NewFromFloat(0.01), decimal.NewFromInt(500), updateTime, nil))
+
+	page, err := s.GetOrderUpdatesPage(ctx, 1, "o1", storage.PageRequest{Cursor: cursor.Encode(), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, uint64(2), page.Items[0].ID)
+	assert.Equal(t, storage.Cursor{Time: updateTime, ID: 2}.Encode(), page.NextCursor)
+	assert.Equal(t, storage.Cursor{Time: updateTime, ID: 2, Newer: true}.Encode(), page.PrevCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
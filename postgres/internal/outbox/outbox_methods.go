@@ -0,0 +1,222 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/query"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// OutboxStorage реализует интерфейс OutboxStorage.
+type OutboxStorage struct {
+	db storage.DBInterface
+}
+
+// NewOutboxStorage создает новый экземпляр OutboxStorage.
+func NewOutboxStorage(db storage.DBInterface) *OutboxStorage {
+	return &OutboxStorage{db: db}
+}
+
+const outboxColumns = `id, user_id, topic, COALESCE(dedup_key, ''), payload, status, attempts, last_error, next_attempt_at, locked_until, created_at, sent_at`
+
+// EnqueueMessage ставит сообщение в очередь. Повтор с тем же dedup_key не вставляет строку.
+func (s *OutboxStorage) EnqueueMessage(ctx context.Context, msg *domain.OutboxMessage) (bool, error) {
+	query := `
+		INSERT INTO outbox (user_id, topic, dedup_key, payload)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (dedup_key) DO NOTHING
+		RETURNING id, status, next_attempt_at, created_at`
+
+	payload := msg.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	err := s.db.QueryRowContext(ctx, query, msg.UserID, msg.Topic, msg.DedupKey, payload).
+		Scan(&msg.ID, &msg.Status, &msg.NextAttemptAt, &msg.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to enqueue outbox message: %w", postgreserr.Classify(err))
+	}
+
+	return true, nil
+}
+
+// GetOutboxMessage получает сообщение по ID.
+func (s *OutboxStorage) GetOutboxMessage(ctx context.Context, id uint64) (*domain.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbox WHERE id = $1`
+
+	msg, err := scanOutboxMessage(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("outbox message %d not found: %w", id, postgreserr.ErrOutboxMessageNotFound)
+		}
+		return nil, fmt.Errorf("failed to get outbox message: %w", postgreserr.Classify(err))
+	}
+
+	return msg, nil
+}
+
+// GetOutboxMessages получает сообщения со статусом status по возрастанию ID.
+func (s *OutboxStorage) GetOutboxMessages(ctx context.Context, status domain.OutboxStatus, limit int) ([]*domain.OutboxMessage, error) {
+	text, args := query.From("outbox", outboxColumns).
+		Where("status = ?", status).
+		OrderBy("id", false).
+		Limit(limit).
+		Build()
+
+	return s.queryMessages(ctx, text, args...)
+}
+
+// ClaimMessages захватывает готовые к отправке сообщения. Подзапрос блокирует строки
+// с SKIP LOCKED, поэтому несколько диспетчеров не ждут друг друга и не берут одно сообщение;
+// захват после коммита держится за счет locked_until.
+func (s *OutboxStorage) ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	query := `
+		UPDATE outbox SET
+			attempts = attempts + 1,
+			locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = 'PENDING'
+				AND next_attempt_at <= CURRENT_TIMESTAMP
+				AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + outboxColumns
+
+	messages, err := s.queryMessages(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING не сохраняет порядок подзапроса
+	sortMessages(messages)
+	return messages, nil
+}
+
+// MarkMessageSent переводит сообщение в SENT.
+func (s *OutboxStorage) MarkMessageSent(ctx context.Context, id uint64) error {
+	query := `
+		UPDATE outbox SET status = 'SENT', locked_until = NULL, sent_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+
+	return s.exec(ctx, "mark outbox message sent", id, query, id)
+}
+
+// RetryMessage снимает захват и откладывает следующую попытку на delay.
+func (s *OutboxStorage) RetryMessage(ctx context.Context, id uint64, lastError string, delay time.Duration) error {
+	query := `
+		UPDATE outbox SET
+			locked_until = NULL,
+			last_error = $2,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		WHERE id = $1`
+
+	return s.exec(ctx, "retry outbox message", id, query, id, lastError, delay.Seconds())
+}
+
+// DeadLetterMessage переводит сообщение в DEAD.
+func (s *OutboxStorage) DeadLetterMessage(ctx context.Context, id uint64, lastError string) error {
+	query := `UPDATE outbox SET status = 'DEAD', locked_until = NULL, last_error = $2 WHERE id = $1`
+
+	return s.exec(ctx, "dead-letter outbox message", id, query, id, lastError)
+}
+
+// RequeueMessage возвращает сообщение DEAD в очередь. Сообщения в других статусах не меняются.
+func (s *OutboxStorage) RequeueMessage(ctx context.Context, id uint64) error {
+	query := `
+		UPDATE outbox SET status = 'PENDING', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'DEAD'`
+
+	return s.exec(ctx, "requeue outbox message", id, query, id)
+}
+
+// exec выполняет UPDATE одного сообщения и возвращает ErrOutboxMessageNotFound, если строка не изменилась.
+func (s *OutboxStorage) exec(ctx context.Context, action string, id uint64, query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, postgreserr.Classify(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", postgreserr.Classify(err))
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("outbox message %d not found: %w", id, postgreserr.ErrOutboxMessageNotFound)
+	}
+
+	return nil
+}
+
+func (s *OutboxStorage) queryMessages(ctx context.Context, query string, args ...interface{}) ([]*domain.OutboxMessage, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox messages: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var messages []*domain.OutboxMessage
+	for rows.Next() {
+		msg, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", postgreserr.Classify(err))
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox rows: %w", postgreserr.Classify(err))
+	}
+
+	return messages, nil
+}
+
+// sortMessages упорядочивает захваченные сообщения так же, как подзапрос захвата.
+func sortMessages(messages []*domain.OutboxMessage) {
+	slices.SortFunc(messages, func(a, b *domain.OutboxMessage) int {
+		if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+func scanOutboxMessage(row storage.RowInterface) (*domain.OutboxMessage, error) {
+	var msg domain.OutboxMessage
+	err := row.Scan(
+		&msg.ID,
+		&msg.UserID,
+		&msg.Topic,
+		&msg.DedupKey,
+		&msg.Payload,
+		&msg.Status,
+		&msg.Attempts,
+		&msg.LastError,
+		&msg.NextAttemptAt,
+		&msg.LockedUntil,
+		&msg.CreatedAt,
+		&msg.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Ensure OutboxStorage implements OutboxStorage interface
+var _ storage.OutboxStorage = (*OutboxStorage)(nil)
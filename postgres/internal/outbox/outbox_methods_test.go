@@ -0,0 +1,208 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+var outboxRowColumns = []string{
+	"id", "user_id", "topic", "dedup_key", "payload", "status", "attempts", "last_error",
+	"next_attempt_at", "locked_until", "created_at", "sent_at",
+}
+
+func newTestStorage(t *testing.T) (*OutboxStorage, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewOutboxStorage(storage.NewDBAdapter(db)), mock
+}
+
+func TestOutboxStorage_EnqueueMessage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("enqueues", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+		now := time.Now()
+		msg := &domain.OutboxMessage{
+			UserID:   1,
+			Topic:    domain.OutboxTopicOrderFilled,
+			DedupKey: "order.filled:42",
+			Payload:  []byte(`{"order_id":"42"}`),
+		}
+
+		mock.ExpectQuery("INSERT INTO outbox .* VALUES \\(\\$1, \\$2, NULLIF\\(\\$3, ''\\), \\$4\\) ON CONFLICT \\(dedup_key\\) DO NOTHING").
+			WithArgs(uint64(1), domain.OutboxTopicOrderFilled, "order.filled:42", []byte(`{"order_id":"42"}`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "next_attempt_at", "created_at"}).
+				AddRow(7, "PENDING", now, now))
+
+		enqueued, err := s.EnqueueMessage(ctx, msg)
+		require.NoError(t, err)
+		assert.True(t, enqueued)
+		assert.Equal(t, uint64(7), msg.ID)
+		assert.Equal(t, domain.OutboxPending, msg.Status)
+		assert.Equal(t, now, msg.CreatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty payload becomes empty object", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+		now := time.Now()
+
+		mock.ExpectQuery("INSERT INTO outbox").
+			WithArgs(uint64(1), "note", "", []byte("{}")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "next_attempt_at", "created_at"}).
+				AddRow(1, "PENDING", now, now))
+
+		_, err := s.EnqueueMessage(ctx, &domain.OutboxMessage{UserID: 1, Topic: "note"})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate dedup key", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+
+		mock.ExpectQuery("INSERT INTO outbox").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "next_attempt_at", "created_at"}))
+
+		enqueued, err := s.EnqueueMessage(ctx, &domain.OutboxMessage{UserID: 1, Topic: "note", DedupKey: "k"})
+		require.NoError(t, err)
+		assert.False(t, enqueued)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOutboxStorage_ClaimMessages(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("claims with skip locked in order", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+		now := time.Now()
+		lease := now.Add(30 * time.Second)
+
+		mock.ExpectQuery("UPDATE outbox SET attempts = attempts \\+ 1, locked_until = CURRENT_TIMESTAMP \\+ make_interval\\(secs => \\$2\\) "+
+			"WHERE id IN \\( SELECT id FROM outbox WHERE status = 'PENDING' .* ORDER BY next_attempt_at, id LIMIT \\$1 FOR UPDATE SKIP LOCKED \\) RETURNING").
+			WithArgs(10, 30.0).
+			WillReturnRows(sqlmock.NewRows(outboxRowColumns).
+				AddRow(2, 1, "note", "", []byte("{}"), "PENDING", 1, "", now, lease, now, nil).
+				AddRow(1, 1, "note", "k", []byte("{}"), "PENDING", 3, "timeout", now.Add(-time.Minute), lease, now, nil))
+
+		messages, err := s.ClaimMessages(ctx, 10, 30*time.Second)
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		assert.Equal(t, uint64(1), messages[0].ID)
+		assert.Equal(t, int32(3), messages[0].Attempts)
+		assert.Equal(t, "timeout", messages[0].LastError)
+		assert.Equal(t, &lease, messages[0].LockedUntil)
+		assert.Equal(t, uint64(2), messages[1].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+
+		mock.ExpectQuery("UPDATE outbox").WillReturnError(errors.New("connection reset"))
+
+		_, err := s.ClaimMessages(ctx, 10, time.Second)
+		assert.ErrorContains(t, err, "failed to query outbox messages: connection reset")
+	})
+}
+
+func TestOutboxStorage_GetOutboxMessages(t *testing.T) {
+	ctx := context.Background()
+	s, mock := newTestStorage(t)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT id, user_id, topic, COALESCE\\(dedup_key, ''\\), .* FROM outbox WHERE status = \\$1 ORDER BY id ASC LIMIT \\$2").
+		WithArgs(domain.OutboxDead, 5).
+		WillReturnRows(sqlmock.NewRows(outboxRowColumns).
+			AddRow(3, 1, "note", "", []byte("{}"), "DEAD", 5, "blocked", now, nil, now, nil))
+
+	messages, err := s.GetOutboxMessages(ctx, domain.OutboxDead, 5)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, domain.OutboxDead, messages[0].Status)
+	assert.Nil(t, messages[0].LockedUntil)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxStorage_GetOutboxMessage(t *testing.T) {
+	ctx := context.Background()
+	s, mock := newTestStorage(t)
+
+	mock.ExpectQuery("FROM outbox WHERE id = \\$1").
+		WithArgs(uint64(9)).
+		WillReturnRows(sqlmock.NewRows(outboxRowColumns))
+
+	_, err := s.GetOutboxMessage(ctx, 9)
+	assert.ErrorIs(t, err, postgreserr.ErrOutboxMessageNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxStorage_Transitions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("mark sent", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+
+		mock.ExpectExec("UPDATE outbox SET status = 'SENT', locked_until = NULL, sent_at = CURRENT_TIMESTAMP WHERE id = \\$1").
+			WithArgs(uint64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, s.MarkMessageSent(ctx, 1))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+
+		mock.ExpectExec("UPDATE outbox SET locked_until = NULL, last_error = \\$2, next_attempt_at = CURRENT_TIMESTAMP \\+ make_interval\\(secs => \\$3\\) WHERE id = \\$1").
+			WithArgs(uint64(1), "timeout", 1.5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, s.RetryMessage(ctx, 1, "timeout", 1500*time.Millisecond))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+
+		mock.ExpectExec("UPDATE outbox SET status = 'DEAD', locked_until = NULL, last_error = \\$2 WHERE id = \\$1").
+			WithArgs(uint64(1), "blocked").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, s.DeadLetterMessage(ctx, 1, "blocked"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requeue only dead", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+
+		mock.ExpectExec("UPDATE outbox SET status = 'PENDING', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP WHERE id = \\$1 AND status = 'DEAD'").
+			WithArgs(uint64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := s.RequeueMessage(ctx, 1)
+		assert.ErrorIs(t, err, postgreserr.ErrOutboxMessageNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("exec error", func(t *testing.T) {
+		s, mock := newTestStorage(t)
+
+		mock.ExpectExec("UPDATE outbox").WillReturnError(errors.New("connection reset"))
+
+		err := s.MarkMessageSent(ctx, 1)
+		assert.ErrorContains(t, err, "failed to mark outbox message sent: connection reset")
+	})
+}
@@ -0,0 +1,146 @@
+package prices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// PriceStorage реализует интерфейс PriceStorage.
+type PriceStorage struct {
+	db storage.DBInterface
+}
+
+// NewPriceStorage создает новый экземпляр PriceStorage.
+func NewPriceStorage(db storage.DBInterface) *PriceStorage {
+	return &PriceStorage{db: db}
+}
+
+const priceColumns = `id, symbol, price, source, observed_at, created_at`
+
+// SavePrices сохраняет наблюдения цен в одной транзакции.
+func (s *PriceStorage) SavePrices(ctx context.Context, prices []*domain.Price) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		INSERT INTO prices (symbol, price, source, observed_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (symbol, source, observed_at) DO UPDATE SET price = EXCLUDED.price
+		RETURNING id, created_at`
+
+	for _, p := range prices {
+		err = tx.QueryRowContext(ctx, query, p.Symbol, p.Price, p.Source, p.ObservedAt).Scan(&p.ID, &p.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save price %s: %w", p.Symbol, postgreserr.Classify(err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+// GetLatestPrice получает последнюю цену символа.
+func (s *PriceStorage) GetLatestPrice(ctx context.Context, symbol string) (*domain.Price, error) {
+	query := `SELECT ` + priceColumns + ` FROM prices WHERE symbol = $1 ORDER BY observed_at DESC, id DESC LIMIT 1`
+
+	var p domain.Price
+	err := s.db.QueryRowContext(ctx, query, symbol).Scan(
+		&p.ID,
+		&p.Symbol,
+		&p.Price,
+		&p.Source,
+		&p.ObservedAt,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("price of %s not found: %w", symbol, postgreserr.ErrPriceNotFound)
+		}
+		return nil, fmt.Errorf("failed to get price: %w", postgreserr.Classify(err))
+	}
+
+	return &p, nil
+}
+
+// GetLatestPrices получает последнюю цену каждого символа.
+func (s *PriceStorage) GetLatestPrices(ctx context.Context) ([]*domain.Price, error) {
+	query := `
+		SELECT DISTINCT ON (symbol) ` + priceColumns + `
+		FROM prices
+		ORDER BY symbol, observed_at DESC, id DESC`
+
+	return s.queryPrices(ctx, query)
+}
+
+// GetPricesAt получает последнюю цену каждого символа на момент t.
+func (s *PriceStorage) GetPricesAt(ctx context.Context, t time.Time) ([]*domain.Price, error) {
+	query := `
+		SELECT DISTINCT ON (symbol) ` + priceColumns + `
+		FROM prices
+		WHERE observed_at <= $1
+		ORDER BY symbol, observed_at DESC, id DESC`
+
+	return s.queryPrices(ctx, query, t)
+}
+
+// GetPriceHistory получает цены символа за период [from, to].
+func (s *PriceStorage) GetPriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]*domain.Price, error) {
+	query := `
+		SELECT ` + priceColumns + `
+		FROM prices
+		WHERE symbol = $1 AND observed_at >= $2 AND observed_at <= $3
+		ORDER BY observed_at, id`
+
+	return s.queryPrices(ctx, query, symbol, from, to)
+}
+
+func (s *PriceStorage) queryPrices(ctx context.Context, query string, args ...interface{}) ([]*domain.Price, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prices: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var prices []*domain.Price
+	for rows.Next() {
+		p := &domain.Price{}
+		err := rows.Scan(
+			&p.ID,
+			&p.Symbol,
+			&p.Price,
+			&p.Source,
+			&p.ObservedAt,
+			&p.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price: %w", postgreserr.Classify(err))
+		}
+		prices = append(prices, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price rows: %w", postgreserr.Classify(err))
+	}
+
+	return prices, nil
+}
+
+// Ensure PriceStorage implements PriceStorage interface
+var _ storage.PriceStorage = (*PriceStorage)(nil)
@@ -0,0 +1,120 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+var priceRowColumns = []string{"id", "symbol", "price", "source", "observed_at", "created_at"}
+
+func TestPriceStorage_SavePrices(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("saves prices in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewPriceStorage(storage.NewDBAdapter(db))
+		prices := []*domain.Price{
+			{Symbol: "BTCUSDT", Price: decimal.NewFromInt(50000), Source: "mexc", ObservedAt: now},
+			{Symbol: "ETHUSDT", Price: decimal.NewFromInt(3000), Source: "mexc", ObservedAt: now},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO prices").
+			WithArgs("BTCUSDT", prices[0].Price, "mexc", now).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+		mock.ExpectQuery("ON CONFLICT \\(symbol, source, observed_at\\) DO UPDATE").
+			WithArgs("ETHUSDT", prices[1].Price, "mexc", now).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, now))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.SavePrices(ctx, prices))
+		assert.Equal(t, uint64(2), prices[1].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewPriceStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO prices").WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err = s.SavePrices(ctx, []*domain.Price{{Symbol: "BTCUSDT", Source: "mexc", ObservedAt: now}})
+		assert.ErrorContains(t, err, "failed to save price BTCUSDT")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPriceStorage_GetLatestPrice(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewPriceStorage(storage.NewDBAdapter(db))
+		now := time.Now()
+
+		mock.ExpectQuery("FROM prices WHERE symbol = \\$1 ORDER BY observed_at DESC, id DESC LIMIT 1").
+			WithArgs("BTCUSDT").
+			WillReturnRows(sqlmock.NewRows(priceRowColumns).AddRow(1, "BTCUSDT", "50000", "mexc", now, now))
+
+		p, err := s.GetLatestPrice(ctx, "BTCUSDT")
+		require.NoError(t, err)
+		assert.True(t, decimal.NewFromInt(50000).Equal(p.Price))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewPriceStorage(storage.NewDBAdapter(db))
+		mock.ExpectQuery("FROM prices").WillReturnRows(sqlmock.NewRows(priceRowColumns))
+
+		_, err = s.GetLatestPrice(ctx, "BTCUSDT")
+		assert.ErrorIs(t, err, postgreserr.ErrPriceNotFound)
+	})
+}
+
+func TestPriceStorage_GetPricesAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewPriceStorage(storage.NewDBAdapter(db))
+	at := time.Now()
+
+	mock.ExpectQuery("SELECT DISTINCT ON \\(symbol\\)").
+		WithArgs(at).
+		WillReturnRows(sqlmock.NewRows(priceRowColumns).
+			AddRow(1, "BTCUSDT", "50000", "mexc", at, at).
+			AddRow(2, "ETHUSDT", "3000", "mexc", at, at))
+
+	prices, err := s.GetPricesAt(context.Background(), at)
+	require.NoError(t, err)
+	require.Len(t, prices, 2)
+	assert.Equal(t, "ETHUSDT", prices[1].Symbol)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,127 @@
+// Package query собирает SELECT с условиями, сортировкой и пагинацией.
+// Плейсхолдеры $n нумеруются автоматически, поэтому хранилища не считают аргументы вручную.
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Select — строящийся запрос SELECT columns FROM table.
+type Select struct {
+	columns string
+	table   string
+	where   []string
+	orderBy []string
+	args    []interface{}
+	limit   int
+	offset  int
+}
+
+// From начинает запрос к таблице.
+func From(table, columns string) *Select {
+	return &Select{table: table, columns: columns}
+}
+
+// Where добавляет условие через AND. Каждый ? в cond заменяется плейсхолдером
+// очередного аргумента из args.
+func (s *Select) Where(cond string, args ...interface{}) *Select {
+	if n := strings.Count(cond, "?"); n != len(args) {
+		panic(fmt.Sprintf("query: condition %q has %d placeholders for %d args", cond, n, len(args)))
+	}
+
+	var b strings.Builder
+	for _, part := range strings.SplitAfter(cond, "?") {
+		if !strings.HasSuffix(part, "?") {
+			b.WriteString(part)
+			continue
+		}
+		b.WriteString(strings.TrimSuffix(part, "?"))
+		b.WriteString(s.arg(args[0]))
+		args = args[1:]
+	}
+	s.where = append(s.where, b.String())
+	return s
+}
+
+// Prefix добавляет условие column LIKE 'prefix%'. Символы % и _ в prefix
+// сравниваются буквально. Пустой prefix условие не добавляет.
+func (s *Select) Prefix(column, prefix string) *Select {
+	if prefix == "" {
+		return s
+	}
+	escaped := likeEscaper.Replace(prefix)
+	return s.Where(column+" LIKE ?", escaped+"%")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// In добавляет условие column = ANY(values), для одного значения — column = value.
+// Пустой список условие не добавляет.
+func In[T ~string](s *Select, column string, values []T) *Select {
+	switch len(values) {
+	case 0:
+		return s
+	case 1:
+		return s.Where(column+" = ?", string(values[0]))
+	}
+	list := make([]string, len(values))
+	for i, v := range values {
+		list[i] = string(v)
+	}
+	return s.Where(column+" = ANY(?)", pq.Array(list))
+}
+
+// OrderBy добавляет колонку сортировки.
+func (s *Select) OrderBy(column string, desc bool) *Select {
+	if desc {
+		column += " DESC"
+	} else {
+		column += " ASC"
+	}
+	s.orderBy = append(s.orderBy, column)
+	return s
+}
+
+// Limit ограничивает число строк; 0 — без ограничения.
+func (s *Select) Limit(n int) *Select {
+	s.limit = n
+	return s
+}
+
+// Offset пропускает n строк; 0 — без пропуска.
+func (s *Select) Offset(n int) *Select {
+	s.offset = n
+	return s
+}
+
+// Build возвращает текст запроса и его аргументы.
+func (s *Select) Build() (string, []interface{}) {
+	var b strings.Builder
+	b.WriteString("SELECT " + s.columns + " FROM " + s.table)
+	if len(s.where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(s.where, " AND "))
+	}
+	if len(s.orderBy) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(s.orderBy, ", "))
+	}
+
+	// LIMIT и OFFSET добавляются к копии, чтобы Build можно было вызывать повторно
+	args := append([]interface{}(nil), s.args...)
+	if s.limit > 0 {
+		args = append(args, s.limit)
+		b.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
+	}
+	if s.offset > 0 {
+		args = append(args, s.offset)
+		b.WriteString(fmt.Sprintf(" OFFSET $%d", len(args)))
+	}
+	return b.String(), args
+}
+
+func (s *Select) arg(v interface{}) string {
+	s.args = append(s.args, v)
+	return fmt.Sprintf("$%d", len(s.args))
+}
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect_Build(t *testing.T) {
+	t.Run("conditions, sort and pagination", func(t *testing.T) {
+		q := From("orders", "id, symbol").
+			Where("user_id = ?", 7).
+			Where("(created_at, id) < (?, ?)", "t", 3)
+		In(q, "status", []string{"NEW", "FILLED"})
+		q.Prefix("client_order_id", "bot_")
+
+		text, args := q.OrderBy("price", false).OrderBy("id", false).Limit(10).Offset(20).Build()
+		assert.Equal(t, "SELECT id, symbol FROM orders WHERE user_id = $1 AND (created_at, id) < ($2, $3) "+
+			"AND status = ANY($4) AND client_order_id LIKE $5 ORDER BY price ASC, id ASC LIMIT $6 OFFSET $7", text)
+		assert.Equal(t, []interface{}{7, "t", 3, pq.Array([]string{"NEW", "FILLED"}), `bot\_%`, 10, 20}, args)
+	})
+
+	t.Run("empty optional parts", func(t *testing.T) {
+		q := From("trades", "id")
+		In(q, "symbol", []string(nil))
+		q.Prefix("client_order_id", "")
+
+		text, args := q.Limit(0).Offset(0).Build()
+		assert.Equal(t, "SELECT id FROM trades", text)
+		assert.Empty(t, args)
+	})
+
+	t.Run("single value is compared directly", func(t *testing.T) {
+		type status string
+		q := From("orders", "id")
+		In(q, "status", []status{"NEW"})
+
+		text, args := q.Build()
+		assert.Equal(t, "SELECT id FROM orders WHERE status = $1", text)
+		assert.Equal(t, []interface{}{"NEW"}, args)
+	})
+
+	t.Run("build is repeatable", func(t *testing.T) {
+		q := From("trades", "id").Where("user_id = ?", 1).Limit(5)
+
+		first, firstArgs := q.Build()
+		second, secondArgs := q.Build()
+		assert.Equal(t, first, second)
+		assert.Equal(t, firstArgs, secondArgs)
+	})
+
+	t.Run("prefix escapes like wildcards", func(t *testing.T) {
+		_, args := From("orders", "id").Prefix("client_order_id", `50%_a\b`).Build()
+		assert.Equal(t, []interface{}{`50\%\_a\\b%`}, args)
+	})
+
+	t.Run("placeholder count must match args", func(t *testing.T) {
+		assert.Panics(t, func() { From("t", "id").Where("a = ? AND b = ?", 1) })
+	})
+}
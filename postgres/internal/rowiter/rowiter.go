@@ -0,0 +1,55 @@
+// Package rowiter отдает строки запроса как iter.Seq2 без загрузки результата в память.
+package rowiter
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// Query выполняет запрос при первом обходе и отдает строки по одной.
+// Ошибка запроса, сканирования или отмена контекста отдается последним элементом.
+// Если потребитель прерывает цикл, строки закрываются и соединение возвращается в пул.
+// what называет записи в тексте ошибок, например "user trades".
+func Query[T any](ctx context.Context, db storage.DBInterface, what, query string, args []interface{}, scan func(storage.RowInterface) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(zero, fmt.Errorf("failed to query %s: %w", what, postgreserr.Classify(err)))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				yield(zero, fmt.Errorf("failed to query %s: %w", what, postgreserr.Classify(err)))
+				return
+			}
+			v, err := scan(rows)
+			if err != nil {
+				yield(zero, fmt.Errorf("failed to scan %s: %w", what, postgreserr.Classify(err)))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, fmt.Errorf("%s rows error: %w", what, postgreserr.Classify(err)))
+		}
+	}
+}
+
+// Err возвращает итератор, который отдает только err. Нужен, когда запрос
+// нельзя построить, например из-за некорректного фильтра.
+func Err[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
@@ -0,0 +1,127 @@
+package rowiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func scanID(row storage.RowInterface) (int64, error) {
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("yields all rows", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT id FROM t WHERE a = \\$1").
+			WithArgs("x").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3)).
+			RowsWillBeClosed()
+
+		var ids []int64
+		for id, err := range Query(ctx, storage.NewDBAdapter(db), "things", "SELECT id FROM t WHERE a = $1", []interface{}{"x"}, scanID) {
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+		assert.Equal(t, []int64{1, 2, 3}, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query is lazy and break closes rows", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		seq := Query(ctx, storage.NewDBAdapter(db), "things", "SELECT id FROM t", nil, scanID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		mock.ExpectQuery("SELECT id FROM t").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3)).
+			RowsWillBeClosed()
+
+		var ids []int64
+		for id, err := range seq {
+			require.NoError(t, err)
+			ids = append(ids, id)
+			if len(ids) == 2 {
+				break
+			}
+		}
+		assert.Equal(t, []int64{1, 2}, ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("errors are yielded last", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT id FROM t").WillReturnError(errors.New("connection refused"))
+		mock.ExpectQuery("SELECT id FROM t").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow("bad"))
+		mock.ExpectQuery("SELECT id FROM t").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).RowError(1, errors.New("broken pipe")).AddRow(2))
+
+		collect := func() ([]int64, error) {
+			var ids []int64
+			for id, err := range Query(ctx, storage.NewDBAdapter(db), "things", "SELECT id FROM t", nil, scanID) {
+				if err != nil {
+					return ids, err
+				}
+				ids = append(ids, id)
+			}
+			return ids, nil
+		}
+
+		ids, err := collect()
+		assert.Empty(t, ids)
+		assert.ErrorContains(t, err, "failed to query things")
+
+		ids, err = collect()
+		assert.Equal(t, []int64{1}, ids)
+		assert.ErrorContains(t, err, "failed to scan things")
+
+		ids, err = collect()
+		assert.Equal(t, []int64{1}, ids)
+		assert.ErrorContains(t, err, "things rows error")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("context cancellation stops iteration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mock.ExpectQuery("SELECT id FROM t").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+
+		var ids []int64
+		var iterErr error
+		for id, err := range Query(ctx, storage.NewDBAdapter(db), "things", "SELECT id FROM t", nil, scanID) {
+			if err != nil {
+				iterErr = err
+				break
+			}
+			ids = append(ids, id)
+			cancel()
+		}
+		assert.Equal(t, []int64{1}, ids)
+		assert.ErrorIs(t, iterErr, context.Canceled)
+	})
+}
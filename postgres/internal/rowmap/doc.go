@@ -0,0 +1,5 @@
+// Package rowmap содержит сгенерированные по тегам db списки колонок, INSERT/UPDATE
+// и scan-функции для типов domain. После изменения полей или тегов запустите go generate.
+package rowmap
+
+//go:generate go run ./rowgen -domain ../../../domain -o rowmap_gen.go -extra User=key_version,data_key User=users Order=orders OrderList=order_lists OrderUpdate=order_updates Trade=trades UserBalance=user_balances BalanceSnapshot=balance_snapshots
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Опции тега db, которые понимает генератор.
+const (
+	// optAuto — значение назначает БД: колонка не пишется в INSERT и UPDATE
+	// и возвращается через RETURNING.
+	optAuto = "auto"
+	// optNow — как auto, но UPDATE выставляет CURRENT_TIMESTAMP (updated_at).
+	optNow = "now"
+	// optNullZero — NULL в БД соответствует пустой строке: COALESCE при чтении, NULLIF при записи.
+	optNullZero = "nullzero"
+)
+
+// spec — тип domain и таблица, для которых генерируется код.
+type spec struct {
+	Type   string
+	Table  string
+	Extra  []string // колонки таблицы, которых нет в структуре
+	Fields []field
+}
+
+// field — поле структуры с тегом db.
+type field struct {
+	Name     string
+	Column   string
+	Auto     bool
+	Now      bool
+	NullZero bool
+}
+
+// parseSpec разбирает аргумент вида Type=table.
+func parseSpec(arg string) (spec, error) {
+	typ, table, ok := strings.Cut(arg, "=")
+	if !ok || typ == "" || table == "" {
+		return spec{}, fmt.Errorf("invalid type spec %q, want Type=table", arg)
+	}
+	return spec{Type: typ, Table: table}, nil
+}
+
+// parseTag разбирает тег db поля. Пустой результат означает, что поле не хранится в БД.
+func parseTag(name, tag string) (field, bool, error) {
+	parts := strings.Split(reflect.StructTag(tag).Get("db"), ",")
+	if parts[0] == "" || parts[0] == "-" {
+		return field{}, false, nil
+	}
+
+	f := field{Name: name, Column: parts[0]}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case optAuto:
+			f.Auto = true
+		case optNow:
+			f.Now = true
+		case optNullZero:
+			f.NullZero = true
+		default:
+			return field{}, false, fmt.Errorf("field %s: unknown db tag option %q", name, opt)
+		}
+	}
+	return f, true, nil
+}
+
+// loadStructs читает поля с тегами db у всех структур пакета в dir.
+func loadStructs(dir string) (map[string][]field, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", dir, err)
+	}
+
+	structs := make(map[string][]field)
+	for _, pkg := range pkgs {
+		for name, file := range pkg.Files {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, s := range gen.Specs {
+					ts := s.(*ast.TypeSpec)
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					fields, err := structFields(st)
+					if err != nil {
+						return nil, fmt.Errorf("type %s: %w", ts.Name.Name, err)
+					}
+					structs[ts.Name.Name] = fields
+				}
+			}
+		}
+	}
+	return structs, nil
+}
+
+func structFields(st *ast.StructType) ([]field, error) {
+	var fields []field
+	for _, f := range st.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		tag, err := strconv.Unquote(f.Tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range f.Names {
+			fd, ok, err := parseTag(name.Name, tag)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				fields = append(fields, fd)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// generate возвращает отформатированный исходный код пакета pkg для specs.
+func generate(pkg string, structs map[string][]field, specs []spec) ([]byte, error) {
+	for i := range specs {
+		fields, ok := structs[specs[i].Type]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found", specs[i].Type)
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("type %s has no db tags", specs[i].Type)
+		}
+		specs[i].Fields = fields
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Type < specs[j].Type })
+
+	// Scan-функции, которым нужен storage.RowInterface, генерируются только для типов без дополнительных колонок.
+	scans := false
+	for _, s := range specs {
+		scans = scans || len(s.Extra) == 0
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, struct {
+		Package string
+		Scans   bool
+		Specs   []spec
+	}{pkg, scans, specs}); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+// Written — поля, которые пишутся в INSERT и UPDATE, в порядке аргументов.
+func (s spec) Written() []field {
+	var out []field
+	for _, f := range s.Fields {
+		if !f.Auto && !f.Now {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Returning — поля, значения которых назначает БД при вставке.
+func (s spec) Returning() []field {
+	var out []field
+	for _, f := range s.Fields {
+		if f.Auto || f.Now {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Columns — список SELECT в порядке полей структуры, затем дополнительные колонки.
+func (s spec) Columns() string {
+	cols := make([]string, 0, len(s.Fields)+len(s.Extra))
+	for _, f := range s.Fields {
+		if f.NullZero {
+			cols = append(cols, fmt.Sprintf("COALESCE(%s, '')", f.Column))
+			continue
+		}
+		cols = append(cols, f.Column)
+	}
+	return strings.Join(append(cols, s.Extra...), ", ")
+}
+
+func (s spec) insertColumns() []string {
+	var cols []string
+	for _, f := range s.Written() {
+		cols = append(cols, f.Column)
+	}
+	return append(cols, s.Extra...)
+}
+
+// placeholders возвращает $n для каждой записываемой колонки.
+func (s spec) placeholders() []string {
+	var out []string
+	for i, f := range s.Written() {
+		p := "$" + strconv.Itoa(i+1)
+		if f.NullZero {
+			p = fmt.Sprintf("NULLIF(%s, '')", p)
+		}
+		out = append(out, p)
+	}
+	for i := range s.Extra {
+		out = append(out, "$"+strconv.Itoa(len(s.Written())+i+1))
+	}
+	return out
+}
+
+// InsertColumns — колонки INSERT через запятую.
+func (s spec) InsertColumns() string { return strings.Join(s.insertColumns(), ", ") }
+
+// Insert — INSERT без RETURNING.
+func (s spec) Insert() string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		s.Table, s.InsertColumns(), strings.Join(s.placeholders(), ", "))
+}
+
+// HasUpdate сообщает, есть ли у таблицы колонка id для UPDATE ... WHERE id.
+func (s spec) HasUpdate() bool {
+	for _, f := range s.Fields {
+		if f.Column == "id" {
+			return len(s.insertColumns()) > 0
+		}
+	}
+	return false
+}
+
+// Update — UPDATE всех записываемых колонок по id.
+func (s spec) Update() string {
+	cols, values := s.insertColumns(), s.placeholders()
+	set := make([]string, 0, len(cols)+1)
+	for i := range cols {
+		set = append(set, cols[i]+" = "+values[i])
+	}
+	for _, f := range s.Fields {
+		if f.Now {
+			set = append(set, f.Column+" = CURRENT_TIMESTAMP")
+		}
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", s.Table, strings.Join(set, ", "), len(cols)+1)
+}
+
+// ReturningColumns — колонки RETURNING через запятую.
+func (s spec) ReturningColumns() string {
+	var cols []string
+	for _, f := range s.Returning() {
+		cols = append(cols, f.Column)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// ExtraList — дополнительные колонки через запятую для комментариев.
+func (s spec) ExtraList() string { return strings.Join(s.Extra, ", ") }
+
+var fileTemplate = template.Must(template.New("rowmap").Parse(`// Code generated by rowgen from db tags in domain; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/samar/sup_bot/metacore/domain"
+{{- if .Scans}}
+	"github.com/samar/sup_bot/metacore/storage"
+{{- end}}
+)
+{{range .Specs}}{{$t := .Type}}
+// {{$t}}Columns — колонки {{.Table}} в порядке {{$t}}Dest{{if .Extra}}, затем {{.ExtraList}}{{end}}.
+const {{$t}}Columns = ` + "`{{.Columns}}`" + `
+
+// {{$t}}InsertColumns — колонки Insert{{$t}} в порядке {{$t}}Args.
+const {{$t}}InsertColumns = ` + "`{{.InsertColumns}}`" + `
+
+// Insert{{$t}} вставляет строку {{.Table}}; аргументы — {{$t}}Args.
+const Insert{{$t}} = ` + "`{{.Insert}}`" + `
+{{if .HasUpdate}}
+// Update{{$t}} обновляет строку {{.Table}} по id; аргументы — {{$t}}Args и id.
+const Update{{$t}} = ` + "`{{.Update}}`" + `
+{{end}}{{if .Returning}}
+// {{$t}}Returning — колонки, которые назначает БД, в порядке {{$t}}ReturningDest.
+const {{$t}}Returning = ` + "`{{.ReturningColumns}}`" + `
+{{end}}
+// {{$t}}Args возвращает аргументы Insert{{$t}}{{if .Extra}}; extra — значения {{.ExtraList}}{{end}}.
+func {{$t}}Args(v *domain.{{$t}}{{if .Extra}}, extra ...interface{}{{end}}) []interface{} {
+	return {{if .Extra}}append({{end}}[]interface{}{
+{{- range .Written}}
+		v.{{.Name}},
+{{- end}}
+	}{{if .Extra}}, extra...){{end}}
+}
+
+// {{$t}}Dest возвращает указатели на поля в порядке {{$t}}Columns{{if .Extra}} без {{.ExtraList}}{{end}}.
+func {{$t}}Dest(v *domain.{{$t}}) []interface{} {
+	return []interface{}{
+{{- range .Fields}}
+		&v.{{.Name}},
+{{- end}}
+	}
+}
+{{if .Returning}}
+// {{$t}}ReturningDest возвращает указатели на поля {{$t}}Returning.
+func {{$t}}ReturningDest(v *domain.{{$t}}) []interface{} {
+	return []interface{}{
+{{- range .Returning}}
+		&v.{{.Name}},
+{{- end}}
+	}
+}
+{{end}}{{if not .Extra}}
+// Scan{{$t}} читает строку, выбранную с {{$t}}Columns.
+func Scan{{$t}}(row storage.RowInterface) (*domain.{{$t}}, error) {
+	var v domain.{{$t}}
+	if err := row.Scan({{$t}}Dest(&v)...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+{{end}}{{end}}`))
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTag(t *testing.T) {
+	t.Run("options", func(t *testing.T) {
+		f, ok, err := parseTag("TimeInForce", `db:"time_in_force,nullzero"`)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, field{Name: "TimeInForce", Column: "time_in_force", NullZero: true}, f)
+
+		f, ok, err = parseTag("UpdatedAt", `db:"updated_at,now" json:"updated_at"`)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, f.Now)
+	})
+
+	t.Run("skipped fields", func(t *testing.T) {
+		_, ok, err := parseTag("Balance", `db:"-"`)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		_, ok, err = parseTag("Raw", `json:"raw"`)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("unknown option", func(t *testing.T) {
+		_, _, err := parseTag("ID", `db:"id,serial"`)
+		assert.ErrorContains(t, err, `unknown db tag option "serial"`)
+	})
+}
+
+func TestSpecStatements(t *testing.T) {
+	s := spec{
+		Type:  "Account",
+		Table: "accounts",
+		Extra: []string{"key_version"},
+		Fields: []field{
+			{Name: "ID", Column: "id", Auto: true},
+			{Name: "Name", Column: "name"},
+			{Name: "Note", Column: "note", NullZero: true},
+			{Name: "UpdatedAt", Column: "updated_at", Now: true},
+		},
+	}
+
+	assert.Equal(t, "id, name, COALESCE(note, ''), updated_at, key_version", s.Columns())
+	assert.Equal(t, "INSERT INTO accounts (name, note, key_version) VALUES ($1, NULLIF($2, ''), $3)", s.Insert())
+	assert.Equal(t, "UPDATE accounts SET name = $1, note = NULLIF($2, ''), key_version = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4", s.Update())
+	assert.Equal(t, "id, updated_at", s.ReturningColumns())
+}
+
+func TestGenerate(t *testing.T) {
+	structs := map[string][]field{
+		"Account": {
+			{Name: "ID", Column: "id", Auto: true},
+			{Name: "Name", Column: "name"},
+		},
+	}
+
+	t.Run("scan function", func(t *testing.T) {
+		src, err := generate("rowmap", structs, []spec{{Type: "Account", Table: "accounts"}})
+		require.NoError(t, err)
+		assert.Contains(t, string(src), "func ScanAccount(row storage.RowInterface) (*domain.Account, error)")
+		assert.Contains(t, string(src), "const InsertAccount = `INSERT INTO accounts (name) VALUES ($1)`")
+	})
+
+	t.Run("extra columns", func(t *testing.T) {
+		src, err := generate("rowmap", structs, []spec{{Type: "Account", Table: "accounts", Extra: []string{"secret"}}})
+		require.NoError(t, err)
+		assert.Contains(t, string(src), "func AccountArgs(v *domain.Account, extra ...interface{}) []interface{}")
+		assert.NotContains(t, string(src), "ScanAccount")
+		assert.NotContains(t, string(src), "metacore/storage")
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := generate("rowmap", structs, []spec{{Type: "Missing", Table: "missing"}})
+		assert.ErrorContains(t, err, "type Missing not found")
+	})
+}
+
+// TestGeneratedUpToDate проверяет, что rowmap_gen.go соответствует текущим тегам domain.
+func TestGeneratedUpToDate(t *testing.T) {
+	doc, err := os.ReadFile(filepath.Join("..", "doc.go"))
+	require.NoError(t, err)
+
+	var args []string
+	for _, line := range strings.Split(string(doc), "\n") {
+		if rest, ok := strings.CutPrefix(line, "//go:generate go run ./rowgen "); ok {
+			args = strings.Fields(rest)
+		}
+	}
+	require.NotEmpty(t, args, "go:generate directive not found")
+
+	cfg, err := parseArgs(args)
+	require.NoError(t, err)
+	cfg.domainDir = filepath.Join("..", cfg.domainDir)
+
+	want, err := source(cfg)
+	require.NoError(t, err)
+
+	got, err := os.ReadFile(filepath.Join("..", cfg.out))
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "rowmap_gen.go is stale, run go generate ./postgres/internal/rowmap")
+}
@@ -0,0 +1,95 @@
+// Команда rowgen генерирует списки колонок, INSERT/UPDATE и scan-функции
+// по тегам db структур пакета domain, чтобы хранилища не перечисляли поля вручную.
+//
+// Использование:
+//
+//	rowgen -domain ../../../domain -o rowmap_gen.go [-extra Type=col1,col2] Type=table...
+//
+// Опции тега db: auto — значение назначает БД (id, created_at), now — как auto,
+// но UPDATE выставляет CURRENT_TIMESTAMP (updated_at), nullzero — пустая строка хранится как NULL.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// extraFlag собирает значения -extra Type=col1,col2.
+type extraFlag map[string][]string
+
+func (e extraFlag) String() string { return fmt.Sprint(map[string][]string(e)) }
+
+func (e extraFlag) Set(value string) error {
+	typ, cols, ok := strings.Cut(value, "=")
+	if !ok || typ == "" || cols == "" {
+		return fmt.Errorf("invalid extra columns %q, want Type=col1,col2", value)
+	}
+	e[typ] = strings.Split(cols, ",")
+	return nil
+}
+
+// config — разобранные аргументы командной строки.
+type config struct {
+	domainDir string
+	out       string
+	pkg       string
+	specs     []spec
+}
+
+func main() {
+	cfg, err := parseArgs(os.Args[1:])
+	if err == nil {
+		err = run(cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rowgen:", err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs разбирает флаги и аргументы Type=table.
+func parseArgs(args []string) (*config, error) {
+	cfg := &config{}
+	extra := extraFlag{}
+
+	fs := flag.NewFlagSet("rowgen", flag.ContinueOnError)
+	fs.StringVar(&cfg.domainDir, "domain", "domain", "каталог пакета domain")
+	fs.StringVar(&cfg.out, "o", "rowmap_gen.go", "файл для сгенерированного кода")
+	fs.StringVar(&cfg.pkg, "package", "rowmap", "имя пакета сгенерированного кода")
+	fs.Var(extra, "extra", "колонки таблицы, которых нет в структуре: Type=col1,col2")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() == 0 {
+		return nil, fmt.Errorf("no types given, want Type=table")
+	}
+	for _, arg := range fs.Args() {
+		s, err := parseSpec(arg)
+		if err != nil {
+			return nil, err
+		}
+		s.Extra = extra[s.Type]
+		cfg.specs = append(cfg.specs, s)
+	}
+	return cfg, nil
+}
+
+// source генерирует код по конфигурации.
+func source(cfg *config) ([]byte, error) {
+	structs, err := loadStructs(cfg.domainDir)
+	if err != nil {
+		return nil, err
+	}
+	return generate(cfg.pkg, structs, cfg.specs)
+}
+
+func run(cfg *config) error {
+	src, err := source(cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cfg.out, src, 0o644)
+}
@@ -0,0 +1,459 @@
+// Code generated by rowgen from db tags in domain; DO NOT EDIT.
+
+package rowmap
+
+import (
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// BalanceSnapshotColumns — колонки balance_snapshots в порядке BalanceSnapshotDest.
+const BalanceSnapshotColumns = `id, user_id, asset, free, locked, recorded_at`
+
+// BalanceSnapshotInsertColumns — колонки InsertBalanceSnapshot в порядке BalanceSnapshotArgs.
+const BalanceSnapshotInsertColumns = `user_id, asset, free, locked, recorded_at`
+
+// InsertBalanceSnapshot вставляет строку balance_snapshots; аргументы — BalanceSnapshotArgs.
+const InsertBalanceSnapshot = `INSERT INTO balance_snapshots (user_id, asset, free, locked, recorded_at) VALUES ($1, $2, $3, $4, $5)`
+
+// UpdateBalanceSnapshot обновляет строку balance_snapshots по id; аргументы — BalanceSnapshotArgs и id.
+const UpdateBalanceSnapshot = `UPDATE balance_snapshots SET user_id = $1, asset = $2, free = $3, locked = $4, recorded_at = $5 WHERE id = $6`
+
+// BalanceSnapshotReturning — колонки, которые назначает БД, в порядке BalanceSnapshotReturningDest.
+const BalanceSnapshotReturning = `id`
+
+// BalanceSnapshotArgs возвращает аргументы InsertBalanceSnapshot.
+func BalanceSnapshotArgs(v *domain.BalanceSnapshot) []interface{} {
+	return []interface{}{
+		v.UserID,
+		v.Asset,
+		v.Free,
+		v.Locked,
+		v.RecordedAt,
+	}
+}
+
+// BalanceSnapshotDest возвращает указатели на поля в порядке BalanceSnapshotColumns.
+func BalanceSnapshotDest(v *domain.BalanceSnapshot) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.UserID,
+		&v.Asset,
+		&v.Free,
+		&v.Locked,
+		&v.RecordedAt,
+	}
+}
+
+// BalanceSnapshotReturningDest возвращает указатели на поля BalanceSnapshotReturning.
+func BalanceSnapshotReturningDest(v *domain.BalanceSnapshot) []interface{} {
+	return []interface{}{
+		&v.ID,
+	}
+}
+
+// ScanBalanceSnapshot читает строку, выбранную с BalanceSnapshotColumns.
+func ScanBalanceSnapshot(row storage.RowInterface) (*domain.BalanceSnapshot, error) {
+	var v domain.BalanceSnapshot
+	if err := row.Scan(BalanceSnapshotDest(&v)...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// OrderColumns — колонки orders в порядке OrderDest.
+const OrderColumns = `id, internal_id, user_id, mexc_order_id, symbol, side, type, status, price, quantity, quote_order_qty, executed_quantity, cummulative_quote_qty, client_order_id, transact_time, COALESCE(time_in_force, ''), stop_price, iceberg_qty, COALESCE(order_list_id, ''), created_at, updated_at`
+
+// OrderInsertColumns — колонки InsertOrder в порядке OrderArgs.
+const OrderInsertColumns = `internal_id, user_id, mexc_order_id, symbol, side, type, status, price, quantity, quote_order_qty, executed_quantity, cummulative_quote_qty, client_order_id, transact_time, time_in_force, stop_price, iceberg_qty, order_list_id`
+
+// InsertOrder вставляет строку orders; аргументы — OrderArgs.
+const InsertOrder = `INSERT INTO orders (internal_id, user_id, mexc_order_id, symbol, side, type, status, price, quantity, quote_order_qty, executed_quantity, cummulative_quote_qty, client_order_id, transact_time, time_in_force, stop_price, iceberg_qty, order_list_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''), $16, $17, NULLIF($18, ''))`
+
+// UpdateOrder обновляет строку orders по id; аргументы — OrderArgs и id.
+const UpdateOrder = `UPDATE orders SET internal_id = $1, user_id = $2, mexc_order_id = $3, symbol = $4, side = $5, type = $6, status = $7, price = $8, quantity = $9, quote_order_qty = $10, executed_quantity = $11, cummulative_quote_qty = $12, client_order_id = $13, transact_time = $14, time_in_force = NULLIF($15, ''), stop_price = $16, iceberg_qty = $17, order_list_id = NULLIF($18, ''), updated_at = CURRENT_TIMESTAMP WHERE id = $19`
+
+// OrderReturning — колонки, которые назначает БД, в порядке OrderReturningDest.
+const OrderReturning = `id, created_at, updated_at`
+
+// OrderArgs возвращает аргументы InsertOrder.
+func OrderArgs(v *domain.Order) []interface{} {
+	return []interface{}{
+		v.InternalID,
+		v.UserID,
+		v.MexcOrderID,
+		v.Symbol,
+		v.Side,
+		v.Type,
+		v.Status,
+		v.Price,
+		v.Quantity,
+		v.QuoteOrderQty,
+		v.ExecutedQuantity,
+		v.CummulativeQuoteQty,
+		v.ClientOrderID,
+		v.TransactTime,
+		v.TimeInForce,
+		v.StopPrice,
+		v.IcebergQty,
+		v.OrderListID,
+	}
+}
+
+// OrderDest возвращает указатели на поля в порядке OrderColumns.
+func OrderDest(v *domain.Order) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.InternalID,
+		&v.UserID,
+		&v.MexcOrderID,
+		&v.Symbol,
+		&v.Side,
+		&v.Type,
+		&v.Status,
+		&v.Price,
+		&v.Quantity,
+		&v.QuoteOrderQty,
+		&v.ExecutedQuantity,
+		&v.CummulativeQuoteQty,
+		&v.ClientOrderID,
+		&v.TransactTime,
+		&v.TimeInForce,
+		&v.StopPrice,
+		&v.IcebergQty,
+		&v.OrderListID,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	}
+}
+
+// OrderReturningDest возвращает указатели на поля OrderReturning.
+func OrderReturningDest(v *domain.Order) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	}
+}
+
+// ScanOrder читает строку, выбранную с OrderColumns.
+func ScanOrder(row storage.RowInterface) (*domain.Order, error) {
+	var v domain.Order
+	if err := row.Scan(OrderDest(&v)...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// OrderListColumns — колонки order_lists в порядке OrderListDest.
+const OrderListColumns = `id, user_id, mexc_order_list_id, COALESCE(list_client_order_id, ''), symbol, contingency_type, list_status_type, list_order_status, transaction_time, created_at, updated_at`
+
+// OrderListInsertColumns — колонки InsertOrderList в порядке OrderListArgs.
+const OrderListInsertColumns = `user_id, mexc_order_list_id, list_client_order_id, symbol, contingency_type, list_status_type, list_order_status, transaction_time`
+
+// InsertOrderList вставляет строку order_lists; аргументы — OrderListArgs.
+const InsertOrderList = `INSERT INTO order_lists (user_id, mexc_order_list_id, list_client_order_id, symbol, contingency_type, list_status_type, list_order_status, transaction_time) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)`
+
+// UpdateOrderList обновляет строку order_lists по id; аргументы — OrderListArgs и id.
+const UpdateOrderList = `UPDATE order_lists SET user_id = $1, mexc_order_list_id = $2, list_client_order_id = NULLIF($3, ''), symbol = $4, contingency_type = $5, list_status_type = $6, list_order_status = $7, transaction_time = $8, updated_at = CURRENT_TIMESTAMP WHERE id = $9`
+
+// OrderListReturning — колонки, которые назначает БД, в порядке OrderListReturningDest.
+const OrderListReturning = `id, created_at, updated_at`
+
+// OrderListArgs возвращает аргументы InsertOrderList.
+func OrderListArgs(v *domain.OrderList) []interface{} {
+	return []interface{}{
+		v.UserID,
+		v.MexcOrderListID,
+		v.ListClientOrderID,
+		v.Symbol,
+		v.ContingencyType,
+		v.ListStatusType,
+		v.ListOrderStatus,
+		v.TransactionTime,
+	}
+}
+
+// OrderListDest возвращает указатели на поля в порядке OrderListColumns.
+func OrderListDest(v *domain.OrderList) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.UserID,
+		&v.MexcOrderListID,
+		&v.ListClientOrderID,
+		&v.Symbol,
+		&v.ContingencyType,
+		&v.ListStatusType,
+		&v.ListOrderStatus,
+		&v.TransactionTime,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	}
+}
+
+// OrderListReturningDest возвращает указатели на поля OrderListReturning.
+func OrderListReturningDest(v *domain.OrderList) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	}
+}
+
+// ScanOrderList читает строку, выбранную с OrderListColumns.
+func ScanOrderList(row storage.RowInterface) (*domain.OrderList, error) {
+	var v domain.OrderList
+	if err := row.Scan(OrderListDest(&v)...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// OrderUpdateColumns — колонки order_updates в порядке OrderUpdateDest.
+const OrderUpdateColumns = `id, user_id, order_id, status, executed_quantity, cummulative_quote_qty, update_time, raw_data`
+
+// OrderUpdateInsertColumns — колонки InsertOrderUpdate в порядке OrderUpdateArgs.
+const OrderUpdateInsertColumns = `user_id, order_id, status, executed_quantity, cummulative_quote_qty, update_time, raw_data`
+
+// InsertOrderUpdate вставляет строку order_updates; аргументы — OrderUpdateArgs.
+const InsertOrderUpdate = `INSERT INTO order_updates (user_id, order_id, status, executed_quantity, cummulative_quote_qty, update_time, raw_data) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+// UpdateOrderUpdate обновляет строку order_updates по id; аргументы — OrderUpdateArgs и id.
+const UpdateOrderUpdate = `UPDATE order_updates SET user_id = $1, order_id = $2, status = $3, executed_quantity = $4, cummulative_quote_qty = $5, update_time = $6, raw_data = $7 WHERE id = $8`
+
+// OrderUpdateReturning — колонки, которые назначает БД, в порядке OrderUpdateReturningDest.
+const OrderUpdateReturning = `id`
+
+// OrderUpdateArgs возвращает аргументы InsertOrderUpdate.
+func OrderUpdateArgs(v *domain.OrderUpdate) []interface{} {
+	return []interface{}{
+		v.UserID,
+		v.OrderID,
+		v.Status,
+		v.ExecutedQuantity,
+		v.CummulativeQuoteQty,
+		v.UpdateTime,
+		v.RawData,
+	}
+}
+
+// OrderUpdateDest возвращает указатели на поля в порядке OrderUpdateColumns.
+func OrderUpdateDest(v *domain.OrderUpdate) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.UserID,
+		&v.OrderID,
+		&v.Status,
+		&v.ExecutedQuantity,
+		&v.CummulativeQuoteQty,
+		&v.UpdateTime,
+		&v.RawData,
+	}
+}
+
+// OrderUpdateReturningDest возвращает указатели на поля OrderUpdateReturning.
+func OrderUpdateReturningDest(v *domain.OrderUpdate) []interface{} {
+	return []interface{}{
+		&v.ID,
+	}
+}
+
+// ScanOrderUpdate читает строку, выбранную с OrderUpdateColumns.
+func ScanOrderUpdate(row storage.RowInterface) (*domain.OrderUpdate, error) {
+	var v domain.OrderUpdate
+	if err := row.Scan(OrderUpdateDest(&v)...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// TradeColumns — колонки trades в порядке TradeDest.
+const TradeColumns = `id, user_id, mexc_trade_id, order_id, symbol, price, quantity, quote_quantity, commission, commission_asset, trade_time, is_buyer, is_maker, created_at`
+
+// TradeInsertColumns — колонки InsertTrade в порядке TradeArgs.
+const TradeInsertColumns = `user_id, mexc_trade_id, order_id, symbol, price, quantity, quote_quantity, commission, commission_asset, trade_time, is_buyer, is_maker`
+
+// InsertTrade вставляет строку trades; аргументы — TradeArgs.
+const InsertTrade = `INSERT INTO trades (user_id, mexc_trade_id, order_id, symbol, price, quantity, quote_quantity, commission, commission_asset, trade_time, is_buyer, is_maker) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+// UpdateTrade обновляет строку trades по id; аргументы — TradeArgs и id.
+const UpdateTrade = `UPDATE trades SET user_id = $1, mexc_trade_id = $2, order_id = $3, symbol = $4, price = $5, quantity = $6, quote_quantity = $7, commission = $8, commission_asset = $9, trade_time = $10, is_buyer = $11, is_maker = $12 WHERE id = $13`
+
+// TradeReturning — колонки, которые назначает БД, в порядке TradeReturningDest.
+const TradeReturning = `id, created_at`
+
+// TradeArgs возвращает аргументы InsertTrade.
+func TradeArgs(v *domain.Trade) []interface{} {
+	return []interface{}{
+		v.UserID,
+		v.MexcTradeID,
+		v.OrderID,
+		v.Symbol,
+		v.Price,
+		v.Quantity,
+		v.QuoteQuantity,
+		v.Commission,
+		v.CommissionAsset,
+		v.TradeTime,
+		v.IsBuyer,
+		v.IsMaker,
+	}
+}
+
+// TradeDest возвращает указатели на поля в порядке TradeColumns.
+func TradeDest(v *domain.Trade) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.UserID,
+		&v.MexcTradeID,
+		&v.OrderID,
+		&v.Symbol,
+		&v.Price,
+		&v.Quantity,
+		&v.QuoteQuantity,
+		&v.Commission,
+		&v.CommissionAsset,
+		&v.TradeTime,
+		&v.IsBuyer,
+		&v.IsMaker,
+		&v.CreatedAt,
+	}
+}
+
+// TradeReturningDest возвращает указатели на поля TradeReturning.
+func TradeReturningDest(v *domain.Trade) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.CreatedAt,
+	}
+}
+
+// ScanTrade читает строку, выбранную с TradeColumns.
+func ScanTrade(row storage.RowInterface) (*domain.Trade, error) {
+	var v domain.Trade
+	if err := row.Scan(TradeDest(&v)...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// UserColumns — колонки users в порядке UserDest, затем key_version, data_key.
+const UserColumns = `id, telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key, kyc_status, can_trade, can_withdraw, can_deposit, account_type, permissions, last_account_sync, is_active, created_at, updated_at, key_version, data_key`
+
+// UserInsertColumns — колонки InsertUser в порядке UserArgs.
+const UserInsertColumns = `telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key, kyc_status, can_trade, can_withdraw, can_deposit, account_type, permissions, last_account_sync, is_active, key_version, data_key`
+
+// InsertUser вставляет строку users; аргументы — UserArgs.
+const InsertUser = `INSERT INTO users (telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key, kyc_status, can_trade, can_withdraw, can_deposit, account_type, permissions, last_account_sync, is_active, key_version, data_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+
+// UpdateUser обновляет строку users по id; аргументы — UserArgs и id.
+const UpdateUser = `UPDATE users SET telegram_id = $1, mexc_uid = $2, username = $3, email = $4, mexc_api_key = $5, mexc_secret_key = $6, kyc_status = $7, can_trade = $8, can_withdraw = $9, can_deposit = $10, account_type = $11, permissions = $12, last_account_sync = $13, is_active = $14, key_version = $15, data_key = $16, updated_at = CURRENT_TIMESTAMP WHERE id = $17`
+
+// UserReturning — колонки, которые назначает БД, в порядке UserReturningDest.
+const UserReturning = `id, created_at, updated_at`
+
+// UserArgs возвращает аргументы InsertUser; extra — значения key_version, data_key.
+func UserArgs(v *domain.User, extra ...interface{}) []interface{} {
+	return append([]interface{}{
+		v.TelegramID,
+		v.MexcUID,
+		v.Username,
+		v.Email,
+		v.MexcAPIKey,
+		v.MexcSecretKey,
+		v.KYCStatus,
+		v.CanTrade,
+		v.CanWithdraw,
+		v.CanDeposit,
+		v.AccountType,
+		v.Permissions,
+		v.LastAccountSync,
+		v.IsActive,
+	}, extra...)
+}
+
+// UserDest возвращает указатели на поля в порядке UserColumns без key_version, data_key.
+func UserDest(v *domain.User) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.TelegramID,
+		&v.MexcUID,
+		&v.Username,
+		&v.Email,
+		&v.MexcAPIKey,
+		&v.MexcSecretKey,
+		&v.KYCStatus,
+		&v.CanTrade,
+		&v.CanWithdraw,
+		&v.CanDeposit,
+		&v.AccountType,
+		&v.Permissions,
+		&v.LastAccountSync,
+		&v.IsActive,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	}
+}
+
+// UserReturningDest возвращает указатели на поля UserReturning.
+func UserReturningDest(v *domain.User) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+	}
+}
+
+// UserBalanceColumns — колонки user_balances в порядке UserBalanceDest.
+const UserBalanceColumns = `id, user_id, asset, free, locked, updated_at`
+
+// UserBalanceInsertColumns — колонки InsertUserBalance в порядке UserBalanceArgs.
+const UserBalanceInsertColumns = `user_id, asset, free, locked, updated_at`
+
+// InsertUserBalance вставляет строку user_balances; аргументы — UserBalanceArgs.
+const InsertUserBalance = `INSERT INTO user_balances (user_id, asset, free, locked, updated_at) VALUES ($1, $2, $3, $4, $5)`
+
+// UpdateUserBalance обновляет строку user_balances по id; аргументы — UserBalanceArgs и id.
+const UpdateUserBalance = `UPDATE user_balances SET user_id = $1, asset = $2, free = $3, locked = $4, updated_at = $5 WHERE id = $6`
+
+// UserBalanceReturning — колонки, которые назначает БД, в порядке UserBalanceReturningDest.
+const UserBalanceReturning = `id`
+
+// UserBalanceArgs возвращает аргументы InsertUserBalance.
+func UserBalanceArgs(v *domain.UserBalance) []interface{} {
+	return []interface{}{
+		v.UserID,
+		v.Asset,
+		v.Free,
+		v.Locked,
+		v.UpdatedAt,
+	}
+}
+
+// UserBalanceDest возвращает указатели на поля в порядке UserBalanceColumns.
+func UserBalanceDest(v *domain.UserBalance) []interface{} {
+	return []interface{}{
+		&v.ID,
+		&v.UserID,
+		&v.Asset,
+		&v.Free,
+		&v.Locked,
+		&v.UpdatedAt,
+	}
+}
+
+// UserBalanceReturningDest возвращает указатели на поля UserBalanceReturning.
+func UserBalanceReturningDest(v *domain.UserBalance) []interface{} {
+	return []interface{}{
+		&v.ID,
+	}
+}
+
+// ScanUserBalance читает строку, выбранную с UserBalanceColumns.
+func ScanUserBalance(row storage.RowInterface) (*domain.UserBalance, error) {
+	var v domain.UserBalance
+	if err := row.Scan(UserBalanceDest(&v)...); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
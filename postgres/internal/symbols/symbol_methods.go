@@ -0,0 +1,152 @@
+package symbols
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// SymbolStorage реализует интерфейс SymbolStorage.
+type SymbolStorage struct {
+	db storage.DBInterface
+}
+
+// NewSymbolStorage создает новый экземпляр SymbolStorage.
+func NewSymbolStorage(db storage.DBInterface) *SymbolStorage {
+	return &SymbolStorage{db: db}
+}
+
+const symbolColumns = `id, symbol, base_asset, quote_asset, status, tick_size, step_size,
+	min_qty, max_qty, min_notional, max_notional, COALESCE(order_types, ''), COALESCE(permissions, ''), updated_at`
+
+// UpsertSymbols создает или обновляет символы в одной транзакции.
+func (s *SymbolStorage) UpsertSymbols(ctx context.Context, symbols []*domain.Symbol) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		INSERT INTO symbols (
+			symbol, base_asset, quote_asset, status, tick_size, step_size,
+			min_qty, max_qty, min_notional, max_notional, order_types, permissions
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''))
+		ON CONFLICT (symbol) DO UPDATE SET
+			base_asset = EXCLUDED.base_asset,
+			quote_asset = EXCLUDED.quote_asset,
+			status = EXCLUDED.status,
+			tick_size = EXCLUDED.tick_size,
+			step_size = EXCLUDED.step_size,
+			min_qty = EXCLUDED.min_qty,
+			max_qty = EXCLUDED.max_qty,
+			min_notional = EXCLUDED.min_notional,
+			max_notional = EXCLUDED.max_notional,
+			order_types = EXCLUDED.order_types,
+			permissions = EXCLUDED.permissions,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, updated_at`
+
+	for _, sym := range symbols {
+		err = tx.QueryRowContext(ctx, query,
+			sym.Symbol,
+			sym.BaseAsset,
+			sym.QuoteAsset,
+			sym.Status,
+			sym.TickSize,
+			sym.StepSize,
+			sym.MinQty,
+			sym.MaxQty,
+			sym.MinNotional,
+			sym.MaxNotional,
+			sym.OrderTypes,
+			sym.Permissions,
+		).Scan(&sym.ID, &sym.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to upsert symbol %s: %w", sym.Symbol, postgreserr.Classify(err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+// GetSymbol получает символ по имени.
+func (s *SymbolStorage) GetSymbol(ctx context.Context, symbol string) (*domain.Symbol, error) {
+	query := `SELECT ` + symbolColumns + ` FROM symbols WHERE symbol = $1`
+
+	sym, err := scanSymbol(s.db.QueryRowContext(ctx, query, symbol))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("symbol %s not found: %w", symbol, postgreserr.ErrSymbolNotFound)
+		}
+		return nil, fmt.Errorf("failed to get symbol: %w", postgreserr.Classify(err))
+	}
+
+	return sym, nil
+}
+
+// GetSymbols получает все символы.
+func (s *SymbolStorage) GetSymbols(ctx context.Context) ([]*domain.Symbol, error) {
+	query := `SELECT ` + symbolColumns + ` FROM symbols ORDER BY symbol`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbols: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var symbols []*domain.Symbol
+	for rows.Next() {
+		sym, err := scanSymbol(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan symbol: %w", postgreserr.Classify(err))
+		}
+		symbols = append(symbols, sym)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating symbol rows: %w", postgreserr.Classify(err))
+	}
+
+	return symbols, nil
+}
+
+func scanSymbol(row storage.RowInterface) (*domain.Symbol, error) {
+	var sym domain.Symbol
+	err := row.Scan(
+		&sym.ID,
+		&sym.Symbol,
+		&sym.BaseAsset,
+		&sym.QuoteAsset,
+		&sym.Status,
+		&sym.TickSize,
+		&sym.StepSize,
+		&sym.MinQty,
+		&sym.MaxQty,
+		&sym.MinNotional,
+		&sym.MaxNotional,
+		&sym.OrderTypes,
+		&sym.Permissions,
+		&sym.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &sym, nil
+}
+
+// Ensure SymbolStorage implements SymbolStorage interface
+var _ storage.SymbolStorage = (*SymbolStorage)(nil)
@@ -0,0 +1,108 @@
+package symbols
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+var symbolRowColumns = []string{
+	"id", "symbol", "base_asset", "quote_asset", "status", "tick_size", "step_size",
+	"min_qty", "max_qty", "min_notional", "max_notional", "order_types", "permissions", "updated_at",
+}
+
+func TestSymbolStorage_UpsertSymbols(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("upserts in one transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSymbolStorage(storage.NewDBAdapter(db))
+		sym := &domain.Symbol{
+			Symbol:     "BTCUSDT",
+			BaseAsset:  "BTC",
+			QuoteAsset: "USDT",
+			Status:     domain.SymbolStatusEnabled,
+			TickSize:   decimal.RequireFromString("0.01"),
+			OrderTypes: `["LIMIT"]`,
+		}
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("ON CONFLICT \\(symbol\\) DO UPDATE").
+			WithArgs("BTCUSDT", "BTC", "USDT", domain.SymbolStatusEnabled, sym.TickSize, sym.StepSize,
+				sym.MinQty, sym.MaxQty, sym.MinNotional, sym.MaxNotional, `["LIMIT"]`, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(7, now))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.UpsertSymbols(ctx, []*domain.Symbol{sym}))
+		assert.Equal(t, uint64(7), sym.ID)
+		assert.Equal(t, now, sym.UpdatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSymbolStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO symbols").WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err = s.UpsertSymbols(ctx, []*domain.Symbol{{Symbol: "BTCUSDT"}})
+		assert.ErrorContains(t, err, "failed to upsert symbol BTCUSDT")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSymbolStorage_GetSymbol(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSymbolStorage(storage.NewDBAdapter(db))
+		mock.ExpectQuery("FROM symbols WHERE symbol = \\$1").
+			WithArgs("BTCUSDT").
+			WillReturnRows(sqlmock.NewRows(symbolRowColumns).AddRow(
+				1, "BTCUSDT", "BTC", "USDT", "ENABLED", "0.01", "0.000001",
+				"0.0001", "0", "5", "2000000", `["LIMIT"]`, `["SPOT"]`, time.Now()))
+
+		sym, err := s.GetSymbol(ctx, "BTCUSDT")
+		require.NoError(t, err)
+		assert.Equal(t, domain.SymbolStatusEnabled, sym.Status)
+		assert.True(t, decimal.NewFromInt(5).Equal(sym.MinNotional))
+		assert.True(t, sym.AllowsOrderType(domain.OrderTypeLimit))
+		assert.False(t, sym.AllowsOrderType(domain.OrderTypeMarket))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSymbolStorage(storage.NewDBAdapter(db))
+		mock.ExpectQuery("FROM symbols").WillReturnRows(sqlmock.NewRows(symbolRowColumns))
+
+		_, err = s.GetSymbol(ctx, "BTCUSDT")
+		assert.ErrorIs(t, err, postgreserr.ErrSymbolNotFound)
+	})
+}
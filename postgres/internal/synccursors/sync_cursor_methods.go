@@ -0,0 +1,125 @@
+package synccursors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// SyncCursorStorage реализует интерфейс SyncCursorStorage.
+type SyncCursorStorage struct {
+	db storage.DBInterface
+}
+
+// NewSyncCursorStorage создает новый экземпляр SyncCursorStorage.
+func NewSyncCursorStorage(db storage.DBInterface) *SyncCursorStorage {
+	return &SyncCursorStorage{db: db}
+}
+
+const syncCursorColumns = `id, user_id, stream, symbol, from_id, last_time, status, last_error, version, updated_at`
+
+// GetSyncCursor получает курсор синхронизации по пользователю, потоку и символу.
+func (s *SyncCursorStorage) GetSyncCursor(ctx context.Context, userID uint64, stream domain.SyncStream, symbol string) (*domain.SyncCursor, error) {
+	query := `SELECT ` + syncCursorColumns + ` FROM sync_cursors WHERE user_id = $1 AND stream = $2 AND symbol = $3`
+
+	cursor, err := scanSyncCursor(s.db.QueryRowContext(ctx, query, userID, stream, symbol))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("sync cursor %s %s not found: %w", stream, symbol, postgreserr.ErrSyncCursorNotFound)
+		}
+		return nil, fmt.Errorf("failed to get sync cursor: %w", postgreserr.Classify(err))
+	}
+
+	return cursor, nil
+}
+
+// GetSyncCursors получает все курсоры пользователя.
+func (s *SyncCursorStorage) GetSyncCursors(ctx context.Context, userID uint64) ([]*domain.SyncCursor, error) {
+	query := `SELECT ` + syncCursorColumns + ` FROM sync_cursors WHERE user_id = $1 ORDER BY stream, symbol`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync cursors: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var cursors []*domain.SyncCursor
+	for rows.Next() {
+		cursor, err := scanSyncCursor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync cursor: %w", postgreserr.Classify(err))
+		}
+		cursors = append(cursors, cursor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync cursor rows: %w", postgreserr.Classify(err))
+	}
+
+	return cursors, nil
+}
+
+// SaveSyncCursor создает или обновляет курсор с проверкой версии.
+// Обновление блокирует строку курсора до конца транзакции, поэтому одновременная
+// синхронизация того же потока дождется коммита и получит ErrSyncCursorConflict.
+func (s *SyncCursorStorage) SaveSyncCursor(ctx context.Context, cursor *domain.SyncCursor) error {
+	query := `
+		INSERT INTO sync_cursors (user_id, stream, symbol, from_id, last_time, status, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, stream, symbol) DO UPDATE SET
+			from_id = EXCLUDED.from_id,
+			last_time = EXCLUDED.last_time,
+			status = EXCLUDED.status,
+			last_error = EXCLUDED.last_error,
+			version = sync_cursors.version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE sync_cursors.version = $8
+		RETURNING id, version, updated_at`
+
+	err := s.db.QueryRowContext(ctx, query,
+		cursor.UserID,
+		cursor.Stream,
+		cursor.Symbol,
+		cursor.FromID,
+		cursor.LastTime,
+		cursor.Status,
+		cursor.LastError,
+		cursor.Version,
+	).Scan(&cursor.ID, &cursor.Version, &cursor.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("sync cursor %s %s (version %d): %w", cursor.Stream, cursor.Symbol, cursor.Version, postgreserr.ErrSyncCursorConflict)
+		}
+		return fmt.Errorf("failed to save sync cursor: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+func scanSyncCursor(row storage.RowInterface) (*domain.SyncCursor, error) {
+	var cursor domain.SyncCursor
+	err := row.Scan(
+		&cursor.ID,
+		&cursor.UserID,
+		&cursor.Stream,
+		&cursor.Symbol,
+		&cursor.FromID,
+		&cursor.LastTime,
+		&cursor.Status,
+		&cursor.LastError,
+		&cursor.Version,
+		&cursor.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// Ensure SyncCursorStorage implements SyncCursorStorage interface
+var _ storage.SyncCursorStorage = (*SyncCursorStorage)(nil)
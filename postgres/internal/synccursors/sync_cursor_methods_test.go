@@ -0,0 +1,102 @@
+package synccursors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+var syncCursorRowColumns = []string{
+	"id", "user_id", "stream", "symbol", "from_id", "last_time", "status", "last_error", "version", "updated_at",
+}
+
+func TestSyncCursorStorage_SaveSyncCursor(t *testing.T) {
+	ctx := context.Background()
+	lastTime := time.Now().Add(-time.Minute)
+
+	t.Run("saves with expected version", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSyncCursorStorage(storage.NewDBAdapter(db))
+		cursor := &domain.SyncCursor{
+			UserID:   1,
+			Stream:   domain.SyncStreamTrades,
+			Symbol:   "BTCUSDT",
+			FromID:   "42",
+			LastTime: &lastTime,
+			Status:   domain.SyncStatusOK,
+			Version:  3,
+		}
+		now := time.Now()
+
+		mock.ExpectQuery("ON CONFLICT \\(user_id, stream, symbol\\) DO UPDATE .* WHERE sync_cursors.version = \\$8").
+			WithArgs(uint64(1), domain.SyncStreamTrades, "BTCUSDT", "42", &lastTime, domain.SyncStatusOK, "", uint64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "version", "updated_at"}).AddRow(5, 4, now))
+
+		require.NoError(t, s.SaveSyncCursor(ctx, cursor))
+		assert.Equal(t, uint64(5), cursor.ID)
+		assert.Equal(t, uint64(4), cursor.Version)
+		assert.Equal(t, now, cursor.UpdatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stale version conflicts", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSyncCursorStorage(storage.NewDBAdapter(db))
+		mock.ExpectQuery("INSERT INTO sync_cursors").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "version", "updated_at"}))
+
+		err = s.SaveSyncCursor(ctx, &domain.SyncCursor{UserID: 1, Stream: domain.SyncStreamTrades, Symbol: "BTCUSDT", Version: 1})
+		assert.ErrorIs(t, err, postgreserr.ErrSyncCursorConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSyncCursorStorage_GetSyncCursor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSyncCursorStorage(storage.NewDBAdapter(db))
+		mock.ExpectQuery("FROM sync_cursors WHERE user_id = \\$1 AND stream = \\$2 AND symbol = \\$3").
+			WithArgs(uint64(1), domain.SyncStreamDeposits, "").
+			WillReturnRows(sqlmock.NewRows(syncCursorRowColumns).
+				AddRow(2, 1, "DEPOSITS", "", "", nil, "FAILED", "timeout", 7, time.Now()))
+
+		cursor, err := s.GetSyncCursor(ctx, 1, domain.SyncStreamDeposits, "")
+		require.NoError(t, err)
+		assert.Equal(t, domain.SyncStatusFailed, cursor.Status)
+		assert.Nil(t, cursor.LastTime)
+		assert.Equal(t, "timeout", cursor.LastError)
+		assert.Equal(t, uint64(7), cursor.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewSyncCursorStorage(storage.NewDBAdapter(db))
+		mock.ExpectQuery("FROM sync_cursors").WillReturnRows(sqlmock.NewRows(syncCursorRowColumns))
+
+		_, err = s.GetSyncCursor(ctx, 1, domain.SyncStreamTrades, "BTCUSDT")
+		assert.ErrorIs(t, err, postgreserr.ErrSyncCursorNotFound)
+	})
+}
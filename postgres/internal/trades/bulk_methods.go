@@ -0,0 +1,164 @@
+package trades
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/ledger"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowmap"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// copyBatchSize — число сделок, загружаемых в staging-таблицу за один шаг.
+// Ограничивает память под ответ INSERT ... RETURNING и проводки ledger.
+const copyBatchSize = 10000
+
+// stagingColumns — колонки trades, которые загружаются через COPY.
+var stagingColumns = strings.Split(rowmap.TradeInsertColumns, ", ")
+
+const (
+	// Временная таблица живет до конца транзакции и очищается после каждой пачки.
+	createStagingQuery = `
+		CREATE TEMP TABLE IF NOT EXISTS trades_staging ON COMMIT DROP AS
+		SELECT ` + rowmap.TradeInsertColumns + `
+		FROM trades WITH NO DATA`
+
+	insertFromStagingQuery = `
+		INSERT INTO trades (` + rowmap.TradeInsertColumns + `)
+		SELECT ` + rowmap.TradeInsertColumns + `
+		FROM trades_staging
+		ORDER BY trade_time, mexc_trade_id
+		ON CONFLICT (mexc_trade_id) DO NOTHING
+		RETURNING mexc_trade_id, ` + rowmap.TradeReturning
+
+	truncateStagingQuery = `TRUNCATE trades_staging`
+)
+
+// CreateTrades записывает сделки пачками по copyBatchSize: COPY во временную таблицу,
+// затем INSERT ... ON CONFLICT DO NOTHING. Новые сделки проводятся по ledger.
+// Вся загрузка идет в одной транзакции: при ошибке не записывается ни одна сделка.
+func (s *TradeStorage) CreateTrades(ctx context.Context, trades []*domain.Trade) (result storage.BulkInsertResult, err error) {
+	if len(trades) == 0 {
+		return result, nil
+	}
+	for _, trade := range trades {
+		if _, _, err := domain.SplitSymbol(trade.Symbol); err != nil {
+			return result, fmt.Errorf("failed to create trade %s: %w", trade.MexcTradeID, err)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, createStagingQuery); err != nil {
+		return result, fmt.Errorf("failed to create staging table: %w", postgreserr.Classify(err))
+	}
+
+	for start := 0; start < len(trades); start += copyBatchSize {
+		end := min(start+copyBatchSize, len(trades))
+
+		var inserted int
+		inserted, err = insertBatch(ctx, tx, trades[start:end])
+		if err != nil {
+			return storage.BulkInsertResult{}, err
+		}
+		result.Inserted += inserted
+	}
+	result.Skipped = len(trades) - result.Inserted
+
+	if err = tx.Commit(); err != nil {
+		return storage.BulkInsertResult{}, fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return result, nil
+}
+
+// insertBatch загружает пачку в trades_staging, переносит новые сделки в trades
+// и проводит их по ledger. Возвращает число записанных сделок.
+func insertBatch(ctx context.Context, tx storage.Tx, batch []*domain.Trade) (int, error) {
+	// Повторы внутри пачки отбрасываются заранее: записывается первое вхождение
+	byID := make(map[string]*domain.Trade, len(batch))
+	unique := make([]*domain.Trade, 0, len(batch))
+	for _, trade := range batch {
+		if _, ok := byID[trade.MexcTradeID]; ok {
+			continue
+		}
+		byID[trade.MexcTradeID] = trade
+		unique = append(unique, trade)
+	}
+
+	if err := copyTrades(ctx, tx, unique); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, insertFromStagingQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert trades: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var entries []*domain.LedgerEntry
+	inserted := 0
+	for rows.Next() {
+		var mexcTradeID string
+		var trade domain.Trade
+		if err := rows.Scan(append([]interface{}{&mexcTradeID}, rowmap.TradeReturningDest(&trade)...)...); err != nil {
+			return 0, fmt.Errorf("failed to scan inserted trade: %w", postgreserr.Classify(err))
+		}
+
+		original := byID[mexcTradeID]
+		original.ID, original.CreatedAt = trade.ID, trade.CreatedAt
+		tradeEntries, err := domain.TradeLedgerEntries(original)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create trade %s: %w", mexcTradeID, err)
+		}
+		entries = append(entries, tradeEntries...)
+		inserted++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to insert trades: %w", postgreserr.Classify(err))
+	}
+	rows.Close()
+
+	if err := ledger.CopyEntries(ctx, tx, entries); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, truncateStagingQuery); err != nil {
+		return 0, fmt.Errorf("failed to truncate staging table: %w", postgreserr.Classify(err))
+	}
+
+	return inserted, nil
+}
+
+// copyTrades загружает сделки в trades_staging через COPY.
+func copyTrades(ctx context.Context, tx storage.Tx, trades []*domain.Trade) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("trades_staging", stagingColumns...))
+	if err != nil {
+		return fmt.Errorf("failed to prepare trades copy: %w", postgreserr.Classify(err))
+	}
+	defer stmt.Close()
+
+	for _, trade := range trades {
+		if _, err = stmt.ExecContext(ctx, rowmap.TradeArgs(trade)...); err != nil {
+			return fmt.Errorf("failed to copy trade %s: %w", trade.MexcTradeID, postgreserr.Classify(err))
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to copy trades: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package trades
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func TestTradeStorage_CreateTrades(t *testing.T) {
+	ctx := context.Background()
+	tradeTime := time.Now().Add(-time.Hour)
+
+	newTrade := func(id string) *domain.Trade {
+		return &domain.Trade{
+			UserID:          1,
+			MexcTradeID:     id,
+			OrderID:         "order123",
+			Symbol:          "BTCUSDT",
+			Price:           decimal.NewFromFloat(50000),
+			Quantity:        decimal.NewFromFloat(0.01),
+			QuoteQuantity:   decimal.NewFromFloat(500),
+			Commission:      decimal.Zero,
+			CommissionAsset: "USDT",
+			TradeTime:       tradeTime,
+			IsBuyer:         true,
+		}
+	}
+	copyArgs := func(trade *domain.Trade) []driver.Value {
+		return []driver.Value{
+			trade.UserID, trade.MexcTradeID, trade.OrderID, trade.Symbol, trade.Price, trade.Quantity,
+			trade.QuoteQuantity, trade.Commission, trade.CommissionAsset, trade.TradeTime, trade.IsBuyer, trade.IsMaker,
+		}
+	}
+
+	t.Run("copies, skips existing and posts new trades", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		fresh, existing := newTrade("1"), newTrade("2")
+		repeated := newTrade("1")
+		createdAt := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMP TABLE IF NOT EXISTS trades_staging").WillReturnResult(sqlmock.NewResult(0, 0))
+		copyTrades := mock.ExpectPrepare(`COPY "trades_staging"`)
+		// Повтор внутри пачки в staging не попадает
+		copyTrades.ExpectExec().WithArgs(copyArgs(fresh)...).WillReturnResult(sqlmock.NewResult(0, 1))
+		copyTrades.ExpectExec().WithArgs(copyArgs(existing)...).WillReturnResult(sqlmock.NewResult(0, 1))
+		copyTrades.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO trades .* FROM trades_staging .* ON CONFLICT \\(mexc_trade_id\\) DO NOTHING").
+			WillReturnRows(sqlmock.NewRows([]string{"mexc_trade_id", "id", "created_at"}).AddRow("1", 10, createdAt))
+		copyLedger := mock.ExpectPrepare(`COPY "ledger_entries"`)
+		for _, entry := range []struct {
+			account domain.LedgerAccount
+			asset   string
+			amount  string
+		}{
+			{domain.LedgerAccountUser, "BTC", "0.01"},
+			{domain.LedgerAccountExchange, "BTC", "-0.01"},
+			{domain.LedgerAccountUser, "USDT", "-500"},
+			{domain.LedgerAccountExchange, "USDT", "500"},
+		} {
+			copyLedger.ExpectExec().
+				WithArgs(fresh.UserID, "trade:1", string(entry.account), entry.asset, decimal.RequireFromString(entry.amount),
+					string(domain.LedgerEntryTrade), "1", tradeTime).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		copyLedger.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("TRUNCATE trades_staging").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := s.CreateTrades(ctx, []*domain.Trade{fresh, existing, repeated})
+		require.NoError(t, err)
+		assert.Equal(t, storage.BulkInsertResult{Inserted: 1, Skipped: 2}, result)
+		assert.Equal(t, uint64(10), fresh.ID)
+		assert.Equal(t, createdAt, fresh.CreatedAt)
+		assert.Zero(t, existing.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insert error rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		trade := newTrade("1")
+
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMP TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		copyTrades := mock.ExpectPrepare(`COPY "trades_staging"`)
+		copyTrades.ExpectExec().WithArgs(copyArgs(trade)...).WillReturnResult(sqlmock.NewResult(0, 1))
+		copyTrades.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO trades").WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		result, err := s.CreateTrades(ctx, []*domain.Trade{trade})
+		assert.ErrorContains(t, err, "failed to insert trades")
+		assert.Zero(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown symbol fails before transaction", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		trade := newTrade("1")
+		trade.Symbol = "XYZ"
+
+		_, err = s.CreateTrades(ctx, []*domain.Trade{trade})
+		assert.ErrorIs(t, err, domain.ErrUnknownSymbol)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		s := NewTradeStorage(nil)
+
+		result, err := s.CreateTrades(ctx, nil)
+		require.NoError(t, err)
+		assert.Zero(t, result)
+	})
+}
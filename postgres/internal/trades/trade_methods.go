@@ -5,9 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
+	"iter"
+	"time"
 
 	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/keyset"
+	"github.com/samar/sup_bot/metacore/postgres/internal/ledger"
+	"github.com/samar/sup_bot/metacore/postgres/internal/query"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowiter"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowmap"
 	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
 	"github.com/samar/sup_bot/metacore/storage"
 )
@@ -25,71 +31,52 @@ func NewTradeStorage(db storage.DBInterface) *TradeStorage {
 	return &TradeStorage{db: db}
 }
 
-// CreateTrade создает новую сделку.
-func (s *TradeStorage) CreateTrade(ctx context.Context, trade *domain.Trade) error {
-	query := `
-		INSERT INTO trades (
-			user_id, mexc_trade_id, order_id, symbol, price, quantity,
-			quote_quantity, commission, commission_asset, trade_time,
-			is_buyer, is_maker
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
-		) RETURNING id, created_at`
-
-	err := s.db.QueryRowContext(ctx, query,
-		trade.UserID,
-		trade.MexcTradeID,
-		trade.OrderID,
-		trade.Symbol,
-		trade.Price,
-		trade.Quantity,
-		trade.QuoteQuantity,
-		trade.Commission,
-		trade.CommissionAsset,
-		trade.TradeTime,
-		trade.IsBuyer,
-		trade.IsMaker,
-	).Scan(&trade.ID, &trade.CreatedAt)
-
+// CreateTrade создает новую сделку и проводит ее по ledger в одной транзакции.
+func (s *TradeStorage) CreateTrade(ctx context.Context, trade *domain.Trade) (err error) {
+	entries, err := domain.TradeLedgerEntries(trade)
 	if err != nil {
 		return fmt.Errorf("failed to create trade: %w", err)
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := rowmap.InsertTrade + ` RETURNING ` + rowmap.TradeReturning
+
+	err = tx.QueryRowContext(ctx, query, rowmap.TradeArgs(trade)...).
+		Scan(rowmap.TradeReturningDest(trade)...)
+	if err != nil {
+		return fmt.Errorf("failed to create trade: %w", postgreserr.Classify(err))
+	}
+
+	if err = ledger.InsertEntries(ctx, tx, entries); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
 	return nil
 }
 
 // GetTradeByID получает сделку по MEXC Trade ID.
 func (s *TradeStorage) GetTradeByID(ctx context.Context, mexcTradeID string) (*domain.Trade, error) {
-	query := `
-		SELECT id, user_id, mexc_trade_id, order_id, symbol, price, quantity,
-			   quote_quantity, commission, commission_asset, trade_time,
-			   is_buyer, is_maker, created_at
-		FROM trades
-		WHERE mexc_trade_id = $1`
-
-	trade := &domain.Trade{}
-	err := s.db.QueryRowContext(ctx, query, mexcTradeID).Scan(
-		&trade.ID,
-		&trade.UserID,
-		&trade.MexcTradeID,
-		&trade.OrderID,
-		&trade.Symbol,
-		&trade.Price,
-		&trade.Quantity,
-		&trade.QuoteQuantity,
-		&trade.Commission,
-		&trade.CommissionAsset,
-		&trade.TradeTime,
-		&trade.IsBuyer,
-		&trade.IsMaker,
-		&trade.CreatedAt,
-	)
+	query := `SELECT ` + rowmap.TradeColumns + ` FROM trades WHERE mexc_trade_id = $1`
 
+	trade, err := rowmap.ScanTrade(s.db.QueryRowContext(ctx, query, mexcTradeID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, postgreserr.ErrTradeNotFound
 		}
-		return nil, fmt.Errorf("failed to get trade: %w", err)
+		return nil, fmt.Errorf("failed to get trade: %w", postgreserr.Classify(err))
 	}
 
 	return trade, nil
@@ -102,87 +89,197 @@ func (s *TradeStorage) GetUserTrades(ctx context.Context, userID uint64, filters
 		filter = filters[0]
 	}
 
-	// Базовый запрос
-	queryBuilder := strings.Builder{}
-	queryBuilder.WriteString(`
-		SELECT id, user_id, mexc_trade_id, order_id, symbol, price, quantity,
-			   quote_quantity, commission, commission_asset, trade_time,
-			   is_buyer, is_maker, created_at
-		FROM trades
-		WHERE user_id = $1`)
+	text, args, err := userTradesListQuery(userID, filter)
+	if err != nil {
+		return nil, err
+	}
 
-	args := []interface{}{userID}
-	argCount := 1
+	rows, err := s.db.QueryContext(ctx, text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user trades: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
+}
 
-	// Добавляем фильтры
-	if filter.Symbol != "" {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" AND symbol = $%d", argCount))
-		args = append(args, filter.Symbol)
+// IterUserTrades обходит сделки пользователя с фильтрацией, не загружая их в память.
+// Порядок и фильтры те же, что у GetUserTrades.
+func (s *TradeStorage) IterUserTrades(ctx context.Context, userID uint64, filters ...storage.TradeFilter) iter.Seq2[*domain.Trade, error] {
+	var filter storage.TradeFilter
+	if len(filters) > 0 {
+		filter = filters[0]
 	}
 
-	if filter.StartTime != nil {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" AND trade_time >= $%d", argCount))
-		args = append(args, *filter.StartTime)
+	text, args, err := userTradesListQuery(userID, filter)
+	if err != nil {
+		return rowiter.Err[*domain.Trade](err)
 	}
+	return rowiter.Query(ctx, s.db, "user trades", text, args, rowmap.ScanTrade)
+}
 
-	if filter.EndTime != nil {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" AND trade_time <= $%d", argCount))
-		args = append(args, *filter.EndTime)
+// GetUserTradesPage получает страницу сделок пользователя по курсору (trade_time, id).
+// Сортировка, Limit и Offset фильтра не используются.
+func (s *TradeStorage) GetUserTradesPage(ctx context.Context, userID uint64, filter storage.TradeFilter, page storage.PageRequest) (*storage.Page[*domain.Trade], error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	cursor, limit, err := page.Parse()
+	if err != nil {
+		return nil, err
 	}
 
-	// Сортировка - новые сделки первыми
-	queryBuilder.WriteString(" ORDER BY trade_time DESC, id DESC")
+	text, args := keyset.Apply(userTradesQuery(userID, filter), "trade_time", cursor, limit).Build()
+	rows, err := s.db.QueryContext(ctx, text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user trades: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
 
-	// Лимит и оффсет
-	if filter.Limit > 0 {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argCount))
-		args = append(args, filter.Limit)
+	trades, err := scanTrades(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	if filter.Offset > 0 {
-		argCount++
-		queryBuilder.WriteString(fmt.Sprintf(" OFFSET $%d", argCount))
-		args = append(args, filter.Offset)
+	return storage.NewPage(trades, cursor, limit, tradeKey), nil
+}
+
+// tradeSortColumns сопоставляет поля сортировки колонкам trades
+var tradeSortColumns = map[storage.SortField]string{
+	"":                     "trade_time",
+	storage.SortByTime:     "trade_time",
+	storage.SortByPrice:    "price",
+	storage.SortByQuantity: "quantity",
+}
+
+// userTradesListQuery строит запрос GetUserTrades: фильтр, сортировка, Limit и Offset.
+func userTradesListQuery(userID uint64, filter storage.TradeFilter) (string, []interface{}, error) {
+	if err := filter.Validate(); err != nil {
+		return "", nil, err
 	}
 
-	query := queryBuilder.String()
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	// По умолчанию новые сделки первыми
+	desc := !filter.SortAsc
+	text, args := userTradesQuery(userID, filter).
+		OrderBy(tradeSortColumns[filter.SortBy], desc).
+		OrderBy("id", desc).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Build()
+	return text, args, nil
+}
+
+// userTradesQuery строит SELECT сделок пользователя с условиями фильтра без сортировки и лимитов.
+func userTradesQuery(userID uint64, filter storage.TradeFilter) *query.Select {
+	q := query.From("trades", rowmap.TradeColumns).Where("user_id = ?", userID)
+	query.In(q, "symbol", filter.AllSymbols())
+	if filter.Side != "" {
+		q.Where("is_buyer = ?", filter.Side == domain.OrderSideBuy)
+	}
+	if filter.MinPrice != nil {
+		q.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		q.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.MinQuantity != nil {
+		q.Where("quantity >= ?", *filter.MinQuantity)
+	}
+	if filter.MaxQuantity != nil {
+		q.Where("quantity <= ?", *filter.MaxQuantity)
+	}
+	if filter.StartTime != nil {
+		q.Where("trade_time >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		q.Where("trade_time <= ?", *filter.EndTime)
+	}
+	return q
+}
+
+func tradeKey(t *domain.Trade) (time.Time, uint64) {
+	return t.TradeTime, t.ID
+}
+
+// GetTradesByOrderID получает сделки ордера по mexc_order_id.
+// Сортировка: trade_time ASC, id ASC — в порядке исполнения.
+func (s *TradeStorage) GetTradesByOrderID(ctx context.Context, orderID string) ([]*domain.Trade, error) {
+	query := `SELECT ` + rowmap.TradeColumns + ` FROM trades WHERE order_id = $1 ORDER BY trade_time, id`
+
+	rows, err := s.db.QueryContext(ctx, query, orderID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query user trades: %w", err)
+		return nil, fmt.Errorf("failed to query order trades: %w", postgreserr.Classify(err))
 	}
 	defer rows.Close()
 
-	var trades []*domain.Trade
+	return scanTrades(rows)
+}
+
+// GetFillMismatches находит ордера пользователя, у которых executed_quantity
+// или cummulative_quote_qty не совпадают с суммой их сделок.
+// Ордер без сделок совпадает, только если его исполненные объемы нулевые.
+func (s *TradeStorage) GetFillMismatches(ctx context.Context, userID uint64) ([]*domain.FillMismatch, error) {
+	query := `
+		SELECT o.mexc_order_id, o.user_id, o.symbol, o.status,
+		       COALESCE(o.executed_quantity, 0), COALESCE(o.cummulative_quote_qty, 0),
+		       COALESCE(t.quantity, 0), COALESCE(t.quote_quantity, 0), COALESCE(t.trade_count, 0)
+		FROM orders o
+		LEFT JOIN (
+			SELECT order_id, SUM(quantity) AS quantity, SUM(quote_quantity) AS quote_quantity, COUNT(*) AS trade_count
+			FROM trades
+			WHERE user_id = $1
+			GROUP BY order_id
+		) t ON t.order_id = o.mexc_order_id
+		WHERE o.user_id = $1
+		  AND (COALESCE(o.executed_quantity, 0) <> COALESCE(t.quantity, 0)
+		    OR COALESCE(o.cummulative_quote_qty, 0) <> COALESCE(t.quote_quantity, 0))
+		ORDER BY o.id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fill mismatches: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var mismatches []*domain.FillMismatch
 	for rows.Next() {
-		trade := &domain.Trade{}
+		m := &domain.FillMismatch{}
 		err := rows.Scan(
-			&trade.ID,
-			&trade.UserID,
-			&trade.MexcTradeID,
-			&trade.OrderID,
-			&trade.Symbol,
-			&trade.Price,
-			&trade.Quantity,
-			&trade.QuoteQuantity,
-			&trade.Commission,
-			&trade.CommissionAsset,
-			&trade.TradeTime,
-			&trade.IsBuyer,
-			&trade.IsMaker,
-			&trade.CreatedAt,
+			&m.OrderID,
+			&m.UserID,
+			&m.Symbol,
+			&m.Status,
+			&m.ExecutedQuantity,
+			&m.CummulativeQuoteQty,
+			&m.TradesQuantity,
+			&m.TradesQuoteQuantity,
+			&m.TradeCount,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan trade: %w", err)
+			return nil, fmt.Errorf("failed to scan fill mismatch: %w", postgreserr.Classify(err))
 		}
-		trades = append(trades, trade)
+		mismatches = append(mismatches, m)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating trade rows: %w", err)
+		return nil, fmt.Errorf("error iterating fill mismatch rows: %w", postgreserr.Classify(err))
+	}
+
+	return mismatches, nil
+}
+
+func scanTrades(rows *sql.Rows) ([]*domain.Trade, error) {
+	var trades []*domain.Trade
+	for rows.Next() {
+		trade, err := rowmap.ScanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trade: %w", postgreserr.Classify(err))
+		}
+		trades = append(trades, trade)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trade rows: %w", postgreserr.Classify(err))
 	}
 
 	return trades, nil
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -44,6 +45,7 @@ func TestTradeStorage_CreateTrade(t *testing.T) {
 			IsMaker:         false,
 		}
 
+		mock.ExpectBegin()
 		mock.ExpectQuery("INSERT INTO trades").
 			WithArgs(
 				trade.UserID,
@@ -60,6 +62,26 @@ func TestTradeStorage_CreateTrade(t *testing.T) {
 				trade.IsMaker,
 			).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, createdAt))
+		// Покупка: BTC приходит, USDT уходит, комиссия в USDT
+		for i, entry := range []struct {
+			account domain.LedgerAccount
+			asset   string
+			amount  string
+			kind    domain.LedgerEntryKind
+		}{
+			{domain.LedgerAccountUser, "BTC", "0.01", domain.LedgerEntryTrade},
+			{domain.LedgerAccountExchange, "BTC", "-0.01", domain.LedgerEntryTrade},
+			{domain.LedgerAccountUser, "USDT", "-500", domain.LedgerEntryTrade},
+			{domain.LedgerAccountExchange, "USDT", "500", domain.LedgerEntryTrade},
+			{domain.LedgerAccountUser, "USDT", "-0.5", domain.LedgerEntryCommission},
+			{domain.LedgerAccountFees, "USDT", "0.5", domain.LedgerEntryCommission},
+		} {
+			mock.ExpectQuery("INSERT INTO ledger_entries").
+				WithArgs(trade.UserID, "trade:123456", entry.account, entry.asset,
+					decimal.RequireFromString(entry.amount), entry.kind, "123456", trade.TradeTime).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(i+1, createdAt))
+		}
+		mock.ExpectCommit()
 
 		err = s.CreateTrade(ctx, trade)
 		assert.NoError(t, err)
@@ -68,6 +90,47 @@ func TestTradeStorage_CreateTrade(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("ledger error rolls back the trade", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		trade := &domain.Trade{
+			UserID:          1,
+			MexcTradeID:     "123456",
+			Symbol:          "BTCUSDT",
+			Quantity:        decimal.NewFromFloat(0.01),
+			QuoteQuantity:   decimal.NewFromFloat(500),
+			CommissionAsset: "USDT",
+			TradeTime:       time.Now(),
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO trades").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+		mock.ExpectQuery("INSERT INTO ledger_entries").
+			WillReturnError(errors.New("ledger error"))
+		mock.ExpectRollback()
+
+		err = s.CreateTrade(ctx, trade)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create ledger entry trade:123456")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown symbol", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+
+		err = s.CreateTrade(ctx, &domain.Trade{UserID: 1, MexcTradeID: "123456", Symbol: "XYZ"})
+		assert.ErrorIs(t, err, domain.ErrUnknownSymbol)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("database error", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		assert.NoError(t, err)
@@ -91,9 +154,11 @@ func TestTradeStorage_CreateTrade(t *testing.T) {
 			IsMaker:         false,
 		}
 
+		mock.ExpectBegin()
 		mock.ExpectQuery("INSERT INTO trades").
 			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
 
 		err = s.CreateTrade(ctx, trade)
 		assert.Error(t, err)
@@ -256,6 +321,33 @@ func TestTradeStorage_GetUserTrades(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("extended filter and sort", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		maxPrice, minQty := decimal.NewFromInt(60000), decimal.RequireFromString("0.1")
+
+		mock.ExpectQuery(`FROM trades WHERE user_id = \$1 AND symbol = ANY\(\$2\) AND is_buyer = \$3 AND price <= \$4 `+
+			`AND quantity >= \$5 ORDER BY quantity DESC, id DESC OFFSET \$6`).
+			WithArgs(uint64(1), `{"BTCUSDT","ETHUSDT"}`, false, maxPrice, minQty, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		trades, err := s.GetUserTrades(ctx, 1, TradeFilter{
+			Symbol:      "ETHUSDT",
+			Symbols:     []string{"BTCUSDT"},
+			Side:        domain.OrderSideSell,
+			MaxPrice:    &maxPrice,
+			MinQuantity: &minQty,
+			SortBy:      storage.SortByQuantity,
+			Offset:      10,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, trades)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("query error", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
 		assert.NoError(t, err)
@@ -308,3 +400,217 @@ func TestTradeStorage_GetUserTrades(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestTradeStorage_GetUserTradesPage(t *testing.T) {
+	ctx := context.Background()
+	columns := []string{
+		"id", "user_id", "mexc_trade_id", "order_id", "symbol", "price", "quantity",
+		"quote_quantity", "commission", "commission_asset", "trade_time",
+		"is_buyer", "is_maker", "created_at",
+	}
+	tradeTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	addTrade := func(rows *sqlmock.Rows, id int) *sqlmock.Rows {
+		return rows.AddRow(
+			id, 1, "t"+strconv.Itoa(id), "order123", "BTCUSDT", decimal.NewFromInt(50000), decimal.NewFromFloat(0.01),
+			decimal.NewFromInt(500), decimal.NewFromFloat(0.5), "USDT", tradeTime,
+			true, false, tradeTime,
+		)
+	}
+
+	t.Run("older page by cursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		cursor := storage.Cursor{Time: tradeTime, ID: 10}
+
+		rows := addTrade(addTrade(addTrade(sqlmock.NewRows(columns), 9), 8), 7)
+		mock.ExpectQuery(`FROM trades WHERE user_id = \$1 AND symbol = \$2 AND \(trade_time, id\) < \(\$3, \$4\) ORDER BY trade_time DESC, id DESC LIMIT \$5`).
+			WithArgs(uint64(1), "BTCUSDT", tradeTime, uint64(10), 3).
+			WillReturnRows(rows)
+
+		page, err := s.GetUserTradesPage(ctx, 1, TradeFilter{Symbol: "BTCUSDT", Limit: 100, Offset: 5},
+			storage.PageRequest{Cursor: cursor.Encode(), Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, uint64(9), page.Items[0].ID)
+		assert.Equal(t, storage.Cursor{Time: tradeTime, ID: 8}.Encode(), page.NextCursor)
+		assert.Equal(t, storage.Cursor{Time: tradeTime, ID: 9, Newer: true}.Encode(), page.PrevCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("newer page is returned newest first", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		cursor := storage.Cursor{Time: tradeTime, ID: 7, Newer: true}
+
+		rows := addTrade(addTrade(sqlmock.NewRows(columns), 8), 9)
+		mock.ExpectQuery(`AND \(trade_time, id\) > \(\$2, \$3\) ORDER BY trade_time ASC, id ASC LIMIT \$4`).
+			WithArgs(uint64(1), tradeTime, uint64(7), 3).
+			WillReturnRows(rows)
+
+		page, err := s.GetUserTradesPage(ctx, 1, TradeFilter{}, storage.PageRequest{Cursor: cursor.Encode(), Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, uint64(9), page.Items[0].ID)
+		assert.Equal(t, storage.Cursor{Time: tradeTime, ID: 8}.Encode(), page.NextCursor)
+		assert.Empty(t, page.PrevCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+
+		_, err = s.GetUserTradesPage(ctx, 1, TradeFilter{}, storage.PageRequest{Cursor: "garbage"})
+		assert.ErrorIs(t, err, storage.ErrInvalidCursor)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTradeStorage_IterUserTrades(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewTradeStorage(storage.NewDBAdapter(db))
+	tradeTime := time.Now()
+
+	rows := sqlmock.NewRows([]string{
+		"id", "user_id", "mexc_trade_id", "order_id", "symbol", "price", "quantity",
+		"quote_quantity", "commission", "commission_asset", "trade_time",
+		"is_buyer", "is_maker", "created_at",
+	})
+	for _, id := range []int{3, 2, 1} {
+		rows.AddRow(
+			id, 1, "t"+strconv.Itoa(id), "order123", "BTCUSDT", decimal.NewFromInt(50000), decimal.NewFromFloat(0.01),
+			decimal.NewFromInt(500), decimal.NewFromFloat(0.5), "USDT", tradeTime,
+			true, false, tradeTime,
+		)
+	}
+
+	mock.ExpectQuery(`FROM trades WHERE user_id = \$1 AND symbol = \$2 ORDER BY trade_time DESC, id DESC`).
+		WithArgs(uint64(1), "BTCUSDT").
+		WillReturnRows(rows).
+		RowsWillBeClosed()
+
+	var ids []string
+	for trade, err := range s.IterUserTrades(ctx, 1, TradeFilter{Symbol: "BTCUSDT"}) {
+		assert.NoError(t, err)
+		ids = append(ids, trade.MexcTradeID)
+		if len(ids) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"t3", "t2"}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTradeStorage_GetTradesByOrderID(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful get", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+		tradeTime := time.Now()
+
+		rows := sqlmock.NewRows([]string{
+			"id", "user_id", "mexc_trade_id", "order_id", "symbol", "price", "quantity",
+			"quote_quantity", "commission", "commission_asset", "trade_time",
+			"is_buyer", "is_maker", "created_at",
+		}).AddRow(
+			1, 1, "t1", "order123", "BTCUSDT", decimal.NewFromFloat(50000), decimal.NewFromFloat(0.01),
+			decimal.NewFromFloat(500), decimal.NewFromFloat(0.5), "USDT", tradeTime,
+			true, false, tradeTime,
+		).AddRow(
+			2, 1, "t2", "order123", "BTCUSDT", decimal.NewFromFloat(51000), decimal.NewFromFloat(0.01),
+			decimal.NewFromFloat(510), decimal.NewFromFloat(0.51), "USDT", tradeTime,
+			true, false, tradeTime,
+		)
+
+		mock.ExpectQuery("FROM trades WHERE order_id = \\$1 ORDER BY trade_time, id").
+			WithArgs("order123").
+			WillReturnRows(rows)
+
+		trades, err := s.GetTradesByOrderID(ctx, "order123")
+		assert.NoError(t, err)
+		assert.Len(t, trades, 2)
+		assert.Equal(t, "t1", trades[0].MexcTradeID)
+		assert.Equal(t, "order123", trades[1].OrderID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectQuery("FROM trades WHERE order_id").
+			WillReturnError(errors.New("query error"))
+
+		trades, err := s.GetTradesByOrderID(ctx, "order123")
+		assert.Error(t, err)
+		assert.Nil(t, trades)
+		assert.Contains(t, err.Error(), "failed to query order trades")
+	})
+}
+
+func TestTradeStorage_GetFillMismatches(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful get", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+
+		rows := sqlmock.NewRows([]string{
+			"mexc_order_id", "user_id", "symbol", "status", "executed_quantity", "cummulative_quote_qty",
+			"quantity", "quote_quantity", "trade_count",
+		}).AddRow("order123", 1, "BTCUSDT", "FILLED", "0.02", "1000", "0.01", "500", 1)
+
+		mock.ExpectQuery("FROM orders o\\s+LEFT JOIN").
+			WithArgs(uint64(1)).
+			WillReturnRows(rows)
+
+		mismatches, err := s.GetFillMismatches(ctx, 1)
+		assert.NoError(t, err)
+		assert.Len(t, mismatches, 1)
+		assert.Equal(t, "order123", mismatches[0].OrderID)
+		assert.Equal(t, domain.OrderStatusFilled, mismatches[0].Status)
+		assert.True(t, decimal.RequireFromString("0.02").Equal(mismatches[0].ExecutedQuantity))
+		assert.True(t, decimal.RequireFromString("0.01").Equal(mismatches[0].TradesQuantity))
+		assert.Equal(t, 1, mismatches[0].TradeCount)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		s := NewTradeStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectQuery("FROM orders o").
+			WillReturnError(sql.ErrConnDone)
+
+		_, err = s.GetFillMismatches(ctx, 1)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.Contains(t, err.Error(), "failed to query fill mismatches")
+	})
+}
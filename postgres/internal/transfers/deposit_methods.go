@@ -0,0 +1,153 @@
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// DepositStorage реализует интерфейс DepositStorage.
+type DepositStorage struct {
+	db storage.DBInterface
+}
+
+// NewDepositStorage создает новый экземпляр DepositStorage.
+func NewDepositStorage(db storage.DBInterface) *DepositStorage {
+	return &DepositStorage{db: db}
+}
+
+const depositColumns = `id, user_id, mexc_deposit_id, asset, network, amount, fee,
+	address, memo, tx_id, status, insert_time, created_at, updated_at`
+
+// UpsertDeposits создает или обновляет вводы в одной транзакции
+// и проводит по ledger вводы в статусе SUCCESS.
+func (s *DepositStorage) UpsertDeposits(ctx context.Context, deposits []*domain.Deposit) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		INSERT INTO deposits (
+			user_id, mexc_deposit_id, asset, network, amount, fee,
+			address, memo, tx_id, status, insert_time
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (mexc_deposit_id) DO UPDATE SET
+			network = EXCLUDED.network,
+			amount = EXCLUDED.amount,
+			fee = EXCLUDED.fee,
+			address = EXCLUDED.address,
+			memo = EXCLUDED.memo,
+			tx_id = EXCLUDED.tx_id,
+			status = EXCLUDED.status,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at`
+
+	for _, d := range deposits {
+		err = tx.QueryRowContext(ctx, query,
+			d.UserID,
+			d.MexcDepositID,
+			d.Asset,
+			d.Network,
+			d.Amount,
+			d.Fee,
+			d.Address,
+			d.Memo,
+			d.TxID,
+			d.Status,
+			d.InsertTime,
+		).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to upsert deposit %s: %w", d.MexcDepositID, postgreserr.Classify(err))
+		}
+
+		if err = postSucceeded(ctx, tx, d.Status, d.LedgerEntries()); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+// GetDepositByID получает ввод по MEXC ID.
+func (s *DepositStorage) GetDepositByID(ctx context.Context, mexcDepositID string) (*domain.Deposit, error) {
+	query := `SELECT ` + depositColumns + ` FROM deposits WHERE mexc_deposit_id = $1`
+
+	d, err := scanDeposit(s.db.QueryRowContext(ctx, query, mexcDepositID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, postgreserr.ErrDepositNotFound
+		}
+		return nil, fmt.Errorf("failed to get deposit: %w", postgreserr.Classify(err))
+	}
+
+	return d, nil
+}
+
+// GetUserDeposits получает вводы пользователя с фильтрацией.
+// Сортировка: insert_time DESC, id DESC.
+func (s *DepositStorage) GetUserDeposits(ctx context.Context, userID uint64, filters ...storage.TransferFilter) ([]*domain.Deposit, error) {
+	text, args := userTransfersQuery("deposits", depositColumns, "insert_time", userID, filters)
+
+	rows, err := s.db.QueryContext(ctx, text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user deposits: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var deposits []*domain.Deposit
+	for rows.Next() {
+		d, err := scanDeposit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deposit: %w", postgreserr.Classify(err))
+		}
+		deposits = append(deposits, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deposit rows: %w", postgreserr.Classify(err))
+	}
+
+	return deposits, nil
+}
+
+func scanDeposit(row storage.RowInterface) (*domain.Deposit, error) {
+	var d domain.Deposit
+	err := row.Scan(
+		&d.ID,
+		&d.UserID,
+		&d.MexcDepositID,
+		&d.Asset,
+		&d.Network,
+		&d.Amount,
+		&d.Fee,
+		&d.Address,
+		&d.Memo,
+		&d.TxID,
+		&d.Status,
+		&d.InsertTime,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Ensure DepositStorage implements DepositStorage interface
+var _ storage.DepositStorage = (*DepositStorage)(nil)
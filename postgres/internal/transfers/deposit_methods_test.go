@@ -0,0 +1,158 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+var depositRowColumns = []string{
+	"id", "user_id", "mexc_deposit_id", "asset", "network", "amount", "fee",
+	"address", "memo", "tx_id", "status", "insert_time", "created_at", "updated_at",
+}
+
+func TestDepositStorage_UpsertDeposits(t *testing.T) {
+	ctx := context.Background()
+	insertTime := time.Now().Add(-time.Hour)
+
+	newDeposit := func(status domain.TransferStatus) *domain.Deposit {
+		return &domain.Deposit{
+			UserID:        1,
+			MexcDepositID: "d1",
+			Asset:         "USDT",
+			Network:       "TRC20",
+			Amount:        decimal.NewFromInt(100),
+			Address:       "TXaddr",
+			TxID:          "0xabc",
+			Status:        status,
+			InsertTime:    insertTime,
+		}
+	}
+	expectUpsert := func(mock sqlmock.Sqlmock, d *domain.Deposit) {
+		mock.ExpectQuery("INSERT INTO deposits .* ON CONFLICT \\(mexc_deposit_id\\) DO UPDATE").
+			WithArgs(d.UserID, d.MexcDepositID, d.Asset, d.Network, d.Amount, d.Fee,
+				d.Address, d.Memo, d.TxID, d.Status, d.InsertTime).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(3, time.Now(), time.Now()))
+	}
+
+	t.Run("pending deposit is not posted", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewDepositStorage(storage.NewDBAdapter(db))
+		d := newDeposit(domain.TransferStatusPending)
+
+		mock.ExpectBegin()
+		expectUpsert(mock, d)
+		mock.ExpectCommit()
+
+		require.NoError(t, s.UpsertDeposits(ctx, []*domain.Deposit{d}))
+		assert.Equal(t, uint64(3), d.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("successful deposit is posted once", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewDepositStorage(storage.NewDBAdapter(db))
+		d := newDeposit(domain.TransferStatusSuccess)
+
+		mock.ExpectBegin()
+		expectUpsert(mock, d)
+		mock.ExpectQuery("SELECT EXISTS").WithArgs("deposit:d1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		for _, e := range []struct {
+			account domain.LedgerAccount
+			amount  string
+		}{
+			{domain.LedgerAccountUser, "100"},
+			{domain.LedgerAccountExternal, "-100"},
+		} {
+			mock.ExpectQuery("INSERT INTO ledger_entries").
+				WithArgs(d.UserID, "deposit:d1", e.account, "USDT", decimal.RequireFromString(e.amount),
+					domain.LedgerEntryDeposit, "d1", insertTime).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		}
+		// Повторная загрузка: проводки уже есть
+		expectUpsert(mock, d)
+		mock.ExpectQuery("SELECT EXISTS").WithArgs("deposit:d1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectCommit()
+
+		require.NoError(t, s.UpsertDeposits(ctx, []*domain.Deposit{d, d}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewDepositStorage(storage.NewDBAdapter(db))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO deposits").WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		err = s.UpsertDeposits(ctx, []*domain.Deposit{newDeposit(domain.TransferStatusSuccess)})
+		assert.ErrorContains(t, err, "failed to upsert deposit d1")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDepositStorage_GetUserDeposits(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewDepositStorage(storage.NewDBAdapter(db))
+	start := time.Now().Add(-24 * time.Hour)
+
+	mock.ExpectQuery("FROM deposits WHERE user_id = \\$1 AND asset = \\$2 AND status = \\$3 AND insert_time >= \\$4 "+
+		"ORDER BY insert_time DESC, id DESC LIMIT \\$5 OFFSET \\$6").
+		WithArgs(uint64(1), "USDT", domain.TransferStatusSuccess, start, 10, 20).
+		WillReturnRows(sqlmock.NewRows(depositRowColumns).
+			AddRow(3, 1, "d1", "USDT", "TRC20", "100", "0", "TXaddr", "", "0xabc", "SUCCESS", time.Now(), time.Now(), time.Now()))
+
+	deposits, err := s.GetUserDeposits(ctx, 1, storage.TransferFilter{
+		Asset:     "USDT",
+		Status:    domain.TransferStatusSuccess,
+		StartTime: &start,
+		Limit:     10,
+		Offset:    20,
+	})
+	require.NoError(t, err)
+	require.Len(t, deposits, 1)
+	assert.Equal(t, "d1", deposits[0].MexcDepositID)
+	assert.True(t, decimal.NewFromInt(100).Equal(deposits[0].Amount))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositStorage_GetDepositByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewDepositStorage(storage.NewDBAdapter(db))
+	mock.ExpectQuery("FROM deposits WHERE mexc_deposit_id = \\$1").
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(depositRowColumns))
+
+	_, err = s.GetDepositByID(context.Background(), "missing")
+	assert.ErrorIs(t, err, postgreserr.ErrDepositNotFound)
+}
@@ -0,0 +1,64 @@
+package transfers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/ledger"
+	"github.com/samar/sup_bot/metacore/postgres/internal/query"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// userTransfersQuery строит запрос операций пользователя с фильтрами, сортировкой
+// по timeColumn (новые первыми) и пагинацией.
+func userTransfersQuery(table, columns, timeColumn string, userID uint64, filters []storage.TransferFilter) (string, []interface{}) {
+	var filter storage.TransferFilter
+	if len(filters) > 0 {
+		filter = filters[0]
+	}
+
+	q := query.From(table, columns).Where("user_id = ?", userID)
+	if filter.Asset != "" {
+		q.Where("asset = ?", filter.Asset)
+	}
+	if filter.Status != "" {
+		q.Where("status = ?", filter.Status)
+	}
+	if filter.StartTime != nil {
+		q.Where(timeColumn+" >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		q.Where(timeColumn+" <= ?", *filter.EndTime)
+	}
+
+	return q.OrderBy(timeColumn, true).
+		OrderBy("id", true).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Build()
+}
+
+// postSucceeded проводит завершенную операцию по ledger, если она еще не проведена.
+// Upsert операции блокирует ее строку до конца транзакции, поэтому одновременные
+// обновления одной операции не проведут ее дважды.
+func postSucceeded(ctx context.Context, tx storage.Tx, status domain.TransferStatus, entries []*domain.LedgerEntry) error {
+	if status != domain.TransferStatusSuccess || len(entries) == 0 {
+		return nil
+	}
+
+	var posted bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE transaction_id = $1)`,
+		entries[0].TransactionID,
+	).Scan(&posted)
+	if err != nil {
+		return fmt.Errorf("failed to check ledger entries %s: %w", entries[0].TransactionID, postgreserr.Classify(err))
+	}
+	if posted {
+		return nil
+	}
+
+	return ledger.InsertEntries(ctx, tx, entries)
+}
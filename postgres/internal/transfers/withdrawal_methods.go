@@ -0,0 +1,153 @@
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// WithdrawalStorage реализует интерфейс WithdrawalStorage.
+type WithdrawalStorage struct {
+	db storage.DBInterface
+}
+
+// NewWithdrawalStorage создает новый экземпляр WithdrawalStorage.
+func NewWithdrawalStorage(db storage.DBInterface) *WithdrawalStorage {
+	return &WithdrawalStorage{db: db}
+}
+
+const withdrawalColumns = `id, user_id, mexc_withdrawal_id, asset, network, amount, fee,
+	address, memo, tx_id, status, apply_time, created_at, updated_at`
+
+// UpsertWithdrawals создает или обновляет выводы в одной транзакции
+// и проводит по ledger выводы в статусе SUCCESS.
+func (s *WithdrawalStorage) UpsertWithdrawals(ctx context.Context, withdrawals []*domain.Withdrawal) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		INSERT INTO withdrawals (
+			user_id, mexc_withdrawal_id, asset, network, amount, fee,
+			address, memo, tx_id, status, apply_time
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (mexc_withdrawal_id) DO UPDATE SET
+			network = EXCLUDED.network,
+			amount = EXCLUDED.amount,
+			fee = EXCLUDED.fee,
+			address = EXCLUDED.address,
+			memo = EXCLUDED.memo,
+			tx_id = EXCLUDED.tx_id,
+			status = EXCLUDED.status,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at`
+
+	for _, w := range withdrawals {
+		err = tx.QueryRowContext(ctx, query,
+			w.UserID,
+			w.MexcWithdrawalID,
+			w.Asset,
+			w.Network,
+			w.Amount,
+			w.Fee,
+			w.Address,
+			w.Memo,
+			w.TxID,
+			w.Status,
+			w.ApplyTime,
+		).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to upsert withdrawal %s: %w", w.MexcWithdrawalID, postgreserr.Classify(err))
+		}
+
+		if err = postSucceeded(ctx, tx, w.Status, w.LedgerEntries()); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return nil
+}
+
+// GetWithdrawalByID получает вывод по MEXC ID.
+func (s *WithdrawalStorage) GetWithdrawalByID(ctx context.Context, mexcWithdrawalID string) (*domain.Withdrawal, error) {
+	query := `SELECT ` + withdrawalColumns + ` FROM withdrawals WHERE mexc_withdrawal_id = $1`
+
+	w, err := scanWithdrawal(s.db.QueryRowContext(ctx, query, mexcWithdrawalID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, postgreserr.ErrWithdrawalNotFound
+		}
+		return nil, fmt.Errorf("failed to get withdrawal: %w", postgreserr.Classify(err))
+	}
+
+	return w, nil
+}
+
+// GetUserWithdrawals получает выводы пользователя с фильтрацией.
+// Сортировка: apply_time DESC, id DESC.
+func (s *WithdrawalStorage) GetUserWithdrawals(ctx context.Context, userID uint64, filters ...storage.TransferFilter) ([]*domain.Withdrawal, error) {
+	text, args := userTransfersQuery("withdrawals", withdrawalColumns, "apply_time", userID, filters)
+
+	rows, err := s.db.QueryContext(ctx, text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user withdrawals: %w", postgreserr.Classify(err))
+	}
+	defer rows.Close()
+
+	var withdrawals []*domain.Withdrawal
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan withdrawal: %w", postgreserr.Classify(err))
+		}
+		withdrawals = append(withdrawals, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating withdrawal rows: %w", postgreserr.Classify(err))
+	}
+
+	return withdrawals, nil
+}
+
+func scanWithdrawal(row storage.RowInterface) (*domain.Withdrawal, error) {
+	var w domain.Withdrawal
+	err := row.Scan(
+		&w.ID,
+		&w.UserID,
+		&w.MexcWithdrawalID,
+		&w.Asset,
+		&w.Network,
+		&w.Amount,
+		&w.Fee,
+		&w.Address,
+		&w.Memo,
+		&w.TxID,
+		&w.Status,
+		&w.ApplyTime,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Ensure WithdrawalStorage implements WithdrawalStorage interface
+var _ storage.WithdrawalStorage = (*WithdrawalStorage)(nil)
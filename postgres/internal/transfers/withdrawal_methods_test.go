@@ -0,0 +1,80 @@
+package transfers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func TestWithdrawalStorage_UpsertWithdrawals(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewWithdrawalStorage(storage.NewDBAdapter(db))
+	applyTime := time.Now().Add(-time.Hour)
+	w := &domain.Withdrawal{
+		UserID:           1,
+		MexcWithdrawalID: "w1",
+		Asset:            "USDT",
+		Network:          "TRC20",
+		Amount:           decimal.NewFromInt(40),
+		Fee:              decimal.NewFromInt(1),
+		Address:          "TXout",
+		Status:           domain.TransferStatusSuccess,
+		ApplyTime:        applyTime,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO withdrawals .* ON CONFLICT \\(mexc_withdrawal_id\\) DO UPDATE").
+		WithArgs(w.UserID, w.MexcWithdrawalID, w.Asset, w.Network, w.Amount, w.Fee,
+			w.Address, w.Memo, w.TxID, w.Status, w.ApplyTime).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, time.Now(), time.Now()))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("withdrawal:w1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	// Вывод и комиссия списываются со счета USER
+	for _, e := range []struct {
+		account domain.LedgerAccount
+		amount  string
+		kind    domain.LedgerEntryKind
+	}{
+		{domain.LedgerAccountUser, "-40", domain.LedgerEntryWithdrawal},
+		{domain.LedgerAccountExternal, "40", domain.LedgerEntryWithdrawal},
+		{domain.LedgerAccountUser, "-1", domain.LedgerEntryCommission},
+		{domain.LedgerAccountFees, "1", domain.LedgerEntryCommission},
+	} {
+		mock.ExpectQuery("INSERT INTO ledger_entries").
+			WithArgs(w.UserID, "withdrawal:w1", e.account, "USDT", decimal.RequireFromString(e.amount), e.kind, "w1", applyTime).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	}
+	mock.ExpectCommit()
+
+	require.NoError(t, s.UpsertWithdrawals(ctx, []*domain.Withdrawal{w}))
+	assert.Equal(t, uint64(4), w.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithdrawalStorage_GetWithdrawalByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewWithdrawalStorage(storage.NewDBAdapter(db))
+	mock.ExpectQuery("FROM withdrawals WHERE mexc_withdrawal_id = \\$1").
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = s.GetWithdrawalByID(context.Background(), "missing")
+	assert.ErrorIs(t, err, postgreserr.ErrWithdrawalNotFound)
+}
@@ -0,0 +1,188 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowmap"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/secrets"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// credentials — ключи MEXC API в том виде, в котором они хранятся в строке users.
+// keyVersion = secrets.PlaintextVersion означает, что ключи не зашифрованы.
+type credentials struct {
+	apiKey     string
+	secretKey  string
+	keyVersion int
+	dataKey    []byte
+}
+
+// sealCredentials шифрует ключи пользователя текущим мастер-ключом.
+func (s *UserStorage) sealCredentials(ctx context.Context, user *domain.User) (*credentials, error) {
+	if s.cipher == nil {
+		return &credentials{apiKey: user.MexcAPIKey, secretKey: user.MexcSecretKey}, nil
+	}
+
+	sealed, err := s.cipher.Seal(ctx, user.MexcAPIKey, user.MexcSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+
+	return &credentials{
+		apiKey:     sealed.Fields[0],
+		secretKey:  sealed.Fields[1],
+		keyVersion: sealed.KeyVersion,
+		dataKey:    sealed.DataKey,
+	}, nil
+}
+
+// openCredentials расшифровывает ключи строки users.
+func (s *UserStorage) openCredentials(ctx context.Context, creds *credentials) (apiKey, secretKey string, err error) {
+	if creds.keyVersion == secrets.PlaintextVersion {
+		return creds.apiKey, creds.secretKey, nil
+	}
+	if s.cipher == nil {
+		return "", "", fmt.Errorf("credentials are encrypted with key version %d but no key provider is configured: %w",
+			creds.keyVersion, secrets.ErrKeyNotFound)
+	}
+
+	fields, err := s.cipher.Open(ctx, creds.keyVersion, creds.dataKey, creds.apiKey, creds.secretKey)
+	if err != nil {
+		return "", "", err
+	}
+	return fields[0], fields[1], nil
+}
+
+// userArgs возвращает аргументы rowmap.InsertUser, подставляя ключи в том виде,
+// в котором они хранятся в БД.
+func userArgs(user *domain.User, creds *credentials) []interface{} {
+	stored := *user
+	stored.MexcAPIKey, stored.MexcSecretKey = creds.apiKey, creds.secretKey
+	return rowmap.UserArgs(&stored, creds.keyVersion, creds.dataKey)
+}
+
+// scanUser читает строку с колонками rowmap.UserColumns и расшифровывает ключи.
+func (s *UserStorage) scanUser(ctx context.Context, row storage.RowInterface) (*domain.User, error) {
+	var user domain.User
+	var creds credentials
+
+	err := row.Scan(append(rowmap.UserDest(&user), &creds.keyVersion, &creds.dataKey)...)
+	if err != nil {
+		return nil, err
+	}
+
+	creds.apiKey, creds.secretKey = user.MexcAPIKey, user.MexcSecretKey
+	user.MexcAPIKey, user.MexcSecretKey, err = s.openCredentials(ctx, &creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials of user %d: %w", user.ID, err)
+	}
+
+	return &user, nil
+}
+
+// RotateEncryptionKey перешифровывает ключи MEXC API всех пользователей текущим мастер-ключом.
+// Строки обрабатываются пачками по batchSize, каждая пачка — в своей транзакции,
+// поэтому ротацию можно выполнять на работающей системе. Незашифрованные строки
+// (оставшиеся с версии без шифрования) тоже шифруются. Строки, заблокированные
+// другими транзакциями, пропускаются до следующего запуска.
+// Возвращает количество перешифрованных строк.
+func (s *UserStorage) RotateEncryptionKey(ctx context.Context, batchSize int) (int, error) {
+	if s.cipher == nil {
+		return 0, fmt.Errorf("failed to rotate encryption key: no key provider is configured: %w", secrets.ErrKeyNotFound)
+	}
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("failed to rotate encryption key: invalid batch size %d", batchSize)
+	}
+
+	version, err := s.cipher.CurrentVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rotate encryption key: %w", err)
+	}
+
+	total := 0
+	for {
+		n, err := s.rotateBatch(ctx, version, batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// rotateBatch перешифровывает до limit строк с версией ключа, отличной от version.
+func (s *UserStorage) rotateBatch(ctx context.Context, version, limit int) (n int, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		SELECT id, mexc_api_key, mexc_secret_key, key_version, data_key
+		FROM users
+		WHERE key_version <> $1
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, query, version, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query users for rotation: %w", postgreserr.Classify(err))
+	}
+
+	type rotation struct {
+		id    uint64
+		creds credentials
+	}
+	var batch []rotation
+	for rows.Next() {
+		var r rotation
+		if err = rows.Scan(&r.id, &r.creds.apiKey, &r.creds.secretKey, &r.creds.keyVersion, &r.creds.dataKey); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user: %w", postgreserr.Classify(err))
+		}
+		batch = append(batch, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating over users: %w", postgreserr.Classify(err))
+	}
+
+	update := `
+		UPDATE users SET mexc_api_key = $1, mexc_secret_key = $2, key_version = $3, data_key = $4
+		WHERE id = $5`
+
+	for _, r := range batch {
+		user := &domain.User{ID: r.id}
+		user.MexcAPIKey, user.MexcSecretKey, err = s.openCredentials(ctx, &r.creds)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt credentials of user %d: %w", r.id, err)
+		}
+
+		var sealed *credentials
+		sealed, err = s.sealCredentials(ctx, user)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err = tx.ExecContext(ctx, update, sealed.apiKey, sealed.secretKey, sealed.keyVersion, sealed.dataKey, r.id); err != nil {
+			return 0, fmt.Errorf("failed to update user %d: %w", r.id, postgreserr.Classify(err))
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+
+	return len(batch), nil
+}
@@ -0,0 +1,191 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/secrets"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// captured запоминает значение аргумента запроса
+type captured struct {
+	value driver.Value
+}
+
+func (c *captured) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+func testKeys(t *testing.T, versions ...int) *secrets.StaticKeys {
+	t.Helper()
+	keys := make(map[int][]byte)
+	for _, v := range versions {
+		keys[v] = bytes.Repeat([]byte{byte(v)}, secrets.KeySize)
+	}
+	provider, err := secrets.NewStaticKeys(keys)
+	require.NoError(t, err)
+	return provider
+}
+
+var userRowColumns = []string{
+	"id", "telegram_id", "mexc_uid", "username", "email", "mexc_api_key", "mexc_secret_key",
+	"kyc_status", "can_trade", "can_withdraw", "can_deposit", "account_type",
+	"permissions", "last_account_sync", "is_active", "created_at", "updated_at",
+	"key_version", "data_key",
+}
+
+func userRow(apiKey, secretKey interface{}, keyVersion, dataKey interface{}) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(userRowColumns).AddRow(
+		1, 100, "uid", "user", "user@example.com", apiKey, secretKey,
+		1, true, false, true, "SPOT", "[]", nil, true, now, now,
+		keyVersion, dataKey,
+	)
+}
+
+func TestUserStorage_EncryptedCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("credentials are stored encrypted and read back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewEncryptedUserStorage(storage.NewDBAdapter(db), testKeys(t, 1))
+		user := &domain.User{MexcUID: "uid", MexcAPIKey: "api_key", MexcSecretKey: "secret_key"}
+
+		apiKey, secretKey, version, dataKey := &captured{}, &captured{}, &captured{}, &captured{}
+		mock.ExpectQuery("INSERT INTO users").
+			WithArgs(sqlmock.AnyArg(), "uid", sqlmock.AnyArg(), sqlmock.AnyArg(), apiKey, secretKey,
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), version, dataKey).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, time.Now(), time.Now()))
+
+		require.NoError(t, s.CreateUser(ctx, user))
+		assert.NotEqual(t, "api_key", apiKey.value)
+		assert.NotEqual(t, "secret_key", secretKey.value)
+		assert.NotContains(t, apiKey.value, "api_key")
+		assert.EqualValues(t, 1, version.value)
+		assert.NotEmpty(t, dataKey.value)
+		// Доменная модель не изменяется
+		assert.Equal(t, "api_key", user.MexcAPIKey)
+
+		mock.ExpectQuery("FROM users WHERE id").
+			WillReturnRows(userRow(apiKey.value, secretKey.value, version.value, dataKey.value))
+
+		got, err := s.GetUserByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "api_key", got.MexcAPIKey)
+		assert.Equal(t, "secret_key", got.MexcSecretKey)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("plaintext rows stay readable", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewEncryptedUserStorage(storage.NewDBAdapter(db), testKeys(t, 1))
+		mock.ExpectQuery("FROM users WHERE mexc_uid").
+			WillReturnRows(userRow("api_key", "secret_key", 0, nil))
+
+		got, err := s.GetUserByMexcUID(ctx, "uid")
+		require.NoError(t, err)
+		assert.Equal(t, "api_key", got.MexcAPIKey)
+	})
+
+	t.Run("encrypted rows require a key provider", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		sealed, err := secrets.NewCipher(testKeys(t, 1)).Seal(ctx, "api_key", "secret_key")
+		require.NoError(t, err)
+
+		s := NewUserStorage(storage.NewDBAdapter(db))
+		mock.ExpectQuery("FROM users WHERE id").
+			WillReturnRows(userRow(sealed.Fields[0], sealed.Fields[1], sealed.KeyVersion, sealed.DataKey))
+
+		_, err = s.GetUserByID(ctx, 1)
+		assert.ErrorIs(t, err, secrets.ErrKeyNotFound)
+		assert.Contains(t, err.Error(), "failed to decrypt credentials of user 1")
+	})
+}
+
+func TestUserStorage_RotateEncryptionKey(t *testing.T) {
+	ctx := context.Background()
+	rotationColumns := []string{"id", "mexc_api_key", "mexc_secret_key", "key_version", "data_key"}
+
+	t.Run("re-encrypts old and plaintext rows in batches", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		old, err := secrets.NewCipher(testKeys(t, 1)).Seal(ctx, "api_1", "secret_1")
+		require.NoError(t, err)
+
+		keys := testKeys(t, 1, 2)
+		s := NewEncryptedUserStorage(storage.NewDBAdapter(db), keys)
+
+		apiKey, secretKey, dataKey := &captured{}, &captured{}, &captured{}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE SKIP LOCKED").WithArgs(2, 2).
+			WillReturnRows(sqlmock.NewRows(rotationColumns).
+				AddRow(1, old.Fields[0], old.Fields[1], old.KeyVersion, old.DataKey).
+				AddRow(2, "api_2", "secret_2", 0, nil))
+		mock.ExpectExec("UPDATE users SET mexc_api_key").
+			WithArgs(apiKey, secretKey, 2, dataKey, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET mexc_api_key").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 2, sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE SKIP LOCKED").WithArgs(2, 2).
+			WillReturnRows(sqlmock.NewRows(rotationColumns))
+		mock.ExpectCommit()
+
+		rotated, err := s.RotateEncryptionKey(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, rotated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		fields, err := secrets.NewCipher(keys).Open(ctx, 2, dataKey.value.([]byte), apiKey.value.(string), secretKey.value.(string))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api_1", "secret_1"}, fields)
+	})
+
+	t.Run("unknown key version rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		s := NewEncryptedUserStorage(storage.NewDBAdapter(db), testKeys(t, 2))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE SKIP LOCKED").
+			WillReturnRows(sqlmock.NewRows(rotationColumns).AddRow(1, "x", "y", 1, []byte("key")))
+		mock.ExpectRollback()
+
+		rotated, err := s.RotateEncryptionKey(ctx, 10)
+		assert.ErrorIs(t, err, secrets.ErrKeyNotFound)
+		assert.Zero(t, rotated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requires a key provider", func(t *testing.T) {
+		_, err := NewUserStorage(nil).RotateEncryptionKey(ctx, 10)
+		assert.ErrorIs(t, err, secrets.ErrKeyNotFound)
+	})
+}
@@ -5,213 +5,113 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"iter"
 
 	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowiter"
+	"github.com/samar/sup_bot/metacore/postgres/internal/rowmap"
 	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/secrets"
 	"github.com/samar/sup_bot/metacore/storage"
 )
 
 // UserStorage реализует интерфейс UserStorage.
+// Если задан cipher, ключи MEXC API хранятся в БД зашифрованными.
 type UserStorage struct {
-	db storage.DBInterface
+	db     storage.DBInterface
+	cipher *secrets.Cipher
 }
 
-// NewUserStorage создает новый экземпляр UserStorage.
+// NewUserStorage создает новый экземпляр UserStorage без шифрования ключей.
 func NewUserStorage(db storage.DBInterface) *UserStorage {
 	return &UserStorage{db: db}
 }
 
+// NewEncryptedUserStorage создает UserStorage, шифрующий ключи MEXC API мастер-ключами keys.
+func NewEncryptedUserStorage(db storage.DBInterface, keys secrets.KeyProvider) *UserStorage {
+	return &UserStorage{db: db, cipher: secrets.NewCipher(keys)}
+}
+
 // CreateUser создает нового пользователя.
 func (s *UserStorage) CreateUser(ctx context.Context, user *domain.User) error {
-	query := `
-		INSERT INTO users (
-			telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key,
-			kyc_status, can_trade, can_withdraw, can_deposit,
-			account_type, permissions, last_account_sync, is_active
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
-		) RETURNING id, created_at, updated_at`
-
-	err := s.db.QueryRowContext(ctx, query,
-		user.TelegramID,
-		user.MexcUID,
-		user.Username,
-		user.Email,
-		user.MexcAPIKey,
-		user.MexcSecretKey,
-		user.KYCStatus,
-		user.CanTrade,
-		user.CanWithdraw,
-		user.CanDeposit,
-		user.AccountType,
-		user.Permissions,
-		user.LastAccountSync,
-		user.IsActive,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	query := rowmap.InsertUser + ` RETURNING ` + rowmap.UserReturning
 
+	creds, err := s.sealCredentials(ctx, user)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
+	err = s.db.QueryRowContext(ctx, query, userArgs(user, creds)...).
+		Scan(rowmap.UserReturningDest(user)...)
+
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", postgreserr.Classify(err))
+	}
+
 	return nil
 }
 
 // GetUserByID получает пользователя по ID.
 func (s *UserStorage) GetUserByID(ctx context.Context, id uint64) (*domain.User, error) {
-	query := `
-		SELECT id, telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key,
-		       kyc_status, can_trade, can_withdraw, can_deposit, account_type,
-		       permissions, last_account_sync, is_active, created_at, updated_at
-		FROM users WHERE id = $1`
-
-	var user domain.User
-
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.TelegramID,
-		&user.MexcUID,
-		&user.Username,
-		&user.Email,
-		&user.MexcAPIKey,
-		&user.MexcSecretKey,
-		&user.KYCStatus,
-		&user.CanTrade,
-		&user.CanWithdraw,
-		&user.CanDeposit,
-		&user.AccountType,
-		&user.Permissions,
-		&user.LastAccountSync,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	query := `SELECT ` + rowmap.UserColumns + ` FROM users WHERE id = $1`
 
+	user, err := s.scanUser(ctx, s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("user with id %d not found: %w", id, postgreserr.ErrUserNotFound)
 		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get user: %w", postgreserr.Classify(err))
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // GetUserByMexcUID получает пользователя по MEXC UID.
 func (s *UserStorage) GetUserByMexcUID(ctx context.Context, mexcUID string) (*domain.User, error) {
-	query := `
-		SELECT id, telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key,
-		       kyc_status, can_trade, can_withdraw, can_deposit, account_type,
-		       permissions, last_account_sync, is_active, created_at, updated_at
-		FROM users WHERE mexc_uid = $1`
-
-	var user domain.User
-
-	err := s.db.QueryRowContext(ctx, query, mexcUID).Scan(
-		&user.ID,
-		&user.TelegramID,
-		&user.MexcUID,
-		&user.Username,
-		&user.Email,
-		&user.MexcAPIKey,
-		&user.MexcSecretKey,
-		&user.KYCStatus,
-		&user.CanTrade,
-		&user.CanWithdraw,
-		&user.CanDeposit,
-		&user.AccountType,
-		&user.Permissions,
-		&user.LastAccountSync,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	query := `SELECT ` + rowmap.UserColumns + ` FROM users WHERE mexc_uid = $1`
 
+	user, err := s.scanUser(ctx, s.db.QueryRowContext(ctx, query, mexcUID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("user with mexc_uid %s not found: %w", mexcUID, postgreserr.ErrUserNotFound)
 		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get user: %w", postgreserr.Classify(err))
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // GetUserByTelegramID получает пользователя по Telegram ID.
 func (s *UserStorage) GetUserByTelegramID(ctx context.Context, telegramID int64) (*domain.User, error) {
-	query := `
-		SELECT id, telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key,
-		       kyc_status, can_trade, can_withdraw, can_deposit, account_type,
-		       permissions, last_account_sync, is_active, created_at, updated_at
-		FROM users WHERE telegram_id = $1`
-
-	var user domain.User
-
-	err := s.db.QueryRowContext(ctx, query, telegramID).Scan(
-		&user.ID,
-		&user.TelegramID,
-		&user.MexcUID,
-		&user.Username,
-		&user.Email,
-		&user.MexcAPIKey,
-		&user.MexcSecretKey,
-		&user.KYCStatus,
-		&user.CanTrade,
-		&user.CanWithdraw,
-		&user.CanDeposit,
-		&user.AccountType,
-		&user.Permissions,
-		&user.LastAccountSync,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	query := `SELECT ` + rowmap.UserColumns + ` FROM users WHERE telegram_id = $1`
 
+	user, err := s.scanUser(ctx, s.db.QueryRowContext(ctx, query, telegramID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("user with telegram_id %d not found: %w", telegramID, postgreserr.ErrUserNotFound)
 		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get user: %w", postgreserr.Classify(err))
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // UpdateUser обновляет пользователя.
 func (s *UserStorage) UpdateUser(ctx context.Context, user *domain.User) error {
-	query := `
-		UPDATE users SET 
-			telegram_id = $1, mexc_uid = $2, username = $3, email = $4, mexc_api_key = $5,
-			mexc_secret_key = $6, kyc_status = $7, can_trade = $8,
-			can_withdraw = $9, can_deposit = $10, account_type = $11,
-			permissions = $12, last_account_sync = $13, is_active = $14,
-			updated_at = CURRENT_TIMESTAMP
-		WHERE id = $15`
-
-	result, err := s.db.ExecContext(ctx, query,
-		user.TelegramID,
-		user.MexcUID,
-		user.Username,
-		user.Email,
-		user.MexcAPIKey,
-		user.MexcSecretKey,
-		user.KYCStatus,
-		user.CanTrade,
-		user.CanWithdraw,
-		user.CanDeposit,
-		user.AccountType,
-		user.Permissions,
-		user.LastAccountSync,
-		user.IsActive,
-		user.ID,
-	)
-
+	creds, err := s.sealCredentials(ctx, user)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
+	result, err := s.db.ExecContext(ctx, rowmap.UpdateUser, append(userArgs(user, creds), user.ID)...)
+
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", postgreserr.Classify(err))
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get rows affected: %w", postgreserr.Classify(err))
 	}
 
 	if rowsAffected == 0 {
@@ -227,12 +127,12 @@ func (s *UserStorage) DeleteUser(ctx context.Context, id uint64) error {
 
 	result, err := s.db.ExecContext(ctx, query, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		return fmt.Errorf("failed to delete user: %w", postgreserr.Classify(err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get rows affected: %w", postgreserr.Classify(err))
 	}
 
 	if rowsAffected == 0 {
@@ -244,56 +144,40 @@ func (s *UserStorage) DeleteUser(ctx context.Context, id uint64) error {
 
 // GetAllUsers получает всех пользователей.
 func (s *UserStorage) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
-	query := `
-		SELECT id, telegram_id, mexc_uid, username, email, mexc_api_key, mexc_secret_key,
-		       kyc_status, can_trade, can_withdraw, can_deposit, account_type,
-		       permissions, last_account_sync, is_active, created_at, updated_at
-		FROM users ORDER BY id`
+	query := `SELECT ` + rowmap.UserColumns + ` FROM users ORDER BY id`
 
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query users: %w", err)
+		return nil, fmt.Errorf("failed to query users: %w", postgreserr.Classify(err))
 	}
 	defer rows.Close()
 
 	var users []*domain.User
 
 	for rows.Next() {
-		var user domain.User
-		err := rows.Scan(
-			&user.ID,
-			&user.TelegramID,
-			&user.MexcUID,
-			&user.Username,
-			&user.Email,
-			&user.MexcAPIKey,
-			&user.MexcSecretKey,
-			&user.KYCStatus,
-			&user.CanTrade,
-			&user.CanWithdraw,
-			&user.CanDeposit,
-			&user.AccountType,
-			&user.Permissions,
-			&user.LastAccountSync,
-			&user.IsActive,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
-
+		user, err := s.scanUser(ctx, rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return nil, fmt.Errorf("failed to scan user: %w", postgreserr.Classify(err))
 		}
 
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over users: %w", err)
+		return nil, fmt.Errorf("error iterating over users: %w", postgreserr.Classify(err))
 	}
 
 	return users, nil
 }
 
+// IterAllUsers обходит всех пользователей в порядке id, не загружая их в память.
+func (s *UserStorage) IterAllUsers(ctx context.Context) iter.Seq2[*domain.User, error] {
+	query := `SELECT ` + rowmap.UserColumns + ` FROM users ORDER BY id`
+	return rowiter.Query(ctx, s.db, "users", query, nil, func(row storage.RowInterface) (*domain.User, error) {
+		return s.scanUser(ctx, row)
+	})
+}
+
 // Close закрывает соединение с БД.
 func (s *UserStorage) Close() {
 	if s.db != nil {
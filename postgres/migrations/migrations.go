@@ -0,0 +1,414 @@
+// Package migrations реализует версионированные миграции схемы PostgreSQL.
+// Файлы миграций встраиваются в бинарник через embed.FS и именуются
+// NNNN_name.up.sql / NNNN_name.down.sql. Примененные версии хранятся в schema_migrations
+// вместе с контрольной суммой up-скрипта, чтобы обнаруживать изменение уже примененных миграций.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// lockKey — ключ advisory lock, которым сериализуются миграции между экземплярами бота.
+const lockKey int64 = 7_402_913_655
+
+var (
+	// ErrChecksumMismatch возвращается, если примененная миграция была изменена после применения.
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrUnknownVersion возвращается, если в БД применена версия, которой нет среди файлов.
+	ErrUnknownVersion = errors.New("applied migration is unknown")
+	// ErrIrreversible возвращается при откате миграции без down-скрипта.
+	ErrIrreversible = errors.New("migration has no down script")
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration описывает одну версию схемы.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status описывает состояние миграции в конкретной БД.
+type Status struct {
+	Version          int64
+	Name             string
+	Applied          bool
+	AppliedAt        *time.Time
+	ChecksumMismatch bool
+	// Missing=true означает, что версия применена в БД, но файла миграции нет.
+	Missing bool
+}
+
+// Migrator применяет и откатывает миграции.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New создает Migrator со встроенными миграциями.
+func New(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	return NewWithFS(db, sub)
+}
+
+// NewWithFS создает Migrator с миграциями из произвольной файловой системы.
+func NewWithFS(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load читает миграции из корня fsys и сортирует их по версии.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+			m.Checksum = checksum(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrations возвращает известные миграции по возрастанию версии.
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Up применяет все непримененные миграции и возвращает их количество.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(state); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := state[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает n последних примененных миграций и возвращает количество откаченных.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(state); err != nil {
+			return err
+		}
+
+		for _, migration := range m.lastApplied(state, n) {
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Redo откатывает и заново применяет последнюю примененную миграцию.
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(state); err != nil {
+			return err
+		}
+
+		last := m.lastApplied(state, 1)
+		if len(last) == 0 {
+			return nil
+		}
+		if err := m.revert(ctx, conn, last[0]); err != nil {
+			return err
+		}
+		return m.apply(ctx, conn, last[0])
+	})
+}
+
+// Status возвращает состояние всех известных и примененных миграций по возрастанию версии.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[int64]bool, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[migration.Version] = true
+			st := Status{Version: migration.Version, Name: migration.Name}
+			if rec, ok := state[migration.Version]; ok {
+				appliedAt := rec.appliedAt
+				st.Applied = true
+				st.AppliedAt = &appliedAt
+				st.ChecksumMismatch = rec.checksum != migration.Checksum
+			}
+			statuses = append(statuses, st)
+		}
+
+		for version, rec := range state {
+			if known[version] {
+				continue
+			}
+			appliedAt := rec.appliedAt
+			statuses = append(statuses, Status{Version: version, Name: rec.name, Applied: true, AppliedAt: &appliedAt, Missing: true})
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
+
+// appliedRecord — строка таблицы schema_migrations.
+type appliedRecord struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock.
+// Session-level блокировка требует, чтобы все запросы шли через одно соединение.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Снимаем блокировку даже при отмененном контексте запроса
+		if _, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); unlockErr != nil && err == nil {
+			err = fmt.Errorf("failed to release migration lock: %w", unlockErr)
+		}
+	}()
+
+	createQuery := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`
+	if _, err := conn.ExecContext(ctx, createQuery); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) loadApplied(ctx context.Context, conn *sql.Conn) (map[int64]appliedRecord, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	state := make(map[int64]appliedRecord)
+	for rows.Next() {
+		var version int64
+		var rec appliedRecord
+		if err := rows.Scan(&version, &rec.name, &rec.checksum, &rec.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		state[version] = rec
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("schema_migrations rows error: %w", err)
+	}
+	return state, nil
+}
+
+// verify проверяет, что примененные миграции не были изменены.
+func (m *Migrator) verify(state map[int64]appliedRecord) error {
+	for _, migration := range m.migrations {
+		rec, ok := state[migration.Version]
+		if ok && rec.checksum != migration.Checksum {
+			return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, ErrChecksumMismatch)
+		}
+	}
+	return nil
+}
+
+// lastApplied возвращает до n последних примененных миграций в порядке убывания версии.
+func (m *Migrator) lastApplied(state map[int64]appliedRecord, n int) []Migration {
+	versions := make([]int64, 0, len(state))
+	for version := range state {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	byVersion := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var result []Migration
+	for _, version := range versions {
+		if len(result) == n {
+			break
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			// Откатить версию без файла невозможно — возвращаем заглушку, revert вернет ошибку
+			migration = Migration{Version: version, Name: state[version].name}
+		}
+		result = append(result, migration)
+	}
+	return result
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, migration.Checksum)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if migration.Up == "" {
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, ErrUnknownVersion)
+	}
+	if migration.Down == "" {
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, ErrIrreversible)
+	}
+
+	err := inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revert migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	log.Printf("Reverted migration %04d_%s", migration.Version, migration.Name)
+	return nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
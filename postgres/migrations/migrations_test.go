@@ -0,0 +1,226 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0002_add_column.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN c INT;")},
+		"0002_add_column.down.sql": {Data: []byte("ALTER TABLE t DROP COLUMN c;")},
+		"0001_init.up.sql":         {Data: []byte("CREATE TABLE t (id INT);")},
+		"0001_init.down.sql":       {Data: []byte("DROP TABLE t;")},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("sorted and paired", func(t *testing.T) {
+		migrations, err := Load(testFS())
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "init", migrations[0].Name)
+		assert.Equal(t, "CREATE TABLE t (id INT);", migrations[0].Up)
+		assert.Equal(t, "DROP TABLE t;", migrations[0].Down)
+		assert.Len(t, migrations[0].Checksum, 64)
+
+		assert.Equal(t, int64(2), migrations[1].Version)
+		assert.NotEqual(t, migrations[0].Checksum, migrations[1].Checksum)
+	})
+
+	t.Run("invalid file name", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"init.sql": {Data: []byte("SELECT 1")}})
+		assert.ErrorContains(t, err, "invalid migration file name")
+	})
+
+	t.Run("missing up script", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"0001_init.down.sql": {Data: []byte("DROP TABLE t;")}})
+		assert.ErrorContains(t, err, "has no up script")
+	})
+
+	t.Run("conflicting names", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"0001_init.up.sql":  {Data: []byte("SELECT 1")},
+			"0001_other.up.sql": {Data: []byte("SELECT 2")},
+		})
+		assert.ErrorContains(t, err, "conflicting names")
+	})
+
+	t.Run("embedded migrations are valid", func(t *testing.T) {
+		m, err := New(nil)
+		require.NoError(t, err)
+		require.NotEmpty(t, m.Migrations())
+		assert.Equal(t, int64(1), m.Migrations()[0].Version)
+	})
+}
+
+// expectLocked ожидает захват блокировки и создание schema_migrations.
+func expectLocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectUnlocked(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func appliedRows(migrations ...Migration) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	for _, m := range migrations {
+		rows.AddRow(m.Version, m.Name, m.Checksum, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	return rows
+}
+
+func TestMigrator_Up(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("applies pending migrations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		m, err := NewWithFS(db, testFS())
+		require.NoError(t, err)
+		all := m.Migrations()
+
+		expectLocked(mock)
+		mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(appliedRows(all[0]))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(all[1].Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(int64(2), "add_column", all[1].Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectUnlocked(mock)
+
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		m, err := NewWithFS(db, testFS())
+		require.NoError(t, err)
+		all := m.Migrations()
+
+		expectLocked(mock)
+		mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(appliedRows())
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(all[0].Up)).WillReturnError(errors.New("syntax error"))
+		mock.ExpectRollback()
+		expectUnlocked(mock)
+
+		applied, err := m.Up(ctx)
+		assert.ErrorContains(t, err, "failed to apply migration 0001_init")
+		assert.Equal(t, 0, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		m, err := NewWithFS(db, testFS())
+		require.NoError(t, err)
+		edited := m.Migrations()[0]
+		edited.Checksum = "edited"
+
+		expectLocked(mock)
+		mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(appliedRows(edited))
+		expectUnlocked(mock)
+
+		_, err = m.Up(ctx)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Down(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reverts last migrations in reverse order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		m, err := NewWithFS(db, testFS())
+		require.NoError(t, err)
+		all := m.Migrations()
+
+		expectLocked(mock)
+		mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(appliedRows(all...))
+		for _, migration := range []Migration{all[1], all[0]} {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(migration.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).WithArgs(migration.Version).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+		expectUnlocked(mock)
+
+		reverted, err := m.Down(ctx, 5)
+		require.NoError(t, err)
+		assert.Equal(t, 2, reverted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("irreversible migration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		fsys := testFS()
+		delete(fsys, "0002_add_column.down.sql")
+		m, err := NewWithFS(db, fsys)
+		require.NoError(t, err)
+
+		expectLocked(mock)
+		mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(appliedRows(m.Migrations()...))
+		expectUnlocked(mock)
+
+		_, err = m.Down(ctx, 1)
+		assert.ErrorIs(t, err, ErrIrreversible)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := NewWithFS(db, testFS())
+	require.NoError(t, err)
+	all := m.Migrations()
+	orphan := Migration{Version: 9, Name: "removed", Checksum: "x"}
+
+	expectLocked(mock)
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(appliedRows(all[0], orphan))
+	expectUnlocked(mock)
+
+	statuses, err := m.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].ChecksumMismatch)
+	assert.False(t, statuses[1].Applied)
+	assert.Nil(t, statuses[1].AppliedAt)
+	assert.True(t, statuses[2].Missing)
+	assert.Equal(t, "removed", statuses[2].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS order_updates;
+DROP TABLE IF EXISTS trades;
+DROP TABLE IF EXISTS user_balances;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS users;
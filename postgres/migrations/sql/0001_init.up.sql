@@ -1,4 +1,5 @@
--- Создание схемы базы данных
+-- Базовая схема базы данных.
+-- IF NOT EXISTS оставлен, чтобы миграция применялась к БД, развернутым из старого schema.sql.
 
 -- Создание таблицы пользователей
 CREATE TABLE IF NOT EXISTS users (
@@ -80,4 +81,4 @@ CREATE TABLE IF NOT EXISTS order_updates (
                                              cummulative_quote_qty DECIMAL(30, 15),
                                              update_time TIMESTAMP NOT NULL,
                                              raw_data JSONB
-);
\ No newline at end of file
+);
@@ -0,0 +1,4 @@
+-- telegram_id используется UserStorage, но отсутствовал в базовой схеме
+ALTER TABLE users ADD COLUMN IF NOT EXISTS telegram_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_orders_order_list_id;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS order_list_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS iceberg_qty;
+ALTER TABLE orders DROP COLUMN IF EXISTS stop_price;
+ALTER TABLE orders DROP COLUMN IF EXISTS time_in_force;
+
+DROP TABLE IF EXISTS order_lists;
@@ -0,0 +1,24 @@
+-- Списки связанных ордеров (OCO)
+CREATE TABLE IF NOT EXISTS order_lists (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mexc_order_list_id VARCHAR(255) NOT NULL UNIQUE,
+    list_client_order_id VARCHAR(255),
+    symbol VARCHAR(20) NOT NULL,
+    contingency_type VARCHAR(20) NOT NULL,
+    list_status_type VARCHAR(20) NOT NULL,
+    list_order_status VARCHAR(20) NOT NULL,
+    transaction_time TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_lists_user ON order_lists(user_id);
+
+-- Расширенные поля ордеров MEXC
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS time_in_force VARCHAR(10);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS stop_price DECIMAL(30, 15);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS iceberg_qty DECIMAL(30, 15);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS order_list_id VARCHAR(255); -- REFERENCES order_lists(mexc_order_list_id)
+
+CREATE INDEX IF NOT EXISTS idx_orders_order_list_id ON orders(order_list_id) WHERE order_list_id IS NOT NULL;
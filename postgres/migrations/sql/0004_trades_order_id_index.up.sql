@@ -0,0 +1,2 @@
+-- Сделки по ордеру: GetTradesByOrderID и сверка исполнения ордеров
+CREATE INDEX IF NOT EXISTS idx_trades_order_id ON trades(order_id);
@@ -0,0 +1,8 @@
+-- Внимание: откат удаляет data_key, зашифрованные ключи (key_version > 0) станут нечитаемыми
+DROP INDEX IF EXISTS idx_users_key_version;
+
+ALTER TABLE users ALTER COLUMN mexc_secret_key TYPE VARCHAR(255);
+ALTER TABLE users ALTER COLUMN mexc_api_key TYPE VARCHAR(255);
+
+ALTER TABLE users DROP COLUMN IF EXISTS data_key;
+ALTER TABLE users DROP COLUMN IF EXISTS key_version;
@@ -0,0 +1,11 @@
+-- Шифрование ключей MEXC API (envelope): версия мастер-ключа и зашифрованный ключ данных.
+-- key_version = 0 — ключи хранятся открыто (строки до включения шифрования).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS key_version INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS data_key BYTEA;
+
+-- Зашифрованные значения длиннее исходных ключей
+ALTER TABLE users ALTER COLUMN mexc_api_key TYPE TEXT;
+ALTER TABLE users ALTER COLUMN mexc_secret_key TYPE TEXT;
+
+-- Поиск строк для ротации ключа
+CREATE INDEX IF NOT EXISTS idx_users_key_version ON users(key_version);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_user_balances_snapshot ON user_balances;
+DROP FUNCTION IF EXISTS record_balance_snapshot();
+DROP TABLE IF EXISTS balance_snapshots;
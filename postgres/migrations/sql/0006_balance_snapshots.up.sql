@@ -0,0 +1,38 @@
+-- История балансов: снимок добавляется при каждом изменении free/locked в user_balances
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    asset VARCHAR(20) NOT NULL,
+    free DECIMAL(30, 15) NOT NULL,
+    locked DECIMAL(30, 15) NOT NULL,
+    recorded_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_user_asset_time
+    ON balance_snapshots(user_id, asset, recorded_at DESC);
+
+-- Снимок пишется триггером в той же транзакции, что и изменение баланса.
+-- Удаление строки не записывается: UpdateUserBalances удаляет только нулевые балансы,
+-- нулевое значение уже попало в историю при обновлении, а каскадное удаление
+-- пользователя удаляет и его историю.
+CREATE OR REPLACE FUNCTION record_balance_snapshot() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.free = OLD.free AND NEW.locked = OLD.locked THEN
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO balance_snapshots (user_id, asset, free, locked, recorded_at)
+    VALUES (NEW.user_id, NEW.asset, NEW.free, NEW.locked, NEW.updated_at);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_user_balances_snapshot ON user_balances;
+CREATE TRIGGER trg_user_balances_snapshot
+    AFTER INSERT OR UPDATE ON user_balances
+    FOR EACH ROW EXECUTE FUNCTION record_balance_snapshot();
+
+-- Начальная история для уже сохраненных балансов
+INSERT INTO balance_snapshots (user_id, asset, free, locked, recorded_at)
+SELECT user_id, asset, free, locked, updated_at FROM user_balances;
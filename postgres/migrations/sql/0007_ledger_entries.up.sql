@@ -0,0 +1,19 @@
+-- Ledger двойной записи: проводки каждой операции по каждому активу в сумме дают ноль.
+-- Баланс пользователя — сумма проводок счета USER по активу.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    transaction_id VARCHAR(255) NOT NULL,
+    account VARCHAR(20) NOT NULL,
+    asset VARCHAR(20) NOT NULL,
+    amount DECIMAL(30, 15) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    ref_id VARCHAR(255) NOT NULL,
+    entry_time TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Повторная проводка той же операции отклоняется
+    UNIQUE (transaction_id, account, asset, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_user_account_asset
+    ON ledger_entries(user_id, account, asset, entry_time, id);
@@ -0,0 +1,13 @@
+-- Наблюдения цен символов для оценки портфеля
+CREATE TABLE IF NOT EXISTS prices (
+    id BIGSERIAL PRIMARY KEY,
+    symbol VARCHAR(20) NOT NULL,
+    price DECIMAL(30, 15) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    observed_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (symbol, source, observed_at)
+);
+
+-- Последняя цена символа на момент: GetLatestPrice, GetPricesAt, GetPriceHistory
+CREATE INDEX IF NOT EXISTS idx_prices_symbol_observed_at ON prices(symbol, observed_at DESC);
@@ -0,0 +1,17 @@
+-- Каталог торговых пар и их правил из /api/v3/exchangeInfo
+CREATE TABLE IF NOT EXISTS symbols (
+    id BIGSERIAL PRIMARY KEY,
+    symbol VARCHAR(20) NOT NULL UNIQUE,
+    base_asset VARCHAR(20) NOT NULL,
+    quote_asset VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    tick_size DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    step_size DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    min_qty DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    max_qty DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    min_notional DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    max_notional DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    order_types TEXT, -- JSON array
+    permissions TEXT, -- JSON array
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,14 @@
+-- Позиции инкрементальной синхронизации потоков биржи по пользователю и символу
+CREATE TABLE IF NOT EXISTS sync_cursors (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    stream VARCHAR(20) NOT NULL,
+    symbol VARCHAR(20) NOT NULL DEFAULT '', -- пусто для потоков без символа
+    from_id VARCHAR(100) NOT NULL DEFAULT '',
+    last_time TIMESTAMP,
+    status VARCHAR(20) NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    version BIGINT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, stream, symbol)
+);
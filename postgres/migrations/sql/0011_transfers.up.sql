@@ -0,0 +1,38 @@
+-- Вводы и выводы активов: /api/v3/capital/deposit/hisrec и /api/v3/capital/withdraw/history
+CREATE TABLE IF NOT EXISTS deposits (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mexc_deposit_id VARCHAR(255) NOT NULL UNIQUE,
+    asset VARCHAR(20) NOT NULL,
+    network VARCHAR(50) NOT NULL DEFAULT '',
+    amount DECIMAL(30, 15) NOT NULL,
+    fee DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    memo VARCHAR(255) NOT NULL DEFAULT '',
+    tx_id VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    insert_time TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deposits_user_time ON deposits(user_id, insert_time DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS withdrawals (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mexc_withdrawal_id VARCHAR(255) NOT NULL UNIQUE,
+    asset VARCHAR(20) NOT NULL,
+    network VARCHAR(50) NOT NULL DEFAULT '',
+    amount DECIMAL(30, 15) NOT NULL,
+    fee DECIMAL(30, 15) NOT NULL DEFAULT 0,
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    memo VARCHAR(255) NOT NULL DEFAULT '',
+    tx_id VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL,
+    apply_time TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_withdrawals_user_time ON withdrawals(user_id, apply_time DESC, id DESC);
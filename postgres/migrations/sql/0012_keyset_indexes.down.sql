@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_order_updates_user_order_time;
+DROP INDEX IF EXISTS idx_orders_user_created;
+DROP INDEX IF EXISTS idx_trades_user_time;
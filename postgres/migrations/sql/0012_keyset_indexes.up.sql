@@ -0,0 +1,4 @@
+-- Keyset-пагинация: условие (время, id) < курсора и сортировка читаются одним проходом по индексу
+CREATE INDEX IF NOT EXISTS idx_trades_user_time ON trades(user_id, trade_time DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_user_created ON orders(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_order_updates_user_order_time ON order_updates(user_id, order_id, update_time DESC, id DESC);
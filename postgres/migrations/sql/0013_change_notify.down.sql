@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trg_user_balances_notify_update ON user_balances;
+DROP TRIGGER IF EXISTS trg_user_balances_notify ON user_balances;
+DROP TRIGGER IF EXISTS trg_order_updates_notify ON order_updates;
+DROP TRIGGER IF EXISTS trg_trades_notify ON trades;
+DROP TRIGGER IF EXISTS trg_orders_notify ON orders;
+DROP FUNCTION IF EXISTS notify_change();
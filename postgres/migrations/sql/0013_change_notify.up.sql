@@ -0,0 +1,57 @@
+-- Лента изменений: триггеры публикуют в канал metacore_changes компактный JSON
+-- {table, op, id, user_id, key, symbol, status}. Полная строка не передается
+-- (payload NOTIFY ограничен 8000 байт), подписчик перечитывает нужные данные сам.
+-- Уведомление доставляется после коммита транзакции.
+-- Аргументы триггера: колонка ключа записи и, необязательно, колонка статуса.
+CREATE OR REPLACE FUNCTION notify_change() RETURNS trigger AS $$
+DECLARE
+    rec JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := to_jsonb(OLD);
+    ELSE
+        rec := to_jsonb(NEW);
+    END IF;
+
+    PERFORM pg_notify('metacore_changes', jsonb_strip_nulls(jsonb_build_object(
+        'table', TG_TABLE_NAME,
+        'op', TG_OP,
+        'id', rec->'id',
+        'user_id', rec->'user_id',
+        'key', rec->>TG_ARGV[0],
+        'symbol', rec->>'symbol',
+        'status', CASE WHEN TG_NARGS > 1 THEN rec->>TG_ARGV[1] END
+    ))::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_orders_notify ON orders;
+CREATE TRIGGER trg_orders_notify
+    AFTER INSERT OR UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION notify_change('mexc_order_id', 'status');
+
+DROP TRIGGER IF EXISTS trg_trades_notify ON trades;
+CREATE TRIGGER trg_trades_notify
+    AFTER INSERT OR DELETE ON trades
+    FOR EACH ROW EXECUTE FUNCTION notify_change('mexc_trade_id');
+
+DROP TRIGGER IF EXISTS trg_order_updates_notify ON order_updates;
+CREATE TRIGGER trg_order_updates_notify
+    AFTER INSERT ON order_updates
+    FOR EACH ROW EXECUTE FUNCTION notify_change('order_id', 'status');
+
+-- UpdateUserBalances переписывает все активы при каждой синхронизации,
+-- поэтому о балансе сообщается только при изменении free или locked
+DROP TRIGGER IF EXISTS trg_user_balances_notify ON user_balances;
+CREATE TRIGGER trg_user_balances_notify
+    AFTER INSERT OR DELETE ON user_balances
+    FOR EACH ROW EXECUTE FUNCTION notify_change('asset');
+
+DROP TRIGGER IF EXISTS trg_user_balances_notify_update ON user_balances;
+CREATE TRIGGER trg_user_balances_notify_update
+    AFTER UPDATE ON user_balances
+    FOR EACH ROW
+    WHEN (OLD.free IS DISTINCT FROM NEW.free OR OLD.locked IS DISTINCT FROM NEW.locked)
+    EXECUTE FUNCTION notify_change('asset');
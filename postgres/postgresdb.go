@@ -8,12 +8,21 @@ import (
 
 	"github.com/samar/sup_bot/metacore/configs"
 	"github.com/samar/sup_bot/metacore/postgres/internal/balances"
+	"github.com/samar/sup_bot/metacore/postgres/internal/changefeed"
+	"github.com/samar/sup_bot/metacore/postgres/internal/ledger"
 	"github.com/samar/sup_bot/metacore/postgres/internal/orders"
+	"github.com/samar/sup_bot/metacore/postgres/internal/outbox"
+	"github.com/samar/sup_bot/metacore/postgres/internal/prices"
+	"github.com/samar/sup_bot/metacore/postgres/internal/symbols"
+	"github.com/samar/sup_bot/metacore/postgres/internal/synccursors"
 	"github.com/samar/sup_bot/metacore/postgres/internal/trades"
+	"github.com/samar/sup_bot/metacore/postgres/internal/transfers"
 	"github.com/samar/sup_bot/metacore/postgres/internal/users"
+	"github.com/samar/sup_bot/metacore/postgres/schemacheck"
+	"github.com/samar/sup_bot/metacore/secrets"
 	"github.com/samar/sup_bot/metacore/storage"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // DB represents a connection to the PostgreSQL database
@@ -21,6 +30,12 @@ type DB struct {
 	db *sql.DB
 	storage.FullStorage
 	UserStorage storage.UserStorage
+
+	// keys — мастер-ключи шифрования ключей MEXC API, nil если шифрование выключено
+	keys  secrets.KeyProvider
+	users *users.UserStorage
+	// feed — лента изменений LISTEN/NOTIFY, nil если строка подключения неизвестна
+	feed *changefeed.Feed
 }
 
 // NewPostgresDB creates a new PostgreSQL connection
@@ -31,6 +46,11 @@ func NewPostgresDB(cfg configs.Config) (*DB, error) {
 		cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.DBName, cfg.DB.SSLMode,
 	)
 
+	keys, err := keyProvider(cfg.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load encryption keys: %w", err)
+	}
+
 	// Создаем соединение с БД
 	db, err := sql.Open("postgres", connString)
 	if err != nil {
@@ -54,32 +74,97 @@ func NewPostgresDB(cfg configs.Config) (*DB, error) {
 
 	log.Println("Successfully connected to PostgreSQL database")
 
-	// Создаем storage слои
-	dbAdapter := storage.NewDBAdapter(db)
-	orderStorage := orders.NewOrderStorage(dbAdapter)
-	userStorage := users.NewUserStorage(dbAdapter)
-	tradeStorage := trades.NewTradeStorage(dbAdapter)
-	balanceStorage := balances.NewBalanceStorage(dbAdapter)
-	orderUpdateStorage := orders.NewOrderUpdateStorage(dbAdapter)
-
-	// Создаем FullStorage, объединяющий все storage
-	fullStorage := &fullStorage{
-		UserStorage:        userStorage,
-		OrderStorage:       orderStorage,
-		TradeStorage:       tradeStorage,
-		BalanceStorage:     balanceStorage,
-		OrderUpdateStorage: orderUpdateStorage,
+	// Проверяем, что схема БД соответствует доменным моделям
+	if !cfg.DB.SkipSchemaCheck {
+		if err := schemacheck.Verify(ctx, db, schemacheck.DefaultTables()); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("schema check failed (run migrations or set SkipSchemaCheck): %w", err)
+		}
+	}
+
+	if keys == nil {
+		log.Println("Warning: encryption keys are not configured, MEXC API keys are stored in plaintext")
+	}
+
+	result := newDB(db, keys)
+	result.feed = newChangeFeed(connString)
+	return result, nil
+}
+
+// newChangeFeed создает ленту изменений; соединение LISTEN открывается при первой подписке
+// и переподключается само с паузой от DefaultListenerMinReconnect до DefaultListenerMaxReconnect.
+func newChangeFeed(connString string) *changefeed.Feed {
+	return changefeed.New(func() changefeed.Listener {
+		return pq.NewListener(connString, configs.DefaultListenerMinReconnect, configs.DefaultListenerMaxReconnect,
+			func(event pq.ListenerEventType, err error) {
+				switch event {
+				case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+					log.Printf("Change feed connection lost: %v", err)
+				case pq.ListenerEventReconnected:
+					log.Println("Change feed reconnected, subscribers will resync")
+				}
+			})
+	}, changefeed.DefaultBuffer)
+}
+
+// keyProvider загружает мастер-ключи из файла или переменной окружения.
+// Возвращает nil, если источник ключей не задан.
+func keyProvider(cfg configs.SecretsConfig) (secrets.KeyProvider, error) {
+	switch {
+	case cfg.KeyFile != "":
+		return secrets.KeysFromFile(cfg.KeyFile)
+	case cfg.KeyEnv != "":
+		return secrets.KeysFromEnv(cfg.KeyEnv)
 	}
+	return nil, nil
+}
+
+// newDB собирает storage слои поверх открытого соединения
+func newDB(db *sql.DB, keys secrets.KeyProvider) *DB {
+	adapter := storage.NewDBAdapter(db)
+	fullStorage := newFullStorage(adapter, keys)
 
 	return &DB{
 		db:          db,
 		FullStorage: fullStorage,
-		UserStorage: userStorage,
-	}, nil
+		UserStorage: fullStorage.UserStorage,
+		keys:        keys,
+		users:       newUserStorage(adapter, keys),
+	}
+}
+
+// newUserStorage создает хранилище пользователей, шифрующее ключи MEXC API, если заданы keys
+func newUserStorage(db storage.DBInterface, keys secrets.KeyProvider) *users.UserStorage {
+	if keys == nil {
+		return users.NewUserStorage(db)
+	}
+	return users.NewEncryptedUserStorage(db, keys)
+}
+
+// newFullStorage создает storage слои поверх соединения или транзакции
+func newFullStorage(db storage.DBInterface, keys secrets.KeyProvider) *fullStorage {
+	return &fullStorage{
+		UserStorage:        newUserStorage(db, keys),
+		OrderStorage:       orders.NewOrderStorage(db),
+		TradeStorage:       trades.NewTradeStorage(db),
+		BalanceStorage:     balances.NewBalanceStorage(db),
+		OrderUpdateStorage: orders.NewOrderUpdateStorage(db),
+		OrderListStorage:   orders.NewOrderListStorage(db),
+		LedgerStorage:      ledger.NewLedgerStorage(db),
+		PriceStorage:       prices.NewPriceStorage(db),
+		SymbolStorage:      symbols.NewSymbolStorage(db),
+		SyncCursorStorage:  synccursors.NewSyncCursorStorage(db),
+		DepositStorage:     transfers.NewDepositStorage(db),
+		WithdrawalStorage:  transfers.NewWithdrawalStorage(db),
+		OutboxStorage:      outbox.NewOutboxStorage(db),
+	}
 }
 
-// Close закрывает соединение с БД
+// Close закрывает ленту изменений и соединение с БД
 func (db *DB) Close() {
+	if db.feed != nil {
+		db.feed.Close()
+	}
 	if db.db != nil {
 		db.db.Close()
 	}
@@ -90,6 +175,23 @@ func (db *DB) GetUserStorage() storage.UserStorage {
 	return db.UserStorage
 }
 
+// RotateEncryptionKey перешифровывает ключи MEXC API всех пользователей текущим мастер-ключом
+// пачками по batchSize строк. Возвращает количество перешифрованных строк.
+func (db *DB) RotateEncryptionKey(ctx context.Context, batchSize int) (int, error) {
+	return db.users.RotateEncryptionKey(ctx, batchSize)
+}
+
+// Subscribe подписывается на изменения ордеров, сделок, балансов и истории ордеров.
+// Первым событием приходит маркер storage.EventResync, после него и после каждого
+// следующего маркера подписчик перечитывает состояние из хранилища.
+// Канал закрывается после отмены ctx или Close.
+func (db *DB) Subscribe(ctx context.Context, filter storage.EventFilter) (<-chan storage.Event, error) {
+	if db.feed == nil {
+		return nil, fmt.Errorf("failed to subscribe: change feed is not configured")
+	}
+	return db.feed.Subscribe(ctx, filter)
+}
+
 // Ping проверяет соединение
 func (db *DB) Ping(ctx context.Context) error {
 	return db.db.PingContext(ctx)
@@ -102,7 +204,18 @@ type fullStorage struct {
 	storage.TradeStorage
 	storage.BalanceStorage
 	storage.OrderUpdateStorage
+	storage.OrderListStorage
+	storage.LedgerStorage
+	storage.PriceStorage
+	storage.SymbolStorage
+	storage.SyncCursorStorage
+	storage.DepositStorage
+	storage.WithdrawalStorage
+	storage.OutboxStorage
 }
 
 // Ensure fullStorage implements FullStorage interface
 var _ storage.FullStorage = (*fullStorage)(nil)
+
+// Ensure DB implements ChangeFeed interface
+var _ storage.ChangeFeed = (*DB)(nil)
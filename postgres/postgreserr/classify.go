@@ -0,0 +1,107 @@
+package postgreserr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Error — классифицированная ошибка PostgreSQL.
+// errors.Is(err, ErrDuplicate) и аналогичные проверки сравнивают Kind,
+// а errors.As позволяет получить ограничение, таблицу и колонку.
+type Error struct {
+	// Kind — один из классов: ErrDuplicate, ErrForeignKeyViolation и т.д.
+	Kind error
+	// Code — SQLSTATE, пусто для ошибок соединения на стороне драйвера
+	Code       string
+	Constraint string
+	Table      string
+	Column     string
+	Detail     string
+	// Err — исходная ошибка драйвера
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Constraint != "" {
+		msg += fmt.Sprintf(" (constraint %s)", e.Constraint)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify сопоставляет ошибку драйвера с классом ошибки и возвращает *Error.
+// Неизвестные ошибки, nil и уже классифицированные ошибки возвращаются без изменений.
+// Deadlock (40P01) относится к ErrSerialization: обе ошибки лечатся повтором транзакции.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		kind := kindByCode(string(pqErr.Code))
+		if kind == nil {
+			return err
+		}
+		return &Error{
+			Kind:       kind,
+			Code:       string(pqErr.Code),
+			Constraint: pqErr.Constraint,
+			Table:      pqErr.Table,
+			Column:     pqErr.Column,
+			Detail:     pqErr.Detail,
+			Err:        err,
+		}
+	}
+
+	switch {
+	case errors.Is(err, driver.ErrBadConn):
+		return &Error{Kind: ErrConnectionLost, Err: err}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return &Error{Kind: ErrQueryCanceled, Err: err}
+	}
+
+	return err
+}
+
+func kindByCode(code string) error {
+	switch code {
+	case "23505": // unique_violation
+		return ErrDuplicate
+	case "23503": // foreign_key_violation
+		return ErrForeignKeyViolation
+	case "23514": // check_violation
+		return ErrCheckViolation
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return ErrSerialization
+	case "57014": // query_canceled
+		return ErrQueryCanceled
+	case "57P01", "57P02", "57P03": // admin_shutdown, crash_shutdown, cannot_connect_now
+		return ErrConnectionLost
+	}
+	if strings.HasPrefix(code, "08") { // connection_exception
+		return ErrConnectionLost
+	}
+	return nil
+}
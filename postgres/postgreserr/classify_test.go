@@ -0,0 +1,84 @@
+package postgreserr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	t.Run("pq codes", func(t *testing.T) {
+		cases := []struct {
+			code string
+			kind error
+		}{
+			{"23505", ErrDuplicate},
+			{"23503", ErrForeignKeyViolation},
+			{"23514", ErrCheckViolation},
+			{"40001", ErrSerialization},
+			{"40P01", ErrSerialization},
+			{"57014", ErrQueryCanceled},
+			{"08006", ErrConnectionLost},
+			{"57P01", ErrConnectionLost},
+		}
+		for _, c := range cases {
+			err := Classify(&pq.Error{Code: pq.ErrorCode(c.code)})
+			assert.ErrorIs(t, err, c.kind, c.code)
+		}
+	})
+
+	t.Run("carries constraint details and keeps original error", func(t *testing.T) {
+		original := &pq.Error{
+			Code:       "23505",
+			Message:    `duplicate key value violates unique constraint "orders_mexc_order_id_key"`,
+			Constraint: "orders_mexc_order_id_key",
+			Table:      "orders",
+			Detail:     "Key (mexc_order_id)=(o1) already exists.",
+		}
+		err := fmt.Errorf("failed to create order: %w", Classify(fmt.Errorf("exec: %w", original)))
+
+		var pgErr *Error
+		require.True(t, errors.As(err, &pgErr))
+		assert.Equal(t, "23505", pgErr.Code)
+		assert.Equal(t, "orders_mexc_order_id_key", pgErr.Constraint)
+		assert.Equal(t, "orders", pgErr.Table)
+		assert.Equal(t, original.Detail, pgErr.Detail)
+
+		var pqErr *pq.Error
+		require.True(t, errors.As(err, &pqErr))
+		assert.Same(t, original, pqErr)
+
+		assert.ErrorIs(t, err, ErrDuplicate)
+		assert.NotErrorIs(t, err, ErrForeignKeyViolation)
+		assert.Contains(t, err.Error(), "duplicate key (constraint orders_mexc_order_id_key)")
+	})
+
+	t.Run("driver and context errors", func(t *testing.T) {
+		assert.ErrorIs(t, Classify(driver.ErrBadConn), ErrConnectionLost)
+
+		err := Classify(fmt.Errorf("query: %w", context.DeadlineExceeded))
+		assert.ErrorIs(t, err, ErrQueryCanceled)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("unknown errors are returned as is", func(t *testing.T) {
+		assert.NoError(t, Classify(nil))
+
+		plain := errors.New("boom")
+		assert.Same(t, plain, Classify(plain))
+
+		syntax := &pq.Error{Code: "42601"}
+		assert.Equal(t, error(syntax), Classify(syntax))
+	})
+
+	t.Run("already classified", func(t *testing.T) {
+		err := Classify(&pq.Error{Code: "23503"})
+		assert.Equal(t, err, Classify(err))
+	})
+}
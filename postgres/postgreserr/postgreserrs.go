@@ -6,3 +6,23 @@ var ErrOrderNotFound = errors.New("order not found")
 var ErrUserNotFound = errors.New("user not found")
 var ErrBalanceNotFound = errors.New("balance not found")
 var ErrTradeNotFound = errors.New("trade not found")
+var ErrOrderListNotFound = errors.New("order list not found")
+var ErrPriceNotFound = errors.New("price not found")
+var ErrSymbolNotFound = errors.New("symbol not found")
+var ErrSyncCursorNotFound = errors.New("sync cursor not found")
+var ErrDepositNotFound = errors.New("deposit not found")
+var ErrWithdrawalNotFound = errors.New("withdrawal not found")
+var ErrOutboxMessageNotFound = errors.New("outbox message not found")
+
+// ErrSyncCursorConflict возвращается, если курсор синхронизации изменился после чтения.
+var ErrSyncCursorConflict = errors.New("sync cursor was changed concurrently")
+
+// Классы ошибок PostgreSQL, см. Classify
+var (
+	ErrDuplicate           = errors.New("duplicate key")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrCheckViolation      = errors.New("check constraint violation")
+	ErrSerialization       = errors.New("serialization failure")
+	ErrConnectionLost      = errors.New("connection lost")
+	ErrQueryCanceled       = errors.New("query canceled")
+)
@@ -0,0 +1,325 @@
+// Package schemacheck сравнивает db-теги доменных структур с реальной схемой БД.
+// Проверка читает information_schema.columns и сообщает об отсутствующих и лишних колонках,
+// несовпадении типов и точности DECIMAL, чтобы расхождение обнаруживалось при старте,
+// а не на первом запросе.
+package schemacheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+)
+
+// Точность денежных колонок: DECIMAL(30, 15)
+const (
+	DecimalPrecision = 30
+	DecimalScale     = 15
+)
+
+// ErrSchemaDrift возвращается Verify, если схема БД не совпадает с доменными моделями.
+var ErrSchemaDrift = errors.New("database schema drift detected")
+
+// Table связывает таблицу БД с доменной структурой.
+type Table struct {
+	Name string
+	// Model — значение доменной структуры, например domain.User{}
+	Model interface{}
+	// Internal — служебные колонки таблицы, которых нет в доменной модели
+	Internal []string
+}
+
+// DefaultTables возвращает таблицы, которые используют storage слои metacore.
+func DefaultTables() []Table {
+	return []Table{
+		{Name: "users", Model: domain.User{}, Internal: []string{"key_version", "data_key"}},
+		{Name: "orders", Model: domain.Order{}},
+		{Name: "trades", Model: domain.Trade{}},
+		{Name: "user_balances", Model: domain.UserBalance{}},
+		{Name: "balance_snapshots", Model: domain.BalanceSnapshot{}},
+		{Name: "order_updates", Model: domain.OrderUpdate{}},
+		{Name: "order_lists", Model: domain.OrderList{}},
+		{Name: "ledger_entries", Model: domain.LedgerEntry{}},
+		{Name: "prices", Model: domain.Price{}},
+		{Name: "symbols", Model: domain.Symbol{}},
+		{Name: "sync_cursors", Model: domain.SyncCursor{}},
+		{Name: "deposits", Model: domain.Deposit{}},
+		{Name: "withdrawals", Model: domain.Withdrawal{}},
+		{Name: "outbox", Model: domain.OutboxMessage{}},
+	}
+}
+
+// IssueKind — тип расхождения.
+type IssueKind string
+
+const (
+	MissingTable      IssueKind = "missing table"
+	MissingColumn     IssueKind = "missing column"
+	ExtraColumn       IssueKind = "extra column"
+	TypeMismatch      IssueKind = "type mismatch"
+	PrecisionMismatch IssueKind = "precision mismatch"
+)
+
+// Issue описывает одно расхождение.
+type Issue struct {
+	Table    string
+	Column   string
+	Kind     IssueKind
+	Expected string
+	Actual   string
+}
+
+func (i Issue) String() string {
+	target := i.Table
+	if i.Column != "" {
+		target += "." + i.Column
+	}
+	switch {
+	case i.Expected != "" && i.Actual != "":
+		return fmt.Sprintf("%s: %s (expected %s, got %s)", target, i.Kind, i.Expected, i.Actual)
+	case i.Expected != "":
+		return fmt.Sprintf("%s: %s (expected %s)", target, i.Kind, i.Expected)
+	case i.Actual != "":
+		return fmt.Sprintf("%s: %s (%s)", target, i.Kind, i.Actual)
+	default:
+		return fmt.Sprintf("%s: %s", target, i.Kind)
+	}
+}
+
+// Report — результат проверки.
+type Report struct {
+	Issues []Issue
+}
+
+// OK возвращает true, если расхождений нет.
+func (r *Report) OK() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *Report) String() string {
+	if r.OK() {
+		return "schema matches domain models"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d schema issue(s):", len(r.Issues))
+	for _, issue := range r.Issues {
+		b.WriteString("\n  - ")
+		b.WriteString(issue.String())
+	}
+	return b.String()
+}
+
+// DriftError оборачивает отчет с расхождениями.
+type DriftError struct {
+	Report *Report
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrSchemaDrift, e.Report)
+}
+
+func (e *DriftError) Is(target error) bool {
+	return target == ErrSchemaDrift
+}
+
+// Querier — минимальный интерфейс для чтения information_schema.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// column — фактическое описание колонки из information_schema.
+type column struct {
+	dataType  string
+	precision sql.NullInt64
+	scale     sql.NullInt64
+}
+
+// Check сравнивает таблицы с доменными моделями и возвращает отчет.
+// Ошибка возвращается только при невозможности прочитать схему.
+func Check(ctx context.Context, db Querier, tables []Table) (*Report, error) {
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		names = append(names, t.Name)
+	}
+
+	query := `
+		SELECT table_name, column_name, data_type, numeric_precision, numeric_scale
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ANY($1)`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query information_schema: %w", err)
+	}
+	defer rows.Close()
+
+	actual := make(map[string]map[string]column)
+	for rows.Next() {
+		var table, name string
+		var c column
+		if err := rows.Scan(&table, &name, &c.dataType, &c.precision, &c.scale); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		if actual[table] == nil {
+			actual[table] = make(map[string]column)
+		}
+		actual[table][name] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("columns rows error: %w", err)
+	}
+
+	report := &Report{}
+	for _, t := range tables {
+		report.Issues = append(report.Issues, compareTable(t, actual[t.Name])...)
+	}
+	return report, nil
+}
+
+// Verify выполняет Check и возвращает *DriftError, если найдены расхождения.
+func Verify(ctx context.Context, db Querier, tables []Table) error {
+	report, err := Check(ctx, db, tables)
+	if err != nil {
+		return err
+	}
+	if !report.OK() {
+		return &DriftError{Report: report}
+	}
+	return nil
+}
+
+func compareTable(t Table, actual map[string]column) []Issue {
+	if len(actual) == 0 {
+		return []Issue{{Table: t.Name, Kind: MissingTable}}
+	}
+
+	var issues []Issue
+	expected := ExpectedColumns(t.Model)
+	seen := make(map[string]bool, len(expected))
+
+	for _, exp := range expected {
+		seen[exp.Name] = true
+		got, ok := actual[exp.Name]
+		if !ok {
+			issues = append(issues, Issue{Table: t.Name, Column: exp.Name, Kind: MissingColumn, Expected: strings.Join(exp.Types, " or ")})
+			continue
+		}
+		if len(exp.Types) > 0 && !contains(exp.Types, got.dataType) {
+			issues = append(issues, Issue{Table: t.Name, Column: exp.Name, Kind: TypeMismatch, Expected: strings.Join(exp.Types, " or "), Actual: got.dataType})
+			continue
+		}
+		if exp.Decimal && (got.precision.Int64 != DecimalPrecision || got.scale.Int64 != DecimalScale) {
+			issues = append(issues, Issue{
+				Table: t.Name, Column: exp.Name, Kind: PrecisionMismatch,
+				Expected: fmt.Sprintf("DECIMAL(%d, %d)", DecimalPrecision, DecimalScale),
+				Actual:   fmt.Sprintf("DECIMAL(%d, %d)", got.precision.Int64, got.scale.Int64),
+			})
+		}
+	}
+
+	for _, name := range t.Internal {
+		seen[name] = true
+	}
+
+	var extra []string
+	for name := range actual {
+		if !seen[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		issues = append(issues, Issue{Table: t.Name, Column: name, Kind: ExtraColumn, Actual: actual[name].dataType})
+	}
+
+	return issues
+}
+
+// ExpectedColumn — ожидаемая колонка, выведенная из db-тега.
+type ExpectedColumn struct {
+	Name string
+	// Types — допустимые значения information_schema.columns.data_type; пусто — тип не проверяется
+	Types   []string
+	Decimal bool
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	decimalType     = reflect.TypeOf(decimal.Decimal{})
+	nullDecimalType = reflect.TypeOf(decimal.NullDecimal{})
+)
+
+// ExpectedColumns возвращает колонки модели в порядке объявления полей.
+func ExpectedColumns(model interface{}) []ExpectedColumn {
+	typ := reflect.TypeOf(model)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	var columns []ExpectedColumn
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := strings.Split(field.Tag.Get("db"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		col := ExpectedColumn{Name: tag, Types: postgresTypes(field.Type)}
+		col.Decimal = derefType(field.Type) == decimalType || derefType(field.Type) == nullDecimalType
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// postgresTypes сопоставляет Go-тип с допустимыми типами PostgreSQL.
+func postgresTypes(t reflect.Type) []string {
+	t = derefType(t)
+	switch t {
+	case timeType:
+		return []string{"timestamp without time zone", "timestamp with time zone"}
+	case decimalType, nullDecimalType:
+		return []string{"numeric"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return []string{"character varying", "text"}
+	case reflect.Bool:
+		return []string{"boolean"}
+	case reflect.Int16:
+		return []string{"smallint"}
+	case reflect.Int32, reflect.Uint32:
+		return []string{"integer"}
+	case reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint:
+		return []string{"bigint"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return []string{"jsonb", "json", "bytea"}
+		}
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
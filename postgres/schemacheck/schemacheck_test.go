@@ -0,0 +1,121 @@
+package schemacheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sample struct {
+	ID        uint64          `db:"id"`
+	Name      string          `db:"name"`
+	Amount    decimal.Decimal `db:"amount"`
+	Flag      bool            `db:"flag"`
+	SyncedAt  *time.Time      `db:"synced_at"`
+	Raw       []byte          `db:"raw"`
+	Ignored   string          `db:"-"`
+	NoTag     string
+	SmallCode int16 `db:"small_code"`
+}
+
+func TestExpectedColumns(t *testing.T) {
+	columns := ExpectedColumns(&sample{})
+
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"id", "name", "amount", "flag", "synced_at", "raw", "small_code"}, names)
+
+	assert.Equal(t, []string{"bigint"}, columns[0].Types)
+	assert.Equal(t, []string{"numeric"}, columns[2].Types)
+	assert.True(t, columns[2].Decimal)
+	assert.Contains(t, columns[4].Types, "timestamp without time zone")
+	assert.Contains(t, columns[5].Types, "jsonb")
+	assert.Equal(t, []string{"smallint"}, columns[6].Types)
+}
+
+func columnRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"table_name", "column_name", "data_type", "numeric_precision", "numeric_scale"})
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	tables := []Table{
+		{Name: "samples", Model: sample{}, Internal: []string{"secret_version"}},
+		{Name: "others", Model: sample{}},
+	}
+
+	t.Run("reports drift", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		rows := columnRows().
+			AddRow("samples", "id", "bigint", 64, 0).
+			AddRow("samples", "name", "integer", 32, 0).
+			AddRow("samples", "amount", "numeric", 20, 8).
+			AddRow("samples", "flag", "boolean", nil, nil).
+			AddRow("samples", "synced_at", "timestamp without time zone", nil, nil).
+			AddRow("samples", "raw", "jsonb", nil, nil).
+			AddRow("samples", "secret_version", "integer", 32, 0).
+			AddRow("samples", "legacy", "text", nil, nil)
+
+		mock.ExpectQuery("SELECT table_name, column_name, data_type, numeric_precision, numeric_scale").
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnRows(rows)
+
+		report, err := Check(ctx, db, tables)
+		require.NoError(t, err)
+		assert.False(t, report.OK())
+
+		assert.Equal(t, []Issue{
+			{Table: "samples", Column: "name", Kind: TypeMismatch, Expected: "character varying or text", Actual: "integer"},
+			{Table: "samples", Column: "amount", Kind: PrecisionMismatch, Expected: "DECIMAL(30, 15)", Actual: "DECIMAL(20, 8)"},
+			{Table: "samples", Column: "small_code", Kind: MissingColumn, Expected: "smallint"},
+			{Table: "samples", Column: "legacy", Kind: ExtraColumn, Actual: "text"},
+			{Table: "others", Kind: MissingTable},
+		}, report.Issues)
+
+		assert.Contains(t, report.String(), "5 schema issue(s)")
+		assert.Contains(t, report.String(), "samples.small_code: missing column (expected smallint)")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("verify returns drift error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT table_name").WithArgs(sqlmock.AnyArg()).WillReturnRows(columnRows())
+
+		err = Verify(ctx, db, tables[:1])
+		assert.ErrorIs(t, err, ErrSchemaDrift)
+		var driftErr *DriftError
+		require.True(t, errors.As(err, &driftErr))
+		assert.Len(t, driftErr.Report.Issues, 1)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT table_name").WithArgs(sqlmock.AnyArg()).WillReturnError(errors.New("connection refused"))
+
+		_, err = Check(ctx, db, tables)
+		assert.ErrorContains(t, err, "connection refused")
+	})
+}
+
+func TestDefaultTablesHaveColumns(t *testing.T) {
+	for _, table := range DefaultTables() {
+		assert.NotEmpty(t, ExpectedColumns(table.Model), table.Name)
+	}
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+const (
+	// maxTxAttempts — сколько раз WithTx выполняет транзакцию при конфликтах сериализации
+	maxTxAttempts = 3
+	// txRetryDelay — базовая задержка перед повтором, удваивается с каждой попыткой
+	txRetryDelay = 20 * time.Millisecond
+)
+
+// WithTx выполняет fn в одной транзакции: все storage, доступные через tx,
+// работают поверх одного *sql.Tx. Если fn возвращает ошибку или паникует,
+// транзакция откатывается, иначе коммитится.
+// При ошибках сериализации (SQLSTATE 40001) и deadlock (40P01) транзакция
+// повторяется целиком, поэтому fn не должна иметь побочных эффектов вне БД.
+func (db *DB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx storage.FullStorage) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = db.runTx(ctx, opts, fn)
+		if err == nil || !isRetryableTxError(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry aborted: %v)", err, ctx.Err())
+		case <-time.After(txRetryDelay << (attempt - 1)):
+		}
+	}
+}
+
+func (db *DB) runTx(ctx context.Context, opts *sql.TxOptions, fn func(tx storage.FullStorage) error) (err error) {
+	sqlTx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	tx := storage.NewTxAdapter(sqlTx)
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+			}
+		}
+	}()
+
+	if err = fn(newFullStorage(tx, db.keys)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", postgreserr.Classify(err))
+	}
+	return nil
+}
+
+// isRetryableTxError сообщает, можно ли повторить транзакцию
+func isRetryableTxError(err error) bool {
+	return errors.Is(postgreserr.Classify(err), postgreserr.ErrSerialization)
+}
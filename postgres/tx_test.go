@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func TestDB_WithTx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commits on success", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE orders SET status").WithArgs("FILLED", "order_1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM orders").WithArgs("order_1").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = newDB(sqlDB, nil).WithTx(ctx, nil, func(tx storage.FullStorage) error {
+			if err := tx.UpdateOrderStatus(ctx, "order_1", "FILLED"); err != nil {
+				return err
+			}
+			return tx.DeleteOrderByID(ctx, "order_1")
+		})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE orders SET status").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		fnErr := errors.New("boom")
+		err = newDB(sqlDB, nil).WithTx(ctx, nil, func(tx storage.FullStorage) error {
+			if err := tx.UpdateOrderStatus(ctx, "order_1", "FILLED"); err != nil {
+				return err
+			}
+			return fnErr
+		})
+		assert.ErrorIs(t, err, fnErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on panic", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = newDB(sqlDB, nil).WithTx(ctx, nil, func(storage.FullStorage) error {
+				panic("boom")
+			})
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("retries serialization failure", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE orders SET status").WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE orders SET status").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		calls := 0
+		err = newDB(sqlDB, nil).WithTx(ctx, nil, func(tx storage.FullStorage) error {
+			calls++
+			return tx.UpdateOrderStatus(ctx, "order_1", "FILLED")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		for i := 0; i < maxTxAttempts; i++ {
+			mock.ExpectBegin()
+			mock.ExpectCommit().WillReturnError(&pq.Error{Code: "40P01"})
+		}
+
+		calls := 0
+		err = newDB(sqlDB, nil).WithTx(ctx, nil, func(storage.FullStorage) error {
+			calls++
+			return nil
+		})
+		assert.ErrorIs(t, err, postgreserr.ErrSerialization)
+		var pqErr *pq.Error
+		require.True(t, errors.As(err, &pqErr))
+		assert.Equal(t, pq.ErrorCode("40P01"), pqErr.Code)
+		assert.Equal(t, maxTxAttempts, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nested transaction uses savepoint", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = newDB(sqlDB, nil).WithTx(ctx, nil, func(tx storage.FullStorage) error {
+			return tx.UpdateUserBalances(ctx, 1, []*domain.UserBalance{{Asset: "BTC"}})
+		})
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// PlaintextVersion — версия ключа для записей, сохраненных без шифрования
+const PlaintextVersion = 0
+
+// ErrDecrypt возвращается, если данные повреждены или зашифрованы другим ключом.
+var ErrDecrypt = errors.New("failed to decrypt")
+
+// Sealed — зашифрованные поля одной записи.
+type Sealed struct {
+	// KeyVersion — версия мастер-ключа, которым обернут DataKey
+	KeyVersion int
+	// DataKey — ключ данных, зашифрованный мастер-ключом
+	DataKey []byte
+	// Fields — зашифрованные значения в base64 в порядке аргументов Seal
+	Fields []string
+}
+
+// Cipher шифрует поля записи по envelope-схеме.
+type Cipher struct {
+	keys KeyProvider
+}
+
+// NewCipher создает Cipher поверх провайдера мастер-ключей.
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+// CurrentVersion возвращает версию мастер-ключа, которой шифруются новые данные.
+func (c *Cipher) CurrentVersion(ctx context.Context) (int, error) {
+	version, _, err := c.keys.CurrentKey(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get current master key: %w", err)
+	}
+	return version, nil
+}
+
+// Seal шифрует поля записи новым ключом данных, обернутым текущим мастер-ключом.
+// Каждое поле привязано к своей позиции, поэтому значения нельзя переставить местами.
+func (c *Cipher) Seal(ctx context.Context, fields ...string) (*Sealed, error) {
+	version, masterKey, err := c.keys.CurrentKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current master key: %w", err)
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrapped, err := seal(masterKey, dataKey, dataKeyAAD(version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	sealed := &Sealed{KeyVersion: version, DataKey: wrapped, Fields: make([]string, len(fields))}
+	for i, field := range fields {
+		ciphertext, err := seal(dataKey, []byte(field), fieldAAD(i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt field %d: %w", i, err)
+		}
+		sealed.Fields[i] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	return sealed, nil
+}
+
+// Open расшифровывает поля, зашифрованные Seal.
+// Для PlaintextVersion поля возвращаются без изменений.
+func (c *Cipher) Open(ctx context.Context, keyVersion int, dataKey []byte, fields ...string) ([]string, error) {
+	if keyVersion == PlaintextVersion {
+		return fields, nil
+	}
+
+	masterKey, err := c.keys.Key(ctx, keyVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get master key: %w", err)
+	}
+
+	plainKey, err := open(masterKey, dataKey, dataKeyAAD(keyVersion))
+	if err != nil {
+		return nil, fmt.Errorf("%w: data key (version %d)", ErrDecrypt, keyVersion)
+	}
+
+	result := make([]string, len(fields))
+	for i, field := range fields {
+		ciphertext, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %d is not valid base64", ErrDecrypt, i)
+		}
+		plaintext, err := open(plainKey, ciphertext, fieldAAD(i))
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %d", ErrDecrypt, i)
+		}
+		result[i] = string(plaintext)
+	}
+
+	return result, nil
+}
+
+func dataKeyAAD(version int) []byte {
+	return []byte("metacore/data-key/v" + strconv.Itoa(version))
+}
+
+func fieldAAD(index int) []byte {
+	return []byte("metacore/field/" + strconv.Itoa(index))
+}
+
+// seal шифрует AES-GCM и возвращает nonce || ciphertext.
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open расшифровывает результат seal.
+func open(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher(t *testing.T) {
+	ctx := context.Background()
+
+	v1, err := NewStaticKeys(map[int][]byte{1: testKey(1)})
+	require.NoError(t, err)
+	v2, err := NewStaticKeys(map[int][]byte{1: testKey(1), 2: testKey(2)})
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		c := NewCipher(v1)
+
+		sealed, err := c.Seal(ctx, "api_key", "secret_key", "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, sealed.KeyVersion)
+		require.Len(t, sealed.Fields, 3)
+		assert.NotContains(t, sealed.Fields[0], "api_key")
+		assert.NotContains(t, string(sealed.DataKey), string(testKey(1)))
+
+		fields, err := c.Open(ctx, sealed.KeyVersion, sealed.DataKey, sealed.Fields...)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api_key", "secret_key", ""}, fields)
+	})
+
+	t.Run("each seal uses a new data key", func(t *testing.T) {
+		c := NewCipher(v1)
+
+		a, err := c.Seal(ctx, "same")
+		require.NoError(t, err)
+		b, err := c.Seal(ctx, "same")
+		require.NoError(t, err)
+		assert.NotEqual(t, a.DataKey, b.DataKey)
+		assert.NotEqual(t, a.Fields, b.Fields)
+	})
+
+	t.Run("old versions stay readable after rotation", func(t *testing.T) {
+		old, err := NewCipher(v1).Seal(ctx, "api_key")
+		require.NoError(t, err)
+
+		c := NewCipher(v2)
+		fields, err := c.Open(ctx, old.KeyVersion, old.DataKey, old.Fields...)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api_key"}, fields)
+
+		fresh, err := c.Seal(ctx, "api_key")
+		require.NoError(t, err)
+		assert.Equal(t, 2, fresh.KeyVersion)
+
+		_, err = NewCipher(v1).Open(ctx, fresh.KeyVersion, fresh.DataKey, fresh.Fields...)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("plaintext version", func(t *testing.T) {
+		fields, err := NewCipher(v1).Open(ctx, PlaintextVersion, nil, "api_key")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"api_key"}, fields)
+	})
+
+	t.Run("tampering is detected", func(t *testing.T) {
+		c := NewCipher(v2)
+		sealed, err := c.Seal(ctx, "api_key", "secret_key")
+		require.NoError(t, err)
+
+		// Поля переставлены местами
+		_, err = c.Open(ctx, sealed.KeyVersion, sealed.DataKey, sealed.Fields[1], sealed.Fields[0])
+		assert.ErrorIs(t, err, ErrDecrypt)
+
+		// Версия ключа подменена
+		_, err = c.Open(ctx, 1, sealed.DataKey, sealed.Fields...)
+		assert.ErrorIs(t, err, ErrDecrypt)
+
+		// Чужой ключ данных
+		other, err := c.Seal(ctx, "api_key", "secret_key")
+		require.NoError(t, err)
+		_, err = c.Open(ctx, sealed.KeyVersion, other.DataKey, sealed.Fields...)
+		assert.ErrorIs(t, err, ErrDecrypt)
+
+		_, err = c.Open(ctx, sealed.KeyVersion, sealed.DataKey, "not base64!", sealed.Fields[1])
+		assert.ErrorIs(t, err, ErrDecrypt)
+
+		_, err = c.Open(ctx, sealed.KeyVersion, []byte("short"), sealed.Fields...)
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+}
@@ -0,0 +1,133 @@
+// Package secrets шифрует чувствительные поля (ключи MEXC API) на уровне приложения.
+// Используется envelope-схема: каждое значение шифруется случайным ключом данных (AES-256-GCM),
+// а ключ данных — мастер-ключом из KeyProvider. Версия мастер-ключа хранится рядом с данными,
+// поэтому мастер-ключ можно сменить без остановки: новые записи шифруются текущим ключом,
+// старые читаются ключом своей версии до перешифрования.
+package secrets
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// KeySize — длина мастер-ключа и ключа данных (AES-256)
+const KeySize = 32
+
+var (
+	ErrKeyNotFound = errors.New("master key not found")
+	ErrInvalidKey  = errors.New("invalid master key")
+)
+
+// KeyProvider выдает мастер-ключи по версиям.
+// Версии — положительные числа; 0 зарезервирован для незашифрованных данных.
+type KeyProvider interface {
+	// CurrentKey возвращает версию и мастер-ключ для шифрования новых данных
+	CurrentKey(ctx context.Context) (version int, key []byte, err error)
+
+	// Key возвращает мастер-ключ указанной версии
+	Key(ctx context.Context, version int) ([]byte, error)
+}
+
+// StaticKeys — набор мастер-ключей в памяти. Текущим считается ключ с наибольшей версией.
+type StaticKeys struct {
+	current int
+	keys    map[int][]byte
+}
+
+// NewStaticKeys создает набор ключей. Каждый ключ должен быть длиной KeySize байт.
+func NewStaticKeys(keys map[int][]byte) (*StaticKeys, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", ErrInvalidKey)
+	}
+
+	s := &StaticKeys{keys: make(map[int][]byte, len(keys))}
+	for version, key := range keys {
+		if version <= 0 {
+			return nil, fmt.Errorf("%w: version %d must be positive", ErrInvalidKey, version)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%w: version %d has %d bytes, want %d", ErrInvalidKey, version, len(key), KeySize)
+		}
+		s.keys[version] = append([]byte(nil), key...)
+		if version > s.current {
+			s.current = version
+		}
+	}
+	return s, nil
+}
+
+// ParseKeys разбирает ключи в формате "версия:base64", разделенные запятыми или переводами строк.
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+func ParseKeys(text string) (*StaticKeys, error) {
+	keys := make(map[int][]byte)
+
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(text, ",", "\n")))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		versionText, encoded, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: expected version:base64, got %q", ErrInvalidKey, line)
+		}
+		version, err := strconv.Atoi(strings.TrimSpace(versionText))
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad version %q", ErrInvalidKey, versionText)
+		}
+		if _, dup := keys[version]; dup {
+			return nil, fmt.Errorf("%w: duplicate version %d", ErrInvalidKey, version)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("%w: version %d is not valid base64: %v", ErrInvalidKey, version, err)
+		}
+		keys[version] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read keys: %w", err)
+	}
+
+	return NewStaticKeys(keys)
+}
+
+// KeysFromEnv читает ключи из переменной окружения в формате ParseKeys.
+func KeysFromEnv(name string) (*StaticKeys, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("%w: environment variable %s is empty", ErrKeyNotFound, name)
+	}
+	return ParseKeys(value)
+}
+
+// KeysFromFile читает ключи из файла в формате ParseKeys, по одному ключу на строку.
+func KeysFromFile(path string) (*StaticKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return ParseKeys(string(data))
+}
+
+// CurrentKey возвращает ключ с наибольшей версией.
+func (s *StaticKeys) CurrentKey(ctx context.Context) (int, []byte, error) {
+	return s.current, s.keys[s.current], nil
+}
+
+// Key возвращает ключ указанной версии.
+func (s *StaticKeys) Key(ctx context.Context, version int) ([]byte, error) {
+	key, ok := s.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: version %d", ErrKeyNotFound, version)
+	}
+	return key, nil
+}
+
+var _ KeyProvider = (*StaticKeys)(nil)
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func encodedKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(testKey(b))
+}
+
+func TestParseKeys(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("current key is the highest version", func(t *testing.T) {
+		keys, err := ParseKeys("1:" + encodedKey(1) + ", 3:" + encodedKey(3) + ",2:" + encodedKey(2))
+		require.NoError(t, err)
+
+		version, key, err := keys.CurrentKey(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 3, version)
+		assert.Equal(t, testKey(3), key)
+
+		key, err = keys.Key(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, testKey(1), key)
+
+		_, err = keys.Key(ctx, 4)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("file with comments", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys")
+		content := "# master keys\n1:" + encodedKey(1) + "\n\n2:" + encodedKey(2) + "\n"
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		keys, err := KeysFromFile(path)
+		require.NoError(t, err)
+		version, _, err := keys.CurrentKey(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, version)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("METACORE_TEST_KEYS", "5:"+encodedKey(5))
+		keys, err := KeysFromEnv("METACORE_TEST_KEYS")
+		require.NoError(t, err)
+		version, _, err := keys.CurrentKey(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 5, version)
+
+		_, err = KeysFromEnv("METACORE_TEST_KEYS_MISSING")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cases := map[string]string{
+			"empty":          "",
+			"no version":     encodedKey(1),
+			"bad version":    "x:" + encodedKey(1),
+			"zero version":   "0:" + encodedKey(1),
+			"bad base64":     "1:not base64!",
+			"short key":      "1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+			"duplicate":      "1:" + encodedKey(1) + ",1:" + encodedKey(2),
+			"negative value": "-1:" + encodedKey(1),
+		}
+		for name, text := range cases {
+			_, err := ParseKeys(text)
+			assert.ErrorIs(t, err, ErrInvalidKey, name)
+		}
+	})
+}
@@ -21,6 +21,15 @@ func (a *DBAdapter) QueryRowContext(ctx context.Context, query string, args ...i
 	return &RowAdapter{Row: row}
 }
 
+// BeginTx implements DBInterface.BeginTx
+func (a *DBAdapter) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := a.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewTxAdapter(tx), nil
+}
+
 // RowAdapter wraps sql.Row to implement RowInterface
 type RowAdapter struct {
 	*sql.Row
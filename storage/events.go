@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// EventTable — таблица, изменения которой публикуются в ленте изменений.
+type EventTable string
+
+const (
+	EventOrders       EventTable = "orders"
+	EventTrades       EventTable = "trades"
+	EventBalances     EventTable = "user_balances"
+	EventOrderUpdates EventTable = "order_updates"
+)
+
+// Valid сообщает, публикуются ли изменения таблицы.
+func (t EventTable) Valid() bool {
+	switch t {
+	case EventOrders, EventTrades, EventBalances, EventOrderUpdates:
+		return true
+	default:
+		return false
+	}
+}
+
+// EventOp — вид изменения.
+type EventOp string
+
+const (
+	EventInsert EventOp = "INSERT"
+	EventUpdate EventOp = "UPDATE"
+	EventDelete EventOp = "DELETE"
+	// EventResync — маркер пересинхронизации: события могли быть потеряны
+	// (переподключение к БД или переполненный буфер подписчика), состояние нужно перечитать.
+	// Первым событием каждой подписки тоже приходит маркер.
+	EventResync EventOp = "RESYNC"
+)
+
+// Event — изменение строки. Событие содержит только ключи записи,
+// актуальное состояние подписчик читает из хранилища.
+type Event struct {
+	Table  EventTable `json:"table"`
+	Op     EventOp    `json:"op"`
+	ID     uint64     `json:"id"`
+	UserID uint64     `json:"user_id"`
+	Key    string     `json:"key,omitempty"` // mexc_order_id, mexc_trade_id, asset или order_id обновления
+	Symbol string     `json:"symbol,omitempty"`
+	Status string     `json:"status,omitempty"` // статус ордера или обновления ордера
+}
+
+// ResyncEvent возвращает маркер пересинхронизации.
+func ResyncEvent() Event {
+	return Event{Op: EventResync}
+}
+
+// IsResync сообщает, является ли событие маркером пересинхронизации.
+func (e Event) IsResync() bool {
+	return e.Op == EventResync
+}
+
+// EventFilter ограничивает события подписки. Пустые поля не ограничивают выборку,
+// маркеры пересинхронизации проходят любой фильтр.
+type EventFilter struct {
+	Tables []EventTable
+	UserID uint64
+}
+
+// Validate проверяет таблицы фильтра.
+func (f EventFilter) Validate() error {
+	for _, t := range f.Tables {
+		if !t.Valid() {
+			return fmt.Errorf("unknown event table %q: %w", t, ErrInvalidFilter)
+		}
+	}
+	return nil
+}
+
+// Match сообщает, проходит ли событие фильтр.
+func (f EventFilter) Match(e Event) bool {
+	if e.IsResync() {
+		return true
+	}
+	if len(f.Tables) > 0 && !slices.Contains(f.Tables, e.Table) {
+		return false
+	}
+	return f.UserID == 0 || f.UserID == e.UserID
+}
+
+// ChangeFeed — лента изменений ордеров, сделок, балансов и истории ордеров.
+type ChangeFeed interface {
+	// Subscribe возвращает канал событий, проходящих filter. Канал закрывается
+	// после отмены ctx или закрытия ленты. Если подписчик не успевает читать,
+	// лишние события отбрасываются и следующим приходит маркер EventResync.
+	Subscribe(ctx context.Context, filter EventFilter) (<-chan Event, error)
+}
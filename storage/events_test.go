@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFilter(t *testing.T) {
+	order := Event{Table: EventOrders, Op: EventUpdate, ID: 1, UserID: 7}
+
+	t.Run("match", func(t *testing.T) {
+		assert.True(t, EventFilter{}.Match(order))
+		assert.True(t, EventFilter{Tables: []EventTable{EventTrades, EventOrders}, UserID: 7}.Match(order))
+		assert.False(t, EventFilter{Tables: []EventTable{EventBalances}}.Match(order))
+		assert.False(t, EventFilter{UserID: 8}.Match(order))
+		assert.True(t, EventFilter{Tables: []EventTable{EventBalances}, UserID: 8}.Match(ResyncEvent()))
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		assert.NoError(t, EventFilter{Tables: []EventTable{EventOrders, EventOrderUpdates}}.Validate())
+		assert.ErrorIs(t, EventFilter{Tables: []EventTable{"prices"}}.Validate(), ErrInvalidFilter)
+	})
+}
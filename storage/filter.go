@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+)
+
+// ErrInvalidFilter возвращается для фильтра с неизвестным значением перечисления.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// SortField — поле сортировки списка. При равенстве значений записи упорядочиваются по id
+// в том же направлении.
+type SortField string
+
+const (
+	// SortByTime — время записи: created_at ордера, trade_time сделки. Используется по умолчанию
+	SortByTime     SortField = "time"
+	SortByPrice    SortField = "price"
+	SortByQuantity SortField = "quantity"
+)
+
+func (f SortField) validate() error {
+	switch f {
+	case "", SortByTime, SortByPrice, SortByQuantity:
+		return nil
+	default:
+		return fmt.Errorf("unknown sort field %q: %w", f, ErrInvalidFilter)
+	}
+}
+
+// OrderFilter определяет фильтры для получения ордеров.
+// Пустые поля не ограничивают выборку, диапазоны включают границы.
+type OrderFilter struct {
+	Symbol              string // объединяется с Symbols
+	Symbols             []string
+	Status              domain.OrderStatus // объединяется со Statuses
+	Statuses            []domain.OrderStatus
+	Side                domain.OrderSide
+	Type                domain.OrderType
+	ClientOrderIDPrefix string
+	MinPrice            *decimal.Decimal
+	MaxPrice            *decimal.Decimal
+	MinQuantity         *decimal.Decimal
+	MaxQuantity         *decimal.Decimal
+	StartTime           *time.Time // по transact_time
+	EndTime             *time.Time
+	SortBy              SortField // по умолчанию SortByTime
+	SortAsc             bool      // по умолчанию от больших значений к меньшим
+	Limit               int
+	Offset              int
+}
+
+// AllSymbols возвращает Symbol вместе с Symbols.
+func (f OrderFilter) AllSymbols() []string {
+	return withOne(f.Symbols, f.Symbol)
+}
+
+// AllStatuses возвращает Status вместе со Statuses.
+func (f OrderFilter) AllStatuses() []domain.OrderStatus {
+	return withOne(f.Statuses, f.Status)
+}
+
+// Validate проверяет значения перечислений фильтра.
+func (f OrderFilter) Validate() error {
+	if f.Side != "" && !f.Side.Valid() {
+		return fmt.Errorf("unknown order side %q: %w", f.Side, ErrInvalidFilter)
+	}
+	if f.Type != "" && !f.Type.Valid() {
+		return fmt.Errorf("unknown order type %q: %w", f.Type, ErrInvalidFilter)
+	}
+	return f.SortBy.validate()
+}
+
+// TradeFilter определяет фильтры для получения сделок.
+// Пустые поля не ограничивают выборку, диапазоны включают границы.
+type TradeFilter struct {
+	Symbol      string // объединяется с Symbols
+	Symbols     []string
+	Side        domain.OrderSide // BUY — сделки, где пользователь покупатель
+	MinPrice    *decimal.Decimal
+	MaxPrice    *decimal.Decimal
+	MinQuantity *decimal.Decimal
+	MaxQuantity *decimal.Decimal
+	StartTime   *time.Time
+	EndTime     *time.Time
+	SortBy      SortField // по умолчанию SortByTime
+	SortAsc     bool      // по умолчанию от больших значений к меньшим
+	Limit       int
+	Offset      int
+}
+
+// AllSymbols возвращает Symbol вместе с Symbols.
+func (f TradeFilter) AllSymbols() []string {
+	return withOne(f.Symbols, f.Symbol)
+}
+
+// Validate проверяет значения перечислений фильтра.
+func (f TradeFilter) Validate() error {
+	if f.Side != "" && !f.Side.Valid() {
+		return fmt.Errorf("unknown order side %q: %w", f.Side, ErrInvalidFilter)
+	}
+	return f.SortBy.validate()
+}
+
+// TransferFilter определяет фильтры для получения вводов и выводов
+type TransferFilter struct {
+	Asset     string
+	Status    domain.TransferStatus
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int
+	Offset    int
+}
+
+func withOne[T comparable](list []T, one T) []T {
+	var zero T
+	if one == zero {
+		return list
+	}
+	return append(append([]T(nil), list...), one)
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"iter"
 	"time"
 
 	"github.com/samar/sup_bot/metacore/domain"
@@ -13,31 +14,57 @@ type RowInterface interface {
 	Scan(dest ...interface{}) error
 }
 
-// OrderFilter определяет фильтры для получения ордеров
-type OrderFilter struct {
-	Symbol    string
-	Status    string
-	StartTime *time.Time
-	EndTime   *time.Time
-	Limit     int
-	Offset    int
+// UpsertResult описывает, что сделал upsert с записью
+type UpsertResult int
+
+const (
+	// UpsertIgnored — запись не изменилась или снимок устарел
+	UpsertIgnored UpsertResult = iota
+	// UpsertCreated — запись создана
+	UpsertCreated
+	// UpsertUpdated — запись обновлена
+	UpsertUpdated
+)
+
+func (r UpsertResult) String() string {
+	switch r {
+	case UpsertCreated:
+		return "created"
+	case UpsertUpdated:
+		return "updated"
+	default:
+		return "ignored"
+	}
 }
 
 type OrderStorage interface {
 	// CreateOrder сохраняет новый ордер в хранилище.
 	CreateOrder(ctx context.Context, order *domain.Order) error
 
+	// UpsertOrder создает ордер или обновляет status, executed_quantity и cummulative_quote_qty
+	// существующего. Снимок, который откатывает ордер назад, игнорируется.
+	UpsertOrder(ctx context.Context, order *domain.Order) (UpsertResult, error)
+
+	// UpsertOrders выполняет UpsertOrder для каждого ордера в одной транзакции.
+	// Результаты возвращаются в порядке входных ордеров.
+	UpsertOrders(ctx context.Context, orders []*domain.Order) ([]UpsertResult, error)
+
 	// DeleteOrderByID удаляет ордер из хранилища по его mexc_order_id.
 	DeleteOrderByID(ctx context.Context, mexcOrderID string) error
 
-	// UpdateOrderStatus обновляет статус ордера (полезно будет сразу)
-	UpdateOrderStatus(ctx context.Context, mexcOrderID, status string) error
+	// UpdateOrderStatus обновляет статус ордера, если переход разрешен таблицей переходов domain.
+	// Недопустимый переход возвращает *domain.TransitionError (errors.Is(err, domain.ErrInvalidTransition)).
+	UpdateOrderStatus(ctx context.Context, mexcOrderID string, status domain.OrderStatus) error
 
 	// GetOrderByID получает ордер по его mexc_order_id (полезно будет сразу)
 	GetOrderByID(ctx context.Context, mexcOrderID string) (*domain.Order, error)
 
 	// GetUserOrders получает ордера пользователя с фильтрами
 	GetUserOrders(ctx context.Context, userID uint64, filters ...OrderFilter) ([]*domain.Order, error)
+	// IterOrders обходит ордера пользователя как GetUserOrders, не загружая их в память
+	IterOrders(ctx context.Context, userID uint64, filters ...OrderFilter) iter.Seq2[*domain.Order, error]
+	// GetUserOrdersPage получает страницу ордеров по курсору (created_at, id); Limit и Offset фильтра игнорируются
+	GetUserOrdersPage(ctx context.Context, userID uint64, filter OrderFilter, page PageRequest) (*Page[*domain.Order], error)
 
 	// GetOpenOrders получает активные ордера пользователя (опционально по символу)
 	GetOpenOrders(ctx context.Context, userID uint64, symbol string) ([]*domain.Order, error)
@@ -59,6 +86,9 @@ type UserStorage interface {
 	// GetAllUsers получает всех пользователей
 	GetAllUsers(ctx context.Context) ([]*domain.User, error)
 
+	// IterAllUsers обходит всех пользователей в порядке id, не загружая их в память
+	IterAllUsers(ctx context.Context) iter.Seq2[*domain.User, error]
+
 	// UpdateUser обновляет пользователя
 	UpdateUser(ctx context.Context, user *domain.User) error
 
@@ -66,24 +96,39 @@ type UserStorage interface {
 	DeleteUser(ctx context.Context, id uint64) error
 }
 
-// TradeFilter определяет фильтры для получения сделок
-type TradeFilter struct {
-	Symbol    string
-	StartTime *time.Time
-	EndTime   *time.Time
-	Limit     int
-	Offset    int
+// BulkInsertResult — итог пакетной записи.
+type BulkInsertResult struct {
+	Inserted int // записано новых строк
+	Skipped  int // пропущено строк, уже существующих в БД или повторенных во входных данных
 }
 
 type TradeStorage interface {
-	// CreateTrade создает новую сделку
+	// CreateTrade создает новую сделку и в той же транзакции проводит ее по ledger
+	// (domain.TradeLedgerEntries). Символ с неизвестным котируемым активом возвращает domain.ErrUnknownSymbol.
 	CreateTrade(ctx context.Context, trade *domain.Trade) error
 
+	// CreateTrades записывает сделки одной транзакцией, пропуская уже существующие mexc_trade_id,
+	// и проводит новые сделки по ledger. Предназначен для загрузки истории /api/v3/myTrades.
+	// ID и CreatedAt заполняются только у записанных сделок.
+	CreateTrades(ctx context.Context, trades []*domain.Trade) (BulkInsertResult, error)
+
 	// GetTradeByID получает сделку по MEXC Trade ID
 	GetTradeByID(ctx context.Context, mexcTradeID string) (*domain.Trade, error)
 
 	// GetUserTrades получает все сделки пользователя с фильтрацией
 	GetUserTrades(ctx context.Context, userID uint64, filters ...TradeFilter) ([]*domain.Trade, error)
+	// IterUserTrades обходит сделки пользователя как GetUserTrades, не загружая их в память
+	IterUserTrades(ctx context.Context, userID uint64, filters ...TradeFilter) iter.Seq2[*domain.Trade, error]
+	// GetUserTradesPage получает страницу сделок по курсору (trade_time, id); Limit и Offset фильтра игнорируются
+	GetUserTradesPage(ctx context.Context, userID uint64, filter TradeFilter, page PageRequest) (*Page[*domain.Trade], error)
+
+	// GetTradesByOrderID получает сделки ордера по mexc_order_id в порядке исполнения.
+	// Сводку исполнения считает domain.SummarizeFills.
+	GetTradesByOrderID(ctx context.Context, orderID string) ([]*domain.Trade, error)
+
+	// GetFillMismatches находит ордера пользователя, у которых executed_quantity
+	// или cummulative_quote_qty не совпадают с суммой их сделок
+	GetFillMismatches(ctx context.Context, userID uint64) ([]*domain.FillMismatch, error)
 }
 
 type BalanceStorage interface {
@@ -98,6 +143,12 @@ type BalanceStorage interface {
 
 	// UpdateUserBalances обновляет все балансы пользователя атомарно
 	UpdateUserBalances(ctx context.Context, userID uint64, balances []*domain.UserBalance) error
+
+	// GetBalancesAt возвращает ненулевые балансы пользователя на момент t, отсортированные по активу
+	GetBalancesAt(ctx context.Context, userID uint64, t time.Time) ([]*domain.BalanceSnapshot, error)
+
+	// GetBalanceHistory возвращает изменения баланса актива за период [from, to] в хронологическом порядке
+	GetBalanceHistory(ctx context.Context, userID uint64, asset string, from, to time.Time) ([]*domain.BalanceSnapshot, error)
 }
 
 type OrderUpdateStorage interface {
@@ -106,6 +157,138 @@ type OrderUpdateStorage interface {
 
 	// GetOrderUpdates возвращает историю изменений по ордеру
 	GetOrderUpdates(ctx context.Context, userID uint64, orderID string) ([]*domain.OrderUpdate, error)
+	// IterOrderUpdates обходит историю ордера как GetOrderUpdates, не загружая ее в память
+	IterOrderUpdates(ctx context.Context, userID uint64, orderID string) iter.Seq2[*domain.OrderUpdate, error]
+	// GetOrderUpdatesPage получает страницу истории ордера по курсору (update_time, id)
+	GetOrderUpdatesPage(ctx context.Context, userID uint64, orderID string, page PageRequest) (*Page[*domain.OrderUpdate], error)
+}
+
+type OrderListStorage interface {
+	// CreateOrderList сохраняет список ордеров (OCO) вместе с ордерами списка в одной транзакции.
+	// OrderListID и UserID ордеров заполняются из списка.
+	CreateOrderList(ctx context.Context, list *domain.OrderList, orders []*domain.Order) error
+
+	// GetOrderList получает список ордеров по mexc_order_list_id
+	GetOrderList(ctx context.Context, mexcOrderListID string) (*domain.OrderList, error)
+
+	// GetOrderListOrders получает ордера списка, отсортированные по id
+	GetOrderListOrders(ctx context.Context, mexcOrderListID string) ([]*domain.Order, error)
+
+	// UpdateOrderListStatus обновляет статусы списка ордеров
+	UpdateOrderListStatus(ctx context.Context, mexcOrderListID string, statusType domain.ListStatusType, orderStatus domain.ListOrderStatus) error
+}
+
+type LedgerStorage interface {
+	// PostEntries записывает проводки (ввод, вывод) в одной транзакции.
+	// Проводки каждой операции по каждому активу должны давать в сумме ноль, иначе domain.ErrUnbalancedEntries.
+	PostEntries(ctx context.Context, entries []*domain.LedgerEntry) error
+
+	// GetLedgerEntries получает проводки счета USER по активу в хронологическом порядке
+	// с нарастающим балансом в Balance
+	GetLedgerEntries(ctx context.Context, userID uint64, asset string) ([]*domain.LedgerEntry, error)
+
+	// GetLedgerBalances получает баланс счета USER по каждому активу, отсортированный по активу
+	GetLedgerBalances(ctx context.Context, userID uint64) ([]*domain.LedgerBalance, error)
+
+	// Reconcile сравнивает балансы ledger с последними балансами user_balances (free + locked)
+	Reconcile(ctx context.Context, userID uint64) (*domain.ReconciliationReport, error)
+}
+
+type PriceStorage interface {
+	// SavePrices сохраняет наблюдения цен в одной транзакции.
+	// Повтор наблюдения (symbol, source, observed_at) обновляет цену.
+	SavePrices(ctx context.Context, prices []*domain.Price) error
+
+	// GetLatestPrice получает последнюю цену символа по всем источникам
+	GetLatestPrice(ctx context.Context, symbol string) (*domain.Price, error)
+
+	// GetLatestPrices получает последнюю цену каждого символа, отсортированные по символу
+	GetLatestPrices(ctx context.Context) ([]*domain.Price, error)
+
+	// GetPricesAt получает последнюю цену каждого символа на момент t, отсортированные по символу
+	GetPricesAt(ctx context.Context, t time.Time) ([]*domain.Price, error)
+
+	// GetPriceHistory получает цены символа за период [from, to] в хронологическом порядке
+	GetPriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]*domain.Price, error)
+}
+
+type SymbolStorage interface {
+	// UpsertSymbols создает или обновляет символы по имени в одной транзакции
+	UpsertSymbols(ctx context.Context, symbols []*domain.Symbol) error
+
+	// GetSymbol получает символ и его правила
+	GetSymbol(ctx context.Context, symbol string) (*domain.Symbol, error)
+
+	// GetSymbols получает все символы, отсортированные по имени
+	GetSymbols(ctx context.Context) ([]*domain.Symbol, error)
+}
+
+type DepositStorage interface {
+	// UpsertDeposits создает или обновляет вводы по mexc_deposit_id в одной транзакции.
+	// Ввод в статусе SUCCESS один раз проводится по ledger (domain.DepositLedgerEntries)
+	UpsertDeposits(ctx context.Context, deposits []*domain.Deposit) error
+
+	// GetDepositByID получает ввод по MEXC ID
+	GetDepositByID(ctx context.Context, mexcDepositID string) (*domain.Deposit, error)
+
+	// GetUserDeposits получает вводы пользователя с фильтрацией, новые первыми
+	GetUserDeposits(ctx context.Context, userID uint64, filters ...TransferFilter) ([]*domain.Deposit, error)
+}
+
+type WithdrawalStorage interface {
+	// UpsertWithdrawals создает или обновляет выводы по mexc_withdrawal_id в одной транзакции.
+	// Вывод в статусе SUCCESS один раз проводится по ledger (domain.WithdrawalLedgerEntries)
+	UpsertWithdrawals(ctx context.Context, withdrawals []*domain.Withdrawal) error
+
+	// GetWithdrawalByID получает вывод по MEXC ID
+	GetWithdrawalByID(ctx context.Context, mexcWithdrawalID string) (*domain.Withdrawal, error)
+
+	// GetUserWithdrawals получает выводы пользователя с фильтрацией, новые первыми
+	GetUserWithdrawals(ctx context.Context, userID uint64, filters ...TransferFilter) ([]*domain.Withdrawal, error)
+}
+
+type SyncCursorStorage interface {
+	// GetSyncCursor получает курсор синхронизации потока stream пользователя.
+	// symbol пуст для потоков без символа (вводы, выводы)
+	GetSyncCursor(ctx context.Context, userID uint64, stream domain.SyncStream, symbol string) (*domain.SyncCursor, error)
+
+	// GetSyncCursors получает все курсоры пользователя, отсортированные по stream и symbol
+	GetSyncCursors(ctx context.Context, userID uint64) ([]*domain.SyncCursor, error)
+
+	// SaveSyncCursor создает или обновляет курсор. Version курсора должна совпадать с сохраненной
+	// (0 для нового курсора), иначе возвращается postgreserr.ErrSyncCursorConflict.
+	// Чтобы сбой не пропустил и не учел дважды данные, вызывайте в WithTx вместе с их записью.
+	SaveSyncCursor(ctx context.Context, cursor *domain.SyncCursor) error
+}
+
+type OutboxStorage interface {
+	// EnqueueMessage ставит сообщение в очередь и заполняет ID, Status, NextAttemptAt и CreatedAt.
+	// Вызывайте в WithTx вместе с изменением, о котором сообщение: оно попадет в очередь только с коммитом.
+	// Если сообщение с тем же DedupKey уже есть, возвращает false и очередь не меняет
+	EnqueueMessage(ctx context.Context, msg *domain.OutboxMessage) (bool, error)
+
+	// GetOutboxMessage получает сообщение по ID
+	GetOutboxMessage(ctx context.Context, id uint64) (*domain.OutboxMessage, error)
+
+	// GetOutboxMessages получает до limit сообщений со статусом status по возрастанию ID.
+	// limit <= 0 — без ограничения
+	GetOutboxMessages(ctx context.Context, status domain.OutboxStatus, limit int) ([]*domain.OutboxMessage, error)
+
+	// ClaimMessages захватывает на lease до limit сообщений PENDING, время отправки которых наступило,
+	// и увеличивает их Attempts. Строки, захваченные другим диспетчером, пропускаются (SKIP LOCKED)
+	ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error)
+
+	// MarkMessageSent переводит сообщение в SENT
+	MarkMessageSent(ctx context.Context, id uint64) error
+
+	// RetryMessage снимает захват, сохраняет ошибку и откладывает отправку на delay
+	RetryMessage(ctx context.Context, id uint64, lastError string, delay time.Duration) error
+
+	// DeadLetterMessage переводит сообщение в DEAD с ошибкой lastError
+	DeadLetterMessage(ctx context.Context, id uint64, lastError string) error
+
+	// RequeueMessage возвращает сообщение DEAD в очередь со сброшенными попытками
+	RequeueMessage(ctx context.Context, id uint64) error
 }
 
 // FullStorage объединяет все интерфейсы хранилища.
@@ -115,6 +298,14 @@ type FullStorage interface {
 	TradeStorage
 	BalanceStorage
 	OrderUpdateStorage
+	OrderListStorage
+	LedgerStorage
+	PriceStorage
+	SymbolStorage
+	SyncCursorStorage
+	DepositStorage
+	WithdrawalStorage
+	OutboxStorage
 }
 
 // DBInterface определяет интерфейс для работы с базой данных,
@@ -124,7 +315,17 @@ type DBInterface interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) RowInterface
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
-	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
 	PingContext(ctx context.Context) error
 	Close() error
 }
+
+// Tx определяет интерфейс транзакции, который возвращает DBInterface.BeginTx.
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) RowInterface
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	Commit() error
+	Rollback() error
+}
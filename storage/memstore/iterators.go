@@ -0,0 +1,63 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// IterAllUsers обходит всех пользователей в порядке id.
+func (s *Store) IterAllUsers(ctx context.Context) iter.Seq2[*domain.User, error] {
+	return iterate(ctx, "users", func() ([]*domain.User, error) {
+		return s.GetAllUsers(ctx)
+	})
+}
+
+// IterOrders обходит ордера пользователя как GetUserOrders.
+func (s *Store) IterOrders(ctx context.Context, userID uint64, filters ...storage.OrderFilter) iter.Seq2[*domain.Order, error] {
+	return iterate(ctx, "orders", func() ([]*domain.Order, error) {
+		return s.GetUserOrders(ctx, userID, filters...)
+	})
+}
+
+// IterUserTrades обходит сделки пользователя как GetUserTrades.
+func (s *Store) IterUserTrades(ctx context.Context, userID uint64, filters ...storage.TradeFilter) iter.Seq2[*domain.Trade, error] {
+	return iterate(ctx, "user trades", func() ([]*domain.Trade, error) {
+		return s.GetUserTrades(ctx, userID, filters...)
+	})
+}
+
+// IterOrderUpdates обходит историю ордера как GetOrderUpdates.
+func (s *Store) IterOrderUpdates(ctx context.Context, userID uint64, orderID string) iter.Seq2[*domain.OrderUpdate, error] {
+	return iterate(ctx, "order updates", func() ([]*domain.OrderUpdate, error) {
+		return s.GetOrderUpdates(ctx, userID, orderID)
+	})
+}
+
+// iterate отдает снимок, сделанный при первом обходе. Блокировка на время обхода
+// не удерживается, поэтому потребитель может обращаться к хранилищу из цикла.
+// Отмена контекста во время обхода отдается последним элементом, как в PostgreSQL.
+func iterate[T any](ctx context.Context, what string, load func() ([]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		items, err := load()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for _, item := range items {
+			if err := ctx.Err(); err != nil {
+				yield(zero, fmt.Errorf("failed to query %s: %w", what, postgreserr.Classify(err)))
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+// ledgerKey соответствует UNIQUE(transaction_id, account, asset, kind) в таблице ledger_entries.
+type ledgerKey struct {
+	transactionID string
+	account       domain.LedgerAccount
+	asset         string
+	kind          domain.LedgerEntryKind
+}
+
+// --- Ledger ---
+
+// PostEntries записывает проводки атомарно.
+func (s *Store) PostEntries(ctx context.Context, entries []*domain.LedgerEntry) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insertEntries(entries)
+}
+
+// insertEntries проверяет все проводки и только затем записывает их.
+// Вызывается под блокировкой.
+func (s *Store) insertEntries(entries []*domain.LedgerEntry) error {
+	if err := domain.ValidateLedgerEntries(entries); err != nil {
+		return err
+	}
+
+	batch := make(map[ledgerKey]struct{}, len(entries))
+	for _, e := range entries {
+		if err := s.checkUserExists(e.UserID, "ledger_entries"); err != nil {
+			return fmt.Errorf("failed to create ledger entry %s: %w", e.TransactionID, err)
+		}
+		key := ledgerKey{e.TransactionID, e.Account, e.Asset, e.Kind}
+		_, inBatch := batch[key]
+		if _, ok := s.ledgerKeys[key]; ok || inBatch {
+			return fmt.Errorf("failed to create ledger entry %s: %w", e.TransactionID,
+				duplicateError("ledger_entries", "ledger_entries_transaction_id_account_asset_kind_key"))
+		}
+		batch[key] = struct{}{}
+	}
+
+	now := s.now()
+	for _, e := range entries {
+		s.nextLedgerID++
+		e.ID = s.nextLedgerID
+		e.CreatedAt = now
+
+		stored := *e
+		s.ledger = append(s.ledger, &stored)
+		s.ledgerKeys[ledgerKey{e.TransactionID, e.Account, e.Asset, e.Kind}] = struct{}{}
+	}
+
+	return nil
+}
+
+// GetLedgerEntries получает проводки счета USER по активу с нарастающим балансом.
+func (s *Store) GetLedgerEntries(ctx context.Context, userID uint64, asset string) ([]*domain.LedgerEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query ledger entries: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []*domain.LedgerEntry
+	for _, e := range s.ledger {
+		if e.UserID == userID && e.Asset == asset && e.Account == domain.LedgerAccountUser {
+			entry := *e
+			entries = append(entries, &entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].EntryTime.Equal(entries[j].EntryTime) {
+			return entries[i].EntryTime.Before(entries[j].EntryTime)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	balance := decimal.Zero
+	for _, e := range entries {
+		balance = balance.Add(e.Amount)
+		e.Balance = balance
+	}
+
+	return entries, nil
+}
+
+// GetLedgerBalances получает баланс счета USER по каждому активу.
+func (s *Store) GetLedgerBalances(ctx context.Context, userID uint64) ([]*domain.LedgerBalance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query ledger balances: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ledgerBalances(userID), nil
+}
+
+// ledgerBalances суммирует проводки счета USER по активам. Вызывается под блокировкой.
+func (s *Store) ledgerBalances(userID uint64) []*domain.LedgerBalance {
+	byAsset := make(map[string]*domain.LedgerBalance)
+	var balances []*domain.LedgerBalance
+	for _, e := range s.ledger {
+		if e.UserID != userID || e.Account != domain.LedgerAccountUser {
+			continue
+		}
+		b, ok := byAsset[e.Asset]
+		if !ok {
+			b = &domain.LedgerBalance{Asset: e.Asset}
+			byAsset[e.Asset] = b
+			balances = append(balances, b)
+		}
+		b.Balance = b.Balance.Add(e.Amount)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Asset < balances[j].Asset })
+
+	return balances
+}
+
+// Reconcile сравнивает балансы ledger с последними балансами user_balances.
+func (s *Store) Reconcile(ctx context.Context, userID uint64) (*domain.ReconciliationReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query ledger balances: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var balances []*domain.UserBalance
+	for key, b := range s.balances {
+		if key.userID == userID {
+			balances = append(balances, b)
+		}
+	}
+
+	return domain.Reconcile(userID, s.ledgerBalances(userID), balances), nil
+}
@@ -0,0 +1,1305 @@
+// Package memstore предоставляет потокобезопасную in-memory реализацию storage.FullStorage.
+// Поведение повторяет PostgreSQL-хранилище: те же ошибки postgreserr, уникальные ключи,
+// фильтры и сортировка. Подходит для unit-тестов и локального запуска без БД.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// balanceKey соответствует UNIQUE(user_id, asset) в таблице user_balances.
+type balanceKey struct {
+	userID uint64
+	asset  string
+}
+
+// Store хранит все сущности в памяти под одним мьютексом.
+type Store struct {
+	mu sync.RWMutex
+
+	users    map[uint64]*domain.User
+	orders   map[string]*domain.Order // по mexc_order_id
+	trades   map[string]*domain.Trade // по mexc_trade_id
+	balances map[balanceKey]*domain.UserBalance
+	updates  []*domain.OrderUpdate
+
+	// snapshots повторяет balance_snapshots: история изменений балансов в порядке записи
+	snapshots []*domain.BalanceSnapshot
+
+	orderLists map[string]*domain.OrderList // по mexc_order_list_id
+
+	// ledger повторяет ledger_entries в порядке записи, ledgerKeys — его уникальный ключ
+	ledger     []*domain.LedgerEntry
+	ledgerKeys map[ledgerKey]struct{}
+
+	prices  map[priceKey]*domain.Price
+	symbols map[string]*domain.Symbol // по symbol
+
+	syncCursors map[syncCursorKey]*domain.SyncCursor
+
+	deposits    map[string]*domain.Deposit    // по mexc_deposit_id
+	withdrawals map[string]*domain.Withdrawal // по mexc_withdrawal_id
+
+	// outbox повторяет таблицу outbox, outboxKeys — ее UNIQUE(dedup_key)
+	outbox     map[uint64]*domain.OutboxMessage
+	outboxKeys map[string]uint64
+
+	// orderInternalIDs повторяет UNIQUE(internal_id) в таблице orders
+	orderInternalIDs map[int64]string
+
+	nextUserID       uint64
+	nextOrderID      uint64
+	nextTradeID      uint64
+	nextBalanceID    uint64
+	nextUpdateID     uint64
+	nextListID       uint64
+	nextSnapshotID   uint64
+	nextLedgerID     uint64
+	nextPriceID      uint64
+	nextSymbolID     uint64
+	nextSyncCursorID uint64
+	nextDepositID    uint64
+	nextWithdrawalID uint64
+	nextOutboxID     uint64
+
+	now func() time.Time
+}
+
+// New создает пустое хранилище.
+func New() *Store {
+	return &Store{
+		users:            make(map[uint64]*domain.User),
+		orders:           make(map[string]*domain.Order),
+		trades:           make(map[string]*domain.Trade),
+		balances:         make(map[balanceKey]*domain.UserBalance),
+		orderInternalIDs: make(map[int64]string),
+		orderLists:       make(map[string]*domain.OrderList),
+		ledgerKeys:       make(map[ledgerKey]struct{}),
+		prices:           make(map[priceKey]*domain.Price),
+		symbols:          make(map[string]*domain.Symbol),
+		syncCursors:      make(map[syncCursorKey]*domain.SyncCursor),
+		deposits:         make(map[string]*domain.Deposit),
+		withdrawals:      make(map[string]*domain.Withdrawal),
+		outbox:           make(map[uint64]*domain.OutboxMessage),
+		outboxKeys:       make(map[string]uint64),
+		now:              time.Now,
+	}
+}
+
+// --- Users ---
+
+// CreateUser создает нового пользователя.
+func (s *Store) CreateUser(ctx context.Context, user *domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to create user: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextUserID++
+	now := s.now()
+
+	stored := copyUser(user)
+	stored.ID = s.nextUserID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.users[stored.ID] = stored
+
+	user.ID = stored.ID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	return nil
+}
+
+// GetUserByID получает пользователя по ID.
+func (s *Store) GetUserByID(ctx context.Context, id uint64) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user with id %d not found: %w", id, postgreserr.ErrUserNotFound)
+	}
+
+	return copyUser(user), nil
+}
+
+// GetUserByMexcUID получает пользователя по MEXC UID.
+func (s *Store) GetUserByMexcUID(ctx context.Context, mexcUID string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if user := s.findUser(func(u *domain.User) bool { return u.MexcUID == mexcUID }); user != nil {
+		return copyUser(user), nil
+	}
+
+	return nil, fmt.Errorf("user with mexc_uid %s not found: %w", mexcUID, postgreserr.ErrUserNotFound)
+}
+
+// GetUserByTelegramID получает пользователя по Telegram ID.
+func (s *Store) GetUserByTelegramID(ctx context.Context, telegramID int64) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if user := s.findUser(func(u *domain.User) bool { return u.TelegramID == telegramID }); user != nil {
+		return copyUser(user), nil
+	}
+
+	return nil, fmt.Errorf("user with telegram_id %d not found: %w", telegramID, postgreserr.ErrUserNotFound)
+}
+
+// GetAllUsers получает всех пользователей, отсортированных по id.
+func (s *Store) GetAllUsers(ctx context.Context) ([]*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []*domain.User
+	for _, u := range s.users {
+		users = append(users, copyUser(u))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return users, nil
+}
+
+// UpdateUser обновляет пользователя.
+func (s *Store) UpdateUser(ctx context.Context, user *domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to update user: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[user.ID]
+	if !ok {
+		return fmt.Errorf("user with id %d not found: %w", user.ID, postgreserr.ErrUserNotFound)
+	}
+
+	stored := copyUser(user)
+	stored.CreatedAt = existing.CreatedAt
+	stored.UpdatedAt = s.now()
+	s.users[user.ID] = stored
+
+	return nil
+}
+
+// DeleteUser удаляет пользователя вместе со всеми связанными записями (ON DELETE CASCADE).
+func (s *Store) DeleteUser(ctx context.Context, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to delete user: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return fmt.Errorf("user with id %d not found: %w", id, postgreserr.ErrUserNotFound)
+	}
+
+	delete(s.users, id)
+
+	for key, o := range s.orders {
+		if o.UserID == id {
+			delete(s.orders, key)
+			delete(s.orderInternalIDs, o.InternalID)
+		}
+	}
+	for key, t := range s.trades {
+		if t.UserID == id {
+			delete(s.trades, key)
+		}
+	}
+	for key := range s.balances {
+		if key.userID == id {
+			delete(s.balances, key)
+		}
+	}
+	for key, l := range s.orderLists {
+		if l.UserID == id {
+			delete(s.orderLists, key)
+		}
+	}
+
+	kept := s.updates[:0]
+	for _, u := range s.updates {
+		if u.UserID != id {
+			kept = append(kept, u)
+		}
+	}
+	s.updates = kept
+
+	keptSnapshots := s.snapshots[:0]
+	for _, b := range s.snapshots {
+		if b.UserID != id {
+			keptSnapshots = append(keptSnapshots, b)
+		}
+	}
+	s.snapshots = keptSnapshots
+
+	keptEntries := s.ledger[:0]
+	for _, e := range s.ledger {
+		if e.UserID != id {
+			keptEntries = append(keptEntries, e)
+			continue
+		}
+		delete(s.ledgerKeys, ledgerKey{e.TransactionID, e.Account, e.Asset, e.Kind})
+	}
+	s.ledger = keptEntries
+
+	for key := range s.syncCursors {
+		if key.userID == id {
+			delete(s.syncCursors, key)
+		}
+	}
+	for key, d := range s.deposits {
+		if d.UserID == id {
+			delete(s.deposits, key)
+		}
+	}
+	for key, w := range s.withdrawals {
+		if w.UserID == id {
+			delete(s.withdrawals, key)
+		}
+	}
+	for key, m := range s.outbox {
+		if m.UserID == id {
+			delete(s.outbox, key)
+			delete(s.outboxKeys, m.DedupKey)
+		}
+	}
+
+	return nil
+}
+
+// findUser возвращает первого пользователя (по возрастанию id), подходящего под условие.
+// Вызывается под блокировкой.
+func (s *Store) findUser(match func(*domain.User) bool) *domain.User {
+	var found *domain.User
+	for _, u := range s.users {
+		if match(u) && (found == nil || u.ID < found.ID) {
+			found = u
+		}
+	}
+	return found
+}
+
+// --- Orders ---
+
+// CreateOrder сохраняет новый ордер в хранилище.
+func (s *Store) CreateOrder(ctx context.Context, order *domain.Order) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to create order: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.insertOrder(order); err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+
+	return nil
+}
+
+// insertOrder проверяет ограничения таблицы orders и сохраняет копию ордера.
+// Вызывается под s.mu.
+func (s *Store) insertOrder(order *domain.Order) (*domain.Order, error) {
+	if err := s.checkUserExists(order.UserID, "orders"); err != nil {
+		return nil, err
+	}
+	if _, ok := s.orders[order.MexcOrderID]; ok {
+		return nil, duplicateError("orders", "orders_mexc_order_id_key")
+	}
+	if _, ok := s.orderInternalIDs[order.InternalID]; ok {
+		return nil, duplicateError("orders", "orders_internal_id_key")
+	}
+
+	s.nextOrderID++
+	now := s.now()
+
+	stored := copyOrder(order)
+	stored.ID = s.nextOrderID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.orders[stored.MexcOrderID] = stored
+	s.orderInternalIDs[stored.InternalID] = stored.MexcOrderID
+
+	return stored, nil
+}
+
+// UpsertOrder создает ордер или обновляет изменяемые поля существующего.
+func (s *Store) UpsertOrder(ctx context.Context, order *domain.Order) (storage.UpsertResult, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.UpsertIgnored, fmt.Errorf("failed to upsert order: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.upsertOrder(order)
+	if err != nil {
+		return storage.UpsertIgnored, fmt.Errorf("failed to upsert order: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpsertOrders выполняет UpsertOrder для каждого ордера атомарно.
+func (s *Store) UpsertOrders(ctx context.Context, orders []*domain.Order) ([]storage.UpsertResult, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rollback := s.snapshotOrders()
+
+	results := make([]storage.UpsertResult, 0, len(orders))
+	for _, order := range orders {
+		result, err := s.upsertOrder(order)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to upsert order %s: %w", order.MexcOrderID, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// snapshotOrders сохраняет копию ордеров и возвращает функцию отката,
+// которая восстанавливает их как при ROLLBACK транзакции. Вызывается под s.mu.
+func (s *Store) snapshotOrders() (rollback func()) {
+	savedOrders := make(map[string]*domain.Order, len(s.orders))
+	for id, order := range s.orders {
+		savedOrders[id] = copyOrder(order)
+	}
+	savedInternalIDs := make(map[int64]string, len(s.orderInternalIDs))
+	for id, mexcID := range s.orderInternalIDs {
+		savedInternalIDs[id] = mexcID
+	}
+	savedNextID := s.nextOrderID
+
+	return func() {
+		s.orders, s.orderInternalIDs, s.nextOrderID = savedOrders, savedInternalIDs, savedNextID
+	}
+}
+
+// upsertOrder вызывается под s.mu.
+func (s *Store) upsertOrder(order *domain.Order) (storage.UpsertResult, error) {
+	existing, ok := s.orders[order.MexcOrderID]
+	if !ok {
+		stored, err := s.insertOrder(order)
+		if err != nil {
+			return storage.UpsertIgnored, err
+		}
+		order.ID, order.CreatedAt, order.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+		return storage.UpsertCreated, nil
+	}
+
+	if !domain.IsOrderAdvance(existing, order) {
+		return storage.UpsertIgnored, nil
+	}
+
+	existing.Status = order.Status
+	existing.ExecutedQuantity = order.ExecutedQuantity
+	existing.CummulativeQuoteQty = order.CummulativeQuoteQty
+	existing.UpdatedAt = s.now()
+	order.ID, order.CreatedAt, order.UpdatedAt = existing.ID, existing.CreatedAt, existing.UpdatedAt
+
+	return storage.UpsertUpdated, nil
+}
+
+// DeleteOrderByID удаляет ордер из хранилища по его mexc_order_id.
+func (s *Store) DeleteOrderByID(ctx context.Context, mexcOrderID string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to delete order: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.orders[mexcOrderID]
+	if !ok {
+		return fmt.Errorf("order with id %s not found: %w", mexcOrderID, postgreserr.ErrOrderNotFound)
+	}
+
+	delete(s.orders, mexcOrderID)
+	delete(s.orderInternalIDs, order.InternalID)
+
+	return nil
+}
+
+// UpdateOrderStatus обновляет статус ордера, если переход разрешен.
+func (s *Store) UpdateOrderStatus(ctx context.Context, mexcOrderID string, status domain.OrderStatus) error {
+	if !status.Valid() {
+		return fmt.Errorf("unknown order status %q: %w", status, domain.ErrInvalidTransition)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to update order status: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, ok := s.orders[mexcOrderID]
+	if !ok {
+		return fmt.Errorf("order with id %s not found: %w", mexcOrderID, postgreserr.ErrOrderNotFound)
+	}
+	if !order.Status.CanTransitionTo(status) {
+		return &domain.TransitionError{OrderID: mexcOrderID, From: order.Status, To: status}
+	}
+
+	order.Status = status
+	order.UpdatedAt = s.now()
+
+	return nil
+}
+
+// GetOrderByID получает ордер по его mexc_order_id.
+func (s *Store) GetOrderByID(ctx context.Context, mexcOrderID string) (*domain.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order, ok := s.orders[mexcOrderID]
+	if !ok {
+		return nil, fmt.Errorf("order with id %s not found: %w", mexcOrderID, postgreserr.ErrOrderNotFound)
+	}
+
+	return copyOrder(order), nil
+}
+
+// GetUserOrders получает ордера пользователя с фильтрами.
+// Сортировка: created_at DESC, id DESC.
+func (s *Store) GetUserOrders(ctx context.Context, userID uint64, filters ...storage.OrderFilter) ([]*domain.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", postgreserr.Classify(err))
+	}
+
+	var filter storage.OrderFilter
+	if len(filters) > 0 {
+		filter = filters[0]
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orders := s.userOrders(userID, filter)
+	sortRecords(orders, filter.SortAsc, orderSortCompare(filter.SortBy), func(o *domain.Order) uint64 { return o.ID })
+	return paginate(orders, filter.Limit, filter.Offset), nil
+}
+
+// GetUserOrdersPage получает страницу ордеров пользователя по курсору (created_at, id).
+func (s *Store) GetUserOrdersPage(ctx context.Context, userID uint64, filter storage.OrderFilter, page storage.PageRequest) (*storage.Page[*domain.Order], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", postgreserr.Classify(err))
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	cursor, limit, err := page.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return keysetPage(s.userOrders(userID, filter), cursor, limit, func(o *domain.Order) (time.Time, uint64) {
+		return o.CreatedAt, o.ID
+	}), nil
+}
+
+// userOrders отбирает ордера пользователя по фильтру без учета Limit и Offset.
+func (s *Store) userOrders(userID uint64, filter storage.OrderFilter) []*domain.Order {
+	symbols, statuses := filter.AllSymbols(), filter.AllStatuses()
+	return s.selectOrders(func(o *domain.Order) bool {
+		if o.UserID != userID {
+			return false
+		}
+		if !matchAny(symbols, o.Symbol) || !matchAny(statuses, o.Status) {
+			return false
+		}
+		if filter.Side != "" && o.Side != filter.Side {
+			return false
+		}
+		if filter.Type != "" && o.Type != filter.Type {
+			return false
+		}
+		if !strings.HasPrefix(o.ClientOrderID, filter.ClientOrderIDPrefix) {
+			return false
+		}
+		if !inRange(o.Price, filter.MinPrice, filter.MaxPrice) || !inRange(o.Quantity, filter.MinQuantity, filter.MaxQuantity) {
+			return false
+		}
+		if filter.StartTime != nil && o.TransactTime.Before(*filter.StartTime) {
+			return false
+		}
+		if filter.EndTime != nil && o.TransactTime.After(*filter.EndTime) {
+			return false
+		}
+		return true
+	})
+}
+
+// GetOpenOrders получает активные ордера пользователя (NEW, PARTIALLY_FILLED).
+func (s *Store) GetOpenOrders(ctx context.Context, userID uint64, symbol string) ([]*domain.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query open orders: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.selectOrders(func(o *domain.Order) bool {
+		if o.UserID != userID {
+			return false
+		}
+		if o.Status != "NEW" && o.Status != "PARTIALLY_FILLED" {
+			return false
+		}
+		return symbol == "" || o.Symbol == symbol
+	}), nil
+}
+
+// selectOrders возвращает копии подходящих ордеров в порядке created_at DESC, id DESC.
+// Вызывается под блокировкой.
+func (s *Store) selectOrders(match func(*domain.Order) bool) []*domain.Order {
+	var orders []*domain.Order
+	for _, o := range s.orders {
+		if match(o) {
+			orders = append(orders, copyOrder(o))
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].ID > orders[j].ID
+	})
+	return orders
+}
+
+// --- Order updates ---
+
+// AppendOrderUpdate добавляет запись об изменении статуса ордера.
+func (s *Store) AppendOrderUpdate(ctx context.Context, update *domain.OrderUpdate) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to append order update: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUserExists(update.UserID, "order_updates"); err != nil {
+		return fmt.Errorf("failed to append order update: %w", postgreserr.Classify(err))
+	}
+
+	s.nextUpdateID++
+	stored := copyOrderUpdate(update)
+	stored.ID = s.nextUpdateID
+	s.updates = append(s.updates, stored)
+
+	return nil
+}
+
+// GetOrderUpdates возвращает историю изменений по ордеру (update_time DESC, id DESC).
+func (s *Store) GetOrderUpdates(ctx context.Context, userID uint64, orderID string) ([]*domain.OrderUpdate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query order updates: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.orderUpdates(userID, orderID), nil
+}
+
+// GetOrderUpdatesPage получает страницу истории ордера по курсору (update_time, id).
+func (s *Store) GetOrderUpdatesPage(ctx context.Context, userID uint64, orderID string, page storage.PageRequest) (*storage.Page[*domain.OrderUpdate], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query order updates: %w", postgreserr.Classify(err))
+	}
+
+	cursor, limit, err := page.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return keysetPage(s.orderUpdates(userID, orderID), cursor, limit, func(u *domain.OrderUpdate) (time.Time, uint64) {
+		return u.UpdateTime, u.ID
+	}), nil
+}
+
+// orderUpdates возвращает историю ордера. Сортировка: update_time DESC, id DESC.
+func (s *Store) orderUpdates(userID uint64, orderID string) []*domain.OrderUpdate {
+	var updates []*domain.OrderUpdate
+	for _, u := range s.updates {
+		if u.UserID == userID && u.OrderID == orderID {
+			updates = append(updates, copyOrderUpdate(u))
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		if !updates[i].UpdateTime.Equal(updates[j].UpdateTime) {
+			return updates[i].UpdateTime.After(updates[j].UpdateTime)
+		}
+		return updates[i].ID > updates[j].ID
+	})
+	return updates
+}
+
+// --- Trades ---
+
+// CreateTrade создает новую сделку и проводит ее по ledger.
+func (s *Store) CreateTrade(ctx context.Context, trade *domain.Trade) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to create trade: %w", postgreserr.Classify(err))
+	}
+
+	entries, err := domain.TradeLedgerEntries(trade)
+	if err != nil {
+		return fmt.Errorf("failed to create trade: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUserExists(trade.UserID, "trades"); err != nil {
+		return fmt.Errorf("failed to create trade: %w", postgreserr.Classify(err))
+	}
+	if _, ok := s.trades[trade.MexcTradeID]; ok {
+		return fmt.Errorf("failed to create trade: %w", duplicateError("trades", "trades_mexc_trade_id_key"))
+	}
+	if err := s.insertEntries(entries); err != nil {
+		return err
+	}
+
+	s.nextTradeID++
+	now := s.now()
+
+	stored := copyTrade(trade)
+	stored.ID = s.nextTradeID
+	stored.CreatedAt = now
+	s.trades[stored.MexcTradeID] = stored
+
+	trade.ID = stored.ID
+	trade.CreatedAt = now
+
+	return nil
+}
+
+// CreateTrades записывает сделки, пропуская уже существующие mexc_trade_id.
+// При ошибке не записывается ни одна сделка.
+func (s *Store) CreateTrades(ctx context.Context, trades []*domain.Trade) (storage.BulkInsertResult, error) {
+	var result storage.BulkInsertResult
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("failed to create trades: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Сначала проверяем всю загрузку, затем записываем
+	var fresh []*domain.Trade
+	var entries []*domain.LedgerEntry
+	seen := make(map[string]struct{}, len(trades))
+	for _, trade := range trades {
+		tradeEntries, err := domain.TradeLedgerEntries(trade)
+		if err != nil {
+			return result, fmt.Errorf("failed to create trade %s: %w", trade.MexcTradeID, err)
+		}
+		_, dup := seen[trade.MexcTradeID]
+		if _, ok := s.trades[trade.MexcTradeID]; ok || dup {
+			continue
+		}
+		seen[trade.MexcTradeID] = struct{}{}
+
+		if err := s.checkUserExists(trade.UserID, "trades"); err != nil {
+			return result, fmt.Errorf("failed to insert trades: %w", postgreserr.Classify(err))
+		}
+		fresh = append(fresh, trade)
+		entries = append(entries, tradeEntries...)
+	}
+	if err := s.insertEntries(entries); err != nil {
+		return result, err
+	}
+
+	now := s.now()
+	for _, trade := range fresh {
+		s.nextTradeID++
+
+		stored := copyTrade(trade)
+		stored.ID = s.nextTradeID
+		stored.CreatedAt = now
+		s.trades[stored.MexcTradeID] = stored
+
+		trade.ID = stored.ID
+		trade.CreatedAt = now
+	}
+
+	result.Inserted = len(fresh)
+	result.Skipped = len(trades) - len(fresh)
+	return result, nil
+}
+
+// GetTradeByID получает сделку по MEXC Trade ID.
+func (s *Store) GetTradeByID(ctx context.Context, mexcTradeID string) (*domain.Trade, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get trade: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trade, ok := s.trades[mexcTradeID]
+	if !ok {
+		return nil, postgreserr.ErrTradeNotFound
+	}
+
+	return copyTrade(trade), nil
+}
+
+// GetUserTrades получает сделки пользователя с фильтрацией.
+// Сортировка: trade_time DESC, id DESC.
+func (s *Store) GetUserTrades(ctx context.Context, userID uint64, filters ...storage.TradeFilter) ([]*domain.Trade, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query user trades: %w", postgreserr.Classify(err))
+	}
+
+	var filter storage.TradeFilter
+	if len(filters) > 0 {
+		filter = filters[0]
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trades := s.userTrades(userID, filter)
+	sortRecords(trades, filter.SortAsc, tradeSortCompare(filter.SortBy), func(t *domain.Trade) uint64 { return t.ID })
+	return paginate(trades, filter.Limit, filter.Offset), nil
+}
+
+// GetUserTradesPage получает страницу сделок пользователя по курсору (trade_time, id).
+func (s *Store) GetUserTradesPage(ctx context.Context, userID uint64, filter storage.TradeFilter, page storage.PageRequest) (*storage.Page[*domain.Trade], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query user trades: %w", postgreserr.Classify(err))
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	cursor, limit, err := page.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return keysetPage(s.userTrades(userID, filter), cursor, limit, func(t *domain.Trade) (time.Time, uint64) {
+		return t.TradeTime, t.ID
+	}), nil
+}
+
+// userTrades отбирает сделки пользователя по фильтру без учета Limit и Offset.
+// Сортировка: trade_time DESC, id DESC.
+func (s *Store) userTrades(userID uint64, filter storage.TradeFilter) []*domain.Trade {
+	symbols := filter.AllSymbols()
+	var trades []*domain.Trade
+	for _, t := range s.trades {
+		if t.UserID != userID {
+			continue
+		}
+		if !matchAny(symbols, t.Symbol) {
+			continue
+		}
+		if filter.Side != "" && t.IsBuyer != (filter.Side == domain.OrderSideBuy) {
+			continue
+		}
+		if !inRange(t.Price, filter.MinPrice, filter.MaxPrice) || !inRange(t.Quantity, filter.MinQuantity, filter.MaxQuantity) {
+			continue
+		}
+		if filter.StartTime != nil && t.TradeTime.Before(*filter.StartTime) {
+			continue
+		}
+		if filter.EndTime != nil && t.TradeTime.After(*filter.EndTime) {
+			continue
+		}
+		trades = append(trades, copyTrade(t))
+	}
+	sort.Slice(trades, func(i, j int) bool {
+		if !trades[i].TradeTime.Equal(trades[j].TradeTime) {
+			return trades[i].TradeTime.After(trades[j].TradeTime)
+		}
+		return trades[i].ID > trades[j].ID
+	})
+
+	return trades
+}
+
+// GetTradesByOrderID получает сделки ордера по mexc_order_id.
+// Сортировка: trade_time ASC, id ASC.
+func (s *Store) GetTradesByOrderID(ctx context.Context, orderID string) ([]*domain.Trade, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query order trades: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.orderTrades(orderID), nil
+}
+
+// GetFillMismatches находит ордера пользователя, у которых исполненные объемы
+// не совпадают с суммой их сделок. Сортировка: id ордера ASC.
+func (s *Store) GetFillMismatches(ctx context.Context, userID uint64) ([]*domain.FillMismatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query fill mismatches: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var orders []*domain.Order
+	for _, o := range s.orders {
+		if o.UserID == userID {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+
+	var mismatches []*domain.FillMismatch
+	for _, o := range orders {
+		var trades []*domain.Trade
+		for _, t := range s.orderTrades(o.MexcOrderID) {
+			if t.UserID == userID {
+				trades = append(trades, t)
+			}
+		}
+		if m := o.CheckFill(domain.SummarizeFills(o.MexcOrderID, trades)); m != nil {
+			mismatches = append(mismatches, m)
+		}
+	}
+
+	return mismatches, nil
+}
+
+// orderTrades возвращает копии сделок ордера в порядке исполнения.
+// Вызывается под блокировкой.
+func (s *Store) orderTrades(orderID string) []*domain.Trade {
+	var trades []*domain.Trade
+	for _, t := range s.trades {
+		if t.OrderID == orderID {
+			trades = append(trades, copyTrade(t))
+		}
+	}
+	sort.Slice(trades, func(i, j int) bool {
+		if !trades[i].TradeTime.Equal(trades[j].TradeTime) {
+			return trades[i].TradeTime.Before(trades[j].TradeTime)
+		}
+		return trades[i].ID < trades[j].ID
+	})
+	return trades
+}
+
+// --- Balances ---
+
+// UpdateBalance обновляет баланс пользователя.
+// Возвращает applied=true если баланс был создан или его значения изменились.
+func (s *Store) UpdateBalance(ctx context.Context, balance *domain.UserBalance) (applied bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("failed to update balance: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	balance.UpdatedAt = s.now()
+
+	if err := s.checkUserExists(balance.UserID, "user_balances"); err != nil {
+		return false, fmt.Errorf("failed to update balance: %w", postgreserr.Classify(err))
+	}
+
+	key := balanceKey{userID: balance.UserID, asset: balance.Asset}
+	existing, ok := s.balances[key]
+	if ok && existing.Free.Equal(balance.Free) && existing.Locked.Equal(balance.Locked) {
+		// Значения не изменились
+		return false, nil
+	}
+
+	balance.ID = s.upsertBalance(key, balance)
+	return true, nil
+}
+
+// GetBalance получает баланс пользователя по активу.
+func (s *Store) GetBalance(ctx context.Context, userID uint64, asset string) (*domain.UserBalance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	balance, ok := s.balances[balanceKey{userID: userID, asset: asset}]
+	if !ok {
+		return nil, postgreserr.ErrBalanceNotFound
+	}
+
+	b := *balance
+	return &b, nil
+}
+
+// GetUserBalances получает все балансы пользователя, отсортированные по активу.
+func (s *Store) GetUserBalances(ctx context.Context, userID uint64) ([]*domain.UserBalance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query user balances: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var balances []*domain.UserBalance
+	for key, b := range s.balances {
+		if key.userID == userID {
+			balance := *b
+			balances = append(balances, &balance)
+		}
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Asset < balances[j].Asset })
+
+	return balances, nil
+}
+
+// UpdateUserBalances обновляет все балансы пользователя атомарно.
+// Как и в PostgreSQL, после upsert удаляются нулевые балансы, не вошедшие в текущий снимок.
+func (s *Store) UpdateUserBalances(ctx context.Context, userID uint64, balances []*domain.UserBalance) error {
+	if len(balances) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUserExists(userID, "user_balances"); err != nil {
+		return fmt.Errorf("failed to update balance for asset %s: %w", balances[0].Asset, err)
+	}
+
+	updateTime := s.now()
+
+	for _, balance := range balances {
+		balance.UserID = userID
+		balance.UpdatedAt = updateTime
+		balance.ID = s.upsertBalance(balanceKey{userID: userID, asset: balance.Asset}, balance)
+	}
+
+	for key, b := range s.balances {
+		if key.userID == userID && b.Free.IsZero() && b.Locked.IsZero() && b.UpdatedAt.Before(updateTime) {
+			delete(s.balances, key)
+		}
+	}
+
+	return nil
+}
+
+// upsertBalance вставляет или перезаписывает баланс и возвращает его id.
+// Как и триггер в PostgreSQL, записывает снимок в историю, если значения изменились.
+// Вызывается под блокировкой.
+func (s *Store) upsertBalance(key balanceKey, balance *domain.UserBalance) uint64 {
+	stored := *balance
+	existing, ok := s.balances[key]
+	if ok {
+		stored.ID = existing.ID
+	} else {
+		s.nextBalanceID++
+		stored.ID = s.nextBalanceID
+	}
+	s.balances[key] = &stored
+
+	if !ok || !existing.Free.Equal(stored.Free) || !existing.Locked.Equal(stored.Locked) {
+		s.nextSnapshotID++
+		s.snapshots = append(s.snapshots, &domain.BalanceSnapshot{
+			ID:         s.nextSnapshotID,
+			UserID:     stored.UserID,
+			Asset:      stored.Asset,
+			Free:       stored.Free,
+			Locked:     stored.Locked,
+			RecordedAt: stored.UpdatedAt,
+		})
+	}
+
+	return stored.ID
+}
+
+// GetBalancesAt возвращает ненулевые балансы пользователя на момент t, отсортированные по активу.
+func (s *Store) GetBalancesAt(ctx context.Context, userID uint64, t time.Time) ([]*domain.BalanceSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query balances at %s: %w", t.Format(time.RFC3339), postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Последний снимок не позже t; при равном времени — с большим id, как ORDER BY recorded_at DESC, id DESC
+	latest := make(map[string]*domain.BalanceSnapshot)
+	for _, b := range s.snapshots {
+		if b.UserID != userID || b.RecordedAt.After(t) {
+			continue
+		}
+		if prev, ok := latest[b.Asset]; ok && prev.RecordedAt.After(b.RecordedAt) {
+			continue
+		}
+		latest[b.Asset] = b
+	}
+
+	var result []*domain.BalanceSnapshot
+	for _, b := range latest {
+		if b.Free.IsZero() && b.Locked.IsZero() {
+			continue
+		}
+		c := *b
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Asset < result[j].Asset })
+
+	return result, nil
+}
+
+// GetBalanceHistory возвращает снимки баланса актива за период [from, to].
+// Сортировка: recorded_at ASC, id ASC.
+func (s *Store) GetBalanceHistory(ctx context.Context, userID uint64, asset string, from, to time.Time) ([]*domain.BalanceSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query balance history: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*domain.BalanceSnapshot
+	for _, b := range s.snapshots {
+		if b.UserID != userID || b.Asset != asset || b.RecordedAt.Before(from) || b.RecordedAt.After(to) {
+			continue
+		}
+		c := *b
+		result = append(result, &c)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].RecordedAt.Equal(result[j].RecordedAt) {
+			return result[i].RecordedAt.Before(result[j].RecordedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// --- helpers ---
+
+// checkUserExists повторяет проверку внешнего ключа user_id REFERENCES users(id).
+// Вызывается под блокировкой.
+func (s *Store) checkUserExists(userID uint64, table string) error {
+	if _, ok := s.users[userID]; !ok {
+		constraint := table + "_user_id_fkey"
+		return &postgreserr.Error{
+			Kind:       postgreserr.ErrForeignKeyViolation,
+			Code:       "23503",
+			Constraint: constraint,
+			Table:      table,
+			Column:     "user_id",
+			Err:        fmt.Errorf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		}
+	}
+	return nil
+}
+
+// duplicateError повторяет ошибку PostgreSQL о нарушении уникальности.
+func duplicateError(table, constraint string) error {
+	return &postgreserr.Error{
+		Kind:       postgreserr.ErrDuplicate,
+		Code:       "23505",
+		Constraint: constraint,
+		Table:      table,
+		Err:        fmt.Errorf("duplicate key value violates unique constraint %q", constraint),
+	}
+}
+
+// paginate применяет OFFSET и LIMIT к уже отсортированной выборке.
+// keysetPage отбирает из списка, отсортированного от новых к старым, записи по курсору
+// в порядке обхода и собирает из них страницу.
+func keysetPage[T any](items []T, cursor *storage.Cursor, limit int, key func(T) (time.Time, uint64)) *storage.Page[T] {
+	if cursor != nil && cursor.Newer {
+		reversed := make([]T, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		items = reversed
+	}
+
+	var rows []T
+	for _, item := range items {
+		if len(rows) > limit {
+			break
+		}
+		if t, id := key(item); cursor.Contains(t, id) {
+			rows = append(rows, item)
+		}
+	}
+
+	return storage.NewPage(rows, cursor, limit, key)
+}
+
+// matchAny сообщает, входит ли v в список; пустой список не ограничивает выборку.
+func matchAny[T comparable](list []T, v T) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// inRange проверяет v по необязательным включающим границам.
+func inRange(v decimal.Decimal, min, max *decimal.Decimal) bool {
+	return (min == nil || !v.LessThan(*min)) && (max == nil || !v.GreaterThan(*max))
+}
+
+// sortRecords упорядочивает записи как ORDER BY field, id: при равенстве поля — по id
+// в том же направлении. По умолчанию от больших значений к меньшим.
+func sortRecords[T any](items []T, asc bool, cmp func(a, b T) int, id func(T) uint64) {
+	sort.Slice(items, func(i, j int) bool {
+		c := cmp(items[i], items[j])
+		if c == 0 {
+			if id(items[i]) < id(items[j]) {
+				c = -1
+			} else {
+				c = 1
+			}
+		}
+		if asc {
+			return c < 0
+		}
+		return c > 0
+	})
+}
+
+func orderSortCompare(field storage.SortField) func(a, b *domain.Order) int {
+	switch field {
+	case storage.SortByPrice:
+		return func(a, b *domain.Order) int { return a.Price.Cmp(b.Price) }
+	case storage.SortByQuantity:
+		return func(a, b *domain.Order) int { return a.Quantity.Cmp(b.Quantity) }
+	default:
+		return func(a, b *domain.Order) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	}
+}
+
+func tradeSortCompare(field storage.SortField) func(a, b *domain.Trade) int {
+	switch field {
+	case storage.SortByPrice:
+		return func(a, b *domain.Trade) int { return a.Price.Cmp(b.Price) }
+	case storage.SortByQuantity:
+		return func(a, b *domain.Trade) int { return a.Quantity.Cmp(b.Quantity) }
+	default:
+		return func(a, b *domain.Trade) int { return a.TradeTime.Compare(b.TradeTime) }
+	}
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(items) {
+			return nil
+		}
+		items = items[offset:]
+	}
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func copyUser(u *domain.User) *domain.User {
+	c := *u
+	if u.LastAccountSync != nil {
+		t := *u.LastAccountSync
+		c.LastAccountSync = &t
+	}
+	return &c
+}
+
+func copyOrder(o *domain.Order) *domain.Order {
+	c := *o
+	return &c
+}
+
+func copyTrade(t *domain.Trade) *domain.Trade {
+	c := *t
+	return &c
+}
+
+func copyOrderUpdate(u *domain.OrderUpdate) *domain.OrderUpdate {
+	c := *u
+	if u.RawData != nil {
+		c.RawData = append([]byte(nil), u.RawData...)
+	}
+	return &c
+}
+
+// Ensure Store implements FullStorage interface
+var _ storage.FullStorage = (*Store)(nil)
@@ -0,0 +1,149 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+	"github.com/samar/sup_bot/metacore/storage/storagetest"
+)
+
+func newTestUser(t *testing.T, s *Store) *domain.User {
+	t.Helper()
+	user := &domain.User{
+		TelegramID:    42,
+		MexcUID:       "uid_42",
+		Username:      "trader",
+		MexcAPIKey:    "key",
+		MexcSecretKey: "secret",
+		IsActive:      true,
+	}
+	require.NoError(t, s.CreateUser(context.Background(), user))
+	return user
+}
+
+func TestStore_Users(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	user := newTestUser(t, s)
+	assert.Equal(t, uint64(1), user.ID)
+	assert.False(t, user.CreatedAt.IsZero())
+
+	got, err := s.GetUserByTelegramID(ctx, 42)
+	require.NoError(t, err)
+	assert.Equal(t, "trader", got.Username)
+
+	// Изменение возвращенной копии не влияет на хранилище
+	got.Username = "changed"
+	again, err := s.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "trader", again.Username)
+
+	_, err = s.GetUserByMexcUID(ctx, "missing")
+	assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+
+	err = s.UpdateUser(ctx, &domain.User{ID: 100})
+	assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+}
+
+func TestStore_OrderUniqueness(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	user := newTestUser(t, s)
+
+	order := &domain.Order{InternalID: 1, UserID: user.ID, MexcOrderID: "o1", Symbol: "BTCUSDT", Status: "NEW", TransactTime: time.Now()}
+	require.NoError(t, s.CreateOrder(ctx, order))
+
+	err := s.CreateOrder(ctx, &domain.Order{InternalID: 2, UserID: user.ID, MexcOrderID: "o1"})
+	assert.ErrorContains(t, err, "orders_mexc_order_id_key")
+
+	err = s.CreateOrder(ctx, &domain.Order{InternalID: 1, UserID: user.ID, MexcOrderID: "o2"})
+	assert.ErrorContains(t, err, "orders_internal_id_key")
+
+	err = s.CreateOrder(ctx, &domain.Order{InternalID: 3, UserID: 999, MexcOrderID: "o3"})
+	assert.ErrorContains(t, err, "orders_user_id_fkey")
+
+	require.NoError(t, s.DeleteOrderByID(ctx, "o1"))
+	// После удаления internal_id и mexc_order_id снова свободны
+	require.NoError(t, s.CreateOrder(ctx, &domain.Order{InternalID: 1, UserID: user.ID, MexcOrderID: "o1"}))
+}
+
+func TestStore_GetUserTradesOrdering(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	user := newTestUser(t, s)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.CreateTrade(ctx, &domain.Trade{
+			UserID:      user.ID,
+			MexcTradeID: fmt.Sprintf("t%d", i),
+			Symbol:      "BTCUSDT",
+			TradeTime:   base.Add(time.Duration(i%3) * time.Hour),
+		}))
+	}
+
+	trades, err := s.GetUserTrades(ctx, user.ID, storage.TradeFilter{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, trades, 2)
+	// Порядок: trade_time DESC, id DESC -> t2(2ч), t4(1ч), t1(1ч), t3(0ч), t0(0ч)
+	assert.Equal(t, "t4", trades[0].MexcTradeID)
+	assert.Equal(t, "t1", trades[1].MexcTradeID)
+}
+
+func TestStore_UpdateBalanceApplied(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	user := newTestUser(t, s)
+
+	balance := &domain.UserBalance{UserID: user.ID, Asset: "BTC", Free: decimal.NewFromFloat(1.5), Locked: decimal.Zero}
+
+	applied, err := s.UpdateBalance(ctx, balance)
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	applied, err = s.UpdateBalance(ctx, &domain.UserBalance{UserID: user.ID, Asset: "BTC", Free: decimal.RequireFromString("1.50"), Locked: decimal.Zero})
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	_, err = s.GetBalance(ctx, user.ID, "ETH")
+	assert.Equal(t, postgreserr.ErrBalanceNotFound, err)
+}
+
+func TestStore_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	user := newTestUser(t, s)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, s.CreateTrade(ctx, &domain.Trade{UserID: user.ID, MexcTradeID: fmt.Sprintf("t%d", i), Symbol: "BTCUSDT", TradeTime: time.Now()}))
+			_, err := s.GetUserTrades(ctx, user.ID)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	trades, err := s.GetUserTrades(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, trades, 50)
+}
+
+func TestStore_Conformance(t *testing.T) {
+	storagetest.RunFullStorageSuite(t, func(t *testing.T) storage.FullStorage {
+		return New()
+	})
+}
@@ -0,0 +1,113 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+// --- Order lists ---
+
+// CreateOrderList сохраняет список ордеров и его ордера атомарно.
+func (s *Store) CreateOrderList(ctx context.Context, list *domain.OrderList, orders []*domain.Order) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUserExists(list.UserID, "order_lists"); err != nil {
+		return fmt.Errorf("failed to create order list: %w", err)
+	}
+	if _, ok := s.orderLists[list.MexcOrderListID]; ok {
+		return fmt.Errorf("failed to create order list: %w", duplicateError("order_lists", "order_lists_mexc_order_list_id_key"))
+	}
+
+	rollback := s.snapshotOrders()
+	for _, order := range orders {
+		order.UserID = list.UserID
+		order.OrderListID = list.MexcOrderListID
+
+		if _, err := s.insertOrder(order); err != nil {
+			rollback()
+			return fmt.Errorf("failed to create order %s: %w", order.MexcOrderID, err)
+		}
+	}
+
+	s.nextListID++
+	now := s.now()
+	list.ID = s.nextListID
+	list.CreatedAt = now
+	list.UpdatedAt = now
+
+	stored := *list
+	s.orderLists[list.MexcOrderListID] = &stored
+
+	return nil
+}
+
+// GetOrderList получает список ордеров по mexc_order_list_id.
+func (s *Store) GetOrderList(ctx context.Context, mexcOrderListID string) (*domain.OrderList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order list: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list, ok := s.orderLists[mexcOrderListID]
+	if !ok {
+		return nil, fmt.Errorf("order list with id %s not found: %w", mexcOrderListID, postgreserr.ErrOrderListNotFound)
+	}
+
+	c := *list
+	return &c, nil
+}
+
+// GetOrderListOrders получает ордера списка, отсортированные по id.
+func (s *Store) GetOrderListOrders(ctx context.Context, mexcOrderListID string) ([]*domain.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query order list orders: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*domain.Order
+	for _, o := range s.orders {
+		if o.OrderListID == mexcOrderListID {
+			result = append(result, copyOrder(o))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// UpdateOrderListStatus обновляет статусы списка ордеров.
+func (s *Store) UpdateOrderListStatus(ctx context.Context, mexcOrderListID string, statusType domain.ListStatusType, orderStatus domain.ListOrderStatus) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to update order list status: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, ok := s.orderLists[mexcOrderListID]
+	if !ok {
+		return fmt.Errorf("order list with id %s not found: %w", mexcOrderListID, postgreserr.ErrOrderListNotFound)
+	}
+
+	list.ListStatusType = statusType
+	list.ListOrderStatus = orderStatus
+	list.UpdatedAt = s.now()
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+// --- Outbox ---
+
+// EnqueueMessage ставит сообщение в очередь. Повтор с тем же DedupKey игнорируется.
+func (s *Store) EnqueueMessage(ctx context.Context, msg *domain.OutboxMessage) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("failed to enqueue outbox message: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg.DedupKey != "" {
+		if _, ok := s.outboxKeys[msg.DedupKey]; ok {
+			return false, nil
+		}
+	}
+	if err := s.checkUserExists(msg.UserID, "outbox"); err != nil {
+		return false, fmt.Errorf("failed to enqueue outbox message: %w", postgreserr.Classify(err))
+	}
+
+	s.nextOutboxID++
+	now := s.now()
+
+	stored := copyOutboxMessage(msg)
+	stored.ID = s.nextOutboxID
+	if len(stored.Payload) == 0 {
+		stored.Payload = []byte("{}")
+	}
+	stored.Status = domain.OutboxPending
+	stored.Attempts = 0
+	stored.LastError = ""
+	stored.NextAttemptAt = now
+	stored.LockedUntil = nil
+	stored.CreatedAt = now
+	stored.SentAt = nil
+	s.outbox[stored.ID] = stored
+	if stored.DedupKey != "" {
+		s.outboxKeys[stored.DedupKey] = stored.ID
+	}
+
+	msg.ID = stored.ID
+	msg.Status = stored.Status
+	msg.NextAttemptAt = now
+	msg.CreatedAt = now
+
+	return true, nil
+}
+
+// GetOutboxMessage получает сообщение по ID.
+func (s *Store) GetOutboxMessage(ctx context.Context, id uint64) (*domain.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get outbox message: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	msg, ok := s.outbox[id]
+	if !ok {
+		return nil, fmt.Errorf("outbox message %d not found: %w", id, postgreserr.ErrOutboxMessageNotFound)
+	}
+
+	return copyOutboxMessage(msg), nil
+}
+
+// GetOutboxMessages получает сообщения со статусом status по возрастанию ID.
+func (s *Store) GetOutboxMessages(ctx context.Context, status domain.OutboxStatus, limit int) ([]*domain.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query outbox messages: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*domain.OutboxMessage
+	for _, msg := range s.outbox {
+		if msg.Status == status {
+			result = append(result, copyOutboxMessage(msg))
+		}
+	}
+	slices.SortFunc(result, func(a, b *domain.OutboxMessage) int { return cmp.Compare(a.ID, b.ID) })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// ClaimMessages захватывает на lease готовые к отправке сообщения в порядке next_attempt_at, id.
+func (s *Store) ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query outbox messages: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var ready []*domain.OutboxMessage
+	for _, msg := range s.outbox {
+		if msg.Status != domain.OutboxPending || msg.NextAttemptAt.After(now) {
+			continue
+		}
+		if msg.LockedUntil != nil && msg.LockedUntil.After(now) {
+			continue
+		}
+		ready = append(ready, msg)
+	}
+	slices.SortFunc(ready, func(a, b *domain.OutboxMessage) int {
+		if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(ready) > limit {
+		ready = ready[:max(limit, 0)]
+	}
+
+	lockedUntil := now.Add(lease)
+	result := make([]*domain.OutboxMessage, 0, len(ready))
+	for _, msg := range ready {
+		msg.Attempts++
+		msg.LockedUntil = &lockedUntil
+		result = append(result, copyOutboxMessage(msg))
+	}
+
+	return result, nil
+}
+
+// MarkMessageSent переводит сообщение в SENT.
+func (s *Store) MarkMessageSent(ctx context.Context, id uint64) error {
+	return s.updateOutboxMessage(ctx, "mark outbox message sent", id, func(msg *domain.OutboxMessage, now time.Time) bool {
+		msg.Status = domain.OutboxSent
+		msg.LockedUntil = nil
+		msg.SentAt = &now
+		return true
+	})
+}
+
+// RetryMessage снимает захват и откладывает следующую попытку на delay.
+func (s *Store) RetryMessage(ctx context.Context, id uint64, lastError string, delay time.Duration) error {
+	return s.updateOutboxMessage(ctx, "retry outbox message", id, func(msg *domain.OutboxMessage, now time.Time) bool {
+		msg.LockedUntil = nil
+		msg.LastError = lastError
+		msg.NextAttemptAt = now.Add(delay)
+		return true
+	})
+}
+
+// DeadLetterMessage переводит сообщение в DEAD.
+func (s *Store) DeadLetterMessage(ctx context.Context, id uint64, lastError string) error {
+	return s.updateOutboxMessage(ctx, "dead-letter outbox message", id, func(msg *domain.OutboxMessage, _ time.Time) bool {
+		msg.Status = domain.OutboxDead
+		msg.LockedUntil = nil
+		msg.LastError = lastError
+		return true
+	})
+}
+
+// RequeueMessage возвращает сообщение DEAD в очередь.
+func (s *Store) RequeueMessage(ctx context.Context, id uint64) error {
+	return s.updateOutboxMessage(ctx, "requeue outbox message", id, func(msg *domain.OutboxMessage, now time.Time) bool {
+		if msg.Status != domain.OutboxDead {
+			return false
+		}
+		msg.Status = domain.OutboxPending
+		msg.Attempts = 0
+		msg.NextAttemptAt = now
+		return true
+	})
+}
+
+// updateOutboxMessage применяет update к сообщению под блокировкой. Если сообщения нет
+// или update вернул false, возвращается ErrOutboxMessageNotFound.
+func (s *Store) updateOutboxMessage(ctx context.Context, action string, id uint64, update func(msg *domain.OutboxMessage, now time.Time) bool) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to %s: %w", action, postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.outbox[id]
+	if !ok || !update(msg, s.now()) {
+		return fmt.Errorf("outbox message %d not found: %w", id, postgreserr.ErrOutboxMessageNotFound)
+	}
+
+	return nil
+}
+
+func copyOutboxMessage(m *domain.OutboxMessage) *domain.OutboxMessage {
+	result := *m
+	result.Payload = append([]byte(nil), m.Payload...)
+	if m.LockedUntil != nil {
+		lockedUntil := *m.LockedUntil
+		result.LockedUntil = &lockedUntil
+	}
+	if m.SentAt != nil {
+		sentAt := *m.SentAt
+		result.SentAt = &sentAt
+	}
+	return &result
+}
@@ -0,0 +1,145 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+// priceKey соответствует UNIQUE(symbol, source, observed_at) в таблице prices.
+type priceKey struct {
+	symbol     string
+	source     string
+	observedAt int64
+}
+
+// --- Prices ---
+
+// SavePrices сохраняет наблюдения цен; повтор наблюдения обновляет цену.
+func (s *Store) SavePrices(ctx context.Context, prices []*domain.Price) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range prices {
+		key := priceKey{p.Symbol, p.Source, p.ObservedAt.UnixNano()}
+		if stored, ok := s.prices[key]; ok {
+			stored.Price = p.Price
+			p.ID = stored.ID
+			p.CreatedAt = stored.CreatedAt
+			continue
+		}
+
+		s.nextPriceID++
+		p.ID = s.nextPriceID
+		p.CreatedAt = s.now()
+
+		stored := *p
+		s.prices[key] = &stored
+	}
+
+	return nil
+}
+
+// GetLatestPrice получает последнюю цену символа.
+func (s *Store) GetLatestPrice(ctx context.Context, symbol string) (*domain.Price, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get price: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.latestPrices(nil) {
+		if p.Symbol == symbol {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("price of %s not found: %w", symbol, postgreserr.ErrPriceNotFound)
+}
+
+// GetLatestPrices получает последнюю цену каждого символа.
+func (s *Store) GetLatestPrices(ctx context.Context) ([]*domain.Price, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query prices: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.latestPrices(nil), nil
+}
+
+// GetPricesAt получает последнюю цену каждого символа на момент t.
+func (s *Store) GetPricesAt(ctx context.Context, t time.Time) ([]*domain.Price, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query prices: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.latestPrices(&t), nil
+}
+
+// GetPriceHistory получает цены символа за период [from, to].
+func (s *Store) GetPriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]*domain.Price, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query prices: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var history []*domain.Price
+	for _, p := range s.sortedPrices() {
+		if p.Symbol == symbol && !p.ObservedAt.Before(from) && !p.ObservedAt.After(to) {
+			history = append(history, p)
+		}
+	}
+
+	return history, nil
+}
+
+// latestPrices повторяет DISTINCT ON (symbol) ... ORDER BY symbol, observed_at DESC, id DESC.
+// Вызывается под блокировкой.
+func (s *Store) latestPrices(at *time.Time) []*domain.Price {
+	latest := make(map[string]*domain.Price)
+	for _, p := range s.sortedPrices() {
+		if at == nil || !p.ObservedAt.After(*at) {
+			latest[p.Symbol] = p
+		}
+	}
+
+	result := make([]*domain.Price, 0, len(latest))
+	for _, p := range latest {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Symbol < result[j].Symbol })
+
+	return result
+}
+
+// sortedPrices возвращает копии цен по observed_at, id. Вызывается под блокировкой.
+func (s *Store) sortedPrices() []*domain.Price {
+	prices := make([]*domain.Price, 0, len(s.prices))
+	for _, p := range s.prices {
+		price := *p
+		prices = append(prices, &price)
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		if !prices[i].ObservedAt.Equal(prices[j].ObservedAt) {
+			return prices[i].ObservedAt.Before(prices[j].ObservedAt)
+		}
+		return prices[i].ID < prices[j].ID
+	})
+	return prices
+}
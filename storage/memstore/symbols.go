@@ -0,0 +1,75 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+// --- Symbols ---
+
+// UpsertSymbols создает или обновляет символы по имени.
+func (s *Store) UpsertSymbols(ctx context.Context, symbols []*domain.Symbol) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, sym := range symbols {
+		if stored, ok := s.symbols[sym.Symbol]; ok {
+			sym.ID = stored.ID
+		} else {
+			s.nextSymbolID++
+			sym.ID = s.nextSymbolID
+		}
+		sym.UpdatedAt = now
+
+		stored := *sym
+		s.symbols[sym.Symbol] = &stored
+	}
+
+	return nil
+}
+
+// GetSymbol получает символ по имени.
+func (s *Store) GetSymbol(ctx context.Context, symbol string) (*domain.Symbol, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get symbol: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sym, ok := s.symbols[symbol]
+	if !ok {
+		return nil, fmt.Errorf("symbol %s not found: %w", symbol, postgreserr.ErrSymbolNotFound)
+	}
+
+	result := *sym
+	return &result, nil
+}
+
+// GetSymbols получает все символы, отсортированные по имени.
+func (s *Store) GetSymbols(ctx context.Context) ([]*domain.Symbol, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query symbols: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	symbols := make([]*domain.Symbol, 0, len(s.symbols))
+	for _, sym := range s.symbols {
+		result := *sym
+		symbols = append(symbols, &result)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Symbol < symbols[j].Symbol })
+
+	return symbols, nil
+}
@@ -0,0 +1,107 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+// syncCursorKey повторяет UNIQUE(user_id, stream, symbol) таблицы sync_cursors.
+type syncCursorKey struct {
+	userID uint64
+	stream domain.SyncStream
+	symbol string
+}
+
+// --- Sync cursors ---
+
+// GetSyncCursor получает курсор синхронизации по пользователю, потоку и символу.
+func (s *Store) GetSyncCursor(ctx context.Context, userID uint64, stream domain.SyncStream, symbol string) (*domain.SyncCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sync cursor: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cursor, ok := s.syncCursors[syncCursorKey{userID, stream, symbol}]
+	if !ok {
+		return nil, fmt.Errorf("sync cursor %s %s not found: %w", stream, symbol, postgreserr.ErrSyncCursorNotFound)
+	}
+
+	return copySyncCursor(cursor), nil
+}
+
+// GetSyncCursors получает все курсоры пользователя, отсортированные по stream и symbol.
+func (s *Store) GetSyncCursors(ctx context.Context, userID uint64) ([]*domain.SyncCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query sync cursors: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*domain.SyncCursor
+	for key, cursor := range s.syncCursors {
+		if key.userID == userID {
+			result = append(result, copySyncCursor(cursor))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Stream != result[j].Stream {
+			return result[i].Stream < result[j].Stream
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+
+	return result, nil
+}
+
+// SaveSyncCursor создает или обновляет курсор с проверкой версии.
+func (s *Store) SaveSyncCursor(ctx context.Context, cursor *domain.SyncCursor) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to save sync cursor: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := syncCursorKey{cursor.UserID, cursor.Stream, cursor.Symbol}
+	var id, version uint64
+	if stored, ok := s.syncCursors[key]; ok {
+		if stored.Version != cursor.Version {
+			return fmt.Errorf("sync cursor %s %s (version %d): %w", cursor.Stream, cursor.Symbol, cursor.Version, postgreserr.ErrSyncCursorConflict)
+		}
+		id, version = stored.ID, stored.Version
+	} else {
+		if err := s.checkUserExists(cursor.UserID, "sync_cursors"); err != nil {
+			return fmt.Errorf("failed to save sync cursor: %w", postgreserr.Classify(err))
+		}
+		s.nextSyncCursorID++
+		id = s.nextSyncCursorID
+	}
+
+	stored := copySyncCursor(cursor)
+	stored.ID = id
+	stored.Version = version + 1
+	stored.UpdatedAt = s.now()
+	s.syncCursors[key] = stored
+
+	cursor.ID = stored.ID
+	cursor.Version = stored.Version
+	cursor.UpdatedAt = stored.UpdatedAt
+
+	return nil
+}
+
+func copySyncCursor(c *domain.SyncCursor) *domain.SyncCursor {
+	result := *c
+	if c.LastTime != nil {
+		lastTime := *c.LastTime
+		result.LastTime = &lastTime
+	}
+	return &result
+}
@@ -0,0 +1,248 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// --- Deposits ---
+
+// UpsertDeposits создает или обновляет вводы по mexc_deposit_id
+// и проводит по ledger вводы в статусе SUCCESS.
+func (s *Store) UpsertDeposits(ctx context.Context, deposits []*domain.Deposit) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range deposits {
+		if err := s.checkUserExists(d.UserID, "deposits"); err != nil {
+			return fmt.Errorf("failed to upsert deposit %s: %w", d.MexcDepositID, postgreserr.Classify(err))
+		}
+	}
+
+	now := s.now()
+	for _, d := range deposits {
+		stored, ok := s.deposits[d.MexcDepositID]
+		if !ok {
+			s.nextDepositID++
+			stored = &domain.Deposit{
+				ID:            s.nextDepositID,
+				UserID:        d.UserID,
+				MexcDepositID: d.MexcDepositID,
+				Asset:         d.Asset,
+				InsertTime:    d.InsertTime,
+				CreatedAt:     now,
+			}
+			s.deposits[d.MexcDepositID] = stored
+		}
+		// Как и ON CONFLICT DO UPDATE: пользователь, актив и время поступления не меняются
+		stored.Network = d.Network
+		stored.Amount = d.Amount
+		stored.Fee = d.Fee
+		stored.Address = d.Address
+		stored.Memo = d.Memo
+		stored.TxID = d.TxID
+		stored.Status = d.Status
+		stored.UpdatedAt = now
+
+		d.ID, d.CreatedAt, d.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+
+		if err := s.postSucceeded(d.Status, d.LedgerEntries()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetDepositByID получает ввод по MEXC ID.
+func (s *Store) GetDepositByID(ctx context.Context, mexcDepositID string) (*domain.Deposit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get deposit: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.deposits[mexcDepositID]
+	if !ok {
+		return nil, postgreserr.ErrDepositNotFound
+	}
+
+	result := *d
+	return &result, nil
+}
+
+// GetUserDeposits получает вводы пользователя с фильтрацией.
+// Сортировка: insert_time DESC, id DESC.
+func (s *Store) GetUserDeposits(ctx context.Context, userID uint64, filters ...storage.TransferFilter) ([]*domain.Deposit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query user deposits: %w", postgreserr.Classify(err))
+	}
+
+	filter := transferFilter(filters)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deposits []*domain.Deposit
+	for _, d := range s.deposits {
+		if d.UserID == userID && matchTransfer(filter, d.Asset, d.Status, d.InsertTime) {
+			result := *d
+			deposits = append(deposits, &result)
+		}
+	}
+	sort.Slice(deposits, func(i, j int) bool {
+		if !deposits[i].InsertTime.Equal(deposits[j].InsertTime) {
+			return deposits[i].InsertTime.After(deposits[j].InsertTime)
+		}
+		return deposits[i].ID > deposits[j].ID
+	})
+
+	return paginate(deposits, filter.Limit, filter.Offset), nil
+}
+
+// --- Withdrawals ---
+
+// UpsertWithdrawals создает или обновляет выводы по mexc_withdrawal_id
+// и проводит по ledger выводы в статусе SUCCESS.
+func (s *Store) UpsertWithdrawals(ctx context.Context, withdrawals []*domain.Withdrawal) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, w := range withdrawals {
+		if err := s.checkUserExists(w.UserID, "withdrawals"); err != nil {
+			return fmt.Errorf("failed to upsert withdrawal %s: %w", w.MexcWithdrawalID, postgreserr.Classify(err))
+		}
+	}
+
+	now := s.now()
+	for _, w := range withdrawals {
+		stored, ok := s.withdrawals[w.MexcWithdrawalID]
+		if !ok {
+			s.nextWithdrawalID++
+			stored = &domain.Withdrawal{
+				ID:               s.nextWithdrawalID,
+				UserID:           w.UserID,
+				MexcWithdrawalID: w.MexcWithdrawalID,
+				Asset:            w.Asset,
+				ApplyTime:        w.ApplyTime,
+				CreatedAt:        now,
+			}
+			s.withdrawals[w.MexcWithdrawalID] = stored
+		}
+		// Как и ON CONFLICT DO UPDATE: пользователь, актив и время заявки не меняются
+		stored.Network = w.Network
+		stored.Amount = w.Amount
+		stored.Fee = w.Fee
+		stored.Address = w.Address
+		stored.Memo = w.Memo
+		stored.TxID = w.TxID
+		stored.Status = w.Status
+		stored.UpdatedAt = now
+
+		w.ID, w.CreatedAt, w.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+
+		if err := s.postSucceeded(w.Status, w.LedgerEntries()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetWithdrawalByID получает вывод по MEXC ID.
+func (s *Store) GetWithdrawalByID(ctx context.Context, mexcWithdrawalID string) (*domain.Withdrawal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get withdrawal: %w", postgreserr.Classify(err))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, ok := s.withdrawals[mexcWithdrawalID]
+	if !ok {
+		return nil, postgreserr.ErrWithdrawalNotFound
+	}
+
+	result := *w
+	return &result, nil
+}
+
+// GetUserWithdrawals получает выводы пользователя с фильтрацией.
+// Сортировка: apply_time DESC, id DESC.
+func (s *Store) GetUserWithdrawals(ctx context.Context, userID uint64, filters ...storage.TransferFilter) ([]*domain.Withdrawal, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query user withdrawals: %w", postgreserr.Classify(err))
+	}
+
+	filter := transferFilter(filters)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var withdrawals []*domain.Withdrawal
+	for _, w := range s.withdrawals {
+		if w.UserID == userID && matchTransfer(filter, w.Asset, w.Status, w.ApplyTime) {
+			result := *w
+			withdrawals = append(withdrawals, &result)
+		}
+	}
+	sort.Slice(withdrawals, func(i, j int) bool {
+		if !withdrawals[i].ApplyTime.Equal(withdrawals[j].ApplyTime) {
+			return withdrawals[i].ApplyTime.After(withdrawals[j].ApplyTime)
+		}
+		return withdrawals[i].ID > withdrawals[j].ID
+	})
+
+	return paginate(withdrawals, filter.Limit, filter.Offset), nil
+}
+
+// postSucceeded проводит завершенную операцию по ledger, если она еще не проведена.
+// Вызывается под блокировкой.
+func (s *Store) postSucceeded(status domain.TransferStatus, entries []*domain.LedgerEntry) error {
+	if status != domain.TransferStatusSuccess || len(entries) == 0 {
+		return nil
+	}
+	e := entries[0]
+	if _, posted := s.ledgerKeys[ledgerKey{e.TransactionID, e.Account, e.Asset, e.Kind}]; posted {
+		return nil
+	}
+	return s.insertEntries(entries)
+}
+
+func transferFilter(filters []storage.TransferFilter) storage.TransferFilter {
+	if len(filters) > 0 {
+		return filters[0]
+	}
+	return storage.TransferFilter{}
+}
+
+func matchTransfer(filter storage.TransferFilter, asset string, status domain.TransferStatus, at time.Time) bool {
+	if filter.Asset != "" && asset != filter.Asset {
+		return false
+	}
+	if filter.Status != "" && status != filter.Status {
+		return false
+	}
+	if filter.StartTime != nil && at.Before(*filter.StartTime) {
+		return false
+	}
+	if filter.EndTime != nil && at.After(*filter.EndTime) {
+		return false
+	}
+	return true
+}
@@ -106,10 +106,10 @@ func (mr *MockDBInterfaceMockRecorder) QueryContext(ctx, query interface{}, args
 }
 
 // BeginTx mocks base method.
-func (m *MockDBInterface) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+func (m *MockDBInterface) BeginTx(ctx context.Context, opts *sql.TxOptions) (storage.Tx, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "BeginTx", ctx, opts)
-	ret0, _ := ret[0].(*sql.Tx)
+	ret0, _ := ret[0].(storage.Tx)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
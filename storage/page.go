@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultPageLimit — размер страницы, если PageRequest.Limit не задан
+	DefaultPageLimit = 50
+	// MaxPageLimit — наибольший размер страницы
+	MaxPageLimit = 1000
+)
+
+// ErrInvalidCursor возвращается для курсора, который не был выдан хранилищем.
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// PageRequest — запрос страницы по курсору.
+type PageRequest struct {
+	// Cursor — NextCursor или PrevCursor предыдущей страницы; пусто — самые новые записи
+	Cursor string
+	// Limit — размер страницы, по умолчанию DefaultPageLimit, не больше MaxPageLimit
+	Limit int
+}
+
+// Parse разбирает курсор и нормализует размер страницы.
+// Для первой страницы курсор равен nil.
+func (r PageRequest) Parse() (*Cursor, int, error) {
+	limit := r.Limit
+	switch {
+	case limit <= 0:
+		limit = DefaultPageLimit
+	case limit > MaxPageLimit:
+		limit = MaxPageLimit
+	}
+
+	if r.Cursor == "" {
+		return nil, limit, nil
+	}
+	cursor, err := DecodeCursor(r.Cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cursor, limit, nil
+}
+
+// Page — страница записей, отсортированных от новых к старым по (время, id).
+// Новые записи, появившиеся во время листания, не сдвигают страницы в сторону старых.
+type Page[T any] struct {
+	Items []T
+	// NextCursor — курсор страницы более старых записей; пусто, если их нет
+	NextCursor string
+	// PrevCursor — курсор страницы более новых записей; пусто, если их нет
+	PrevCursor string
+}
+
+// Cursor — позиция в списке, отсортированном по (время, id) от новых к старым.
+// Записи страницы строго старше (или при Newer — строго новее) позиции.
+type Cursor struct {
+	Time  time.Time
+	ID    uint64
+	Newer bool
+}
+
+// Encode возвращает непрозрачный токен курсора.
+func (c Cursor) Encode() string {
+	direction := 'o'
+	if c.Newer {
+		direction = 'n'
+	}
+	raw := fmt.Sprintf("%c%d.%d", direction, c.Time.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает токен, полученный из Cursor.Encode.
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	var nanos int64
+	var cursor Cursor
+	if _, err := fmt.Sscanf(string(raw[1:]), "%d.%d", &nanos, &cursor.ID); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	switch raw[0] {
+	case 'o':
+	case 'n':
+		cursor.Newer = true
+	default:
+		return nil, ErrInvalidCursor
+	}
+	cursor.Time = time.Unix(0, nanos).UTC()
+
+	// Повторное кодирование отсекает токены с мусором после id
+	if cursor.Encode() != token {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Contains сообщает, попадает ли запись с ключом (t, id) в направление курсора.
+func (c *Cursor) Contains(t time.Time, id uint64) bool {
+	if c == nil {
+		return true
+	}
+	if c.Newer {
+		return t.After(c.Time) || (t.Equal(c.Time) && id > c.ID)
+	}
+	return t.Before(c.Time) || (t.Equal(c.Time) && id < c.ID)
+}
+
+// NewPage собирает страницу из не более чем limit+1 записей, прочитанных по курсору
+// в порядке обхода: от новых к старым, а для Newer — от старых к новым.
+// Лишняя запись означает, что в этом направлении есть еще страница.
+func NewPage[T any](rows []T, cursor *Cursor, limit int, key func(T) (time.Time, uint64)) *Page[T] {
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
+	newer := cursor != nil && cursor.Newer
+	if newer {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	page := &Page[T]{Items: rows}
+	if len(rows) == 0 {
+		return page
+	}
+
+	// Сторона, откуда пришел курсор, непуста: там лежит запись курсора
+	hasOlder := more || newer
+	hasNewer := newer && more || cursor != nil && !newer
+	if hasOlder {
+		t, id := key(rows[len(rows)-1])
+		page.NextCursor = Cursor{Time: t, ID: id}.Encode()
+	}
+	if hasNewer {
+		t, id := key(rows[0])
+		page.PrevCursor = Cursor{Time: t, ID: id, Newer: true}.Encode()
+	}
+
+	return page
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 123456000, time.UTC)
+
+	for _, c := range []Cursor{
+		{Time: ts, ID: 42},
+		{Time: ts, ID: 7, Newer: true},
+	} {
+		got, err := DecodeCursor(c.Encode())
+		require.NoError(t, err)
+		assert.True(t, c.Time.Equal(got.Time))
+		assert.Equal(t, c.ID, got.ID)
+		assert.Equal(t, c.Newer, got.Newer)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	valid := Cursor{Time: time.Unix(100, 0), ID: 1}.Encode()
+
+	for _, token := range []string{
+		"!!!",
+		"bw",
+		valid + "A",
+		encodeRaw("x100.1"),
+		encodeRaw("o100"),
+		encodeRaw("o100.1.2"),
+	} {
+		_, err := DecodeCursor(token)
+		assert.ErrorIs(t, err, ErrInvalidCursor, token)
+	}
+}
+
+func TestPageRequest_Parse(t *testing.T) {
+	cursor, limit, err := PageRequest{}.Parse()
+	require.NoError(t, err)
+	assert.Nil(t, cursor)
+	assert.Equal(t, DefaultPageLimit, limit)
+
+	_, limit, err = PageRequest{Limit: MaxPageLimit + 1}.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, MaxPageLimit, limit)
+
+	_, _, err = PageRequest{Cursor: "bad"}.Parse()
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestNewPage(t *testing.T) {
+	// Записи 5..1: время и id совпадают, от новых к старым
+	key := func(n int) (time.Time, uint64) { return time.Unix(int64(n), 0), uint64(n) }
+	cursor := func(n int, newer bool) *Cursor {
+		ts, id := key(n)
+		return &Cursor{Time: ts, ID: id, Newer: newer}
+	}
+	encode := func(c *Cursor) string {
+		if c == nil {
+			return ""
+		}
+		return c.Encode()
+	}
+
+	tests := []struct {
+		name   string
+		rows   []int
+		cursor *Cursor
+		items  []int
+		next   *Cursor
+		prev   *Cursor
+	}{
+		{name: "first page", rows: []int{5, 4, 3}, items: []int{5, 4}, next: cursor(4, false)},
+		{name: "single page", rows: []int{2, 1}, items: []int{2, 1}},
+		{name: "empty", rows: nil, items: nil},
+		{name: "middle page", rows: []int{3, 2, 1}, cursor: cursor(4, false), items: []int{3, 2}, next: cursor(2, false), prev: cursor(3, true)},
+		{name: "last page", rows: []int{1}, cursor: cursor(2, false), items: []int{1}, prev: cursor(1, true)},
+		{name: "newer page", rows: []int{2, 3, 4}, cursor: cursor(1, true), items: []int{3, 2}, next: cursor(2, false), prev: cursor(3, true)},
+		{name: "newest page", rows: []int{4, 5}, cursor: cursor(3, true), items: []int{5, 4}, next: cursor(4, false)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			page := NewPage(tc.rows, tc.cursor, 2, key)
+			assert.Equal(t, tc.items, page.Items)
+			assert.Equal(t, encode(tc.next), page.NextCursor)
+			assert.Equal(t, encode(tc.prev), page.PrevCursor)
+		})
+	}
+}
+
+func encodeRaw(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
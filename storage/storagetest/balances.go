@@ -0,0 +1,213 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+func runBalanceTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("update balance applied flag", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		balance := &domain.UserBalance{UserID: user.ID, Asset: "BTC", Free: dec("1.5"), Locked: dec("0.5")}
+		applied, err := st.UpdateBalance(ctx, balance)
+		require.NoError(t, err)
+		assert.True(t, applied, "insert is applied")
+		assert.NotZero(t, balance.ID)
+		assert.False(t, balance.UpdatedAt.IsZero())
+
+		applied, err = st.UpdateBalance(ctx, &domain.UserBalance{UserID: user.ID, Asset: "BTC", Free: dec("1.50"), Locked: dec("0.500")})
+		require.NoError(t, err)
+		assert.False(t, applied, "same values are not applied")
+
+		changed := &domain.UserBalance{UserID: user.ID, Asset: "BTC", Free: dec("1.5"), Locked: dec("0")}
+		applied, err = st.UpdateBalance(ctx, changed)
+		require.NoError(t, err)
+		assert.True(t, applied, "changed locked is applied")
+		assert.Equal(t, balance.ID, changed.ID)
+
+		got, err := st.GetBalance(ctx, user.ID, "BTC")
+		require.NoError(t, err)
+		requireDecimal(t, "1.5", got.Free)
+		requireDecimal(t, "0", got.Locked)
+
+		_, err = st.UpdateBalance(ctx, &domain.UserBalance{UserID: user.ID + 1000, Asset: "BTC", Free: dec("1"), Locked: dec("0")})
+		assertConstraint(t, err, postgreserr.ErrForeignKeyViolation, "user_balances_user_id_fkey")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		_, err := st.GetBalance(ctx, user.ID, "BTC")
+		assert.ErrorIs(t, err, postgreserr.ErrBalanceNotFound)
+
+		balances, err := st.GetUserBalances(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+	})
+
+	t.Run("user balances ordered by asset", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		for _, asset := range []string{"USDT", "BTC", "ETH"} {
+			_, err := st.UpdateBalance(ctx, &domain.UserBalance{UserID: user.ID, Asset: asset, Free: dec("1"), Locked: dec("0")})
+			require.NoError(t, err)
+		}
+		_, err := st.UpdateBalance(ctx, &domain.UserBalance{UserID: other.ID, Asset: "ADA", Free: dec("1"), Locked: dec("0")})
+		require.NoError(t, err)
+
+		balances, err := st.GetUserBalances(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"BTC", "ETH", "USDT"}, assets(balances))
+	})
+
+	t.Run("update user balances zero cleanup", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		seed := func(userID uint64, asset, free string) {
+			_, err := st.UpdateBalance(ctx, &domain.UserBalance{UserID: userID, Asset: asset, Free: dec(free), Locked: dec("0")})
+			require.NoError(t, err)
+		}
+		seed(user.ID, "BTC", "1")
+		seed(user.ID, "ETH", "0") // нулевой и отсутствует в снимке — удаляется
+		seed(user.ID, "LTC", "5") // ненулевой и отсутствует в снимке — остается
+		seed(other.ID, "ETH", "0")
+
+		snapshot := []*domain.UserBalance{
+			{Asset: "BTC", Free: dec("2"), Locked: dec("0.1")},
+			{Asset: "XRP", Free: dec("0"), Locked: dec("0")}, // нулевой, но из текущего снимка — остается
+		}
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, snapshot))
+		for _, b := range snapshot {
+			assert.Equal(t, user.ID, b.UserID)
+			assert.NotZero(t, b.ID)
+			assert.False(t, b.UpdatedAt.IsZero())
+		}
+
+		balances, err := st.GetUserBalances(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"BTC", "LTC", "XRP"}, assets(balances))
+		requireDecimal(t, "2", balances[0].Free)
+		requireDecimal(t, "0.1", balances[0].Locked)
+
+		// Следующий снимок без XRP удаляет его как устаревший нулевой баланс
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{{Asset: "BTC", Free: dec("2"), Locked: dec("0")}}))
+		balances, err = st.GetUserBalances(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"BTC", "LTC"}, assets(balances))
+
+		// Балансы других пользователей не затрагиваются
+		_, err = st.GetBalance(ctx, other.ID, "ETH")
+		assert.NoError(t, err)
+
+		// Пустой снимок ничего не меняет
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, nil))
+		balances, err = st.GetUserBalances(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Len(t, balances, 2)
+	})
+
+	t.Run("balance history and point in time", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		before := checkpoint()
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{
+			{Asset: "BTC", Free: dec("1"), Locked: dec("0")},
+			{Asset: "USDT", Free: dec("1000"), Locked: dec("0")},
+		}))
+		first := checkpoint()
+
+		// Повтор тех же значений не пишет историю
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{
+			{Asset: "BTC", Free: dec("1"), Locked: dec("0")},
+			{Asset: "USDT", Free: dec("1000"), Locked: dec("0")},
+		}))
+		_, err := st.UpdateBalance(ctx, &domain.UserBalance{UserID: user.ID, Asset: "BTC", Free: dec("0.5"), Locked: dec("0.5")})
+		require.NoError(t, err)
+		second := checkpoint()
+
+		// USDT обнулен и затем удален следующим снимком
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{
+			{Asset: "BTC", Free: dec("0.5"), Locked: dec("0.5")},
+			{Asset: "USDT", Free: dec("0"), Locked: dec("0")},
+		}))
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{
+			{Asset: "BTC", Free: dec("2"), Locked: dec("0")},
+		}))
+		third := checkpoint()
+
+		_, err = st.UpdateBalance(ctx, &domain.UserBalance{UserID: other.ID, Asset: "BTC", Free: dec("9"), Locked: dec("0")})
+		require.NoError(t, err)
+
+		at, err := st.GetBalancesAt(ctx, user.ID, before)
+		require.NoError(t, err)
+		assert.Empty(t, at)
+
+		at, err = st.GetBalancesAt(ctx, user.ID, first)
+		require.NoError(t, err)
+		require.Len(t, at, 2)
+		assert.Equal(t, "BTC", at[0].Asset)
+		assert.Equal(t, user.ID, at[0].UserID)
+		requireDecimal(t, "1", at[0].Free)
+		assert.Equal(t, "USDT", at[1].Asset)
+		requireDecimal(t, "1000", at[1].Free)
+
+		at, err = st.GetBalancesAt(ctx, user.ID, second)
+		require.NoError(t, err)
+		require.Len(t, at, 2)
+		requireDecimal(t, "0.5", at[0].Free)
+		requireDecimal(t, "0.5", at[0].Locked)
+
+		// Обнуленный баланс не входит в состояние на момент времени
+		at, err = st.GetBalancesAt(ctx, user.ID, third)
+		require.NoError(t, err)
+		require.Len(t, at, 1)
+		assert.Equal(t, "BTC", at[0].Asset)
+		requireDecimal(t, "2", at[0].Free)
+
+		history, err := st.GetBalanceHistory(ctx, user.ID, "BTC", before, third)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		requireDecimal(t, "1", history[0].Free)
+		requireDecimal(t, "0.5", history[1].Free)
+		requireDecimal(t, "2", history[2].Free)
+		assert.True(t, history[0].RecordedAt.Before(history[1].RecordedAt))
+
+		history, err = st.GetBalanceHistory(ctx, user.ID, "USDT", first, third)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		requireDecimal(t, "0", history[0].Free)
+
+		history, err = st.GetBalanceHistory(ctx, user.ID, "BTC", first, second)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		requireDecimal(t, "0.5", history[0].Free)
+
+		// История удаляется вместе с пользователем
+		require.NoError(t, st.DeleteUser(ctx, user.ID))
+		history, err = st.GetBalanceHistory(ctx, user.ID, "BTC", before, third)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+}
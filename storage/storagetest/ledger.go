@@ -0,0 +1,220 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+func runLedgerTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("trades post balanced entries", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		// Покупка 0.01 BTC за 500 USDT с комиссией 0.5 USDT
+		buy := mustCreateTrade(t, st, newTrade(user.ID, "o1", ids))
+
+		// Продажа 0.004 BTC за 220 USDT с комиссией в MX
+		sell := newTrade(user.ID, "o2", ids)
+		sell.IsBuyer = false
+		sell.Quantity = dec("0.004")
+		sell.QuoteQuantity = dec("220")
+		sell.Commission = dec("0.1")
+		sell.CommissionAsset = "MX"
+		sell.TradeTime = at(time.Hour)
+		mustCreateTrade(t, st, sell)
+
+		// Сделка без комиссии проводит только базовый и котируемый актив
+		free := newTrade(user.ID, "o3", ids)
+		free.Commission = dec("0")
+		free.TradeTime = at(2 * time.Hour)
+		mustCreateTrade(t, st, free)
+
+		balances, err := st.GetLedgerBalances(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, balances, 3)
+		assert.Equal(t, "BTC", balances[0].Asset)
+		requireDecimal(t, "0.016", balances[0].Balance)
+		assert.Equal(t, "MX", balances[1].Asset)
+		requireDecimal(t, "-0.1", balances[1].Balance)
+		assert.Equal(t, "USDT", balances[2].Asset)
+		requireDecimal(t, "-780.5", balances[2].Balance)
+
+		entries, err := st.GetLedgerEntries(ctx, user.ID, "USDT")
+		require.NoError(t, err)
+		require.Len(t, entries, 4)
+		for _, e := range entries {
+			assert.NotZero(t, e.ID)
+			assert.Equal(t, domain.LedgerAccountUser, e.Account)
+			assert.False(t, e.CreatedAt.IsZero())
+		}
+		assert.Equal(t, "trade:"+buy.MexcTradeID, entries[0].TransactionID)
+		assert.Equal(t, buy.MexcTradeID, entries[0].RefID)
+		assert.True(t, baseTime.Equal(entries[0].EntryTime))
+
+		kinds := make([]domain.LedgerEntryKind, 0, len(entries))
+		for _, e := range entries {
+			kinds = append(kinds, e.Kind)
+		}
+		assert.Equal(t, []domain.LedgerEntryKind{
+			domain.LedgerEntryTrade, domain.LedgerEntryCommission, domain.LedgerEntryTrade, domain.LedgerEntryTrade,
+		}, kinds)
+		requireDecimal(t, "-500", entries[0].Balance)
+		requireDecimal(t, "-500.5", entries[1].Balance)
+		requireDecimal(t, "-280.5", entries[2].Balance)
+		requireDecimal(t, "-780.5", entries[3].Balance)
+	})
+
+	t.Run("trade and its entries are atomic", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		trade := mustCreateTrade(t, st, newTrade(user.ID, "o1", ids))
+
+		// Повтор сделки отклоняется и не проводится второй раз
+		dup := newTrade(user.ID, "o1", ids)
+		dup.MexcTradeID = trade.MexcTradeID
+		err := st.CreateTrade(ctx, dup)
+		assertConstraint(t, err, postgreserr.ErrDuplicate, "trades_mexc_trade_id_key")
+
+		// Проводки сделки уже существуют — сделка не сохраняется
+		clash := newTrade(user.ID, "o2", ids)
+		require.NoError(t, st.PostEntries(ctx, domain.DepositLedgerEntries(user.ID, "USDT", dec("1"), "x", baseTime)))
+		require.NoError(t, st.PostEntries(ctx, []*domain.LedgerEntry{
+			{UserID: user.ID, TransactionID: "trade:" + clash.MexcTradeID, Account: domain.LedgerAccountUser, Asset: "BTC", Amount: dec("1"), Kind: domain.LedgerEntryTrade, RefID: "manual", EntryTime: baseTime},
+			{UserID: user.ID, TransactionID: "trade:" + clash.MexcTradeID, Account: domain.LedgerAccountExchange, Asset: "BTC", Amount: dec("-1"), Kind: domain.LedgerEntryTrade, RefID: "manual", EntryTime: baseTime},
+		}))
+		err = st.CreateTrade(ctx, clash)
+		assertConstraint(t, err, postgreserr.ErrDuplicate, "ledger_entries_transaction_id_account_asset_kind_key")
+		_, err = st.GetTradeByID(ctx, clash.MexcTradeID)
+		assert.ErrorIs(t, err, postgreserr.ErrTradeNotFound)
+
+		unknown := newTrade(user.ID, "o3", ids)
+		unknown.Symbol = "XYZ"
+		assert.ErrorIs(t, st.CreateTrade(ctx, unknown), domain.ErrUnknownSymbol)
+		_, err = st.GetTradeByID(ctx, unknown.MexcTradeID)
+		assert.ErrorIs(t, err, postgreserr.ErrTradeNotFound)
+
+		balances, err := st.GetLedgerBalances(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, balances, 2)
+		requireDecimal(t, "1.01", balances[0].Balance)
+		requireDecimal(t, "-499.5", balances[1].Balance)
+	})
+
+	t.Run("deposits and withdrawals", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		require.NoError(t, st.PostEntries(ctx, domain.DepositLedgerEntries(user.ID, "USDT", dec("1000"), "d1", baseTime)))
+		require.NoError(t, st.PostEntries(ctx, domain.WithdrawalLedgerEntries(user.ID, "USDT", dec("300"), dec("1"), "w1", at(time.Hour))))
+
+		entries, err := st.GetLedgerEntries(ctx, user.ID, "USDT")
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, domain.LedgerEntryDeposit, entries[0].Kind)
+		assert.Equal(t, "deposit:d1", entries[0].TransactionID)
+		requireDecimal(t, "1000", entries[0].Balance)
+		assert.Equal(t, domain.LedgerEntryWithdrawal, entries[1].Kind)
+		requireDecimal(t, "700", entries[1].Balance)
+		assert.Equal(t, domain.LedgerEntryCommission, entries[2].Kind)
+		requireDecimal(t, "699", entries[2].Balance)
+
+		// Повторная проводка того же вывода отклоняется целиком
+		err = st.PostEntries(ctx, domain.WithdrawalLedgerEntries(user.ID, "USDT", dec("300"), dec("1"), "w1", at(time.Hour)))
+		assertConstraint(t, err, postgreserr.ErrDuplicate, "ledger_entries_transaction_id_account_asset_kind_key")
+
+		unbalanced := domain.DepositLedgerEntries(user.ID, "BTC", dec("1"), "d2", baseTime)
+		unbalanced[1].Amount = dec("-0.5")
+		assert.ErrorIs(t, st.PostEntries(ctx, unbalanced), domain.ErrUnbalancedEntries)
+
+		err = st.PostEntries(ctx, domain.DepositLedgerEntries(user.ID+1000, "BTC", dec("1"), "d3", baseTime))
+		assertConstraint(t, err, postgreserr.ErrForeignKeyViolation, "ledger_entries_user_id_fkey")
+
+		balances, err := st.GetLedgerBalances(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, balances, 1)
+		requireDecimal(t, "699", balances[0].Balance)
+
+		entries, err = st.GetLedgerEntries(ctx, user.ID, "BTC")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("reconcile with balances", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		report, err := st.Reconcile(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, report.OK())
+		assert.Empty(t, report.Items)
+
+		require.NoError(t, st.PostEntries(ctx, domain.DepositLedgerEntries(user.ID, "USDT", dec("1000"), "d1", baseTime)))
+		mustCreateTrade(t, st, newTrade(user.ID, "o1", ids))
+
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{
+			{Asset: "USDT", Free: dec("400"), Locked: dec("99.5")},
+			{Asset: "BTC", Free: dec("0.01"), Locked: dec("0")},
+		}))
+
+		report, err = st.Reconcile(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, report.UserID)
+		assert.True(t, report.OK(), "%+v", report.Mismatches())
+		require.Len(t, report.Items, 2)
+		assert.Equal(t, "BTC", report.Items[0].Asset)
+
+		// Биржа показывает актив, которого нет в ledger, и другой баланс USDT
+		require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{
+			{Asset: "USDT", Free: dec("500"), Locked: dec("0")},
+			{Asset: "BTC", Free: dec("0.01"), Locked: dec("0")},
+			{Asset: "ETH", Free: dec("2"), Locked: dec("0")},
+		}))
+
+		report, err = st.Reconcile(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, report.OK())
+		mismatches := report.Mismatches()
+		require.Len(t, mismatches, 2)
+		assert.Equal(t, "ETH", mismatches[0].Asset)
+		requireDecimal(t, "0", mismatches[0].Ledger)
+		requireDecimal(t, "2", mismatches[0].Exchange)
+		requireDecimal(t, "-2", mismatches[0].Difference)
+		assert.Equal(t, "USDT", mismatches[1].Asset)
+		requireDecimal(t, "499.5", mismatches[1].Ledger)
+		requireDecimal(t, "-0.5", mismatches[1].Difference)
+	})
+
+	t.Run("cascade on user delete", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		mustCreateTrade(t, st, newTrade(user.ID, "o1", ids))
+		mustCreateTrade(t, st, newTrade(other.ID, "o2", ids))
+
+		require.NoError(t, st.DeleteUser(ctx, user.ID))
+
+		balances, err := st.GetLedgerBalances(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+
+		balances, err = st.GetLedgerBalances(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Len(t, balances, 2)
+	})
+}
@@ -0,0 +1,156 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+func newOrderList(userID uint64, ids *seq) *domain.OrderList {
+	return &domain.OrderList{
+		UserID:            userID,
+		MexcOrderListID:   ids.next("list"),
+		ListClientOrderID: ids.next("list_client"),
+		Symbol:            "BTCUSDT",
+		ContingencyType:   domain.ContingencyTypeOCO,
+		ListStatusType:    domain.ListStatusTypeExecStarted,
+		ListOrderStatus:   domain.ListOrderStatusExecuting,
+		TransactionTime:   baseTime,
+	}
+}
+
+// newOCOOrders возвращает пару ордеров OCO: лимитный тейк-профит и стоп-лимит
+func newOCOOrders(ids *seq) []*domain.Order {
+	takeProfit := newOrder(0, ids)
+	takeProfit.Side = domain.OrderSideSell
+	takeProfit.Type = domain.OrderTypeLimitMaker
+	takeProfit.Price = dec("52000")
+
+	stopLoss := newOrder(0, ids)
+	stopLoss.Side = domain.OrderSideSell
+	stopLoss.Type = domain.OrderTypeStopLimit
+	stopLoss.TimeInForce = domain.TimeInForceGTC
+	stopLoss.Price = dec("47900")
+	stopLoss.StopPrice.Decimal = dec("48000")
+	stopLoss.StopPrice.Valid = true
+
+	return []*domain.Order{takeProfit, stopLoss}
+}
+
+func runOrderListTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		list := newOrderList(user.ID, ids)
+		orders := newOCOOrders(ids)
+
+		require.NoError(t, st.CreateOrderList(ctx, list, orders))
+		assert.NotZero(t, list.ID)
+		for _, o := range orders {
+			assert.Equal(t, user.ID, o.UserID)
+			assert.Equal(t, list.MexcOrderListID, o.OrderListID)
+		}
+
+		got, err := st.GetOrderList(ctx, list.MexcOrderListID)
+		require.NoError(t, err)
+		assert.Equal(t, list.ID, got.ID)
+		assert.Equal(t, user.ID, got.UserID)
+		assert.Equal(t, list.ListClientOrderID, got.ListClientOrderID)
+		assert.Equal(t, "BTCUSDT", got.Symbol)
+		assert.Equal(t, domain.ContingencyTypeOCO, got.ContingencyType)
+		assert.Equal(t, domain.ListStatusTypeExecStarted, got.ListStatusType)
+		assert.Equal(t, domain.ListOrderStatusExecuting, got.ListOrderStatus)
+		assert.True(t, baseTime.Equal(got.TransactionTime))
+		assert.False(t, got.CreatedAt.IsZero())
+
+		members, err := st.GetOrderListOrders(ctx, list.MexcOrderListID)
+		require.NoError(t, err)
+		assert.Equal(t, orderIDs(orders), orderIDs(members))
+		assert.Equal(t, domain.OrderTypeStopLimit, members[1].Type)
+		requireDecimal(t, "48000", members[1].StopPrice.Decimal)
+
+		member, err := st.GetOrderByID(ctx, orders[0].MexcOrderID)
+		require.NoError(t, err)
+		assert.Equal(t, list.MexcOrderListID, member.OrderListID)
+
+		members, err = st.GetOrderListOrders(ctx, "missing")
+		require.NoError(t, err)
+		assert.Empty(t, members)
+	})
+
+	t.Run("update status", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		list := newOrderList(user.ID, ids)
+		require.NoError(t, st.CreateOrderList(ctx, list, newOCOOrders(ids)))
+
+		require.NoError(t, st.UpdateOrderListStatus(ctx, list.MexcOrderListID, domain.ListStatusTypeAllDone, domain.ListOrderStatusAllDone))
+
+		got, err := st.GetOrderList(ctx, list.MexcOrderListID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ListStatusTypeAllDone, got.ListStatusType)
+		assert.Equal(t, domain.ListOrderStatusAllDone, got.ListOrderStatus)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		st := factory(t)
+
+		_, err := st.GetOrderList(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrOrderListNotFound)
+
+		err = st.UpdateOrderListStatus(ctx, "missing", domain.ListStatusTypeAllDone, domain.ListOrderStatusAllDone)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderListNotFound)
+	})
+
+	t.Run("failure is atomic", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		existing := mustCreateOrder(t, st, newOrder(user.ID, ids))
+
+		list := newOrderList(user.ID, ids)
+		orders := newOCOOrders(ids)
+		orders[1].MexcOrderID = existing.MexcOrderID
+		err := st.CreateOrderList(ctx, list, orders)
+		assertConstraint(t, err, postgreserr.ErrDuplicate, "orders_mexc_order_id_key")
+
+		_, err = st.GetOrderList(ctx, list.MexcOrderListID)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderListNotFound)
+		_, err = st.GetOrderByID(ctx, orders[0].MexcOrderID)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderNotFound)
+
+		dup := newOrderList(user.ID, ids)
+		require.NoError(t, st.CreateOrderList(ctx, dup, nil))
+		again := newOrderList(user.ID, ids)
+		again.MexcOrderListID = dup.MexcOrderListID
+		assertConstraint(t, st.CreateOrderList(ctx, again, nil), postgreserr.ErrDuplicate, "order_lists_mexc_order_list_id_key")
+
+		assertConstraint(t, st.CreateOrderList(ctx, newOrderList(user.ID+1000, ids), nil),
+			postgreserr.ErrForeignKeyViolation, "order_lists_user_id_fkey")
+	})
+
+	t.Run("cascade on user delete", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		list := newOrderList(user.ID, ids)
+		require.NoError(t, st.CreateOrderList(ctx, list, newOCOOrders(ids)))
+
+		require.NoError(t, st.DeleteUser(ctx, user.ID))
+
+		_, err := st.GetOrderList(ctx, list.MexcOrderListID)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderListNotFound)
+		members, err := st.GetOrderListOrders(ctx, list.MexcOrderListID)
+		require.NoError(t, err)
+		assert.Empty(t, members)
+	})
+}
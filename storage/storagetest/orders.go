@@ -0,0 +1,508 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func runOrderTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		order := mustCreateOrder(t, st, newOrder(user.ID, ids))
+
+		got, err := st.GetOrderByID(ctx, order.MexcOrderID)
+		require.NoError(t, err)
+		assert.NotZero(t, got.ID)
+		assert.Equal(t, order.InternalID, got.InternalID)
+		assert.Equal(t, user.ID, got.UserID)
+		assert.Equal(t, "BTCUSDT", got.Symbol)
+		assert.Equal(t, domain.OrderSideBuy, got.Side)
+		assert.Equal(t, domain.OrderTypeLimit, got.Type)
+		assert.Equal(t, domain.OrderStatusNew, got.Status)
+		assert.Equal(t, order.ClientOrderID, got.ClientOrderID)
+		requireDecimal(t, "50000", got.Price)
+		requireDecimal(t, "0.01", got.Quantity)
+		requireDecimal(t, "500", got.QuoteOrderQty)
+		requireDecimal(t, "0", got.ExecutedQuantity)
+		assert.True(t, baseTime.Equal(got.TransactTime))
+		assert.False(t, got.CreatedAt.IsZero())
+	})
+
+	t.Run("extended fields", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		stop := newOrder(user.ID, ids)
+		stop.Type = domain.OrderTypeStopLimit
+		stop.TimeInForce = domain.TimeInForceGTC
+		stop.StopPrice = decimal.NewNullDecimal(dec("48000.5"))
+		stop.IcebergQty = decimal.NewNullDecimal(dec("0.002"))
+		mustCreateOrder(t, st, stop)
+
+		got, err := st.GetOrderByID(ctx, stop.MexcOrderID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderTypeStopLimit, got.Type)
+		assert.Equal(t, domain.TimeInForceGTC, got.TimeInForce)
+		require.True(t, got.StopPrice.Valid)
+		requireDecimal(t, "48000.5", got.StopPrice.Decimal)
+		require.True(t, got.IcebergQty.Valid)
+		requireDecimal(t, "0.002", got.IcebergQty.Decimal)
+		assert.Empty(t, got.OrderListID)
+
+		// Необязательные поля остаются пустыми
+		plain := mustCreateOrder(t, st, newOrder(user.ID, ids))
+		got, err = st.GetOrderByID(ctx, plain.MexcOrderID)
+		require.NoError(t, err)
+		assert.Empty(t, got.TimeInForce)
+		assert.False(t, got.StopPrice.Valid)
+		assert.False(t, got.IcebergQty.Valid)
+
+		open, err := st.GetOpenOrders(ctx, user.ID, "")
+		require.NoError(t, err)
+		require.Len(t, open, 2)
+		assert.Equal(t, domain.TimeInForceGTC, open[1].TimeInForce)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		st := factory(t)
+
+		_, err := st.GetOrderByID(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrOrderNotFound)
+
+		err = st.UpdateOrderStatus(ctx, "missing", "FILLED")
+		assert.ErrorIs(t, err, postgreserr.ErrOrderNotFound)
+
+		err = st.DeleteOrderByID(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrOrderNotFound)
+	})
+
+	t.Run("duplicate keys", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		order := mustCreateOrder(t, st, newOrder(user.ID, ids))
+
+		sameMexcID := newOrder(user.ID, ids)
+		sameMexcID.MexcOrderID = order.MexcOrderID
+		assertConstraint(t, st.CreateOrder(ctx, sameMexcID), postgreserr.ErrDuplicate, "orders_mexc_order_id_key")
+
+		sameInternalID := newOrder(user.ID, ids)
+		sameInternalID.InternalID = order.InternalID
+		assertConstraint(t, st.CreateOrder(ctx, sameInternalID), postgreserr.ErrDuplicate, "orders_internal_id_key")
+
+		assertConstraint(t, st.CreateOrder(ctx, newOrder(user.ID+1000, ids)), postgreserr.ErrForeignKeyViolation, "orders_user_id_fkey")
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		order := newOrder(user.ID, ids)
+
+		result, err := st.UpsertOrder(ctx, order)
+		require.NoError(t, err)
+		assert.Equal(t, storage.UpsertCreated, result)
+		assert.NotZero(t, order.ID)
+
+		// Тот же снимок повторно ничего не меняет
+		result, err = st.UpsertOrder(ctx, order)
+		require.NoError(t, err)
+		assert.Equal(t, storage.UpsertIgnored, result)
+
+		partial := *order
+		partial.Status = "PARTIALLY_FILLED"
+		partial.ExecutedQuantity = dec("0.004")
+		partial.CummulativeQuoteQty = dec("200")
+		partial.Symbol = "ETHUSDT" // неизменяемые поля не обновляются
+		result, err = st.UpsertOrder(ctx, &partial)
+		require.NoError(t, err)
+		assert.Equal(t, storage.UpsertUpdated, result)
+		assert.Equal(t, order.ID, partial.ID)
+
+		same := partial
+		same.ExecutedQuantity = dec("0.006")
+		same.CummulativeQuoteQty = dec("300")
+		result, err = st.UpsertOrder(ctx, &same)
+		require.NoError(t, err)
+		assert.Equal(t, storage.UpsertUpdated, result, "more fills with the same status")
+
+		backwards := []*domain.Order{
+			func() *domain.Order { o := same; o.Status = "NEW"; return &o }(),
+			func() *domain.Order { o := same; o.ExecutedQuantity = dec("0.005"); return &o }(),
+			func() *domain.Order { o := same; o.CummulativeQuoteQty = dec("100"); return &o }(),
+			func() *domain.Order { o := same; o.Status = "FILLED"; o.ExecutedQuantity = dec("0.001"); return &o }(),
+		}
+		for _, o := range backwards {
+			result, err = st.UpsertOrder(ctx, o)
+			require.NoError(t, err)
+			assert.Equal(t, storage.UpsertIgnored, result, "%s %s", o.Status, o.ExecutedQuantity)
+		}
+
+		filled := same
+		filled.Status = "FILLED"
+		filled.ExecutedQuantity = dec("0.01")
+		filled.CummulativeQuoteQty = dec("500")
+		result, err = st.UpsertOrder(ctx, &filled)
+		require.NoError(t, err)
+		assert.Equal(t, storage.UpsertUpdated, result)
+
+		canceled := filled
+		canceled.Status = "CANCELED"
+		result, err = st.UpsertOrder(ctx, &canceled)
+		require.NoError(t, err)
+		assert.Equal(t, storage.UpsertIgnored, result, "final status is not replaced")
+
+		got, err := st.GetOrderByID(ctx, order.MexcOrderID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusFilled, got.Status)
+		assert.Equal(t, "BTCUSDT", got.Symbol)
+		requireDecimal(t, "0.01", got.ExecutedQuantity)
+		requireDecimal(t, "500", got.CummulativeQuoteQty)
+
+		_, err = st.UpsertOrder(ctx, newOrder(user.ID+1000, ids))
+		assertConstraint(t, err, postgreserr.ErrForeignKeyViolation, "orders_user_id_fkey")
+	})
+
+	t.Run("upsert batch", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		existing := mustCreateOrder(t, st, newOrder(user.ID, ids))
+		stale := mustCreateOrder(t, st, newOrder(user.ID, ids))
+
+		advanced := *existing
+		advanced.Status = "FILLED"
+		advanced.ExecutedQuantity = dec("0.01")
+		fresh := newOrder(user.ID, ids)
+
+		results, err := st.UpsertOrders(ctx, []*domain.Order{&advanced, fresh, stale})
+		require.NoError(t, err)
+		assert.Equal(t, []storage.UpsertResult{storage.UpsertUpdated, storage.UpsertCreated, storage.UpsertIgnored}, results)
+
+		results, err = st.UpsertOrders(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		// Ошибка в середине пакета откатывает весь пакет
+		another := newOrder(user.ID, ids)
+		conflict := newOrder(user.ID, ids)
+		conflict.InternalID = existing.InternalID
+		_, err = st.UpsertOrders(ctx, []*domain.Order{another, conflict})
+		assertConstraint(t, err, postgreserr.ErrDuplicate, "orders_internal_id_key")
+
+		_, err = st.GetOrderByID(ctx, another.MexcOrderID)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderNotFound)
+	})
+
+	t.Run("update status and delete", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		order := mustCreateOrder(t, st, newOrder(user.ID, ids))
+
+		require.NoError(t, st.UpdateOrderStatus(ctx, order.MexcOrderID, "FILLED"))
+		got, err := st.GetOrderByID(ctx, order.MexcOrderID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusFilled, got.Status)
+
+		require.NoError(t, st.DeleteOrderByID(ctx, order.MexcOrderID))
+		_, err = st.GetOrderByID(ctx, order.MexcOrderID)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderNotFound)
+	})
+
+	t.Run("status transitions", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		order := mustCreateOrder(t, st, newOrder(user.ID, ids))
+
+		require.NoError(t, st.UpdateOrderStatus(ctx, order.MexcOrderID, domain.OrderStatusPartiallyFilled))
+		require.NoError(t, st.UpdateOrderStatus(ctx, order.MexcOrderID, domain.OrderStatusPartiallyFilled))
+
+		err := st.UpdateOrderStatus(ctx, order.MexcOrderID, domain.OrderStatusNew)
+		assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+		var transitionErr *domain.TransitionError
+		require.ErrorAs(t, err, &transitionErr)
+		assert.Equal(t, domain.OrderStatusPartiallyFilled, transitionErr.From)
+		assert.Equal(t, domain.OrderStatusNew, transitionErr.To)
+
+		err = st.UpdateOrderStatus(ctx, order.MexcOrderID, "DONE")
+		assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+
+		require.NoError(t, st.UpdateOrderStatus(ctx, order.MexcOrderID, domain.OrderStatusCanceled))
+
+		// Финальный статус не меняется, даже на тот же самый
+		for _, next := range domain.OrderStatuses {
+			err = st.UpdateOrderStatus(ctx, order.MexcOrderID, next)
+			assert.ErrorIs(t, err, domain.ErrInvalidTransition, next)
+		}
+
+		got, err := st.GetOrderByID(ctx, order.MexcOrderID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCanceled, got.Status)
+	})
+
+	t.Run("user orders filters", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		create := func(symbol string, status domain.OrderStatus, transact time.Time) *domain.Order {
+			o := newOrder(user.ID, ids)
+			o.Symbol = symbol
+			o.Status = status
+			o.TransactTime = transact
+			return mustCreateOrder(t, st, o)
+		}
+		btcNew := create("BTCUSDT", "NEW", at(0))
+		btcFilled := create("BTCUSDT", "FILLED", at(time.Hour))
+		ethNew := create("ETHUSDT", "NEW", at(2*time.Hour))
+		ethFilled := create("ETHUSDT", "FILLED", at(3*time.Hour))
+		mustCreateOrder(t, st, newOrder(other.ID, ids))
+
+		cases := []struct {
+			name     string
+			filter   storage.OrderFilter
+			expected []*domain.Order
+		}{
+			{"no filter", storage.OrderFilter{}, []*domain.Order{ethFilled, ethNew, btcFilled, btcNew}},
+			{"symbol", storage.OrderFilter{Symbol: "BTCUSDT"}, []*domain.Order{btcFilled, btcNew}},
+			{"status", storage.OrderFilter{Status: "NEW"}, []*domain.Order{ethNew, btcNew}},
+			{"symbol and status", storage.OrderFilter{Symbol: "ETHUSDT", Status: "FILLED"}, []*domain.Order{ethFilled}},
+			{"start time inclusive", storage.OrderFilter{StartTime: ptr(at(2 * time.Hour))}, []*domain.Order{ethFilled, ethNew}},
+			{"end time inclusive", storage.OrderFilter{EndTime: ptr(at(time.Hour))}, []*domain.Order{btcFilled, btcNew}},
+			{"time range", storage.OrderFilter{StartTime: ptr(at(time.Hour)), EndTime: ptr(at(2 * time.Hour))}, []*domain.Order{ethNew, btcFilled}},
+			{"limit", storage.OrderFilter{Limit: 2}, []*domain.Order{ethFilled, ethNew}},
+			{"limit and offset", storage.OrderFilter{Limit: 2, Offset: 1}, []*domain.Order{ethNew, btcFilled}},
+			{"offset only", storage.OrderFilter{Offset: 3}, []*domain.Order{btcNew}},
+			{"offset past end", storage.OrderFilter{Offset: 10}, nil},
+			{"no match", storage.OrderFilter{Symbol: "XRPUSDT"}, nil},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				orders, err := st.GetUserOrders(ctx, user.ID, tc.filter)
+				require.NoError(t, err)
+				assert.Equal(t, orderIDs(tc.expected), orderIDs(orders))
+			})
+		}
+
+		orders, err := st.GetUserOrders(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Len(t, orders, 4, "filters are optional")
+	})
+
+	t.Run("user orders extended filters and sort", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		create := func(symbol string, side domain.OrderSide, typ domain.OrderType, status domain.OrderStatus, price, qty, clientID string) *domain.Order {
+			o := newOrder(user.ID, ids)
+			o.Symbol, o.Side, o.Type, o.Status = symbol, side, typ, status
+			o.Price, o.Quantity, o.ClientOrderID = dec(price), dec(qty), clientID
+			return mustCreateOrder(t, st, o)
+		}
+		a := create("BTCUSDT", domain.OrderSideBuy, domain.OrderTypeLimit, domain.OrderStatusNew, "50000", "0.01", "bot_a")
+		b := create("ETHUSDT", domain.OrderSideSell, domain.OrderTypeLimit, domain.OrderStatusFilled, "3000", "0.5", "bot_b")
+		c := create("MXUSDT", domain.OrderSideBuy, domain.OrderTypeMarket, domain.OrderStatusCanceled, "0.5", "100", "botxc")
+		d := create("BTCUSDT", domain.OrderSideSell, domain.OrderTypeLimit, domain.OrderStatusPartiallyFilled, "51000", "0.02", "manual_d")
+
+		cases := []struct {
+			name     string
+			filter   storage.OrderFilter
+			expected []*domain.Order
+		}{
+			{"symbols", storage.OrderFilter{Symbols: []string{"BTCUSDT", "ETHUSDT"}}, []*domain.Order{d, b, a}},
+			{"symbol joins symbols", storage.OrderFilter{Symbol: "MXUSDT", Symbols: []string{"ETHUSDT"}}, []*domain.Order{c, b}},
+			{"statuses", storage.OrderFilter{Statuses: []domain.OrderStatus{domain.OrderStatusNew, domain.OrderStatusFilled}}, []*domain.Order{b, a}},
+			{"side", storage.OrderFilter{Side: domain.OrderSideSell}, []*domain.Order{d, b}},
+			{"type", storage.OrderFilter{Type: domain.OrderTypeMarket}, []*domain.Order{c}},
+			{"client id prefix is literal", storage.OrderFilter{ClientOrderIDPrefix: "bot_"}, []*domain.Order{b, a}},
+			{"price range inclusive", storage.OrderFilter{MinPrice: ptr(dec("3000")), MaxPrice: ptr(dec("50000"))}, []*domain.Order{b, a}},
+			{"min quantity inclusive", storage.OrderFilter{MinQuantity: ptr(dec("0.02"))}, []*domain.Order{d, c, b}},
+			{"max quantity", storage.OrderFilter{MaxQuantity: ptr(dec("0.02"))}, []*domain.Order{d, a}},
+			{"sort by price ascending", storage.OrderFilter{SortBy: storage.SortByPrice, SortAsc: true}, []*domain.Order{c, b, a, d}},
+			{"sort by quantity with limit", storage.OrderFilter{SortBy: storage.SortByQuantity, Limit: 2}, []*domain.Order{c, b}},
+			{"oldest first", storage.OrderFilter{SortAsc: true, Side: domain.OrderSideBuy}, []*domain.Order{a, c}},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				orders, err := st.GetUserOrders(ctx, user.ID, tc.filter)
+				require.NoError(t, err)
+				assert.Equal(t, orderIDs(tc.expected), orderIDs(orders))
+			})
+		}
+
+		_, err := st.GetUserOrders(ctx, user.ID, storage.OrderFilter{SortBy: "client_order_id"})
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+		_, err = st.GetUserOrders(ctx, user.ID, storage.OrderFilter{Side: "HOLD"})
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+	})
+
+	t.Run("user orders cursor pages", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		var created []*domain.Order
+		for i := 0; i < 5; i++ {
+			created = append(created, mustCreateOrder(t, st, newOrder(user.ID, ids)))
+		}
+		filled := newOrder(user.ID, ids)
+		filled.Status = "FILLED"
+		mustCreateOrder(t, st, filled)
+
+		// Обход всех NEW-ордеров страницами по два, от новых к старым
+		var paged []string
+		cursor := ""
+		for {
+			page, err := st.GetUserOrdersPage(ctx, user.ID, storage.OrderFilter{Status: "NEW"}, storage.PageRequest{Cursor: cursor, Limit: 2})
+			require.NoError(t, err)
+			for _, o := range page.Items {
+				paged = append(paged, o.MexcOrderID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+
+		var expected []string
+		for i := len(created) - 1; i >= 0; i-- {
+			expected = append(expected, created[i].MexcOrderID)
+		}
+		assert.Equal(t, expected, paged)
+	})
+
+	t.Run("open orders", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		create := func(symbol string, status domain.OrderStatus) *domain.Order {
+			o := newOrder(user.ID, ids)
+			o.Symbol = symbol
+			o.Status = status
+			return mustCreateOrder(t, st, o)
+		}
+		btcNew := create("BTCUSDT", "NEW")
+		btcPartial := create("BTCUSDT", "PARTIALLY_FILLED")
+		create("BTCUSDT", "FILLED")
+		create("BTCUSDT", "CANCELED")
+		ethNew := create("ETHUSDT", "NEW")
+
+		open, err := st.GetOpenOrders(ctx, user.ID, "")
+		require.NoError(t, err)
+		assert.Equal(t, orderIDs([]*domain.Order{ethNew, btcPartial, btcNew}), orderIDs(open))
+
+		open, err = st.GetOpenOrders(ctx, user.ID, "BTCUSDT")
+		require.NoError(t, err)
+		assert.Equal(t, orderIDs([]*domain.Order{btcPartial, btcNew}), orderIDs(open))
+	})
+}
+
+func runOrderUpdateTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("append and get ordered", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+		order := mustCreateOrder(t, st, newOrder(user.ID, ids))
+
+		appendUpdate := func(userID uint64, orderID, status string, updateTime time.Time) {
+			require.NoError(t, st.AppendOrderUpdate(ctx, &domain.OrderUpdate{
+				UserID:              userID,
+				OrderID:             orderID,
+				Status:              status,
+				ExecutedQuantity:    dec("0.005"),
+				CummulativeQuoteQty: dec("250"),
+				UpdateTime:          updateTime,
+				RawData:             []byte(`{"s":"` + status + `"}`),
+			}))
+		}
+		appendUpdate(user.ID, order.MexcOrderID, "NEW", at(0))
+		appendUpdate(user.ID, order.MexcOrderID, "FILLED", at(2*time.Minute))
+		appendUpdate(user.ID, order.MexcOrderID, "PARTIALLY_FILLED", at(time.Minute))
+		appendUpdate(user.ID, "another_order", "NEW", at(0))
+		appendUpdate(other.ID, order.MexcOrderID, "NEW", at(0))
+
+		updates, err := st.GetOrderUpdates(ctx, user.ID, order.MexcOrderID)
+		require.NoError(t, err)
+		require.Len(t, updates, 3)
+		assert.Equal(t, "FILLED", updates[0].Status)
+		assert.Equal(t, "PARTIALLY_FILLED", updates[1].Status)
+		assert.Equal(t, "NEW", updates[2].Status)
+		assert.NotZero(t, updates[0].ID)
+		requireDecimal(t, "0.005", updates[0].ExecutedQuantity)
+		requireDecimal(t, "250", updates[0].CummulativeQuoteQty)
+		assert.JSONEq(t, `{"s":"FILLED"}`, string(updates[0].RawData))
+
+		updates, err = st.GetOrderUpdates(ctx, user.ID, "unknown")
+		require.NoError(t, err)
+		assert.Empty(t, updates)
+	})
+
+	t.Run("same update time ordered by id", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		for _, status := range []string{"NEW", "PARTIALLY_FILLED", "FILLED"} {
+			require.NoError(t, st.AppendOrderUpdate(ctx, &domain.OrderUpdate{
+				UserID: user.ID, OrderID: "o", Status: status,
+				ExecutedQuantity: dec("0"), CummulativeQuoteQty: dec("0"), UpdateTime: at(0),
+			}))
+		}
+
+		updates, err := st.GetOrderUpdates(ctx, user.ID, "o")
+		require.NoError(t, err)
+		require.Len(t, updates, 3)
+		assert.Equal(t, "FILLED", updates[0].Status)
+		assert.Equal(t, "NEW", updates[2].Status)
+	})
+
+	t.Run("cursor pages", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		for i, status := range []string{"NEW", "PARTIALLY_FILLED", "FILLED"} {
+			require.NoError(t, st.AppendOrderUpdate(ctx, &domain.OrderUpdate{
+				UserID: user.ID, OrderID: "o", Status: status,
+				ExecutedQuantity: dec("0"), CummulativeQuoteQty: dec("0"), UpdateTime: at(time.Duration(i) * time.Minute),
+			}))
+		}
+
+		first, err := st.GetOrderUpdatesPage(ctx, user.ID, "o", storage.PageRequest{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, first.Items, 2)
+		assert.Equal(t, "FILLED", first.Items[0].Status)
+		assert.Equal(t, "PARTIALLY_FILLED", first.Items[1].Status)
+
+		second, err := st.GetOrderUpdatesPage(ctx, user.ID, "o", storage.PageRequest{Cursor: first.NextCursor, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, second.Items, 1)
+		assert.Equal(t, "NEW", second.Items[0].Status)
+		assert.Empty(t, second.NextCursor)
+		assert.NotEmpty(t, second.PrevCursor)
+	})
+}
@@ -0,0 +1,237 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func mustEnqueue(t *testing.T, st storage.FullStorage, msg *domain.OutboxMessage) *domain.OutboxMessage {
+	t.Helper()
+	enqueued, err := st.EnqueueMessage(context.Background(), msg)
+	require.NoError(t, err)
+	require.True(t, enqueued)
+	return msg
+}
+
+func runOutboxTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("enqueue and get", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		msg := mustEnqueue(t, st, &domain.OutboxMessage{
+			UserID:   user.ID,
+			Topic:    domain.OutboxTopicOrderFilled,
+			DedupKey: "order.filled:1",
+			Payload:  []byte(`{"order_id": "1"}`),
+		})
+		assert.NotZero(t, msg.ID)
+		assert.Equal(t, domain.OutboxPending, msg.Status)
+		assert.False(t, msg.CreatedAt.IsZero())
+		assert.False(t, msg.NextAttemptAt.IsZero())
+
+		got, err := st.GetOutboxMessage(ctx, msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.UserID)
+		assert.Equal(t, domain.OutboxTopicOrderFilled, got.Topic)
+		assert.Equal(t, "order.filled:1", got.DedupKey)
+		assert.JSONEq(t, `{"order_id": "1"}`, string(got.Payload))
+		assert.Equal(t, int32(0), got.Attempts)
+		assert.Nil(t, got.LockedUntil)
+		assert.Nil(t, got.SentAt)
+
+		noPayload := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+		got, err = st.GetOutboxMessage(ctx, noPayload.ID)
+		require.NoError(t, err)
+		assert.Empty(t, got.DedupKey)
+		assert.JSONEq(t, `{}`, string(got.Payload))
+
+		_, err = st.GetOutboxMessage(ctx, 999999)
+		assert.ErrorIs(t, err, postgreserr.ErrOutboxMessageNotFound)
+	})
+
+	t.Run("dedup key", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		first := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note", DedupKey: "k"})
+
+		again := &domain.OutboxMessage{UserID: user.ID, Topic: "note", DedupKey: "k"}
+		enqueued, err := st.EnqueueMessage(ctx, again)
+		require.NoError(t, err)
+		assert.False(t, enqueued)
+		assert.Zero(t, again.ID)
+
+		// Сообщения без ключа не дедуплицируются
+		mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+		mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+
+		pending, err := st.GetOutboxMessages(ctx, domain.OutboxPending, 0)
+		require.NoError(t, err)
+		require.Len(t, pending, 3)
+		assert.Equal(t, first.ID, pending[0].ID)
+
+		pending, err = st.GetOutboxMessages(ctx, domain.OutboxPending, 2)
+		require.NoError(t, err)
+		assert.Len(t, pending, 2)
+	})
+
+	t.Run("claim leases messages", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		a := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+		b := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+		c := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+
+		claimed, err := st.ClaimMessages(ctx, 2, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 2)
+		assert.Equal(t, a.ID, claimed[0].ID)
+		assert.Equal(t, b.ID, claimed[1].ID)
+		assert.Equal(t, int32(1), claimed[0].Attempts)
+		require.NotNil(t, claimed[0].LockedUntil)
+
+		// Захваченные сообщения не выдаются повторно, пока не истек срок
+		claimed, err = st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, c.ID, claimed[0].ID)
+
+		claimed, err = st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+	})
+
+	t.Run("expired lease is claimed again", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		msg := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+
+		// Диспетчер упал после захвата: срок захвата истек сразу
+		claimed, err := st.ClaimMessages(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+
+		claimed, err = st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, msg.ID, claimed[0].ID)
+		assert.Equal(t, int32(2), claimed[0].Attempts)
+	})
+
+	t.Run("sent", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		msg := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+
+		_, err := st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, st.MarkMessageSent(ctx, msg.ID))
+
+		got, err := st.GetOutboxMessage(ctx, msg.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OutboxSent, got.Status)
+		assert.NotNil(t, got.SentAt)
+		assert.Nil(t, got.LockedUntil)
+
+		claimed, err := st.ClaimMessages(ctx, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		assert.ErrorIs(t, st.MarkMessageSent(ctx, 999999), postgreserr.ErrOutboxMessageNotFound)
+	})
+
+	t.Run("retry delays next attempt", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		delayed := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+		now := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+
+		_, err := st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, st.RetryMessage(ctx, delayed.ID, "timeout", time.Hour))
+		require.NoError(t, st.RetryMessage(ctx, now.ID, "rate limited", 0))
+
+		claimed, err := st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, now.ID, claimed[0].ID)
+		assert.Equal(t, "rate limited", claimed[0].LastError)
+		assert.Equal(t, int32(2), claimed[0].Attempts)
+
+		got, err := st.GetOutboxMessage(ctx, delayed.ID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OutboxPending, got.Status)
+		assert.Equal(t, "timeout", got.LastError)
+		assert.Nil(t, got.LockedUntil)
+		assert.True(t, got.NextAttemptAt.After(got.CreatedAt.Add(30*time.Minute)))
+	})
+
+	t.Run("dead letter and requeue", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		msg := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note"})
+
+		_, err := st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, st.DeadLetterMessage(ctx, msg.ID, "bot was blocked"))
+
+		dead, err := st.GetOutboxMessages(ctx, domain.OutboxDead, 0)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, "bot was blocked", dead[0].LastError)
+		assert.Nil(t, dead[0].LockedUntil)
+
+		claimed, err := st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		require.NoError(t, st.RequeueMessage(ctx, msg.ID))
+		// Повторно вернуть можно только сообщение DEAD
+		assert.ErrorIs(t, st.RequeueMessage(ctx, msg.ID), postgreserr.ErrOutboxMessageNotFound)
+
+		claimed, err = st.ClaimMessages(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		assert.Equal(t, int32(1), claimed[0].Attempts)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		st := factory(t)
+
+		_, err := st.EnqueueMessage(ctx, &domain.OutboxMessage{UserID: 999999, Topic: "note"})
+		assert.ErrorIs(t, err, postgreserr.ErrForeignKeyViolation)
+	})
+
+	t.Run("deleted with user", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		msg := mustEnqueue(t, st, &domain.OutboxMessage{UserID: user.ID, Topic: "note", DedupKey: "k"})
+
+		require.NoError(t, st.DeleteUser(ctx, user.ID))
+		_, err := st.GetOutboxMessage(ctx, msg.ID)
+		assert.ErrorIs(t, err, postgreserr.ErrOutboxMessageNotFound)
+
+		// Ключ удаленного сообщения снова свободен
+		other := mustCreateUser(t, st, ids)
+		mustEnqueue(t, st, &domain.OutboxMessage{UserID: other.ID, Topic: "note", DedupKey: "k"})
+	})
+}
@@ -0,0 +1,104 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+func runPriceTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	price := func(symbol, value, source string, observedAt time.Time) *domain.Price {
+		return &domain.Price{Symbol: symbol, Price: dec(value), Source: source, ObservedAt: observedAt}
+	}
+
+	t.Run("latest and point in time", func(t *testing.T) {
+		st := factory(t)
+
+		_, err := st.GetLatestPrice(ctx, "BTCUSDT")
+		assert.ErrorIs(t, err, postgreserr.ErrPriceNotFound)
+
+		first := price("BTCUSDT", "50000", "mexc", baseTime)
+		require.NoError(t, st.SavePrices(ctx, []*domain.Price{
+			first,
+			price("BTCUSDT", "51000", "mexc", at(time.Hour)),
+			price("BTCUSDT", "51500", "other", at(2*time.Hour)),
+			price("ETHUSDT", "3000", "mexc", at(time.Hour)),
+		}))
+		assert.NotZero(t, first.ID)
+		assert.False(t, first.CreatedAt.IsZero())
+
+		latest, err := st.GetLatestPrice(ctx, "BTCUSDT")
+		require.NoError(t, err)
+		requireDecimal(t, "51500", latest.Price)
+		assert.Equal(t, "other", latest.Source)
+		assert.True(t, at(2*time.Hour).Equal(latest.ObservedAt))
+
+		all, err := st.GetLatestPrices(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, "BTCUSDT", all[0].Symbol)
+		requireDecimal(t, "51500", all[0].Price)
+		assert.Equal(t, "ETHUSDT", all[1].Symbol)
+
+		prices, err := st.GetPricesAt(ctx, at(90*time.Minute))
+		require.NoError(t, err)
+		require.Len(t, prices, 2)
+		requireDecimal(t, "51000", prices[0].Price)
+
+		// Граница включается
+		prices, err = st.GetPricesAt(ctx, baseTime)
+		require.NoError(t, err)
+		require.Len(t, prices, 1)
+		requireDecimal(t, "50000", prices[0].Price)
+
+		prices, err = st.GetPricesAt(ctx, at(-time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, prices)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		st := factory(t)
+
+		require.NoError(t, st.SavePrices(ctx, []*domain.Price{
+			price("BTCUSDT", "3", "mexc", at(2*time.Hour)),
+			price("BTCUSDT", "1", "mexc", baseTime),
+			price("BTCUSDT", "2", "mexc", at(time.Hour)),
+			price("BTCUSDT", "4", "mexc", at(3*time.Hour)),
+			price("ETHUSDT", "9", "mexc", at(time.Hour)),
+		}))
+
+		history, err := st.GetPriceHistory(ctx, "BTCUSDT", at(time.Hour), at(2*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		requireDecimal(t, "2", history[0].Price)
+		requireDecimal(t, "3", history[1].Price)
+
+		history, err = st.GetPriceHistory(ctx, "XRPUSDT", baseTime, at(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("repeated observation updates price", func(t *testing.T) {
+		st := factory(t)
+
+		original := price("BTCUSDT", "50000", "mexc", baseTime)
+		require.NoError(t, st.SavePrices(ctx, []*domain.Price{original}))
+
+		repeated := price("BTCUSDT", "50001", "mexc", baseTime)
+		require.NoError(t, st.SavePrices(ctx, []*domain.Price{repeated}))
+		assert.Equal(t, original.ID, repeated.ID)
+
+		history, err := st.GetPriceHistory(ctx, "BTCUSDT", baseTime, baseTime)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		requireDecimal(t, "50001", history[0].Price)
+	})
+}
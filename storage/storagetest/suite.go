@@ -0,0 +1,187 @@
+// Package storagetest содержит набор контрактных тестов для любой реализации storage.FullStorage.
+// Один и тот же набор запускается для PostgreSQL и in-memory хранилища, поэтому
+// поведение, которое видит вызывающий код, у всех реализаций совпадает.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// Factory возвращает новое пустое хранилище для каждого теста.
+// Очистка ресурсов регистрируется через t.Cleanup.
+type Factory func(t *testing.T) storage.FullStorage
+
+// RunFullStorageSuite прогоняет все контрактные тесты против хранилища, созданного factory.
+func RunFullStorageSuite(t *testing.T, factory Factory) {
+	t.Run("Users", func(t *testing.T) { runUserTests(t, factory) })
+	t.Run("Orders", func(t *testing.T) { runOrderTests(t, factory) })
+	t.Run("OrderUpdates", func(t *testing.T) { runOrderUpdateTests(t, factory) })
+	t.Run("Trades", func(t *testing.T) { runTradeTests(t, factory) })
+	t.Run("Balances", func(t *testing.T) { runBalanceTests(t, factory) })
+	t.Run("OrderLists", func(t *testing.T) { runOrderListTests(t, factory) })
+	t.Run("Ledger", func(t *testing.T) { runLedgerTests(t, factory) })
+	t.Run("Prices", func(t *testing.T) { runPriceTests(t, factory) })
+	t.Run("Symbols", func(t *testing.T) { runSymbolTests(t, factory) })
+	t.Run("SyncCursors", func(t *testing.T) { runSyncCursorTests(t, factory) })
+	t.Run("Transfers", func(t *testing.T) { runTransferTests(t, factory) })
+	t.Run("Outbox", func(t *testing.T) { runOutboxTests(t, factory) })
+}
+
+// baseTime — фиксированная точка отсчета. Время в UTC и с точностью до микросекунд,
+// чтобы значения без потерь проходили через колонки TIMESTAMP.
+var baseTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func at(offset time.Duration) time.Time {
+	return baseTime.Add(offset)
+}
+
+// checkpoint возвращает момент времени, строго разделяющий записи до и после вызова
+// с запасом на округление TIMESTAMP в PostgreSQL до микросекунд.
+func checkpoint() time.Time {
+	time.Sleep(2 * time.Millisecond)
+	t := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	return t
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+// seq выдает уникальные значения в пределах одного теста.
+type seq struct {
+	n int
+}
+
+func (s *seq) next(prefix string) string {
+	s.n++
+	return fmt.Sprintf("%s_%d", prefix, s.n)
+}
+
+func (s *seq) nextInt() int64 {
+	s.n++
+	return int64(s.n)
+}
+
+func mustCreateUser(t *testing.T, st storage.FullStorage, ids *seq) *domain.User {
+	t.Helper()
+	user := &domain.User{
+		TelegramID:    ids.nextInt(),
+		MexcUID:       ids.next("uid"),
+		Username:      ids.next("user"),
+		Email:         "trader@example.com",
+		MexcAPIKey:    "api_key",
+		MexcSecretKey: "secret_key",
+		KYCStatus:     1,
+		CanTrade:      true,
+		CanWithdraw:   true,
+		CanDeposit:    true,
+		AccountType:   "SPOT",
+		Permissions:   `["SPOT"]`,
+		IsActive:      true,
+	}
+	require.NoError(t, st.CreateUser(context.Background(), user))
+	require.NotZero(t, user.ID)
+	return user
+}
+
+func newOrder(userID uint64, ids *seq) *domain.Order {
+	return &domain.Order{
+		InternalID:          ids.nextInt(),
+		UserID:              userID,
+		MexcOrderID:         ids.next("order"),
+		Symbol:              "BTCUSDT",
+		Side:                "BUY",
+		Type:                "LIMIT",
+		Status:              "NEW",
+		Price:               dec("50000"),
+		Quantity:            dec("0.01"),
+		QuoteOrderQty:       dec("500"),
+		ExecutedQuantity:    decimal.Zero,
+		CummulativeQuoteQty: decimal.Zero,
+		ClientOrderID:       ids.next("client"),
+		TransactTime:        baseTime,
+	}
+}
+
+func mustCreateOrder(t *testing.T, st storage.FullStorage, order *domain.Order) *domain.Order {
+	t.Helper()
+	require.NoError(t, st.CreateOrder(context.Background(), order))
+	return order
+}
+
+func newTrade(userID uint64, orderID string, ids *seq) *domain.Trade {
+	return &domain.Trade{
+		UserID:          userID,
+		MexcTradeID:     ids.next("trade"),
+		OrderID:         orderID,
+		Symbol:          "BTCUSDT",
+		Price:           dec("50000"),
+		Quantity:        dec("0.01"),
+		QuoteQuantity:   dec("500"),
+		Commission:      dec("0.5"),
+		CommissionAsset: "USDT",
+		TradeTime:       baseTime,
+		IsBuyer:         true,
+		IsMaker:         false,
+	}
+}
+
+func mustCreateTrade(t *testing.T, st storage.FullStorage, trade *domain.Trade) *domain.Trade {
+	t.Helper()
+	require.NoError(t, st.CreateTrade(context.Background(), trade))
+	require.NotZero(t, trade.ID)
+	return trade
+}
+
+func orderIDs(orders []*domain.Order) []string {
+	ids := make([]string, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.MexcOrderID)
+	}
+	return ids
+}
+
+func tradeIDs(trades []*domain.Trade) []string {
+	ids := make([]string, 0, len(trades))
+	for _, tr := range trades {
+		ids = append(ids, tr.MexcTradeID)
+	}
+	return ids
+}
+
+func assets(balances []*domain.UserBalance) []string {
+	out := make([]string, 0, len(balances))
+	for _, b := range balances {
+		out = append(out, b.Asset)
+	}
+	return out
+}
+
+func requireDecimal(t *testing.T, expected string, actual decimal.Decimal) {
+	t.Helper()
+	require.Truef(t, dec(expected).Equal(actual), "expected %s, got %s", expected, actual)
+}
+
+// assertConstraint проверяет класс ошибки и имя нарушенного ограничения.
+func assertConstraint(t *testing.T, err error, kind error, constraint string) {
+	t.Helper()
+	require.ErrorIs(t, err, kind)
+	var pgErr *postgreserr.Error
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, constraint, pgErr.Constraint)
+}
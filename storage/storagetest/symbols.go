@@ -0,0 +1,89 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+func runSymbolTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	newSymbol := func(symbol, base string) *domain.Symbol {
+		return &domain.Symbol{
+			Symbol:      symbol,
+			BaseAsset:   base,
+			QuoteAsset:  "USDT",
+			Status:      domain.SymbolStatusEnabled,
+			TickSize:    dec("0.01"),
+			StepSize:    dec("0.000001"),
+			MinQty:      dec("0.0001"),
+			MinNotional: dec("5"),
+			MaxNotional: dec("2000000"),
+			OrderTypes:  `["LIMIT","MARKET"]`,
+			Permissions: `["SPOT"]`,
+		}
+	}
+
+	t.Run("upsert and get", func(t *testing.T) {
+		st := factory(t)
+
+		_, err := st.GetSymbol(ctx, "BTCUSDT")
+		assert.ErrorIs(t, err, postgreserr.ErrSymbolNotFound)
+
+		btc := newSymbol("BTCUSDT", "BTC")
+		eth := newSymbol("ETHUSDT", "ETH")
+		eth.OrderTypes = ""
+		require.NoError(t, st.UpsertSymbols(ctx, []*domain.Symbol{eth, btc}))
+		assert.NotZero(t, btc.ID)
+		assert.False(t, btc.UpdatedAt.IsZero())
+
+		got, err := st.GetSymbol(ctx, "BTCUSDT")
+		require.NoError(t, err)
+		assert.Equal(t, btc.ID, got.ID)
+		assert.Equal(t, "BTC", got.BaseAsset)
+		assert.Equal(t, domain.SymbolStatusEnabled, got.Status)
+		requireDecimal(t, "0.01", got.TickSize)
+		requireDecimal(t, "0.000001", got.StepSize)
+		requireDecimal(t, "0.0001", got.MinQty)
+		requireDecimal(t, "0", got.MaxQty)
+		requireDecimal(t, "5", got.MinNotional)
+		requireDecimal(t, "2000000", got.MaxNotional)
+		assert.JSONEq(t, `["LIMIT","MARKET"]`, got.OrderTypes)
+		assert.JSONEq(t, `["SPOT"]`, got.Permissions)
+
+		all, err := st.GetSymbols(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, "BTCUSDT", all[0].Symbol)
+		assert.Equal(t, "ETHUSDT", all[1].Symbol)
+		assert.Empty(t, all[1].OrderTypes)
+	})
+
+	t.Run("upsert updates rules", func(t *testing.T) {
+		st := factory(t)
+
+		btc := newSymbol("BTCUSDT", "BTC")
+		require.NoError(t, st.UpsertSymbols(ctx, []*domain.Symbol{btc}))
+
+		updated := newSymbol("BTCUSDT", "BTC")
+		updated.Status = domain.SymbolStatusPaused
+		updated.TickSize = dec("0.1")
+		require.NoError(t, st.UpsertSymbols(ctx, []*domain.Symbol{updated}))
+		assert.Equal(t, btc.ID, updated.ID)
+
+		got, err := st.GetSymbol(ctx, "BTCUSDT")
+		require.NoError(t, err)
+		assert.Equal(t, domain.SymbolStatusPaused, got.Status)
+		requireDecimal(t, "0.1", got.TickSize)
+
+		all, err := st.GetSymbols(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+}
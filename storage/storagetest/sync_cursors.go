@@ -0,0 +1,112 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+func runSyncCursorTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("save and get", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		_, err := st.GetSyncCursor(ctx, user.ID, domain.SyncStreamTrades, "BTCUSDT")
+		assert.ErrorIs(t, err, postgreserr.ErrSyncCursorNotFound)
+
+		lastTime := at(0)
+		cursor := &domain.SyncCursor{
+			UserID:   user.ID,
+			Stream:   domain.SyncStreamTrades,
+			Symbol:   "BTCUSDT",
+			FromID:   "trade_10",
+			LastTime: &lastTime,
+			Status:   domain.SyncStatusOK,
+		}
+		require.NoError(t, st.SaveSyncCursor(ctx, cursor))
+		assert.NotZero(t, cursor.ID)
+		assert.Equal(t, uint64(1), cursor.Version)
+		assert.False(t, cursor.UpdatedAt.IsZero())
+
+		deposits := &domain.SyncCursor{UserID: user.ID, Stream: domain.SyncStreamDeposits, Status: domain.SyncStatusFailed, LastError: "timeout"}
+		require.NoError(t, st.SaveSyncCursor(ctx, deposits))
+		eth := &domain.SyncCursor{UserID: user.ID, Stream: domain.SyncStreamTrades, Symbol: "ETHUSDT", Status: domain.SyncStatusRunning}
+		require.NoError(t, st.SaveSyncCursor(ctx, eth))
+
+		got, err := st.GetSyncCursor(ctx, user.ID, domain.SyncStreamTrades, "BTCUSDT")
+		require.NoError(t, err)
+		assert.Equal(t, cursor.ID, got.ID)
+		assert.Equal(t, "trade_10", got.FromID)
+		require.NotNil(t, got.LastTime)
+		assert.True(t, lastTime.Equal(*got.LastTime))
+		assert.Equal(t, domain.SyncStatusOK, got.Status)
+		assert.Empty(t, got.LastError)
+
+		got, err = st.GetSyncCursor(ctx, user.ID, domain.SyncStreamDeposits, "")
+		require.NoError(t, err)
+		assert.Nil(t, got.LastTime)
+		assert.Equal(t, "timeout", got.LastError)
+
+		cursors, err := st.GetSyncCursors(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, cursors, 3)
+		assert.Equal(t, domain.SyncStreamDeposits, cursors[0].Stream)
+		assert.Equal(t, "BTCUSDT", cursors[1].Symbol)
+		assert.Equal(t, "ETHUSDT", cursors[2].Symbol)
+
+		other := mustCreateUser(t, st, ids)
+		cursors, err = st.GetSyncCursors(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Empty(t, cursors)
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		cursor := &domain.SyncCursor{UserID: user.ID, Stream: domain.SyncStreamOrders, Symbol: "BTCUSDT", Status: domain.SyncStatusOK}
+		require.NoError(t, st.SaveSyncCursor(ctx, cursor))
+
+		read, err := st.GetSyncCursor(ctx, user.ID, domain.SyncStreamOrders, "BTCUSDT")
+		require.NoError(t, err)
+
+		cursor.FromID = "order_2"
+		require.NoError(t, st.SaveSyncCursor(ctx, cursor))
+		assert.Equal(t, uint64(2), cursor.Version)
+
+		// Курсор, прочитанный до обновления, и повторное создание отклоняются
+		read.FromID = "order_1"
+		assert.ErrorIs(t, st.SaveSyncCursor(ctx, read), postgreserr.ErrSyncCursorConflict)
+		fresh := &domain.SyncCursor{UserID: user.ID, Stream: domain.SyncStreamOrders, Symbol: "BTCUSDT", Status: domain.SyncStatusOK}
+		assert.ErrorIs(t, st.SaveSyncCursor(ctx, fresh), postgreserr.ErrSyncCursorConflict)
+
+		got, err := st.GetSyncCursor(ctx, user.ID, domain.SyncStreamOrders, "BTCUSDT")
+		require.NoError(t, err)
+		assert.Equal(t, "order_2", got.FromID)
+		assert.Equal(t, uint64(2), got.Version)
+
+		assertConstraint(t, st.SaveSyncCursor(ctx, &domain.SyncCursor{UserID: user.ID + 1000, Stream: domain.SyncStreamOrders, Status: domain.SyncStatusOK}),
+			postgreserr.ErrForeignKeyViolation, "sync_cursors_user_id_fkey")
+	})
+
+	t.Run("deleted with user", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		require.NoError(t, st.SaveSyncCursor(ctx, &domain.SyncCursor{UserID: user.ID, Stream: domain.SyncStreamTrades, Symbol: "BTCUSDT", Status: domain.SyncStatusOK}))
+		require.NoError(t, st.DeleteUser(ctx, user.ID))
+
+		_, err := st.GetSyncCursor(ctx, user.ID, domain.SyncStreamTrades, "BTCUSDT")
+		assert.ErrorIs(t, err, postgreserr.ErrSyncCursorNotFound)
+	})
+}
@@ -0,0 +1,402 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func runTradeTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		trade := mustCreateTrade(t, st, newTrade(user.ID, "order_1", ids))
+		assert.False(t, trade.CreatedAt.IsZero())
+
+		got, err := st.GetTradeByID(ctx, trade.MexcTradeID)
+		require.NoError(t, err)
+		assert.Equal(t, trade.ID, got.ID)
+		assert.Equal(t, user.ID, got.UserID)
+		assert.Equal(t, "order_1", got.OrderID)
+		assert.Equal(t, "BTCUSDT", got.Symbol)
+		requireDecimal(t, "50000", got.Price)
+		requireDecimal(t, "0.01", got.Quantity)
+		requireDecimal(t, "500", got.QuoteQuantity)
+		requireDecimal(t, "0.5", got.Commission)
+		assert.Equal(t, "USDT", got.CommissionAsset)
+		assert.True(t, baseTime.Equal(got.TradeTime))
+		assert.True(t, got.IsBuyer)
+		assert.False(t, got.IsMaker)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		st := factory(t)
+
+		_, err := st.GetTradeByID(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrTradeNotFound)
+	})
+
+	t.Run("duplicate mexc trade id", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		trade := mustCreateTrade(t, st, newTrade(user.ID, "order_1", ids))
+
+		dup := newTrade(user.ID, "order_1", ids)
+		dup.MexcTradeID = trade.MexcTradeID
+		assertConstraint(t, st.CreateTrade(ctx, dup), postgreserr.ErrDuplicate, "trades_mexc_trade_id_key")
+
+		assertConstraint(t, st.CreateTrade(ctx, newTrade(user.ID+1000, "order_1", ids)), postgreserr.ErrForeignKeyViolation, "trades_user_id_fkey")
+	})
+
+	t.Run("user trades filters, pagination and ordering", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		create := func(symbol string, tradeTime time.Time) *domain.Trade {
+			tr := newTrade(user.ID, "order", ids)
+			tr.Symbol = symbol
+			tr.TradeTime = tradeTime
+			return mustCreateTrade(t, st, tr)
+		}
+		btc0 := create("BTCUSDT", at(0))
+		eth1 := create("ETHUSDT", at(time.Hour))
+		btc1 := create("BTCUSDT", at(time.Hour)) // то же время — порядок по id DESC
+		btc2 := create("BTCUSDT", at(2*time.Hour))
+		mustCreateTrade(t, st, newTrade(other.ID, "order", ids))
+
+		cases := []struct {
+			name     string
+			filter   storage.TradeFilter
+			expected []*domain.Trade
+		}{
+			{"no filter", storage.TradeFilter{}, []*domain.Trade{btc2, btc1, eth1, btc0}},
+			{"symbol", storage.TradeFilter{Symbol: "BTCUSDT"}, []*domain.Trade{btc2, btc1, btc0}},
+			{"start time inclusive", storage.TradeFilter{StartTime: ptr(at(time.Hour))}, []*domain.Trade{btc2, btc1, eth1}},
+			{"end time inclusive", storage.TradeFilter{EndTime: ptr(at(time.Hour))}, []*domain.Trade{btc1, eth1, btc0}},
+			{"symbol and range", storage.TradeFilter{Symbol: "BTCUSDT", StartTime: ptr(at(time.Hour)), EndTime: ptr(at(time.Hour))}, []*domain.Trade{btc1}},
+			{"limit", storage.TradeFilter{Limit: 1}, []*domain.Trade{btc2}},
+			{"limit and offset", storage.TradeFilter{Limit: 2, Offset: 2}, []*domain.Trade{eth1, btc0}},
+			{"offset past end", storage.TradeFilter{Offset: 4}, nil},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				trades, err := st.GetUserTrades(ctx, user.ID, tc.filter)
+				require.NoError(t, err)
+				assert.Equal(t, tradeIDs(tc.expected), tradeIDs(trades))
+			})
+		}
+
+		// Постраничный обход без пропусков и повторов
+		var paged []*domain.Trade
+		for offset := 0; ; offset += 3 {
+			page, err := st.GetUserTrades(ctx, user.ID, storage.TradeFilter{Limit: 3, Offset: offset})
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			paged = append(paged, page...)
+		}
+		assert.Equal(t, tradeIDs([]*domain.Trade{btc2, btc1, eth1, btc0}), tradeIDs(paged))
+	})
+
+	t.Run("user trades extended filters and sort", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		create := func(symbol string, buyer bool, price, qty string, offset time.Duration) *domain.Trade {
+			tr := newTrade(user.ID, "order", ids)
+			tr.Symbol, tr.IsBuyer, tr.TradeTime = symbol, buyer, at(offset)
+			tr.Price, tr.Quantity = dec(price), dec(qty)
+			tr.QuoteQuantity = tr.Price.Mul(tr.Quantity)
+			return mustCreateTrade(t, st, tr)
+		}
+		a := create("BTCUSDT", true, "50000", "0.01", 0)
+		b := create("ETHUSDT", false, "3000", "0.5", time.Minute)
+		c := create("MXUSDT", true, "2", "100", 2*time.Minute)
+
+		cases := []struct {
+			name     string
+			filter   storage.TradeFilter
+			expected []*domain.Trade
+		}{
+			{"symbols", storage.TradeFilter{Symbols: []string{"BTCUSDT", "MXUSDT"}}, []*domain.Trade{c, a}},
+			{"buyer side", storage.TradeFilter{Side: domain.OrderSideBuy}, []*domain.Trade{c, a}},
+			{"seller side", storage.TradeFilter{Side: domain.OrderSideSell}, []*domain.Trade{b}},
+			{"price range", storage.TradeFilter{MinPrice: ptr(dec("2")), MaxPrice: ptr(dec("3000"))}, []*domain.Trade{c, b}},
+			{"quantity range", storage.TradeFilter{MinQuantity: ptr(dec("0.5")), MaxQuantity: ptr(dec("1"))}, []*domain.Trade{b}},
+			{"sort by quantity ascending", storage.TradeFilter{SortBy: storage.SortByQuantity, SortAsc: true}, []*domain.Trade{a, b, c}},
+			{"sort by price with offset", storage.TradeFilter{SortBy: storage.SortByPrice, Offset: 1}, []*domain.Trade{b, c}},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				trades, err := st.GetUserTrades(ctx, user.ID, tc.filter)
+				require.NoError(t, err)
+				assert.Equal(t, tradeIDs(tc.expected), tradeIDs(trades))
+			})
+		}
+
+		_, err := st.GetUserTrades(ctx, user.ID, storage.TradeFilter{SortBy: "commission"})
+		assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+		for _, err := range st.IterUserTrades(ctx, user.ID, storage.TradeFilter{Side: "HOLD"}) {
+			assert.ErrorIs(t, err, storage.ErrInvalidFilter)
+		}
+	})
+
+	t.Run("user trades cursor pages", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		create := func(symbol string, tradeTime time.Time) *domain.Trade {
+			tr := newTrade(user.ID, "order", ids)
+			tr.Symbol = symbol
+			tr.TradeTime = tradeTime
+			return mustCreateTrade(t, st, tr)
+		}
+		t0 := create("BTCUSDT", at(0))
+		t1 := create("BTCUSDT", at(time.Hour))
+		t2 := create("BTCUSDT", at(time.Hour)) // то же время — порядок по id
+		t3 := create("ETHUSDT", at(2*time.Hour))
+		t4 := create("BTCUSDT", at(3*time.Hour))
+
+		get := func(filter storage.TradeFilter, cursor string) *storage.Page[*domain.Trade] {
+			t.Helper()
+			page, err := st.GetUserTradesPage(ctx, user.ID, filter, storage.PageRequest{Cursor: cursor, Limit: 2})
+			require.NoError(t, err)
+			return page
+		}
+
+		first := get(storage.TradeFilter{}, "")
+		assert.Equal(t, tradeIDs([]*domain.Trade{t4, t3}), tradeIDs(first.Items))
+		assert.Empty(t, first.PrevCursor)
+
+		// Новая сделка во время листания не сдвигает старые страницы
+		fresh := create("BTCUSDT", at(4*time.Hour))
+
+		second := get(storage.TradeFilter{}, first.NextCursor)
+		assert.Equal(t, tradeIDs([]*domain.Trade{t2, t1}), tradeIDs(second.Items))
+		last := get(storage.TradeFilter{}, second.NextCursor)
+		assert.Equal(t, tradeIDs([]*domain.Trade{t0}), tradeIDs(last.Items))
+		assert.Empty(t, last.NextCursor)
+
+		// Обратный обход приходит к новой сделке
+		back := get(storage.TradeFilter{}, last.PrevCursor)
+		assert.Equal(t, tradeIDs([]*domain.Trade{t2, t1}), tradeIDs(back.Items))
+		back = get(storage.TradeFilter{}, back.PrevCursor)
+		assert.Equal(t, tradeIDs([]*domain.Trade{t4, t3}), tradeIDs(back.Items))
+		back = get(storage.TradeFilter{}, back.PrevCursor)
+		assert.Equal(t, tradeIDs([]*domain.Trade{fresh}), tradeIDs(back.Items))
+		assert.Empty(t, back.PrevCursor)
+		assert.NotEmpty(t, back.NextCursor)
+
+		// Фильтр применяется вместе с курсором, Limit и Offset фильтра игнорируются
+		btc := get(storage.TradeFilter{Symbol: "BTCUSDT", Limit: 1, Offset: 1}, first.NextCursor)
+		assert.Equal(t, tradeIDs([]*domain.Trade{t2, t1}), tradeIDs(btc.Items))
+
+		_, err := st.GetUserTradesPage(ctx, user.ID, storage.TradeFilter{}, storage.PageRequest{Cursor: "garbage"})
+		assert.ErrorIs(t, err, storage.ErrInvalidCursor)
+	})
+
+	t.Run("iterate user trades", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		var expected []*domain.Trade
+		for i := 0; i < 4; i++ {
+			tr := newTrade(user.ID, "order", ids)
+			tr.TradeTime = at(time.Duration(i) * time.Minute)
+			if i == 2 {
+				tr.Symbol = "ETHUSDT"
+			}
+			mustCreateTrade(t, st, tr)
+			if tr.Symbol == "BTCUSDT" {
+				expected = append([]*domain.Trade{tr}, expected...)
+			}
+		}
+
+		filter := storage.TradeFilter{Symbol: "BTCUSDT"}
+		var iterated []*domain.Trade
+		for trade, err := range st.IterUserTrades(ctx, user.ID, filter) {
+			require.NoError(t, err)
+			iterated = append(iterated, trade)
+		}
+		assert.Equal(t, tradeIDs(expected), tradeIDs(iterated))
+
+		listed, err := st.GetUserTrades(ctx, user.ID, filter)
+		require.NoError(t, err)
+		assert.Equal(t, tradeIDs(listed), tradeIDs(iterated))
+	})
+
+	t.Run("trades by order and fill summary", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		fill := func(orderID, price, qty, quote, commission, asset string, tradeTime time.Time) *domain.Trade {
+			tr := newTrade(user.ID, orderID, ids)
+			tr.Price = dec(price)
+			tr.Quantity = dec(qty)
+			tr.QuoteQuantity = dec(quote)
+			tr.Commission = dec(commission)
+			tr.CommissionAsset = asset
+			tr.TradeTime = tradeTime
+			return mustCreateTrade(t, st, tr)
+		}
+		second := fill("order_1", "51000", "0.02", "1020", "0.00002", "BTC", at(time.Minute))
+		first := fill("order_1", "50000", "0.01", "500", "0.5", "USDT", at(0))
+		third := fill("order_1", "52000", "0.01", "520", "0.52", "USDT", at(time.Minute)) // то же время — порядок по id
+		fill("order_2", "50000", "1", "50000", "50", "USDT", at(0))
+
+		trades, err := st.GetTradesByOrderID(ctx, "order_1")
+		require.NoError(t, err)
+		assert.Equal(t, tradeIDs([]*domain.Trade{first, second, third}), tradeIDs(trades))
+
+		summary := domain.SummarizeFills("order_1", trades)
+		assert.Equal(t, 3, summary.TradeCount)
+		requireDecimal(t, "0.04", summary.Quantity)
+		requireDecimal(t, "2040", summary.QuoteQuantity)
+		requireDecimal(t, "51000", summary.AvgPrice)
+		require.Len(t, summary.Commissions, 2)
+		requireDecimal(t, "1.02", summary.Commissions["USDT"])
+		requireDecimal(t, "0.00002", summary.Commissions["BTC"])
+
+		trades, err = st.GetTradesByOrderID(ctx, "missing")
+		require.NoError(t, err)
+		assert.Empty(t, trades)
+
+		empty := domain.SummarizeFills("missing", trades)
+		assert.Zero(t, empty.TradeCount)
+		assert.True(t, empty.AvgPrice.IsZero())
+	})
+
+	t.Run("fill mismatches", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		create := func(userID uint64, executed, quote string, trades ...string) *domain.Order {
+			o := newOrder(userID, ids)
+			o.ExecutedQuantity = dec(executed)
+			o.CummulativeQuoteQty = dec(quote)
+			mustCreateOrder(t, st, o)
+			for _, qty := range trades {
+				tr := newTrade(userID, o.MexcOrderID, ids)
+				tr.Quantity = dec(qty)
+				tr.QuoteQuantity = dec(qty).Mul(dec("50000"))
+				mustCreateTrade(t, st, tr)
+			}
+			return o
+		}
+		create(user.ID, "0", "0")                          // без сделок и без исполнения
+		create(user.ID, "0.03", "1500", "0.01", "0.02")    // совпадает
+		missing := create(user.ID, "0.03", "1500", "0.01") // не хватает сделки
+		quote := create(user.ID, "0.01", "499", "0.01")    // расходится только quote
+		noTrades := create(user.ID, "0.01", "500")         // исполнен, но сделок нет
+		create(other.ID, "1", "1")                         // чужой ордер
+
+		mismatches, err := st.GetFillMismatches(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, mismatches, 3)
+
+		assert.Equal(t, missing.MexcOrderID, mismatches[0].OrderID)
+		assert.Equal(t, user.ID, mismatches[0].UserID)
+		assert.Equal(t, "BTCUSDT", mismatches[0].Symbol)
+		assert.Equal(t, domain.OrderStatusNew, mismatches[0].Status)
+		requireDecimal(t, "0.03", mismatches[0].ExecutedQuantity)
+		requireDecimal(t, "1500", mismatches[0].CummulativeQuoteQty)
+		requireDecimal(t, "0.01", mismatches[0].TradesQuantity)
+		requireDecimal(t, "500", mismatches[0].TradesQuoteQuantity)
+		assert.Equal(t, 1, mismatches[0].TradeCount)
+
+		assert.Equal(t, quote.MexcOrderID, mismatches[1].OrderID)
+		requireDecimal(t, "499", mismatches[1].CummulativeQuoteQty)
+		requireDecimal(t, "500", mismatches[1].TradesQuoteQuantity)
+
+		assert.Equal(t, noTrades.MexcOrderID, mismatches[2].OrderID)
+		assert.Zero(t, mismatches[2].TradeCount)
+		assert.True(t, mismatches[2].TradesQuantity.IsZero())
+	})
+
+	t.Run("bulk create skips existing trades", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		existing := mustCreateTrade(t, st, newTrade(user.ID, "order_1", ids))
+
+		fresh := newTrade(user.ID, "order_1", ids)
+		repeated := newTrade(user.ID, "order_1", ids)
+		repeated.MexcTradeID = fresh.MexcTradeID
+		old := newTrade(user.ID, "order_1", ids)
+		old.MexcTradeID = existing.MexcTradeID
+		sell := newTrade(user.ID, "order_2", ids)
+		sell.IsBuyer = false
+		sell.TradeTime = at(time.Hour)
+
+		result, err := st.CreateTrades(ctx, []*domain.Trade{fresh, old, repeated, sell})
+		require.NoError(t, err)
+		assert.Equal(t, storage.BulkInsertResult{Inserted: 2, Skipped: 2}, result)
+		assert.NotZero(t, fresh.ID)
+		assert.NotZero(t, sell.ID)
+		assert.Zero(t, old.ID)
+
+		trades, err := st.GetUserTrades(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Len(t, trades, 3)
+
+		got, err := st.GetTradeByID(ctx, sell.MexcTradeID)
+		require.NoError(t, err)
+		assert.False(t, got.IsBuyer)
+		assert.True(t, sell.TradeTime.Equal(got.TradeTime))
+
+		// Проводки только у новых сделок: две покупки и продажа
+		balances, err := st.GetLedgerBalances(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, balances, 2)
+		requireDecimal(t, "0.01", balances[0].Balance)
+		requireDecimal(t, "-501.5", balances[1].Balance)
+
+		result, err = st.CreateTrades(ctx, []*domain.Trade{newTrade(user.ID, "order_3", ids)})
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Inserted)
+
+		result, err = st.CreateTrades(ctx, nil)
+		require.NoError(t, err)
+		assert.Zero(t, result)
+	})
+
+	t.Run("bulk create is atomic", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		unknown := newTrade(user.ID, "order_1", ids)
+		unknown.Symbol = "XYZ"
+		_, err := st.CreateTrades(ctx, []*domain.Trade{newTrade(user.ID, "order_1", ids), unknown})
+		assert.ErrorIs(t, err, domain.ErrUnknownSymbol)
+
+		_, err = st.CreateTrades(ctx, []*domain.Trade{newTrade(user.ID, "order_1", ids), newTrade(user.ID+1000, "order_1", ids)})
+		assertConstraint(t, err, postgreserr.ErrForeignKeyViolation, "trades_user_id_fkey")
+
+		trades, err := st.GetUserTrades(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, trades)
+	})
+}
@@ -0,0 +1,222 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+func newDeposit(userID uint64, ids *seq) *domain.Deposit {
+	return &domain.Deposit{
+		UserID:        userID,
+		MexcDepositID: ids.next("deposit"),
+		Asset:         "USDT",
+		Network:       "TRC20",
+		Amount:        dec("100"),
+		Address:       "TXaddr",
+		TxID:          ids.next("tx"),
+		Status:        domain.TransferStatusPending,
+		InsertTime:    baseTime,
+	}
+}
+
+func newWithdrawal(userID uint64, ids *seq) *domain.Withdrawal {
+	return &domain.Withdrawal{
+		UserID:           userID,
+		MexcWithdrawalID: ids.next("withdrawal"),
+		Asset:            "USDT",
+		Network:          "TRC20",
+		Amount:           dec("40"),
+		Fee:              dec("1"),
+		Address:          "TXout",
+		Status:           domain.TransferStatusPending,
+		ApplyTime:        baseTime,
+	}
+}
+
+func runTransferTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("deposit upsert and get", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		_, err := st.GetDepositByID(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrDepositNotFound)
+
+		deposit := newDeposit(user.ID, ids)
+		deposit.Memo = "memo_1"
+		require.NoError(t, st.UpsertDeposits(ctx, []*domain.Deposit{deposit}))
+		assert.NotZero(t, deposit.ID)
+		assert.False(t, deposit.CreatedAt.IsZero())
+
+		got, err := st.GetDepositByID(ctx, deposit.MexcDepositID)
+		require.NoError(t, err)
+		assert.Equal(t, deposit.ID, got.ID)
+		assert.Equal(t, user.ID, got.UserID)
+		assert.Equal(t, "USDT", got.Asset)
+		assert.Equal(t, "TRC20", got.Network)
+		requireDecimal(t, "100", got.Amount)
+		requireDecimal(t, "0", got.Fee)
+		assert.Equal(t, "TXaddr", got.Address)
+		assert.Equal(t, "memo_1", got.Memo)
+		assert.Equal(t, deposit.TxID, got.TxID)
+		assert.Equal(t, domain.TransferStatusPending, got.Status)
+		assert.True(t, baseTime.Equal(got.InsertTime))
+
+		assertConstraint(t, st.UpsertDeposits(ctx, []*domain.Deposit{newDeposit(user.ID+1000, ids)}),
+			postgreserr.ErrForeignKeyViolation, "deposits_user_id_fkey")
+	})
+
+	t.Run("deposit is posted to ledger once on success", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		deposit := newDeposit(user.ID, ids)
+		require.NoError(t, st.UpsertDeposits(ctx, []*domain.Deposit{deposit}))
+		balances, err := st.GetLedgerBalances(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+
+		// Повторная загрузка той же истории не меняет ledger
+		for i := 0; i < 2; i++ {
+			update := *deposit
+			update.Status = domain.TransferStatusSuccess
+			require.NoError(t, st.UpsertDeposits(ctx, []*domain.Deposit{&update}))
+			assert.Equal(t, deposit.ID, update.ID)
+		}
+
+		got, err := st.GetDepositByID(ctx, deposit.MexcDepositID)
+		require.NoError(t, err)
+		assert.Equal(t, domain.TransferStatusSuccess, got.Status)
+
+		entries, err := st.GetLedgerEntries(ctx, user.ID, "USDT")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "deposit:"+deposit.MexcDepositID, entries[0].TransactionID)
+		assert.Equal(t, domain.LedgerEntryDeposit, entries[0].Kind)
+		requireDecimal(t, "100", entries[0].Balance)
+	})
+
+	t.Run("withdrawal upsert, ledger and reconcile", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		_, err := st.GetWithdrawalByID(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrWithdrawalNotFound)
+
+		deposit := newDeposit(user.ID, ids)
+		deposit.Status = domain.TransferStatusSuccess
+		require.NoError(t, st.UpsertDeposits(ctx, []*domain.Deposit{deposit}))
+
+		withdrawal := newWithdrawal(user.ID, ids)
+		canceled := newWithdrawal(user.ID, ids)
+		canceled.Status = domain.TransferStatusCanceled
+		require.NoError(t, st.UpsertWithdrawals(ctx, []*domain.Withdrawal{withdrawal, canceled}))
+
+		withdrawal.Status = domain.TransferStatusSuccess
+		withdrawal.TxID = "0xabc"
+		require.NoError(t, st.UpsertWithdrawals(ctx, []*domain.Withdrawal{withdrawal}))
+		require.NoError(t, st.UpsertWithdrawals(ctx, []*domain.Withdrawal{withdrawal}))
+
+		got, err := st.GetWithdrawalByID(ctx, withdrawal.MexcWithdrawalID)
+		require.NoError(t, err)
+		assert.Equal(t, withdrawal.ID, got.ID)
+		assert.Equal(t, domain.TransferStatusSuccess, got.Status)
+		assert.Equal(t, "0xabc", got.TxID)
+		requireDecimal(t, "40", got.Amount)
+		requireDecimal(t, "1", got.Fee)
+		assert.True(t, baseTime.Equal(got.ApplyTime))
+
+		// 100 введено, 40 выведено и 1 комиссия; отмененный вывод не проводится
+		_, err = st.UpdateBalance(ctx, &domain.UserBalance{UserID: user.ID, Asset: "USDT", Free: dec("59")})
+		require.NoError(t, err)
+		report, err := st.Reconcile(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, report.OK(), "mismatches: %v", report.Mismatches())
+
+		assertConstraint(t, st.UpsertWithdrawals(ctx, []*domain.Withdrawal{newWithdrawal(user.ID+1000, ids)}),
+			postgreserr.ErrForeignKeyViolation, "withdrawals_user_id_fkey")
+	})
+
+	t.Run("history filters, pagination and ordering", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		var deposits []*domain.Deposit
+		var withdrawals []*domain.Withdrawal
+		for i, asset := range []string{"USDT", "BTC", "USDT", "USDT"} {
+			d := newDeposit(user.ID, ids)
+			d.Asset = asset
+			d.InsertTime = at(time.Duration(i) * time.Hour)
+			w := newWithdrawal(user.ID, ids)
+			w.Asset = asset
+			w.ApplyTime = d.InsertTime
+			if i == 3 {
+				d.Status, w.Status = domain.TransferStatusSuccess, domain.TransferStatusFailed
+			}
+			deposits = append(deposits, d)
+			withdrawals = append(withdrawals, w)
+		}
+		deposits = append(deposits, newDeposit(other.ID, ids))
+		withdrawals = append(withdrawals, newWithdrawal(other.ID, ids))
+		require.NoError(t, st.UpsertDeposits(ctx, deposits))
+		require.NoError(t, st.UpsertWithdrawals(ctx, withdrawals))
+
+		start, end := at(time.Hour), at(2*time.Hour)
+		cases := []struct {
+			name   string
+			filter storage.TransferFilter
+			want   []int // индексы в deposits/withdrawals
+		}{
+			{"all, newest first", storage.TransferFilter{}, []int{3, 2, 1, 0}},
+			{"by asset", storage.TransferFilter{Asset: "USDT"}, []int{3, 2, 0}},
+			{"by status", storage.TransferFilter{Status: domain.TransferStatusPending}, []int{2, 1, 0}},
+			{"time range", storage.TransferFilter{StartTime: &start, EndTime: &end}, []int{2, 1}},
+			{"limit and offset", storage.TransferFilter{Limit: 2, Offset: 1}, []int{2, 1}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				gotDeposits, err := st.GetUserDeposits(ctx, user.ID, tc.filter)
+				require.NoError(t, err)
+				gotWithdrawals, err := st.GetUserWithdrawals(ctx, user.ID, tc.filter)
+				require.NoError(t, err)
+				require.Len(t, gotDeposits, len(tc.want))
+				require.Len(t, gotWithdrawals, len(tc.want))
+				for i, idx := range tc.want {
+					assert.Equal(t, deposits[idx].MexcDepositID, gotDeposits[i].MexcDepositID)
+					assert.Equal(t, withdrawals[idx].MexcWithdrawalID, gotWithdrawals[i].MexcWithdrawalID)
+				}
+			})
+		}
+	})
+
+	t.Run("deleted with user", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		deposit := newDeposit(user.ID, ids)
+		withdrawal := newWithdrawal(user.ID, ids)
+		require.NoError(t, st.UpsertDeposits(ctx, []*domain.Deposit{deposit}))
+		require.NoError(t, st.UpsertWithdrawals(ctx, []*domain.Withdrawal{withdrawal}))
+		require.NoError(t, st.DeleteUser(ctx, user.ID))
+
+		_, err := st.GetDepositByID(ctx, deposit.MexcDepositID)
+		assert.ErrorIs(t, err, postgreserr.ErrDepositNotFound)
+		_, err = st.GetWithdrawalByID(ctx, withdrawal.MexcWithdrawalID)
+		assert.ErrorIs(t, err, postgreserr.ErrWithdrawalNotFound)
+	})
+}
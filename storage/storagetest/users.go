@@ -0,0 +1,186 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/postgres/postgreserr"
+)
+
+func runUserTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		assert.False(t, user.CreatedAt.IsZero())
+		assert.False(t, user.UpdatedAt.IsZero())
+
+		byID, err := st.GetUserByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.MexcUID, byID.MexcUID)
+		assert.Equal(t, user.TelegramID, byID.TelegramID)
+		assert.Equal(t, user.MexcAPIKey, byID.MexcAPIKey)
+		assert.Equal(t, user.MexcSecretKey, byID.MexcSecretKey)
+		assert.Equal(t, user.Permissions, byID.Permissions)
+		assert.True(t, byID.IsActive)
+		assert.Nil(t, byID.LastAccountSync)
+
+		byUID, err := st.GetUserByMexcUID(ctx, user.MexcUID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, byUID.ID)
+
+		byTelegram, err := st.GetUserByTelegramID(ctx, user.TelegramID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, byTelegram.ID)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		st := factory(t)
+
+		_, err := st.GetUserByID(ctx, 999999)
+		assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+
+		_, err = st.GetUserByMexcUID(ctx, "missing")
+		assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+
+		_, err = st.GetUserByTelegramID(ctx, -1)
+		assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+
+		err = st.UpdateUser(ctx, &domain.User{ID: 999999, MexcAPIKey: "k", MexcSecretKey: "s"})
+		assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+
+		err = st.DeleteUser(ctx, 999999)
+		assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+	})
+
+	t.Run("get all users ordered by id", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+
+		users, err := st.GetAllUsers(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, users)
+
+		first := mustCreateUser(t, st, ids)
+		second := mustCreateUser(t, st, ids)
+		third := mustCreateUser(t, st, ids)
+
+		users, err = st.GetAllUsers(ctx)
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+		assert.Equal(t, []uint64{first.ID, second.ID, third.ID}, []uint64{users[0].ID, users[1].ID, users[2].ID})
+	})
+
+	t.Run("iterate all users", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		first := mustCreateUser(t, st, ids)
+		second := mustCreateUser(t, st, ids)
+		mustCreateUser(t, st, ids)
+
+		// Из цикла можно обращаться к хранилищу
+		var visited []uint64
+		for user, err := range st.IterAllUsers(ctx) {
+			require.NoError(t, err)
+			got, err := st.GetUserByID(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user.MexcAPIKey, got.MexcAPIKey)
+			visited = append(visited, user.ID)
+			if len(visited) == 2 {
+				break
+			}
+		}
+		assert.Equal(t, []uint64{first.ID, second.ID}, visited)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var iterErr error
+		count := 0
+		for _, err := range st.IterAllUsers(cancelled) {
+			if err != nil {
+				iterErr = err
+				break
+			}
+			count++
+			cancel()
+		}
+		assert.Equal(t, 1, count)
+		assert.ErrorIs(t, iterErr, context.Canceled)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+
+		user.Username = "renamed"
+		user.KYCStatus = 2
+		user.CanWithdraw = false
+		user.LastAccountSync = ptr(at(0))
+		require.NoError(t, st.UpdateUser(ctx, user))
+
+		got, err := st.GetUserByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "renamed", got.Username)
+		assert.Equal(t, int16(2), got.KYCStatus)
+		assert.False(t, got.CanWithdraw)
+		require.NotNil(t, got.LastAccountSync)
+		assert.True(t, at(0).Equal(*got.LastAccountSync))
+		assert.False(t, got.UpdatedAt.Before(got.CreatedAt))
+	})
+
+	t.Run("delete cascades", func(t *testing.T) {
+		st := factory(t)
+		ids := &seq{}
+		user := mustCreateUser(t, st, ids)
+		other := mustCreateUser(t, st, ids)
+
+		order := mustCreateOrder(t, st, newOrder(user.ID, ids))
+		otherOrder := mustCreateOrder(t, st, newOrder(other.ID, ids))
+		trade := mustCreateTrade(t, st, newTrade(user.ID, order.MexcOrderID, ids))
+		mustCreateTrade(t, st, newTrade(other.ID, otherOrder.MexcOrderID, ids))
+
+		_, err := st.UpdateBalance(ctx, &domain.UserBalance{UserID: user.ID, Asset: "BTC", Free: dec("1"), Locked: dec("0")})
+		require.NoError(t, err)
+		_, err = st.UpdateBalance(ctx, &domain.UserBalance{UserID: other.ID, Asset: "BTC", Free: dec("2"), Locked: dec("0")})
+		require.NoError(t, err)
+
+		require.NoError(t, st.AppendOrderUpdate(ctx, &domain.OrderUpdate{
+			UserID: user.ID, OrderID: order.MexcOrderID, Status: "NEW",
+			ExecutedQuantity: dec("0"), CummulativeQuoteQty: dec("0"), UpdateTime: at(0),
+		}))
+
+		require.NoError(t, st.DeleteUser(ctx, user.ID))
+
+		_, err = st.GetUserByID(ctx, user.ID)
+		assert.ErrorIs(t, err, postgreserr.ErrUserNotFound)
+
+		_, err = st.GetOrderByID(ctx, order.MexcOrderID)
+		assert.ErrorIs(t, err, postgreserr.ErrOrderNotFound)
+
+		_, err = st.GetTradeByID(ctx, trade.MexcTradeID)
+		assert.ErrorIs(t, err, postgreserr.ErrTradeNotFound)
+
+		_, err = st.GetBalance(ctx, user.ID, "BTC")
+		assert.ErrorIs(t, err, postgreserr.ErrBalanceNotFound)
+
+		updates, err := st.GetOrderUpdates(ctx, user.ID, order.MexcOrderID)
+		require.NoError(t, err)
+		assert.Empty(t, updates)
+
+		// Данные другого пользователя не затронуты
+		_, err = st.GetOrderByID(ctx, otherOrder.MexcOrderID)
+		assert.NoError(t, err)
+		otherTrades, err := st.GetUserTrades(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Len(t, otherTrades, 1)
+		_, err = st.GetBalance(ctx, other.ID, "BTC")
+		assert.NoError(t, err)
+	})
+}
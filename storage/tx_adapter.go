@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TxAdapter wraps sql.Tx to implement DBInterface and Tx.
+// Storages created on top of TxAdapter run all queries in one transaction;
+// nested BeginTx calls are executed as SAVEPOINTs.
+type TxAdapter struct {
+	*sql.Tx
+	savepoints int
+}
+
+// NewTxAdapter creates a new TxAdapter
+func NewTxAdapter(tx *sql.Tx) *TxAdapter {
+	return &TxAdapter{Tx: tx}
+}
+
+// QueryRowContext implements DBInterface.QueryRowContext
+func (a *TxAdapter) QueryRowContext(ctx context.Context, query string, args ...interface{}) RowInterface {
+	row := a.Tx.QueryRowContext(ctx, query, args...)
+	return &RowAdapter{Row: row}
+}
+
+// BeginTx implements DBInterface.BeginTx. Внутри транзакции открывает SAVEPOINT;
+// opts игнорируются, т.к. уровень изоляции задается внешней транзакцией.
+func (a *TxAdapter) BeginTx(ctx context.Context, _ *sql.TxOptions) (Tx, error) {
+	a.savepoints++
+	name := fmt.Sprintf("sp_%d", a.savepoints)
+	if _, err := a.Tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &savepoint{TxAdapter: a, ctx: ctx, name: name}, nil
+}
+
+// PingContext implements DBInterface.PingContext
+func (a *TxAdapter) PingContext(ctx context.Context) error {
+	_, err := a.Tx.ExecContext(ctx, "SELECT 1")
+	return err
+}
+
+// Close implements DBInterface.Close. Транзакция завершается только через Commit/Rollback.
+func (a *TxAdapter) Close() error {
+	return nil
+}
+
+// savepoint — вложенная транзакция внутри TxAdapter
+type savepoint struct {
+	*TxAdapter
+	ctx  context.Context
+	name string
+}
+
+// Commit освобождает SAVEPOINT
+func (s *savepoint) Commit() error {
+	_, err := s.Tx.ExecContext(s.ctx, "RELEASE SAVEPOINT "+s.name)
+	return err
+}
+
+// Rollback откатывает изменения до SAVEPOINT
+func (s *savepoint) Rollback() error {
+	_, err := s.Tx.ExecContext(s.ctx, "ROLLBACK TO SAVEPOINT "+s.name)
+	return err
+}
+
+var (
+	_ DBInterface = (*DBAdapter)(nil)
+	_ DBInterface = (*TxAdapter)(nil)
+	_ Tx          = (*TxAdapter)(nil)
+	_ Tx          = (*savepoint)(nil)
+)
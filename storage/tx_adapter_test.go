@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxAdapter_Savepoints(t *testing.T) {
+	ctx := context.Background()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO t").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	outer, err := NewDBAdapter(db).BeginTx(ctx, nil)
+	require.NoError(t, err)
+	adapter, ok := outer.(*TxAdapter)
+	require.True(t, ok)
+
+	inner, err := adapter.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = inner.ExecContext(ctx, "INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	require.NoError(t, inner.Commit())
+
+	inner, err = adapter.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, inner.Rollback())
+
+	assert.NoError(t, adapter.Close())
+	require.NoError(t, outer.Commit())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,234 @@
+// Package valuation оценивает балансы пользователя в выбранном котируемом активе
+// по ценам из PriceStorage. Если прямой пары нет, пересчет идет через промежуточные
+// активы (например, XYZ -> BTC -> USDT) по кратчайшему пути.
+package valuation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage"
+)
+
+// MaxHops — наибольшее число пар в пути пересчета
+const MaxHops = 3
+
+// divPrecision — точность деления при пересчете через обратную пару
+const divPrecision = 15
+
+// AssetValue — оценка баланса одного актива.
+type AssetValue struct {
+	Asset  string
+	Amount decimal.Decimal // free + locked
+	Price  decimal.Decimal // цена единицы актива в котируемом активе
+	Value  decimal.Decimal // Amount в котируемом активе
+	Route  []string        // символы пар пересчета; пусто для самого котируемого актива
+}
+
+// Valuation — оценка портфеля.
+type Valuation struct {
+	Quote    string
+	Total    decimal.Decimal
+	Assets   []AssetValue // оцененные активы, отсортированы по активу
+	Unvalued []string     // активы без пути пересчета, отсортированы
+}
+
+// step — переход по одной паре.
+type step struct {
+	symbol  string
+	to      string
+	price   decimal.Decimal
+	inverse bool // переход от котируемого актива пары к базовому
+}
+
+func (s step) apply(amount decimal.Decimal) decimal.Decimal {
+	if s.inverse {
+		return amount.DivRound(s.price, divPrecision)
+	}
+	return amount.Mul(s.price)
+}
+
+// Converter пересчитывает активы по набору цен.
+type Converter struct {
+	edges map[string][]step
+}
+
+// NewConverter строит граф пар из цен. Символы с неизвестным котируемым активом
+// и нулевые цены пропускаются; для повторяющихся символов используется последняя цена в списке.
+func NewConverter(prices []*domain.Price) *Converter {
+	latest := make(map[string]decimal.Decimal)
+	for _, p := range prices {
+		latest[p.Symbol] = p.Price
+	}
+
+	c := &Converter{edges: make(map[string][]step)}
+	for symbol, price := range latest {
+		if !price.IsPositive() {
+			continue
+		}
+		base, quote, err := domain.SplitSymbol(symbol)
+		if err != nil {
+			continue
+		}
+		c.edges[base] = append(c.edges[base], step{symbol: symbol, to: quote, price: price})
+		c.edges[quote] = append(c.edges[quote], step{symbol: symbol, to: base, price: price, inverse: true})
+	}
+	for _, steps := range c.edges {
+		sort.Slice(steps, func(i, j int) bool {
+			if steps[i].to != steps[j].to {
+				return steps[i].to < steps[j].to
+			}
+			return !steps[i].inverse && steps[j].inverse
+		})
+	}
+
+	return c
+}
+
+// Convert пересчитывает amount актива asset в quote. Возвращает ok=false, если пути нет.
+func (c *Converter) Convert(amount decimal.Decimal, asset, quote string) (value decimal.Decimal, route []string, ok bool) {
+	path, ok := c.path(asset, quote)
+	if !ok {
+		return decimal.Zero, nil, false
+	}
+
+	value = amount
+	for _, s := range path {
+		value = s.apply(value)
+		route = append(route, s.symbol)
+	}
+	return value, route, true
+}
+
+// path ищет кратчайший путь обходом в ширину.
+func (c *Converter) path(from, to string) ([]step, bool) {
+	if from == to {
+		return nil, true
+	}
+
+	type node struct {
+		asset string
+		path  []step
+	}
+	visited := map[string]bool{from: true}
+	queue := []node{{asset: from}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if len(current.path) == MaxHops {
+			continue
+		}
+
+		for _, s := range c.edges[current.asset] {
+			if visited[s.to] {
+				continue
+			}
+			path := append(append([]step(nil), current.path...), s)
+			if s.to == to {
+				return path, true
+			}
+			visited[s.to] = true
+			queue = append(queue, node{asset: s.to, path: path})
+		}
+	}
+
+	return nil, false
+}
+
+// Value оценивает балансы в котируемом активе quote по ценам prices.
+// Нулевые балансы пропускаются.
+func Value(balances []*domain.UserBalance, prices []*domain.Price, quote string) *Valuation {
+	converter := NewConverter(prices)
+	valuation := &Valuation{Quote: quote}
+
+	amounts := make(map[string]decimal.Decimal)
+	for _, b := range balances {
+		amounts[b.Asset] = amounts[b.Asset].Add(b.Free).Add(b.Locked)
+	}
+	assets := make([]string, 0, len(amounts))
+	for asset := range amounts {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	for _, asset := range assets {
+		amount := amounts[asset]
+		if amount.IsZero() {
+			continue
+		}
+
+		price, route, ok := converter.Convert(decimal.NewFromInt(1), asset, quote)
+		if !ok {
+			valuation.Unvalued = append(valuation.Unvalued, asset)
+			continue
+		}
+		value, _, _ := converter.Convert(amount, asset, quote)
+
+		valuation.Assets = append(valuation.Assets, AssetValue{
+			Asset:  asset,
+			Amount: amount,
+			Price:  price,
+			Value:  value,
+			Route:  route,
+		})
+		valuation.Total = valuation.Total.Add(value)
+	}
+
+	return valuation
+}
+
+// Service оценивает портфель пользователя по данным хранилища.
+type Service struct {
+	balances storage.BalanceStorage
+	prices   storage.PriceStorage
+}
+
+// NewService создает сервис оценки.
+func NewService(balances storage.BalanceStorage, prices storage.PriceStorage) *Service {
+	return &Service{balances: balances, prices: prices}
+}
+
+// ValueUser оценивает текущие балансы пользователя по последним ценам.
+func (s *Service) ValueUser(ctx context.Context, userID uint64, quote string) (*Valuation, error) {
+	balances, err := s.balances.GetUserBalances(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load balances: %w", err)
+	}
+
+	prices, err := s.prices.GetLatestPrices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prices: %w", err)
+	}
+
+	return Value(balances, prices, quote), nil
+}
+
+// ValueUserAt оценивает балансы пользователя на момент t по ценам на тот же момент.
+func (s *Service) ValueUserAt(ctx context.Context, userID uint64, quote string, t time.Time) (*Valuation, error) {
+	snapshots, err := s.balances.GetBalancesAt(ctx, userID, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load balances: %w", err)
+	}
+
+	prices, err := s.prices.GetPricesAt(ctx, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prices: %w", err)
+	}
+
+	balances := make([]*domain.UserBalance, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		balances = append(balances, &domain.UserBalance{
+			UserID: snapshot.UserID,
+			Asset:  snapshot.Asset,
+			Free:   snapshot.Free,
+			Locked: snapshot.Locked,
+		})
+	}
+
+	return Value(balances, prices, quote), nil
+}
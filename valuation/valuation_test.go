@@ -0,0 +1,133 @@
+package valuation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/samar/sup_bot/metacore/domain"
+	"github.com/samar/sup_bot/metacore/storage/memstore"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func requireDecimal(t *testing.T, expected string, actual decimal.Decimal) {
+	t.Helper()
+	require.True(t, dec(expected).Equal(actual), "expected %s, got %s", expected, actual)
+}
+
+func price(symbol, value string) *domain.Price {
+	return &domain.Price{Symbol: symbol, Price: dec(value), Source: "test", ObservedAt: time.Now()}
+}
+
+func balance(asset, free, locked string) *domain.UserBalance {
+	return &domain.UserBalance{Asset: asset, Free: dec(free), Locked: dec(locked)}
+}
+
+func TestValue(t *testing.T) {
+	prices := []*domain.Price{
+		price("BTCUSDT", "50000"),
+		price("ETHBTC", "0.05"),
+		price("XYZBTC", "0.0001"),
+		price("MXUSDT", "2"),
+	}
+
+	t.Run("direct, routed and inverse pairs", func(t *testing.T) {
+		v := Value([]*domain.UserBalance{
+			balance("BTC", "0.5", "0.5"),
+			balance("XYZ", "100", "0"),
+			balance("USDT", "10", "0"),
+			balance("ETH", "2", "0"),
+		}, prices, "USDT")
+
+		assert.Equal(t, "USDT", v.Quote)
+		assert.Empty(t, v.Unvalued)
+		require.Len(t, v.Assets, 4)
+
+		btc := v.Assets[0]
+		assert.Equal(t, "BTC", btc.Asset)
+		requireDecimal(t, "1", btc.Amount)
+		requireDecimal(t, "50000", btc.Value)
+		assert.Equal(t, []string{"BTCUSDT"}, btc.Route)
+
+		eth := v.Assets[1]
+		requireDecimal(t, "2500", eth.Price)
+		requireDecimal(t, "5000", eth.Value)
+
+		usdt := v.Assets[2]
+		requireDecimal(t, "1", usdt.Price)
+		assert.Empty(t, usdt.Route)
+
+		xyz := v.Assets[3]
+		assert.Equal(t, []string{"XYZBTC", "BTCUSDT"}, xyz.Route)
+		requireDecimal(t, "500", xyz.Value)
+
+		requireDecimal(t, "55510", v.Total)
+	})
+
+	t.Run("valued in base asset of a pair", func(t *testing.T) {
+		v := Value([]*domain.UserBalance{balance("USDT", "25000", "0"), balance("MX", "5", "0")}, prices, "BTC")
+		require.Len(t, v.Assets, 2)
+		assert.Equal(t, []string{"MXUSDT", "BTCUSDT"}, v.Assets[0].Route)
+		requireDecimal(t, "0.0002", v.Assets[0].Value)
+		requireDecimal(t, "0.5", v.Assets[1].Value)
+	})
+
+	t.Run("unvalued assets are reported", func(t *testing.T) {
+		v := Value([]*domain.UserBalance{
+			balance("BTC", "1", "0"),
+			balance("ABC", "1", "0"),
+			balance("DUST", "0", "0"),
+		}, append(prices, price("ABCDEF", "1"), price("ZEROUSDT", "0")), "USDT")
+		assert.Equal(t, []string{"ABC"}, v.Unvalued)
+		require.Len(t, v.Assets, 1)
+		requireDecimal(t, "50000", v.Total)
+	})
+
+	t.Run("routes are limited", func(t *testing.T) {
+		chain := []*domain.Price{price("AUSDT", "1"), price("BUSDT", "1")}
+		c := NewConverter(chain)
+		_, route, ok := c.Convert(dec("1"), "A", "B")
+		require.True(t, ok)
+		assert.Equal(t, []string{"AUSDT", "BUSDT"}, route)
+
+		// A -> USDT -> BTC -> ETH -> QQQ требует четыре пары
+		c = NewConverter(append(append(prices, chain...), price("QQQETH", "1")))
+		_, _, ok = c.Convert(dec("1"), "A", "QQQ")
+		assert.False(t, ok)
+	})
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	st := memstore.New()
+
+	user := &domain.User{MexcUID: "uid"}
+	require.NoError(t, st.CreateUser(ctx, user))
+
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, st.SavePrices(ctx, []*domain.Price{
+		{Symbol: "BTCUSDT", Price: dec("40000"), Source: "test", ObservedAt: past},
+	}))
+	require.NoError(t, st.UpdateUserBalances(ctx, user.ID, []*domain.UserBalance{balance("BTC", "1", "0")}))
+	// Цена, наблюденная позже момента оценки
+	require.NoError(t, st.SavePrices(ctx, []*domain.Price{
+		{Symbol: "BTCUSDT", Price: dec("50000"), Source: "test", ObservedAt: time.Now().Add(time.Hour)},
+	}))
+
+	service := NewService(st, st)
+
+	v, err := service.ValueUser(ctx, user.ID, "USDT")
+	require.NoError(t, err)
+	requireDecimal(t, "50000", v.Total)
+
+	v, err = service.ValueUserAt(ctx, user.ID, "USDT", time.Now())
+	require.NoError(t, err)
+	requireDecimal(t, "40000", v.Total)
+}